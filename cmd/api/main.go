@@ -33,6 +33,9 @@ func gracefulShutdown(fiberServer *server.FiberServer, done chan bool) {
 		log.Printf("Server forced to shutdown with error: %v", err)
 	}
 
+	// Let the background workers finish their queued work
+	fiberServer.Close()
+
 	log.Println("Server exiting")
 
 	// Notify the main goroutine that the shutdown is complete
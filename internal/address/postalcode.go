@@ -0,0 +1,65 @@
+// Package address validates postal addresses.
+package address
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidPostalCode is returned when a postal code does not match the
+// format used in its country.
+var ErrInvalidPostalCode = errors.New("invalid postal code for country")
+
+// postalCodeFormats holds the postal code format of each country, keyed by
+// ISO 3166-1 alpha-2 code. Codes are matched after normalization.
+var postalCodeFormats = map[string]*regexp.Regexp{
+	"CA": regexp.MustCompile(`^[A-Z]\d[A-Z] \d[A-Z]\d$`),
+	"DE": regexp.MustCompile(`^\d{5}$`),
+	"ES": regexp.MustCompile(`^(0[1-9]|[1-4]\d|5[0-2])\d{3}$`),
+	"FR": regexp.MustCompile(`^\d{5}$`),
+	"GB": regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? \d[A-Z]{2}$`),
+	"IT": regexp.MustCompile(`^\d{5}$`),
+	"JP": regexp.MustCompile(`^\d{3}-\d{4}$`),
+	"NL": regexp.MustCompile(`^[1-9]\d{3} [A-Z]{2}$`),
+	"US": regexp.MustCompile(`^\d{5}(-\d{4})?$`),
+}
+
+// fallbackFormat accepts postal codes of countries without a known format.
+var fallbackFormat = regexp.MustCompile(`^[A-Z\d][A-Z\d -]{0,18}[A-Z\d]$|^[A-Z\d]$`)
+
+// NormalizePostalCode validates a postal code for the given country and
+// returns it in its canonical form: upper case, with the separator the
+// country uses.
+func NormalizePostalCode(countryCode string, postalCode string) (string, error) {
+	countryCode = strings.ToUpper(strings.TrimSpace(countryCode))
+	code := strings.ToUpper(strings.Join(strings.Fields(postalCode), " "))
+
+	switch countryCode {
+	case "CA", "GB", "NL":
+		// The space before the last three (two for NL) characters is often
+		// left out.
+		code = strings.ReplaceAll(code, " ", "")
+		split := 3
+		if countryCode == "NL" {
+			split = 2
+		}
+		if len(code) > split {
+			code = code[:len(code)-split] + " " + code[len(code)-split:]
+		}
+	case "JP":
+		code = strings.ReplaceAll(code, " ", "")
+		if len(code) == 7 && !strings.Contains(code, "-") {
+			code = code[:3] + "-" + code[3:]
+		}
+	}
+
+	format, ok := postalCodeFormats[countryCode]
+	if !ok {
+		format = fallbackFormat
+	}
+	if !format.MatchString(code) {
+		return "", ErrInvalidPostalCode
+	}
+	return code, nil
+}
@@ -0,0 +1,44 @@
+package address
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalizePostalCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		code    string
+		want    string
+		err     error
+	}{
+		{"germany", "DE", "10115", "10115", nil},
+		{"germany too short", "DE", "1011", "", ErrInvalidPostalCode},
+		{"france", "FR", " 75006 ", "75006", nil},
+		{"united states zip+4", "US", "10001-1234", "10001-1234", nil},
+		{"united states letters", "US", "ABCDE", "", ErrInvalidPostalCode},
+		{"united kingdom lower case", "GB", "ec1v 7jn", "EC1V 7JN", nil},
+		{"united kingdom without space", "GB", "SW1A1AA", "SW1A 1AA", nil},
+		{"united kingdom invalid", "GB", "12345", "", ErrInvalidPostalCode},
+		{"netherlands", "NL", "1012ab", "1012 AB", nil},
+		{"canada", "CA", "k1a0b1", "K1A 0B1", nil},
+		{"japan without dash", "JP", "1000001", "100-0001", nil},
+		{"spain invalid province", "ES", "99001", "", ErrInvalidPostalCode},
+		{"italy", "it", "00184", "00184", nil},
+		{"unknown country", "BE", "1000", "1000", nil},
+		{"unknown country empty", "BE", " ", "", ErrInvalidPostalCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizePostalCode(tt.country, tt.code)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package auction implements proxy bidding. Bidders submit the most they are
+// willing to pay and the auction bids on their behalf, one increment at a
+// time, up to that maximum.
+package auction
+
+import (
+	"errors"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+var (
+	// ErrBidTooLow is returned when a bid is below the minimum bid, or when
+	// the leading bidder does not raise their maximum.
+	ErrBidTooLow = errors.New("bid is too low")
+
+	// ErrEnded is returned for bids placed after the auction ended.
+	ErrEnded = errors.New("auction has ended")
+)
+
+// A bid placed less than SnipeWindow before the end extends the auction to
+// Extension after the bid, so that other bidders can still respond.
+const (
+	SnipeWindow = 2 * time.Minute
+	Extension   = 2 * time.Minute
+)
+
+// Auction is the bidding state of an auction.
+type Auction struct {
+	StartPrice currency.Money
+	// Reserve is the lowest price the seller sells at, if any. It is not
+	// shown to bidders.
+	Reserve   *currency.Money
+	Increment currency.Money
+	// Price is the current price: one increment above the runner-up's
+	// maximum, capped at the leader's maximum.
+	Price currency.Money
+	// Leader is the user ID of the leading bidder, or 0 without bids.
+	Leader int
+	// MaxBid is the leader's maximum. It is not shown to other bidders.
+	MaxBid currency.Money
+	EndsAt time.Time
+}
+
+// DefaultIncrement returns the bid increment used when the seller does not
+// choose one: 5% of the start price, and at least one minor unit.
+func DefaultIncrement(startPrice currency.Money) currency.Money {
+	increment := startPrice.Percent(500)
+	if increment.Units < 1 {
+		increment.Units = 1
+	}
+	return increment
+}
+
+// MinimumBid returns the lowest maximum another bidder may submit.
+func (a Auction) MinimumBid() currency.Money {
+	if a.Leader == 0 {
+		return a.StartPrice
+	}
+	return currency.New(a.Price.Units+a.Increment.Units, a.Price.Currency)
+}
+
+// ReserveMet reports whether the auction has a bid at or above the reserve.
+func (a Auction) ReserveMet() bool {
+	return a.Leader != 0 && (a.Reserve == nil || a.Price.Units >= a.Reserve.Units)
+}
+
+// Bid places a bid of bidder with the given maximum at time now and returns
+// the new state. A tie goes to the earlier bid.
+func (a Auction) Bid(bidder int, maximum currency.Money, now time.Time) (Auction, error) {
+	if maximum.Currency != a.StartPrice.Currency {
+		return a, currency.ErrCurrencyMismatch
+	}
+	if !now.Before(a.EndsAt) {
+		return a, ErrEnded
+	}
+
+	switch {
+	case a.Leader == 0:
+		if maximum.Units < a.StartPrice.Units {
+			return a, ErrBidTooLow
+		}
+		a.Leader, a.MaxBid, a.Price = bidder, maximum, a.StartPrice
+	case bidder == a.Leader:
+		if maximum.Units <= a.MaxBid.Units {
+			return a, ErrBidTooLow
+		}
+		a.MaxBid = maximum
+	default:
+		if maximum.Units < a.MinimumBid().Units {
+			return a, ErrBidTooLow
+		}
+		if maximum.Units > a.MaxBid.Units {
+			a.Price = currency.New(min(maximum.Units, a.MaxBid.Units+a.Increment.Units), maximum.Currency)
+			a.Leader, a.MaxBid = bidder, maximum
+		} else {
+			a.Price = currency.New(min(a.MaxBid.Units, maximum.Units+a.Increment.Units), maximum.Currency)
+		}
+	}
+
+	// A leader whose maximum covers the reserve bids up to it right away.
+	if a.Reserve != nil && a.MaxBid.Units >= a.Reserve.Units && a.Price.Units < a.Reserve.Units {
+		a.Price = *a.Reserve
+	}
+
+	if a.EndsAt.Sub(now) < SnipeWindow {
+		a.EndsAt = now.Add(Extension)
+	}
+	return a, nil
+}
@@ -0,0 +1,111 @@
+package auction
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+func eur(units int64) currency.Money {
+	return currency.New(units, "EUR")
+}
+
+type bid struct {
+	bidder  int
+	maximum currency.Money
+}
+
+func TestBid(t *testing.T) {
+	now := time.Date(2025, time.November, 13, 12, 0, 0, 0, time.UTC)
+	reserve := eur(5000)
+
+	tests := []struct {
+		name       string
+		reserve    *currency.Money
+		bids       []bid
+		err        error
+		leader     int
+		price      currency.Money
+		reserveMet bool
+	}{
+		{"first bid opens at the start price", nil, []bid{{1, eur(3000)}}, nil, 1, eur(1000), true},
+		{"first bid below start price", nil, []bid{{1, eur(900)}}, ErrBidTooLow, 0, currency.Money{}, false},
+		{"higher maximum takes the lead one increment above", nil, []bid{{1, eur(3000)}, {2, eur(4000)}}, nil, 2, eur(3100), true},
+		{"lower maximum raises the leader's price", nil, []bid{{1, eur(3000)}, {2, eur(2000)}}, nil, 1, eur(2100), true},
+		{"price is capped at the leader's maximum", nil, []bid{{1, eur(3000)}, {2, eur(2950)}}, nil, 1, eur(3000), true},
+		{"tie goes to the earlier bid", nil, []bid{{1, eur(3000)}, {2, eur(3000)}}, nil, 1, eur(3000), true},
+		{"new leader is capped at their maximum", nil, []bid{{1, eur(3000)}, {2, eur(3050)}}, nil, 2, eur(3050), true},
+		{"bid below the minimum", nil, []bid{{1, eur(3000)}, {2, eur(2000)}, {3, eur(2150)}}, ErrBidTooLow, 1, eur(2100), true},
+		{"leader raises their maximum", nil, []bid{{1, eur(3000)}, {2, eur(2000)}, {1, eur(6000)}}, nil, 1, eur(2100), true},
+		{"leader must raise their maximum", nil, []bid{{1, eur(3000)}, {1, eur(3000)}}, ErrBidTooLow, 1, eur(1000), true},
+		{"reserve not met", &reserve, []bid{{1, eur(4000)}}, nil, 1, eur(1000), false},
+		{"maximum covering the reserve bids up to it", &reserve, []bid{{1, eur(8000)}}, nil, 1, eur(5000), true},
+		{"reserve met by a later bid", &reserve, []bid{{1, eur(4000)}, {2, eur(7000)}}, nil, 2, eur(5000), true},
+		{"other currency", nil, []bid{{1, currency.New(3000, "USD")}}, currency.ErrCurrencyMismatch, 0, currency.Money{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := Auction{StartPrice: eur(1000), Reserve: tt.reserve, Increment: eur(100), EndsAt: now.Add(time.Hour)}
+			var err error
+			for _, b := range tt.bids {
+				if a, err = a.Bid(b.bidder, b.maximum, now); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if a.Leader != tt.leader {
+				t.Errorf("expected leader %d; got %d", tt.leader, a.Leader)
+			}
+			if a.Price != tt.price {
+				t.Errorf("expected price %v; got %v", tt.price, a.Price)
+			}
+			if a.ReserveMet() != tt.reserveMet {
+				t.Errorf("expected reserve met %v; got %v", tt.reserveMet, a.ReserveMet())
+			}
+		})
+	}
+}
+
+func TestBidExtendsAuctionNearTheEnd(t *testing.T) {
+	now := time.Date(2025, time.November, 13, 12, 0, 0, 0, time.UTC)
+	a := Auction{StartPrice: eur(1000), Increment: eur(100), EndsAt: now.Add(30 * time.Second)}
+
+	a, err := a.Bid(1, eur(2000), now)
+	if err != nil {
+		t.Fatalf("bid: %v", err)
+	}
+	if want := now.Add(Extension); !a.EndsAt.Equal(want) {
+		t.Errorf("expected the auction to end at %v; got %v", want, a.EndsAt)
+	}
+
+	early := Auction{StartPrice: eur(1000), Increment: eur(100), EndsAt: now.Add(time.Hour)}
+	if early, _ = early.Bid(1, eur(2000), now); !early.EndsAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected an early bid not to extend the auction; got %v", early.EndsAt)
+	}
+
+	if _, err := a.Bid(2, eur(3000), a.EndsAt); !errors.Is(err, ErrEnded) {
+		t.Errorf("expected a bid at the end to fail with ErrEnded; got %v", err)
+	}
+}
+
+func TestDefaultIncrement(t *testing.T) {
+	tests := []struct {
+		start currency.Money
+		want  currency.Money
+	}{
+		{eur(10000), eur(500)},
+		{eur(1000), eur(50)},
+		{eur(5), eur(1)},
+		{currency.New(3, "JPY"), currency.New(1, "JPY")},
+	}
+	for _, tt := range tests {
+		if got := DefaultIncrement(tt.start); got != tt.want {
+			t.Errorf("DefaultIncrement(%v) = %v; want %v", tt.start, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// Package currency converts amounts between currencies using exchange rates
+// quoted against the euro.
+package currency
+
+import (
+	"errors"
+	"math"
+	"regexp"
+)
+
+// Base is the currency every rate is quoted against.
+const Base = "EUR"
+
+// ErrUnknownCurrency is returned when no rate exists for a currency.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+var codeFormat = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// zeroDecimal lists the currencies without minor units.
+var zeroDecimal = map[string]bool{
+	"JPY": true,
+	"KRW": true,
+}
+
+// Rates maps currency codes to the number of units worth one euro.
+type Rates map[string]float64
+
+// ValidCode reports whether code looks like an ISO 4217 currency code.
+func ValidCode(code string) bool {
+	return codeFormat.MatchString(code)
+}
+
+// Decimals returns the number of minor unit digits of a currency.
+func Decimals(code string) int {
+	if zeroDecimal[code] {
+		return 0
+	}
+	return 2
+}
+
+// Convert converts an amount to another currency, rounded to the minor units
+// of the target currency. Converted amounts are for display only; orders
+// settle in the seller's currency.
+func (r Rates) Convert(amount Money, to string) (Money, error) {
+	if amount.Currency == to {
+		return amount, nil
+	}
+
+	from, ok := r[amount.Currency]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+	target, ok := r[to]
+	if !ok {
+		return Money{}, ErrUnknownCurrency
+	}
+
+	value := float64(amount.Units) / math.Pow10(Decimals(amount.Currency)) / from * target
+	return Money{Units: int64(math.Round(value * math.Pow10(Decimals(to)))), Currency: to}, nil
+}
@@ -0,0 +1,54 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	rates := Rates{"EUR": 1, "USD": 1.08, "GBP": 0.85, "JPY": 162.5}
+
+	tests := []struct {
+		name   string
+		amount Money
+		to     string
+		want   Money
+		err    error
+	}{
+		{"same currency", New(1250, "EUR"), "EUR", New(1250, "EUR"), nil},
+		{"from base", New(1000, "EUR"), "USD", New(1080, "USD"), nil},
+		{"to base", New(1080, "USD"), "EUR", New(1000, "EUR"), nil},
+		{"cross rate", New(850, "GBP"), "USD", New(1080, "USD"), nil},
+		{"zero decimal target", New(199, "EUR"), "JPY", New(323, "JPY"), nil},
+		{"zero decimal source", New(1625, "JPY"), "EUR", New(1000, "EUR"), nil},
+		{"unknown source", New(100, "CHF"), "EUR", Money{}, ErrUnknownCurrency},
+		{"unknown target", New(100, "EUR"), "CHF", Money{}, ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.amount, tt.to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFileSourceRebasesToEuro(t *testing.T) {
+	rates, err := FileSource{Path: "testdata/usd_rates.json"}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("error reading rates. Err: %v", err)
+	}
+
+	want := Rates{"EUR": 1, "USD": 1.25, "JPY": 187.5}
+	for code, rate := range want {
+		if got := rates[code]; got != rate {
+			t.Errorf("expected %s rate %v; got %v", code, rate, got)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package currency
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidAmount is returned when an amount is not a decimal number or
+	// has more decimals than its currency allows.
+	ErrInvalidAmount = errors.New("invalid amount")
+
+	// ErrCurrencyMismatch is returned when amounts in different currencies
+	// are combined.
+	ErrCurrencyMismatch = errors.New("currency mismatch")
+)
+
+// maxDigits bounds the integer part of parsed amounts so that minor units
+// always fit in an int64.
+const maxDigits = 15
+
+// Money is an exact amount of money, counted in the minor units of its
+// currency (cents for EUR, yen for JPY).
+type Money struct {
+	Units    int64
+	Currency string
+}
+
+// New returns an amount of units minor units of a currency.
+func New(units int64, code string) Money {
+	return Money{Units: units, Currency: code}
+}
+
+// Parse parses a decimal amount such as "12.50" in a currency. Trailing zero
+// decimals beyond the currency's minor units are accepted, so DECIMAL(10, 2)
+// columns parse for currencies without minor units.
+func Parse(amount string, code string) (Money, error) {
+	s := strings.TrimSpace(amount)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, fraction, _ := strings.Cut(s, ".")
+	if whole == "" || len(whole) > maxDigits || !digitsOnly(whole) || !digitsOnly(fraction) {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+
+	decimals := Decimals(code)
+	if len(fraction) > decimals {
+		if strings.Trim(fraction[decimals:], "0") != "" {
+			return Money{}, fmt.Errorf("%w: %q has more than %d decimals", ErrInvalidAmount, amount, decimals)
+		}
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	units, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("%w: %q", ErrInvalidAmount, amount)
+	}
+	if negative {
+		units = -units
+	}
+	return Money{Units: units, Currency: code}, nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Decimal formats the amount without its currency, e.g. "12.50".
+func (m Money) Decimal() string {
+	units := m.Units
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+
+	decimals := Decimals(m.Currency)
+	if decimals == 0 {
+		return sign + strconv.FormatInt(units, 10)
+	}
+	digits := fmt.Sprintf("%0*d", decimals+1, units)
+	return sign + digits[:len(digits)-decimals] + "." + digits[len(digits)-decimals:]
+}
+
+// String formats the amount with its currency, e.g. "12.50 EUR".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsZero reports whether the amount is zero.
+func (m Money) IsZero() bool {
+	return m.Units == 0
+}
+
+// IsPositive reports whether the amount is greater than zero.
+func (m Money) IsPositive() bool {
+	return m.Units > 0
+}
+
+// Add returns m + o.
+func (m Money) Add(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Units: m.Units + o.Units, Currency: m.Currency}, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) (Money, error) {
+	if m.Currency != o.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Units: m.Units - o.Units, Currency: m.Currency}, nil
+}
+
+// Mul returns the amount multiplied by a quantity.
+func (m Money) Mul(quantity int) Money {
+	return Money{Units: m.Units * int64(quantity), Currency: m.Currency}
+}
+
+// Percent returns the given share of the amount in basis points (1/100 of a
+// percent), rounded half away from zero to the minor unit. It is meant for
+// fees and taxes: the remainder is found with Sub so the parts always add up
+// to the original amount.
+func (m Money) Percent(basisPoints int64) Money {
+	n := new(big.Int).Mul(big.NewInt(m.Units), big.NewInt(basisPoints))
+	q, r := new(big.Int).QuoRem(n, big.NewInt(10_000), new(big.Int))
+	if new(big.Int).Abs(r).Cmp(big.NewInt(5_000)) >= 0 {
+		q.Add(q, big.NewInt(int64(n.Sign())))
+	}
+	return Money{Units: q.Int64(), Currency: m.Currency}
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as {"amount": "12.50", "currency": "EUR"}.
+// The amount is a string so clients do not lose precision. Amounts without a
+// currency encode as null.
+func (m Money) MarshalJSON() ([]byte, error) {
+	if m.Currency == "" {
+		return []byte("null"), nil
+	}
+	amount, err := json.Marshal(m.Decimal())
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(moneyJSON{Amount: amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes an amount encoded by MarshalJSON. The amount may also
+// be given as a JSON number; it is parsed as a decimal, never as a float.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	code := strings.ToUpper(v.Currency)
+	if !ValidCode(code) {
+		return fmt.Errorf("%w: %q", ErrUnknownCurrency, v.Currency)
+	}
+
+	amount := string(v.Amount)
+	if unquoted, err := strconv.Unquote(amount); err == nil {
+		amount = unquoted
+	}
+	parsed, err := Parse(amount, code)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount in a DECIMAL column.
+func (m Money) Value() (driver.Value, error) {
+	return m.Decimal(), nil
+}
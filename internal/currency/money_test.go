@@ -0,0 +1,116 @@
+package currency
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"testing/quick"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount string
+		code   string
+		want   Money
+		err    error
+	}{
+		{"12.50", "EUR", New(1250, "EUR"), nil},
+		{"12.5", "EUR", New(1250, "EUR"), nil},
+		{"12", "EUR", New(1200, "EUR"), nil},
+		{"0.07", "EUR", New(7, "EUR"), nil},
+		{"-3.10", "EUR", New(-310, "EUR"), nil},
+		{"1625", "JPY", New(1625, "JPY"), nil},
+		{"1625.00", "JPY", New(1625, "JPY"), nil},
+		{"1625.50", "JPY", Money{}, ErrInvalidAmount},
+		{"0.001", "EUR", Money{}, ErrInvalidAmount},
+		{"12.500", "EUR", New(1250, "EUR"), nil},
+		{"1e3", "EUR", Money{}, ErrInvalidAmount},
+		{".50", "EUR", Money{}, ErrInvalidAmount},
+		{"", "EUR", Money{}, ErrInvalidAmount},
+		{"9999999999999999", "EUR", Money{}, ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.code, func(t *testing.T) {
+			got, err := Parse(tt.amount, tt.code)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %v; got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{New(1250, "EUR"), "12.50 EUR"},
+		{New(7, "EUR"), "0.07 EUR"},
+		{New(-310, "GBP"), "-3.10 GBP"},
+		{New(1625, "JPY"), "1625 JPY"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("expected %q; got %q", tt.want, got)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(New(1250, "EUR"))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `{"amount":"12.50","currency":"EUR"}` {
+		t.Errorf("unexpected encoding %s", data)
+	}
+
+	var m Money
+	if err := json.Unmarshal([]byte(`{"amount":0.1,"currency":"usd"}`), &m); err != nil {
+		t.Fatalf("unmarshal number: %v", err)
+	}
+	if m != New(10, "USD") {
+		t.Errorf("expected 0.10 USD; got %v", m)
+	}
+
+	if err := json.Unmarshal([]byte(`{"amount":"1.005","currency":"EUR"}`), &m); !errors.Is(err, ErrInvalidAmount) {
+		t.Errorf("expected invalid amount; got %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"amount":"1.00"}`), &m); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("expected missing currency to fail; got %v", err)
+	}
+}
+
+func TestAddRejectsMixedCurrencies(t *testing.T) {
+	if _, err := New(100, "EUR").Add(New(100, "USD")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch; got %v", err)
+	}
+}
+
+var codes = []string{"EUR", "USD", "JPY"}
+
+func TestPropertyParseRoundTrip(t *testing.T) {
+	property := func(units int64, pick uint8) bool {
+		m := New(units%1_000_000_000_000_000, codes[int(pick)%len(codes)])
+
+		parsed, err := Parse(m.Decimal(), m.Currency)
+		if err != nil || parsed != m {
+			return false
+		}
+
+		data, err := json.Marshal(m)
+		if err != nil {
+			return false
+		}
+		var decoded Money
+		return json.Unmarshal(data, &decoded) == nil && decoded == m
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
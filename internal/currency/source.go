@@ -0,0 +1,82 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Source provides current exchange rates.
+type Source interface {
+	// Name identifies the source in the stored rates.
+	Name() string
+	Fetch(ctx context.Context) (Rates, error)
+}
+
+// FileSource reads rates from a JSON file so rates can be maintained without
+// network access:
+//
+//	{"base": "EUR", "rates": {"USD": 1.08, "GBP": 0.85}}
+//
+// Rates quoted against another base are converted to the euro.
+type FileSource struct {
+	Path string
+}
+
+type rateFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+func (s FileSource) Name() string {
+	return "file"
+}
+
+func (s FileSource) Fetch(ctx context.Context) (Rates, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file rateFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", s.Path, err)
+	}
+	if file.Base == "" {
+		file.Base = Base
+	}
+
+	rates := Rates{file.Base: 1}
+	for code, rate := range file.Rates {
+		if !ValidCode(code) || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %q in %s", code, s.Path)
+		}
+		rates[code] = rate
+	}
+
+	baseRate, ok := rates[Base]
+	if !ok {
+		return nil, fmt.Errorf("%s has no rate for %s", s.Path, Base)
+	}
+	for code, rate := range rates {
+		rates[code] = rate / baseRate
+	}
+	return rates, nil
+}
+
+// NewSourceFromEnv builds the rate source selected by RATES_SOURCE. Only the
+// "file" source (the default) exists; it reads RATES_FILE, by default
+// exchange_rates.json in the working directory.
+func NewSourceFromEnv() (Source, error) {
+	switch source := os.Getenv("RATES_SOURCE"); source {
+	case "", "file":
+		path := os.Getenv("RATES_FILE")
+		if path == "" {
+			path = "exchange_rates.json"
+		}
+		return FileSource{Path: path}, nil
+	default:
+		return nil, fmt.Errorf("unknown RATES_SOURCE %q", source)
+	}
+}
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"cardmarket_backend/internal/address"
+)
+
+var (
+	// ErrUnknownCountry is returned when an address refers to a country that
+	// does not exist.
+	ErrUnknownCountry = errors.New("unknown country")
+	// ErrAddressNotFound is returned at checkout when the buyer has no address
+	// with the given ID, or no default address when none is given.
+	ErrAddressNotFound = errors.New("shipping address not found")
+)
+
+type Address struct {
+	AddressID     int       `json:"address_id"`
+	RecipientName string    `json:"recipient_name"`
+	StreetName    string    `json:"street_name"`
+	StreetNumber  string    `json:"street_number"`
+	City          string    `json:"city"`
+	State         string    `json:"state"`
+	ZipCode       string    `json:"zip_code"`
+	CountryID     int       `json:"country_id"`
+	Country       string    `json:"country"`
+	IsDefault     bool      `json:"is_default"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type AddressRequest struct {
+	RecipientName string `json:"recipient_name"`
+	StreetName    string `json:"street_name"`
+	StreetNumber  string `json:"street_number"`
+	City          string `json:"city"`
+	State         string `json:"state"`
+	ZipCode       string `json:"zip_code"`
+	CountryID     int    `json:"country_id"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// OrderAddress is the copy of the buyer's address stored on an order at
+// checkout.
+type OrderAddress struct {
+	Name         string `json:"name"`
+	StreetName   string `json:"street_name"`
+	StreetNumber string `json:"street_number"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	ZipCode      string `json:"zip_code"`
+	Country      string `json:"country"`
+}
+
+// String formats the address on a single line. It fills the free-text
+// "shipping_address" column kept for older clients.
+func (a OrderAddress) String() string {
+	return fmt.Sprintf("%s, %s %s, %s %s, %s, %s", a.Name, a.StreetName, a.StreetNumber, a.ZipCode, a.City, a.State, a.Country)
+}
+
+// orderAddressColumns scans the nullable address columns of an order.
+type orderAddressColumns struct {
+	name, streetName, streetNumber, city, state, zipCode, country sql.NullString
+}
+
+func (c *orderAddressColumns) dest() []any {
+	return []any{&c.name, &c.streetName, &c.streetNumber, &c.city, &c.state, &c.zipCode, &c.country}
+}
+
+// address returns nil for orders placed before addresses were stored
+// structurally.
+func (c *orderAddressColumns) address() *OrderAddress {
+	if !c.name.Valid {
+		return nil
+	}
+	return &OrderAddress{
+		Name:         c.name.String,
+		StreetName:   c.streetName.String,
+		StreetNumber: c.streetNumber.String,
+		City:         c.city.String,
+		State:        c.state.String,
+		ZipCode:      c.zipCode.String,
+		Country:      c.country.String,
+	}
+}
+
+const addressSelect = `SELECT a.address_id, a.recipient_name, a.street_name, a.street_number, a.city, a.state, a.zip_code, a.country_id, c.country_name, a.is_default, a.created_at, a.updated_at FROM addresses a JOIN countries c ON a.country_id = c.country_id`
+
+// ListAddresses returns the address book of a user, default address first.
+func (s *service) ListAddresses(userID int) ([]Address, error) {
+	rows, err := s.db.Query(addressSelect+` WHERE a.user_id = $1 ORDER BY a.is_default DESC, a.created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []Address{}
+	for rows.Next() {
+		var a Address
+		if err := rows.Scan(&a.AddressID, &a.RecipientName, &a.StreetName, &a.StreetNumber, &a.City, &a.State, &a.ZipCode, &a.CountryID, &a.Country, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return addresses, nil
+}
+
+// CreateAddress adds an address to the user's address book. The first address
+// of a user always becomes the default one.
+func (s *service) CreateAddress(userID int, req AddressRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if req.ZipCode, err = normalizeZipCode(tx, req.CountryID, req.ZipCode); err != nil {
+		return 0, err
+	}
+
+	var hasDefault bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM addresses WHERE user_id = $1 AND is_default)`, userID).Scan(&hasDefault); err != nil {
+		return 0, err
+	}
+	if !hasDefault {
+		req.IsDefault = true
+	}
+	if req.IsDefault {
+		if err := clearDefaultAddress(tx, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	var addressID int
+	query := `INSERT INTO addresses (user_id, recipient_name, street_name, street_number, city, state, zip_code, country_id, is_default) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING address_id`
+	err = tx.QueryRow(query, userID, req.RecipientName, req.StreetName, req.StreetNumber, req.City, req.State, req.ZipCode, req.CountryID, req.IsDefault).Scan(&addressID)
+	if err != nil {
+		return 0, err
+	}
+
+	return addressID, tx.Commit()
+}
+
+// UpdateAddress replaces an address of the user. Unsetting the default flag
+// is ignored; another address has to be made the default instead.
+func (s *service) UpdateAddress(addressID int, userID int, req AddressRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if req.ZipCode, err = normalizeZipCode(tx, req.CountryID, req.ZipCode); err != nil {
+		return err
+	}
+
+	if req.IsDefault {
+		if err := clearDefaultAddress(tx, userID); err != nil {
+			return err
+		}
+	}
+
+	query := `UPDATE addresses SET recipient_name = $1, street_name = $2, street_number = $3, city = $4, state = $5, zip_code = $6, country_id = $7, is_default = is_default OR $8, updated_at = CURRENT_TIMESTAMP WHERE address_id = $9 AND user_id = $10`
+	result, err := tx.Exec(query, req.RecipientName, req.StreetName, req.StreetNumber, req.City, req.State, req.ZipCode, req.CountryID, req.IsDefault, addressID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// DeleteAddress removes an address of the user. When the default address is
+// removed, the oldest remaining address becomes the default.
+func (s *service) DeleteAddress(addressID int, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var wasDefault bool
+	err = tx.QueryRow(`DELETE FROM addresses WHERE address_id = $1 AND user_id = $2 RETURNING is_default`, addressID, userID).Scan(&wasDefault)
+	if err != nil {
+		return err
+	}
+
+	if wasDefault {
+		query := `UPDATE addresses SET is_default = TRUE, updated_at = CURRENT_TIMESTAMP WHERE address_id = (SELECT address_id FROM addresses WHERE user_id = $1 ORDER BY created_at, address_id LIMIT 1)`
+		if _, err := tx.Exec(query, userID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// checkoutAddress returns the buyer's address an order ships to and its
+// country. An addressID of 0 selects the buyer's default address.
+func checkoutAddress(q queryer, buyerID int, addressID int) (OrderAddress, int, error) {
+	var (
+		a         OrderAddress
+		countryID int
+	)
+	query := `SELECT a.recipient_name, a.street_name, a.street_number, a.city, a.state, a.zip_code, c.country_name, a.country_id FROM addresses a JOIN countries c ON a.country_id = c.country_id WHERE a.user_id = $1 AND (a.address_id = $2 OR ($2 = 0 AND a.is_default))`
+	err := q.QueryRow(query, buyerID, addressID).Scan(&a.Name, &a.StreetName, &a.StreetNumber, &a.City, &a.State, &a.ZipCode, &a.Country, &countryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return OrderAddress{}, 0, ErrAddressNotFound
+	}
+	if err != nil {
+		return OrderAddress{}, 0, err
+	}
+	return a, countryID, nil
+}
+
+// normalizeZipCode validates the postal code against the format of the
+// country and returns its canonical form.
+func normalizeZipCode(q queryer, countryID int, zipCode string) (string, error) {
+	var countryCode string
+	err := q.QueryRow(`SELECT country_code FROM countries WHERE country_id = $1`, countryID).Scan(&countryCode)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUnknownCountry
+	}
+	if err != nil {
+		return "", err
+	}
+	return address.NormalizePostalCode(countryCode, zipCode)
+}
+
+func clearDefaultAddress(q queryer, userID int) error {
+	_, err := q.Exec(`UPDATE addresses SET is_default = FALSE, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND is_default`, userID)
+	return err
+}
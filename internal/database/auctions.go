@@ -0,0 +1,497 @@
+package database
+
+import (
+	"cardmarket_backend/internal/auction"
+	"cardmarket_backend/internal/currency"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Auction statuses stored in the auctions table.
+const (
+	AuctionStatusActive    = "active"
+	AuctionStatusSold      = "sold"
+	AuctionStatusUnsold    = "unsold"
+	AuctionStatusCancelled = "cancelled"
+	AuctionStatusFailed    = "failed"
+)
+
+// maxAuctionDuration bounds how far ahead an auction may end.
+const maxAuctionDuration = 30 * 24 * time.Hour
+
+var (
+	// ErrInvalidAuction is returned when an auction's prices or end time are
+	// out of range.
+	ErrInvalidAuction = errors.New("invalid auction")
+	// ErrOwnAuction is returned when sellers bid on their own auction.
+	ErrOwnAuction = errors.New("cannot bid on your own auction")
+	// ErrAuctionHasBids is returned when an auction with bids is cancelled.
+	ErrAuctionHasBids = errors.New("auction already has bids")
+)
+
+// Auction is an auction as shown to bidders. The reserve price and the
+// leader's maximum are not disclosed.
+type Auction struct {
+	AuctionID    int            `json:"auction_id"`
+	ProductID    int            `json:"product_id"`
+	SellerID     int            `json:"seller_id"`
+	Seller       string         `json:"seller"`
+	Card         string         `json:"card"`
+	Condition    string         `json:"condition"`
+	Language     string         `json:"language"`
+	StartPrice   currency.Money `json:"start_price"`
+	CurrentPrice currency.Money `json:"current_price"`
+	MinimumBid   currency.Money `json:"minimum_bid"`
+	BidIncrement currency.Money `json:"bid_increment"`
+	BidCount     int            `json:"bid_count"`
+	Leader       *string        `json:"leader,omitempty"`
+	HasReserve   bool           `json:"has_reserve"`
+	ReserveMet   bool           `json:"reserve_met"`
+	EndsAt       time.Time      `json:"ends_at"`
+	Status       string         `json:"status"`
+	OrderID      *int           `json:"order_id,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// AuctionRequest puts one copy of a product up for auction. Without a bid
+// increment, auction.DefaultIncrement applies.
+type AuctionRequest struct {
+	ProductID    int             `json:"product_id"`
+	StartPrice   currency.Money  `json:"start_price"`
+	ReservePrice *currency.Money `json:"reserve_price,omitempty"`
+	BidIncrement *currency.Money `json:"bid_increment,omitempty"`
+	EndsAt       time.Time       `json:"ends_at"`
+}
+
+// BidRequest is a proxy bid: the most the bidder is willing to pay, and how
+// the card is shipped if they win. Without an address, the bidder's default
+// address is used.
+type BidRequest struct {
+	MaxAmount        currency.Money `json:"max_amount"`
+	AddressID        int            `json:"address_id"`
+	ShippingMethodID int            `json:"shipping_method_id"`
+}
+
+// BidResult is the auction after a bid. OutbidID is the bidder who lost the
+// lead to it, or 0.
+type BidResult struct {
+	Auction  Auction
+	OutbidID int
+}
+
+// AuctionResult is an auction closed by CloseEndedAuctions. WinnerID and
+// OrderID are 0 when it ended unsold. Failed auctions had a winner whose
+// order could not be created, so OrderID is 0.
+type AuctionResult struct {
+	AuctionID int
+	ProductID int
+	SellerID  int
+	WinnerID  int
+	OrderID   int
+	Price     currency.Money
+	Failed    bool
+}
+
+const auctionSelect = `SELECT a.auction_id, a.product_id, a.seller_id, sellers.username, COALESCE(c.name, sp.name), p.condition, l.language_name, a.start_price, a.reserve_price, a.bid_increment, a.current_price, a.leader_id, a.max_bid, a.currency, a.ends_at, a.bid_count, a.status, a.order_id, a.created_at, leaders.username
+FROM auctions a
+JOIN products p ON a.product_id = p.product_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+JOIN languages l ON p.language_id = l.language_id
+JOIN users sellers ON a.seller_id = sellers.user_id
+LEFT JOIN users leaders ON a.leader_id = leaders.user_id`
+
+// auctionStateColumns scans the bidding state of an auction.
+type auctionStateColumns struct {
+	startPrice, increment, price string
+	reserve, maxBid              sql.NullString
+	leaderID                     sql.NullInt64
+	code                         string
+	endsAt                       time.Time
+}
+
+func (c *auctionStateColumns) dest() []any {
+	return []any{&c.startPrice, &c.reserve, &c.increment, &c.price, &c.leaderID, &c.maxBid, &c.code, &c.endsAt}
+}
+
+func (c *auctionStateColumns) state() (auction.Auction, error) {
+	var (
+		a   auction.Auction
+		err error
+	)
+	if a.StartPrice, err = currency.Parse(c.startPrice, c.code); err != nil {
+		return a, err
+	}
+	if a.Increment, err = currency.Parse(c.increment, c.code); err != nil {
+		return a, err
+	}
+	if a.Price, err = currency.Parse(c.price, c.code); err != nil {
+		return a, err
+	}
+	if c.reserve.Valid {
+		reserve, err := currency.Parse(c.reserve.String, c.code)
+		if err != nil {
+			return a, err
+		}
+		a.Reserve = &reserve
+	}
+	if c.leaderID.Valid && c.maxBid.Valid {
+		a.Leader = int(c.leaderID.Int64)
+		if a.MaxBid, err = currency.Parse(c.maxBid.String, c.code); err != nil {
+			return a, err
+		}
+	}
+	a.EndsAt = c.endsAt
+	return a, nil
+}
+
+func queryAuctions(q queryer, query string, args ...any) ([]Auction, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	auctions := []Auction{}
+	for rows.Next() {
+		var (
+			a     Auction
+			state auctionStateColumns
+		)
+		dest := []any{&a.AuctionID, &a.ProductID, &a.SellerID, &a.Seller, &a.Card, &a.Condition, &a.Language}
+		dest = append(dest, state.dest()...)
+		dest = append(dest, &a.BidCount, &a.Status, &a.OrderID, &a.CreatedAt, &a.Leader)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		bidding, err := state.state()
+		if err != nil {
+			return nil, err
+		}
+		a.StartPrice, a.CurrentPrice, a.BidIncrement, a.EndsAt = bidding.StartPrice, bidding.Price, bidding.Increment, bidding.EndsAt
+		a.MinimumBid = bidding.MinimumBid()
+		a.HasReserve = bidding.Reserve != nil
+		a.ReserveMet = bidding.ReserveMet()
+		auctions = append(auctions, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return auctions, nil
+}
+
+// ListAuctions returns the running auctions, ending soonest first.
+func (s *service) ListAuctions() ([]Auction, error) {
+	return queryAuctions(s.db, auctionSelect+` WHERE a.status = 'active' ORDER BY a.ends_at, a.auction_id`)
+}
+
+func (s *service) GetAuction(auctionID int) (Auction, error) {
+	auctions, err := queryAuctions(s.db, auctionSelect+` WHERE a.auction_id = $1`, auctionID)
+	if err != nil {
+		return Auction{}, err
+	}
+	if len(auctions) == 0 {
+		return Auction{}, sql.ErrNoRows
+	}
+	return auctions[0], nil
+}
+
+// CreateAuction puts one copy of the seller's product up for auction and
+// takes it out of the product's stock. Prices must be in the product's
+// currency.
+func (s *service) CreateAuction(sellerID int, req AuctionRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	product, err := reserveStock(tx, req.ProductID, sellerID, 1)
+	if err != nil {
+		return 0, err
+	}
+	code := product.price.Currency
+
+	increment := auction.DefaultIncrement(req.StartPrice)
+	if req.BidIncrement != nil {
+		increment = *req.BidIncrement
+	}
+	for _, price := range []*currency.Money{&req.StartPrice, req.ReservePrice, &increment} {
+		if price != nil && price.Currency != code {
+			return 0, currency.ErrCurrencyMismatch
+		}
+	}
+	now := time.Now()
+	switch {
+	case !req.StartPrice.IsPositive(), !increment.IsPositive():
+		return 0, ErrInvalidAuction
+	case req.ReservePrice != nil && req.ReservePrice.Units < req.StartPrice.Units:
+		return 0, ErrInvalidAuction
+	case !req.EndsAt.After(now), req.EndsAt.Sub(now) > maxAuctionDuration:
+		return 0, ErrInvalidAuction
+	}
+
+	var auctionID int
+	query := `INSERT INTO auctions (product_id, seller_id, currency, start_price, reserve_price, bid_increment, current_price, ends_at) VALUES ($1, $2, $3, $4, $5, $6, $4, $7) RETURNING auction_id`
+	err = tx.QueryRow(query, req.ProductID, sellerID, code, req.StartPrice, req.ReservePrice, increment, req.EndsAt).Scan(&auctionID)
+	if err != nil {
+		return 0, err
+	}
+	return auctionID, tx.Commit()
+}
+
+// CancelAuction withdraws an auction without bids and puts the copy back in
+// stock.
+func (s *service) CancelAuction(auctionID int, sellerID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var (
+		productID, bidCount int
+		status              string
+	)
+	err = tx.QueryRow(`SELECT product_id, bid_count, status FROM auctions WHERE auction_id = $1 AND seller_id = $2 FOR UPDATE`, auctionID, sellerID).Scan(&productID, &bidCount, &status)
+	if err != nil {
+		return err
+	}
+	if status != AuctionStatusActive {
+		return auction.ErrEnded
+	}
+	if bidCount > 0 {
+		return ErrAuctionHasBids
+	}
+
+	if err := endAuction(tx, auctionID, AuctionStatusCancelled, nil); err != nil {
+		return err
+	}
+	return restockAuction(tx, productID)
+}
+
+// PlaceBid places a proxy bid. The auction row is locked for the duration so
+// that concurrent bids are applied one after the other. The bidder's address
+// must be served by the chosen shipping method.
+func (s *service) PlaceBid(auctionID int, bidderID int, req BidRequest) (BidResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return BidResult{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		sellerID, originCountryID int
+		status                    string
+		state                     auctionStateColumns
+	)
+	query := `SELECT a.seller_id, sellers.country_id, a.status, a.start_price, a.reserve_price, a.bid_increment, a.current_price, a.leader_id, a.max_bid, a.currency, a.ends_at FROM auctions a JOIN users sellers ON a.seller_id = sellers.user_id WHERE a.auction_id = $1 FOR UPDATE OF a`
+	err = tx.QueryRow(query, auctionID).Scan(append([]any{&sellerID, &originCountryID, &status}, state.dest()...)...)
+	if err != nil {
+		return BidResult{}, err
+	}
+	if status != AuctionStatusActive {
+		return BidResult{}, auction.ErrEnded
+	}
+	if sellerID == bidderID {
+		return BidResult{}, ErrOwnAuction
+	}
+
+	current, err := state.state()
+	if err != nil {
+		return BidResult{}, err
+	}
+	next, err := current.Bid(bidderID, req.MaxAmount, time.Now())
+	if err != nil {
+		return BidResult{}, err
+	}
+
+	_, destCountryID, err := checkoutAddress(tx, bidderID, req.AddressID)
+	if err != nil {
+		return BidResult{}, err
+	}
+	if _, err := quoteOrderShipping(tx, sellerID, req.ShippingMethodID, originCountryID, destCountryID, 1, req.MaxAmount); err != nil {
+		return BidResult{}, err
+	}
+
+	query = `INSERT INTO bids (auction_id, bidder_id, max_amount, address_id, shipping_method_id) VALUES ($1, $2, $3, NULLIF($4, 0), $5)`
+	if _, err := tx.Exec(query, auctionID, bidderID, req.MaxAmount, req.AddressID, req.ShippingMethodID); err != nil {
+		return BidResult{}, err
+	}
+
+	query = `UPDATE auctions SET current_price = $2, leader_id = $3, max_bid = $4, ends_at = $5, bid_count = bid_count + 1, updated_at = CURRENT_TIMESTAMP WHERE auction_id = $1`
+	if _, err := tx.Exec(query, auctionID, next.Price, next.Leader, next.MaxBid, next.EndsAt); err != nil {
+		return BidResult{}, err
+	}
+
+	auctions, err := queryAuctions(tx, auctionSelect+` WHERE a.auction_id = $1`, auctionID)
+	if err != nil {
+		return BidResult{}, err
+	}
+	result := BidResult{Auction: auctions[0]}
+	if current.Leader != 0 && current.Leader != next.Leader {
+		result.OutbidID = current.Leader
+	}
+	return result, tx.Commit()
+}
+
+// CloseEndedAuctions closes the auctions that ended before now. An auction
+// whose reserve is met becomes a pending order of the leader at the final
+// price, shipped to the address of their latest bid; other auctions end
+// unsold and the copy goes back in stock. When the winner's order cannot be
+// created, for instance because the seller stopped shipping to their
+// address, the auction fails and the copy goes back in stock too; the error
+// is returned along with the result. Each auction is closed in its own
+// transaction, so one that fails does not hold up the others.
+func (s *service) CloseEndedAuctions(now time.Time) ([]AuctionResult, error) {
+	rows, err := s.db.Query(`SELECT auction_id FROM auctions WHERE status = 'active' AND ends_at <= $1 ORDER BY ends_at`, now)
+	if err != nil {
+		return nil, err
+	}
+	var auctionIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		auctionIDs = append(auctionIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var (
+		results []AuctionResult
+		errs    []error
+	)
+	for _, id := range auctionIDs {
+		result, closed, err := s.closeAuction(id, now)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if closed {
+			results = append(results, result)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (s *service) closeAuction(auctionID int, now time.Time) (AuctionResult, bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return AuctionResult{}, false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		result          AuctionResult
+		originCountryID int
+		status          string
+		state           auctionStateColumns
+	)
+	query := `SELECT a.product_id, a.seller_id, sellers.country_id, a.status, a.start_price, a.reserve_price, a.bid_increment, a.current_price, a.leader_id, a.max_bid, a.currency, a.ends_at FROM auctions a JOIN users sellers ON a.seller_id = sellers.user_id WHERE a.auction_id = $1 FOR UPDATE OF a`
+	err = tx.QueryRow(query, auctionID).Scan(append([]any{&result.ProductID, &result.SellerID, &originCountryID, &status}, state.dest()...)...)
+	if err != nil {
+		return AuctionResult{}, false, err
+	}
+	// A bid may have extended the auction since it was selected.
+	if status != AuctionStatusActive || state.endsAt.After(now) {
+		return AuctionResult{}, false, nil
+	}
+	final, err := state.state()
+	if err != nil {
+		return AuctionResult{}, false, err
+	}
+	result.AuctionID, result.Price = auctionID, final.Price
+
+	if !final.ReserveMet() {
+		if err := endAuction(tx, auctionID, AuctionStatusUnsold, nil); err != nil {
+			return AuctionResult{}, false, err
+		}
+		if err := restockAuction(tx, result.ProductID); err != nil {
+			return AuctionResult{}, false, err
+		}
+		return result, true, tx.Commit()
+	}
+
+	orderID, err := sellAuction(tx, auctionID, result, final.Leader, final.Price, originCountryID, now)
+	if err != nil {
+		// The failed statement may have aborted the transaction, so the
+		// auction is ended in a new one.
+		tx.Rollback()
+		failed, failErr := s.failAuction(auctionID)
+		if failErr != nil {
+			return AuctionResult{}, false, errors.Join(err, failErr)
+		}
+		result.WinnerID, result.Failed = final.Leader, true
+		return result, failed, fmt.Errorf("auction %d: %w", auctionID, err)
+	}
+	if err := endAuction(tx, auctionID, AuctionStatusSold, &orderID); err != nil {
+		return AuctionResult{}, false, err
+	}
+	result.WinnerID, result.OrderID = final.Leader, orderID
+	return result, true, tx.Commit()
+}
+
+// sellAuction creates the pending order of the winner at the final price,
+// shipped to the address of their latest bid.
+func sellAuction(tx queryer, auctionID int, result AuctionResult, winnerID int, price currency.Money, originCountryID int, now time.Time) (int, error) {
+	order := OrderRequest{
+		BuyerID:   winnerID,
+		SellerID:  result.SellerID,
+		ProductID: result.ProductID,
+		Quantity:  1,
+		OrderDate: now,
+	}
+	var addressID sql.NullInt64
+	query := `SELECT address_id, shipping_method_id FROM bids WHERE auction_id = $1 AND bidder_id = $2 ORDER BY created_at DESC, bid_id DESC LIMIT 1`
+	if err := tx.QueryRow(query, auctionID, winnerID).Scan(&addressID, &order.ShippingMethodID); err != nil {
+		return 0, err
+	}
+	order.AddressID = int(addressID.Int64)
+	return insertOrder(tx, order, price, originCountryID)
+}
+
+// failAuction ends an auction whose winning order could not be created and
+// puts the copy back in stock. It reports false when the auction was closed
+// in the meantime.
+func (s *service) failAuction(auctionID int) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	var (
+		productID int
+		status    string
+	)
+	err = tx.QueryRow(`SELECT product_id, status FROM auctions WHERE auction_id = $1 FOR UPDATE`, auctionID).Scan(&productID, &status)
+	if err != nil {
+		return false, err
+	}
+	if status != AuctionStatusActive {
+		return false, nil
+	}
+	if err := endAuction(tx, auctionID, AuctionStatusFailed, nil); err != nil {
+		return false, err
+	}
+	if err := restockAuction(tx, productID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func endAuction(q queryer, auctionID int, status string, orderID *int) error {
+	_, err := q.Exec(`UPDATE auctions SET status = $2, order_id = $3, updated_at = CURRENT_TIMESTAMP WHERE auction_id = $1`, auctionID, status, orderID)
+	return err
+}
+
+func restockAuction(q queryer, productID int) error {
+	_, err := q.Exec(`UPDATE products SET quantity = quantity + 1, updated_at = CURRENT_TIMESTAMP WHERE product_id = $1`, productID)
+	return err
+}
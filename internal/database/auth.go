@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrTokenUsed is returned when a token was already consumed, has expired or
+	// is unknown.
+	ErrTokenUsed = errors.New("token already used or unknown")
+	// ErrEmailNotVerified is returned when an action requires a verified email.
+	ErrEmailNotVerified = errors.New("email address not verified")
+	// ErrInvalidCredentials is returned when an email and password do not
+	// match a user.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// hashPassword returns the bcrypt hash stored in users.password_hash.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Authenticate returns the user with the given email and password.
+func (s *service) Authenticate(email string, password string) (int, error) {
+	var (
+		userID int
+		hash   string
+	)
+	err := s.db.QueryRow(`SELECT user_id, password_hash FROM users WHERE LOWER(email) = LOWER($1)`, email).Scan(&userID, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrInvalidCredentials
+	}
+	if err != nil {
+		return 0, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return 0, ErrInvalidCredentials
+	}
+	return userID, nil
+}
+
+func (s *service) GetUserIDByEmail(email string) (int, error) {
+	var userID int
+	err := s.db.QueryRow(`SELECT user_id FROM users WHERE LOWER(email) = LOWER($1)`, email).Scan(&userID)
+	return userID, err
+}
+
+// CreateUserToken records an issued token so it can be consumed exactly once.
+func (s *service) CreateUserToken(tokenID string, userID int, purpose string, expiresAt time.Time) error {
+	query := `INSERT INTO user_tokens (token_id, user_id, purpose, expires_at) VALUES ($1, $2, $3, $4)`
+	_, err := s.db.Exec(query, tokenID, userID, purpose, expiresAt)
+	return err
+}
+
+// VerifyEmail consumes an email verification token and marks the user's email
+// as verified.
+func (s *service) VerifyEmail(tokenID string, userID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := consumeUserToken(tx, tokenID, userID, "email_verification"); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET email_verified_at = COALESCE(email_verified_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResetPassword consumes a password reset token and stores the new password.
+// Other outstanding reset tokens of the user are invalidated as well.
+func (s *service) ResetPassword(tokenID string, userID int, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := consumeUserToken(tx, tokenID, userID, "password_reset"); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE users SET password_hash = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2`, hash, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND purpose = 'password_reset' AND used_at IS NULL`, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func consumeUserToken(tx *sql.Tx, tokenID string, userID int, purpose string) error {
+	query := `UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP WHERE token_id = $1 AND user_id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`
+
+	result, err := tx.Exec(query, tokenID, userID, purpose)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrTokenUsed
+	}
+
+	return nil
+}
+
+// IsAdmin reports whether the user may act as an administrator.
+func (s *service) IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	err := s.db.QueryRow(`SELECT is_admin FROM users WHERE user_id = $1`, userID).Scan(&isAdmin)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return isAdmin, err
+}
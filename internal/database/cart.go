@@ -0,0 +1,201 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+type CartItem struct {
+	ProductID int            `json:"product_id"`
+	CardID    *int           `json:"card_id,omitempty"`
+	Card      string         `json:"card"`
+	SellerID  int            `json:"seller_id"`
+	Seller    string         `json:"seller"`
+	Condition string         `json:"condition"`
+	Language  string         `json:"language"`
+	Price     currency.Money `json:"price"`
+	Quantity  int            `json:"quantity"`
+	// Available is the stock of the product left. The cart does not reserve
+	// stock, so it may have dropped below Quantity.
+	Available int       `json:"available"`
+	AddedAt   time.Time `json:"added_at"`
+}
+
+// CartWant asks for copies of a card, in any of the given printings.
+type CartWant struct {
+	CardIDs  []int
+	Quantity int
+}
+
+// CartFill reports how a CartWant was filled: from the user's own products,
+// by adding listings to the cart, or not at all.
+type CartFill struct {
+	Owned   int            `json:"owned"`
+	Added   []CartAddition `json:"added"`
+	Missing int            `json:"missing"`
+}
+
+type CartAddition struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+func (s *service) ListCart(userID int) ([]CartItem, error) {
+	query := `SELECT ci.product_id, p.card_id, COALESCE(c.name, sp.name), p.seller_id, sellers.username, p.condition, l.language_name, p.price, p.currency, ci.quantity, p.quantity, ci.added_at
+FROM cart_items ci
+JOIN products p ON ci.product_id = p.product_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+JOIN languages l ON p.language_id = l.language_id
+JOIN users sellers ON p.seller_id = sellers.user_id
+WHERE ci.user_id = $1
+ORDER BY sellers.username, ci.added_at`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []CartItem{}
+	for rows.Next() {
+		var (
+			item        CartItem
+			price, code string
+		)
+		if err := rows.Scan(&item.ProductID, &item.CardID, &item.Card, &item.SellerID, &item.Seller, &item.Condition, &item.Language, &price, &code, &item.Quantity, &item.Available, &item.AddedAt); err != nil {
+			return nil, err
+		}
+		if item.Price, err = currency.Parse(price, code); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// FillCart adds the cheapest available listings of each wanted card to the
+// user's cart, comparing prices in euros. Stock already in the cart is not
+// counted twice. With excludeOwned, copies among the user's own products are
+// subtracted from the quantities wanted first.
+func (s *service) FillCart(userID int, wants []CartWant, excludeOwned bool) ([]CartFill, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// owned holds the user's copies of each card not yet set against a want.
+	owned := map[int]int{}
+	if excludeOwned {
+		rows, err := tx.Query(`SELECT card_id, SUM(quantity) FROM products WHERE seller_id = $1 AND card_id IS NOT NULL GROUP BY card_id`, userID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var cardID, quantity int
+			if err := rows.Scan(&cardID, &quantity); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			owned[cardID] = quantity
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if err := rows.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	fills := make([]CartFill, len(wants))
+	for i, want := range wants {
+		fill := CartFill{Added: []CartAddition{}}
+		need := want.Quantity
+		for _, cardID := range want.CardIDs {
+			use := min(need, owned[cardID])
+			owned[cardID] -= use
+			fill.Owned += use
+			need -= use
+		}
+
+		if need > 0 {
+			fill.Added, err = addCheapestListings(tx, userID, want.CardIDs, need)
+			if err != nil {
+				return nil, err
+			}
+			for _, added := range fill.Added {
+				need -= added.Quantity
+			}
+		}
+		fill.Missing = need
+		fills[i] = fill
+	}
+	return fills, tx.Commit()
+}
+
+// addCheapestListings adds up to quantity copies of the cards to the cart,
+// from the cheapest listings of other sellers first. Sellers on vacation
+// are skipped.
+func addCheapestListings(tx queryer, userID int, cardIDs []int, quantity int) ([]CartAddition, error) {
+	query := `SELECT p.product_id, p.quantity - COALESCE(ci.quantity, 0)
+FROM products p
+JOIN users sellers ON p.seller_id = sellers.user_id
+LEFT JOIN cart_items ci ON ci.product_id = p.product_id AND ci.user_id = $2
+LEFT JOIN exchange_rates er ON er.currency_code = p.currency
+WHERE p.card_id = ANY($1) AND p.is_available AND NOT sellers.on_vacation AND p.seller_id <> $2 AND p.quantity > COALESCE(ci.quantity, 0)
+ORDER BY p.price / COALESCE(er.rate, 1), p.product_id`
+	rows, err := tx.Query(query, cardIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	additions := []CartAddition{}
+	for rows.Next() && quantity > 0 {
+		var (
+			addition  CartAddition
+			available int
+		)
+		if err := rows.Scan(&addition.ProductID, &available); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		addition.Quantity = min(available, quantity)
+		quantity -= addition.Quantity
+		additions = append(additions, addition)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, addition := range additions {
+		_, err := tx.Exec(`INSERT INTO cart_items (user_id, product_id, quantity) VALUES ($1, $2, $3) ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_items.quantity + EXCLUDED.quantity`, userID, addition.ProductID, addition.Quantity)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return additions, nil
+}
+
+func (s *service) RemoveCartItem(userID int, productID int) error {
+	result, err := s.db.Exec(`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
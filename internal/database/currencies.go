@@ -0,0 +1,75 @@
+package database
+
+import "cardmarket_backend/internal/currency"
+
+// orderAmountColumns receives the DECIMAL amounts of an order as text. They
+// are parsed once the order's currency is known, so no amount ever passes
+// through a float.
+type orderAmountColumns struct {
+	shippingCost, tax, total, refunded, code string
+}
+
+// dest returns the scan destinations for o.shipping_cost, o.tax_amount,
+// o.total_amount, o.refunded_amount and o.currency, in that order.
+func (c *orderAmountColumns) dest() []any {
+	return []any{&c.shippingCost, &c.tax, &c.total, &c.refunded, &c.code}
+}
+
+func (c orderAmountColumns) apply(order *Order) error {
+	var err error
+	if order.ShippingCost, err = currency.Parse(c.shippingCost, c.code); err != nil {
+		return err
+	}
+	if order.TaxAmount, err = currency.Parse(c.tax, c.code); err != nil {
+		return err
+	}
+	if order.Total, err = currency.Parse(c.total, c.code); err != nil {
+		return err
+	}
+	order.RefundedAmount, err = currency.Parse(c.refunded, c.code)
+	return err
+}
+
+// ListExchangeRates returns the stored rates, in units worth one euro.
+func (s *service) ListExchangeRates() (currency.Rates, error) {
+	rows, err := s.db.Query(`SELECT currency_code, rate FROM exchange_rates`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := currency.Rates{}
+	for rows.Next() {
+		var (
+			code string
+			rate float64
+		)
+		if err := rows.Scan(&code, &rate); err != nil {
+			return nil, err
+		}
+		rates[code] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// SaveExchangeRates stores the rates fetched from a source. Currencies
+// missing from the source keep their previous rate.
+func (s *service) SaveExchangeRates(rates currency.Rates, source string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO exchange_rates (currency_code, rate, source, updated_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP) ON CONFLICT (currency_code) DO UPDATE SET rate = EXCLUDED.rate, source = EXCLUDED.source, updated_at = EXCLUDED.updated_at`
+	for code, rate := range rates {
+		if _, err := tx.Exec(query, code, rate, source); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -3,12 +3,18 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
 	"time"
 
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/settlement"
+	"cardmarket_backend/internal/tax"
+	"cardmarket_backend/internal/tracking"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -37,78 +43,116 @@ type CardRequest struct {
 }
 
 type Product struct {
-	ProductID   int       `json:"product_id"`
-	Price       float64   `json:"price"`
-	Condition   string    `json:"condition"`
-	Quantity    int       `json:"quantity"`
-	IsAvailable bool      `json:"is_available"`
-	Seller      string    `json:"seller"`
-	Card        string    `json:"card"`
-	Language    string    `json:"language"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ProductID int            `json:"product_id"`
+	Price     currency.Money `json:"price"`
+	// ConvertedPrice is the price in the currency requested by the client.
+	ConvertedPrice *currency.Money `json:"converted_price,omitempty"`
+	Condition      string          `json:"condition"`
+	Quantity       int             `json:"quantity"`
+	IsAvailable    bool            `json:"is_available"`
+	Seller         string          `json:"seller"`
+	// Card is the name of the listed card or sealed product.
+	Card            string    `json:"card"`
+	CardID          *int      `json:"card_id,omitempty"`
+	SealedProductID *int      `json:"sealed_product_id,omitempty"`
+	Language        string    `json:"language"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// Images are only loaded for a single product.
+	Images []ProductImage `json:"images,omitempty"`
+}
+
+type TCGGame struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
 }
 
 type ProductRequest struct {
-	ProductID   int     `json:"product_id"`
-	Price       float64 `json:"price"`
-	Condition   string  `json:"condition"`
-	Quantity    int     `json:"quantity"`
-	IsAvailable bool    `json:"is_available"`
-	SellerID    int     `json:"seller_id"`
-	CardID      int     `json:"card_id"`
-	LanguageID  int     `json:"language_id"`
+	ProductID   int            `json:"product_id"`
+	Price       currency.Money `json:"price"`
+	Condition   string         `json:"condition"`
+	Quantity    int            `json:"quantity"`
+	IsAvailable bool           `json:"is_available"`
+	// SellerID is the signed in user, never taken from the request body.
+	SellerID int `json:"-"`
+	// A product lists either a card or a sealed product.
+	CardID          int `json:"card_id"`
+	SealedProductID int `json:"sealed_product_id"`
+	LanguageID      int `json:"language_id"`
 }
 
 type Order struct {
-	OrderID         int        `json:"order_id"`
-	Buyer           string     `json:"buyer"`
-	Seller          string     `json:"seller"`
-	Quantity        int        `json:"quantity"`
-	Product         string     `json:"product"`
-	OrderDate       time.Time  `json:"order_date"`
-	ShippingAddress string     `json:"shipping_address"`
-	ShippingCost    float64    `json:"shipping_cost"`
-	Total           float64    `json:"total"`
-	Status          string     `json:"status"`
-	TrackingNumber  *string    `json:"tracking_number,omitempty"`
-	ShippedAt       *time.Time `json:"shipped_at,omitempty"`
-	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
-	CreatedAt       time.Time  `json:"created_at"`
-	UpdatedAt       time.Time  `json:"updated_at"`
+	OrderID         int            `json:"order_id"`
+	Buyer           string         `json:"buyer"`
+	Seller          string         `json:"seller"`
+	Quantity        int            `json:"quantity"`
+	Product         string         `json:"product"`
+	OrderDate       time.Time      `json:"order_date"`
+	ShippingAddress string         `json:"shipping_address"`
+	ShipTo          *OrderAddress  `json:"ship_to,omitempty"`
+	ShippingMethod  *string        `json:"shipping_method,omitempty"`
+	ShippingCost    currency.Money `json:"shipping_cost"`
+	TaxAmount       currency.Money `json:"tax_amount"`
+	// TaxLines is only loaded for a single order.
+	TaxLines       []tax.TaxLine  `json:"tax_lines,omitempty"`
+	Total          currency.Money `json:"total"`
+	RefundedAmount currency.Money `json:"refunded_amount"`
+	// ConvertedShippingCost and ConvertedTotal are the amounts in the
+	// currency requested by the client. The order settles in the currency of
+	// Total.
+	ConvertedShippingCost *currency.Money `json:"converted_shipping_cost,omitempty"`
+	ConvertedTotal        *currency.Money `json:"converted_total,omitempty"`
+	Status                string          `json:"status"`
+	Carrier               *string         `json:"carrier,omitempty"`
+	TrackingNumber        *string         `json:"tracking_number,omitempty"`
+	ShippedAt             *time.Time      `json:"shipped_at,omitempty"`
+	DeliveredAt           *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt             time.Time       `json:"created_at"`
+	UpdatedAt             time.Time       `json:"updated_at"`
 }
 
 type OrderRequest struct {
-	BuyerID         int        `json:"buyer_id"`
-	SellerID        int        `json:"seller_id"`
-	Quantity        int        `json:"quantity"`
-	ProductID       int        `json:"product_id"`
-	OrderDate       time.Time  `json:"order_date"`
-	Total           float64    `json:"total"`
-	ShippingAddress string     `json:"shipping_address"`
-	ShippingCost    float64    `json:"shipping_cost"`
-	TrackingNumber  *string    `json:"tracking_number,omitempty"`
-	ShippedAt       *time.Time `json:"shipped_at,omitempty"`
-	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
-	Status          string     `json:"status"`
+	BuyerID          int       `json:"buyer_id"`
+	SellerID         int       `json:"seller_id"`
+	Quantity         int       `json:"quantity"`
+	ProductID        int       `json:"product_id"`
+	OrderDate        time.Time `json:"order_date"`
+	ShippingAddress  string    `json:"shipping_address"`
+	AddressID        int       `json:"address_id"`
+	ShippingMethodID int       `json:"shipping_method_id"`
+	Carrier          *string   `json:"carrier,omitempty"`
+	TrackingNumber   *string   `json:"tracking_number,omitempty"`
+	// Status is the status an update moves the order to. New orders are
+	// always pending; shipping and delivery are recorded by the server.
+	Status string `json:"status"`
 }
 
 type User struct {
-	UserID       int       `json:"user_id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	FirstName    string    `json:"first_name"`
-	LastName     string    `json:"last_name"`
-	StreetName   string    `json:"street_name"`
-	StreetNumber string    `json:"street_number"`
-	City         string    `json:"city"`
-	State        string    `json:"state"`
-	ZipCode      string    `json:"zip_code"`
-	SellerType   string    `json:"seller_type"`
-	Country      string    `json:"country"`
-	Language     string    `json:"language"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	UserID        int        `json:"user_id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	EmailVerified bool       `json:"email_verified"`
+	FirstName     string     `json:"first_name"`
+	LastName      string     `json:"last_name"`
+	StreetName    string     `json:"street_name"`
+	StreetNumber  string     `json:"street_number"`
+	City          string     `json:"city"`
+	State         string     `json:"state"`
+	ZipCode       string     `json:"zip_code"`
+	SellerType    string     `json:"seller_type"`
+	Country       string     `json:"country"`
+	Language      string     `json:"language"`
+	Reputation    Reputation `json:"reputation"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// UserContact holds what is needed to send a user an email.
+type UserContact struct {
+	UserID       int
+	Email        string
+	FirstName    string
+	LanguageCode string
 }
 
 type UserRequest struct {
@@ -145,29 +189,157 @@ type Service interface {
 	UpdateCard(cardID int, card CardRequest) error
 	DeleteCard(cardID int) error
 
-	ListProducts() ([]Product, error)
+	GetTCGGame(tcgGameID int) (TCGGame, error)
+	ListCardsByGame(tcgGameID int) ([]Card, error)
+
+	ListProducts(filter ProductFilter) ([]Product, error)
 	GetProductByID(productID int) (Product, error)
-	CreateProduct(product ProductRequest) error
+	CreateProduct(product ProductRequest) (int, error)
 	UpdateProduct(productID int, product ProductRequest) error
 	DeleteProduct(productID int) error
+	ListProductImages(productID int) ([]ProductImage, error)
+	CheckProductImageUpload(productID int, sellerID int) error
+	AddProductImage(productID int, sellerID int, img ProductImageRequest) (ProductImage, error)
+	DeleteProductImage(productID int, imageID int, sellerID int) (ProductImage, error)
+	ReorderProductImages(productID int, sellerID int, imageIDs []int) ([]ProductImage, error)
+	ListWatchlist(userID int) ([]WatchlistEntry, error)
+	AddWatchlistEntry(userID int, req WatchlistEntryRequest) (WatchlistEntry, error)
+	RemoveWatchlistEntry(userID int, entryID int) error
+	CheckWatchlist(productID int) ([]WatchlistAlert, error)
+	GetCardOverview(cardID int) (CardOverview, error)
+
+	ListSealedProducts(filter SealedProductFilter) ([]SealedProduct, error)
+	GetSealedProduct(sealedProductID int) (SealedProduct, error)
+	CreateSealedProduct(product SealedProductRequest) (int, error)
+	UpdateSealedProduct(sealedProductID int, product SealedProductRequest) error
+	DeleteSealedProduct(sealedProductID int) error
 
 	ListOrders() ([]Order, error)
 	GetOrderByID(orderID int) (Order, error)
-	CreateOrder(order OrderRequest) error
-	UpdateOrder(orderID int, order OrderRequest) error
+	// CreateOrder calculates shipping and total server-side and returns the new order ID.
+	CreateOrder(order OrderRequest) (int, error)
+	UpdateOrder(orderID int, userID int, order OrderRequest) (OrderChange, error)
 	DeleteOrder(orderID int) error
+	ListTrackedShipments() ([]tracking.Shipment, error)
+	MarkOrderDelivered(orderID int, deliveredAt time.Time) (bool, error)
+	CancelUnpaidOrders(placedBefore time.Time) ([]OrderSummary, error)
+	RemindUnshippedOrders(paidBefore time.Time) ([]OrderSummary, error)
+	CompleteShippedOrders(shippedBefore time.Time) ([]OrderSummary, error)
+
+	// AdvisoryLock returns a lock for electing the replica that runs the
+	// background jobs.
+	AdvisoryLock(key int64) *AdvisoryLock
 
 	ListUsers() ([]User, error)
 	GetUserByID(userID int) (User, error)
-	CreateUser(user UserRequest) error
+	CreateUser(user UserRequest) (int, error)
 	UpdateUser(userID int, user UserRequest) error
 	DeleteUser(userID int) error
+	GetUserContact(userID int) (UserContact, error)
+	Authenticate(email string, password string) (int, error)
+	GetUserIDByEmail(email string) (int, error)
+
+	CreateUserToken(tokenID string, userID int, purpose string, expiresAt time.Time) error
+	VerifyEmail(tokenID string, userID int) error
+	ResetPassword(tokenID string, userID int, password string) error
+	IsAdmin(userID int) (bool, error)
+
+	ListOrderReviews(orderID int) ([]Review, error)
+	ListUserReviews(userID int) ([]Review, error)
+	CreateReview(orderID int, reviewerID int, review ReviewRequest) (int, error)
+	RefreshReputations(now time.Time) (int, error)
+
+	ListUserDisputes(userID int) ([]Dispute, error)
+	ListDisputesByStatus(status string) ([]Dispute, error)
+	GetDispute(disputeID int) (Dispute, error)
+	CreateDispute(orderID int, buyerID int, dispute DisputeRequest) (int, error)
+	CreateDisputeMessage(disputeID int, senderID int, body string) error
+	AddDisputeEvidence(disputeID int, userID int, imageURL string) error
+	EscalateDispute(disputeID int) error
+	ResolveDispute(disputeID int, resolverID int, resolution DisputeResolutionRequest) error
+
+	ListAddresses(userID int) ([]Address, error)
+	CreateAddress(userID int, address AddressRequest) (int, error)
+	UpdateAddress(addressID int, userID int, address AddressRequest) error
+	DeleteAddress(addressID int, userID int) error
+
+	ListExchangeRates() (currency.Rates, error)
+	SaveExchangeRates(rates currency.Rates, source string) error
+
+	GetInvoice(orderID int, userID int) (Invoice, error)
+	ListPackingSlips(sellerID int, orderIDs []int) ([]PackingSlip, error)
+
+	ListAuctions() ([]Auction, error)
+	GetAuction(auctionID int) (Auction, error)
+	CreateAuction(sellerID int, auction AuctionRequest) (int, error)
+	CancelAuction(auctionID int, sellerID int) error
+	PlaceBid(auctionID int, bidderID int, bid BidRequest) (BidResult, error)
+	CloseEndedAuctions(now time.Time) ([]AuctionResult, error)
+
+	ListUserOffers(userID int) ([]Offer, error)
+	CreateOffer(productID int, buyerID int, offer OfferRequest) (Offer, error)
+	AcceptOffer(offerID int, userID int) (Offer, error)
+	DeclineOffer(offerID int, userID int) (Offer, error)
+	CounterOffer(offerID int, userID int, counter CounterOfferRequest) (Offer, error)
+	ExpireOffers(now time.Time) ([]Offer, error)
+
+	ListUserTrades(userID int) ([]Trade, error)
+	GetTrade(tradeID int, userID int) (Trade, error)
+	ProposeTrade(proposerID int, trade TradeRequest) (Trade, error)
+	AcceptTrade(tradeID int, userID int, addressID int) (Trade, error)
+	DeclineTrade(tradeID int, userID int) (Trade, error)
+	CounterTrade(tradeID int, userID int, counter TradeRequest) (Trade, error)
+	PayTradeCash(tradeID int, userID int) (Trade, error)
+	ShipTrade(tradeID int, userID int, carrier *string, trackingNumber *string) (Trade, error)
+	ReceiveTrade(tradeID int, userID int) (Trade, error)
+	CancelStalledTrades(acceptedBefore time.Time) ([]Trade, error)
+
+	ListWantlists(userID int) ([]Wantlist, error)
+	GetWantlist(wantlistID int, userID int) (Wantlist, error)
+	CreateWantlist(userID int, wantlist WantlistRequest) (Wantlist, error)
+	DeleteWantlist(wantlistID int, userID int) error
+	MatchWantlists(productID int) ([]WantlistMatch, error)
+
+	ListCart(userID int) ([]CartItem, error)
+	FillCart(userID int, wants []CartWant, excludeOwned bool) ([]CartFill, error)
+	RemoveCartItem(userID int, productID int) error
+
+	ListTaxRates() ([]TaxRate, error)
+	CreateTaxRate(rate TaxRateRequest) (int, error)
+
+	GetSellerProfile(username string) (SellerProfile, error)
+	SetVacation(userID int, onVacation bool, until *time.Time) error
+	EndVacations(now time.Time) ([]int, error)
+
+	ListShippingMethods() ([]ShippingMethod, error)
+	ListSellerShippingMethods(sellerID int) ([]ShippingMethod, error)
+	SetSellerShippingMethods(sellerID int, methodIDs []int) error
+	QuoteShipping(productID int, buyerID int, quantity int) ([]ShippingQuote, error)
+
+	ListConversations(userID int) ([]Conversation, error)
+	CreateConversation(senderID int, conversation ConversationRequest) (int, error)
+	ListMessages(conversationID int, userID int) ([]Message, error)
+	CreateMessage(conversationID int, senderID int, message MessageRequest) (int, error)
+
+	CreateNotification(userID int, notificationType string, payload any) (Notification, error)
+	ListenNotifications(ctx context.Context, deliver func(Notification)) error
+	ListNotifications(userID int, unreadOnly bool) ([]Notification, error)
+	MarkNotificationRead(notificationID int, userID int) error
+	MarkAllNotificationsRead(userID int) error
 }
 
 type service struct {
 	db *sql.DB
 }
 
+// queryer is implemented by both *sql.DB and *sql.Tx so helpers can run inside
+// or outside a transaction.
+type queryer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 var (
 	database   = os.Getenv("DB_DATABASE")
 	password   = os.Getenv("DB_PASSWORD")
@@ -333,9 +505,67 @@ func (s *service) DeleteCard(cardID int) error {
 	return nil
 }
 
-func (s *service) ListProducts() ([]Product, error) {
-	query := `SELECT p.product_id, p.price, p.condition, p.quantity, p.is_available, us.username AS seller, c.name AS card, l.language_name AS language, p.created_at, p.updated_at FROM products p JOIN users us ON p.seller_id = us.user_id JOIN cards c ON p.card_id = c.card_id JOIN languages l ON p.language_id = l.language_id`
-	rows, err := s.db.Query(query)
+func (s *service) GetTCGGame(tcgGameID int) (TCGGame, error) {
+	var game TCGGame
+	err := s.db.QueryRow(`SELECT tcg_game_id, name FROM tcg_games WHERE tcg_game_id = $1`, tcgGameID).Scan(&game.ID, &game.Name)
+	return game, err
+}
+
+// ListCardsByGame returns the cards of a game, by name.
+func (s *service) ListCardsByGame(tcgGameID int) ([]Card, error) {
+	rows, err := s.db.Query(`SELECT c.card_id, c.name, COALESCE(c.set_name, ''), COALESCE(c.card_number, ''), tcg.name FROM cards c JOIN tcg_games tcg ON c.tcg_game_id = tcg.tcg_game_id WHERE c.tcg_game_id = $1 ORDER BY c.name, c.card_id`, tcgGameID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cards := []Card{}
+	for rows.Next() {
+		var card Card
+		if err := rows.Scan(&card.ID, &card.Name, &card.SetName, &card.CardNumber, &card.TCGGame); err != nil {
+			return nil, err
+		}
+		cards = append(cards, card)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return cards, nil
+}
+
+// ProductFilter narrows the product listing. Zero values match every
+// product.
+type ProductFilter struct {
+	SellerID        int
+	TCGGameID       int
+	CardID          int
+	SealedProductID int
+	Condition       string
+	LanguageID      int
+	// Kind is ProductKindSingle or ProductKindSealed.
+	Kind string
+}
+
+// Kinds of items a product lists.
+const (
+	ProductKindSingle = "single"
+	ProductKindSealed = "sealed"
+)
+
+// ListProducts returns the products matching the filter. Products of sellers
+// on vacation are left out.
+func (s *service) ListProducts(filter ProductFilter) ([]Product, error) {
+	query := `SELECT p.product_id, p.price, p.currency, p.condition, p.quantity, p.is_available, us.username AS seller, COALESCE(c.name, sp.name) AS card, p.card_id, p.sealed_product_id, l.language_name AS language, p.created_at, p.updated_at FROM products p JOIN users us ON p.seller_id = us.user_id LEFT JOIN cards c ON p.card_id = c.card_id LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id JOIN languages l ON p.language_id = l.language_id
+WHERE NOT us.on_vacation
+AND ($1 = 0 OR p.seller_id = $1)
+AND ($2 = 0 OR COALESCE(c.tcg_game_id, sp.tcg_game_id) = $2)
+AND ($3 = 0 OR p.card_id = $3)
+AND ($4 = 0 OR p.sealed_product_id = $4)
+AND ($5 = '' OR p.condition = $5)
+AND ($6 = 0 OR p.language_id = $6)
+AND ($7 = '' OR ($7 = 'single') = (p.card_id IS NOT NULL))
+ORDER BY p.product_id`
+	rows, err := s.db.Query(query, filter.SellerID, filter.TCGGameID, filter.CardID, filter.SealedProductID, filter.Condition, filter.LanguageID, filter.Kind)
 	if err != nil {
 		return nil, err
 	}
@@ -343,8 +573,14 @@ func (s *service) ListProducts() ([]Product, error) {
 
 	var products []Product
 	for rows.Next() {
-		var product Product
-		if err := rows.Scan(&product.ProductID, &product.Price, &product.Condition, &product.Quantity, &product.IsAvailable, &product.Seller, &product.Card, &product.Language, &product.CreatedAt, &product.UpdatedAt); err != nil {
+		var (
+			product     Product
+			price, code string
+		)
+		if err := rows.Scan(&product.ProductID, &price, &code, &product.Condition, &product.Quantity, &product.IsAvailable, &product.Seller, &product.Card, &product.CardID, &product.SealedProductID, &product.Language, &product.CreatedAt, &product.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if product.Price, err = currency.Parse(price, code); err != nil {
 			return nil, err
 		}
 		products = append(products, product)
@@ -356,25 +592,75 @@ func (s *service) ListProducts() ([]Product, error) {
 }
 
 func (s *service) GetProductByID(productID int) (Product, error) {
-	var product Product
-	query := `SELECT p.product_id, p.price, p.condition, p.quantity, p.is_available, us.username AS seller, c.name AS card, l.language_name AS language, p.created_at, p.updated_at FROM products p JOIN users us ON p.seller_id = us.user_id JOIN cards c ON p.card_id = c.card_id JOIN languages l ON p.language_id = l.language_id WHERE p.product_id = $1`
-	err := s.db.QueryRow(query, productID).Scan(&product.ProductID, &product.Price, &product.Condition, &product.Quantity, &product.IsAvailable, &product.Seller, &product.Card, &product.Language, &product.CreatedAt, &product.UpdatedAt)
+	var (
+		product     Product
+		price, code string
+	)
+	query := `SELECT p.product_id, p.price, p.currency, p.condition, p.quantity, p.is_available, us.username AS seller, COALESCE(c.name, sp.name) AS card, p.card_id, p.sealed_product_id, l.language_name AS language, p.created_at, p.updated_at FROM products p JOIN users us ON p.seller_id = us.user_id LEFT JOIN cards c ON p.card_id = c.card_id LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id JOIN languages l ON p.language_id = l.language_id WHERE p.product_id = $1`
+	err := s.db.QueryRow(query, productID).Scan(&product.ProductID, &price, &code, &product.Condition, &product.Quantity, &product.IsAvailable, &product.Seller, &product.Card, &product.CardID, &product.SealedProductID, &product.Language, &product.CreatedAt, &product.UpdatedAt)
 	if err != nil {
 		return Product{}, err
 	}
+	if product.Price, err = currency.Parse(price, code); err != nil {
+		return Product{}, err
+	}
 	return product, nil
 }
 
-func (s *service) CreateProduct(product ProductRequest) error {
-	query := `INSERT INTO products (price, condition, quantity, is_available, seller_id, card_id, language_id) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := s.db.Exec(query, product.Price, product.Condition, product.Quantity, product.IsAvailable, product.SellerID, product.CardID, product.LanguageID)
-	return err
+// CreateProduct lists a product for sale. The price must be in the currency
+// of the seller's country. Only sellers with a verified email address may list
+// products.
+func (s *service) CreateProduct(product ProductRequest) (int, error) {
+	if err := product.validateItem(); err != nil {
+		return 0, err
+	}
+
+	var (
+		code     string
+		verified bool
+	)
+	query := `SELECT c.currency_code, u.email_verified_at IS NOT NULL FROM users u JOIN countries c ON u.country_id = c.country_id WHERE u.user_id = $1`
+	err := s.db.QueryRow(query, product.SellerID).Scan(&code, &verified)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !verified) {
+		return 0, ErrEmailNotVerified
+	}
+	if err != nil {
+		return 0, err
+	}
+	if product.Price.Currency != code {
+		return 0, currency.ErrCurrencyMismatch
+	}
+
+	var productID int
+	query = `INSERT INTO products (price, currency, condition, quantity, is_available, seller_id, card_id, sealed_product_id, language_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING product_id`
+	err = s.db.QueryRow(query, product.Price, product.Price.Currency, product.Condition, product.Quantity, product.IsAvailable, product.SellerID, nullID(product.CardID), nullID(product.SealedProductID), product.LanguageID).Scan(&productID)
+	return productID, err
 }
 
+// UpdateProduct updates a product of product.SellerID, who stays its seller.
+// The price stays in the currency the product was listed in.
 func (s *service) UpdateProduct(productID int, product ProductRequest) error {
-	query := `UPDATE products SET price = $1, condition = $2, quantity = $3, is_available = $4, seller_id = $5, card_id = $6, language_id = $7, updated_at = CURRENT_TIMESTAMP WHERE product_id = $8`
+	if err := product.validateItem(); err != nil {
+		return err
+	}
 
-	result, err := s.db.Exec(query, product.Price, product.Condition, product.Quantity, product.IsAvailable, product.SellerID, product.CardID, product.LanguageID, productID)
+	var (
+		sellerID int
+		code     string
+	)
+	if err := s.db.QueryRow(`SELECT seller_id, currency FROM products WHERE product_id = $1`, productID).Scan(&sellerID, &code); err != nil {
+		return err
+	}
+	if sellerID != product.SellerID {
+		return ErrNotProductSeller
+	}
+	if product.Price.Currency != code {
+		return currency.ErrCurrencyMismatch
+	}
+
+	query := `UPDATE products SET price = $1, condition = $2, quantity = $3, is_available = $4, card_id = $5, sealed_product_id = $6, language_id = $7, updated_at = CURRENT_TIMESTAMP WHERE product_id = $8 AND seller_id = $9`
+
+	result, err := s.db.Exec(query, product.Price, product.Condition, product.Quantity, product.IsAvailable, nullID(product.CardID), nullID(product.SealedProductID), product.LanguageID, productID, product.SellerID)
 
 	if err != nil {
 		return err
@@ -416,7 +702,7 @@ func (s *service) DeleteProduct(productID int) error {
 }
 
 func (s *service) ListOrders() ([]Order, error) {
-	query := `SELECT o.order_id, buyers.username AS buyer, sellers.username AS seller, o.quantity, c.name AS product, o.order_date, o.shipping_address, o.shipping_cost, o.total_amount, o.tracking_number, o.shipped_at, o.delivered_at, o.status, o.created_at, o.updated_at FROM orders o JOIN users buyers ON o.buyer_id = buyers.user_id JOIN users sellers ON o.seller_id = sellers.user_id JOIN products product ON o.product_id = product.product_id JOIN cards c ON product.card_id = c.card_id`
+	query := `SELECT o.order_id, buyers.username AS buyer, sellers.username AS seller, o.quantity, COALESCE(c.name, sp.name) AS product, o.order_date, o.shipping_address, sm.name AS shipping_method, o.shipping_cost, o.tax_amount, o.total_amount, o.refunded_amount, o.currency, o.carrier, o.tracking_number, o.shipped_at, o.delivered_at, o.status, o.created_at, o.updated_at, o.shipping_name, o.shipping_street_name, o.shipping_street_number, o.shipping_city, o.shipping_state, o.shipping_zip_code, sc.country_name FROM orders o JOIN users buyers ON o.buyer_id = buyers.user_id JOIN users sellers ON o.seller_id = sellers.user_id JOIN products product ON o.product_id = product.product_id LEFT JOIN cards c ON product.card_id = c.card_id LEFT JOIN sealed_products sp ON product.sealed_product_id = sp.sealed_product_id LEFT JOIN shipping_methods sm ON o.shipping_method_id = sm.shipping_method_id LEFT JOIN countries sc ON o.shipping_country_id = sc.country_id`
 	rows, err := s.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -425,10 +711,20 @@ func (s *service) ListOrders() ([]Order, error) {
 
 	var orders []Order
 	for rows.Next() {
-		var order Order
-		if err := rows.Scan(&order.OrderID, &order.Buyer, &order.Seller, &order.Quantity, &order.Product, &order.OrderDate, &order.ShippingAddress, &order.ShippingCost, &order.Total, &order.TrackingNumber, &order.ShippedAt, &order.DeliveredAt, &order.Status, &order.CreatedAt, &order.UpdatedAt); err != nil {
+		var (
+			order   Order
+			amounts orderAmountColumns
+			address orderAddressColumns
+		)
+		dest := append([]any{&order.OrderID, &order.Buyer, &order.Seller, &order.Quantity, &order.Product, &order.OrderDate, &order.ShippingAddress, &order.ShippingMethod}, amounts.dest()...)
+		dest = append(append(dest, &order.Carrier, &order.TrackingNumber, &order.ShippedAt, &order.DeliveredAt, &order.Status, &order.CreatedAt, &order.UpdatedAt), address.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		if err := amounts.apply(&order); err != nil {
 			return nil, err
 		}
+		order.ShipTo = address.address()
 		orders = append(orders, order)
 	}
 	if err := rows.Err(); err != nil {
@@ -439,40 +735,277 @@ func (s *service) ListOrders() ([]Order, error) {
 
 func (s *service) GetOrderByID(orderID int) (Order, error) {
 	var order Order
-	query := `SELECT o.order_id, buyers.username AS buyer, sellers.username AS seller, o.quantity, c.name AS product, o.order_date, o.shipping_address, o.shipping_cost, o.total_amount, o.tracking_number, o.shipped_at, o.delivered_at, o.status, o.created_at, o.updated_at FROM orders o JOIN users buyers ON o.buyer_id = buyers.user_id JOIN users sellers ON o.seller_id = sellers.user_id JOIN products product ON o.product_id = product.product_id JOIN cards c ON product.card_id = c.card_id WHERE o.order_id = $1`
-	err := s.db.QueryRow(query, orderID).Scan(&order.OrderID, &order.Buyer, &order.Seller, &order.Quantity, &order.Product, &order.OrderDate, &order.ShippingAddress, &order.ShippingCost, &order.Total, &order.TrackingNumber, &order.ShippedAt, &order.DeliveredAt, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+	query := `SELECT o.order_id, buyers.username AS buyer, sellers.username AS seller, o.quantity, COALESCE(c.name, sp.name) AS product, o.order_date, o.shipping_address, sm.name AS shipping_method, o.shipping_cost, o.tax_amount, o.total_amount, o.refunded_amount, o.currency, o.carrier, o.tracking_number, o.shipped_at, o.delivered_at, o.status, o.created_at, o.updated_at, o.shipping_name, o.shipping_street_name, o.shipping_street_number, o.shipping_city, o.shipping_state, o.shipping_zip_code, sc.country_name FROM orders o JOIN users buyers ON o.buyer_id = buyers.user_id JOIN users sellers ON o.seller_id = sellers.user_id JOIN products product ON o.product_id = product.product_id LEFT JOIN cards c ON product.card_id = c.card_id LEFT JOIN sealed_products sp ON product.sealed_product_id = sp.sealed_product_id LEFT JOIN shipping_methods sm ON o.shipping_method_id = sm.shipping_method_id LEFT JOIN countries sc ON o.shipping_country_id = sc.country_id WHERE o.order_id = $1`
+	var (
+		amounts orderAmountColumns
+		address orderAddressColumns
+	)
+	dest := append([]any{&order.OrderID, &order.Buyer, &order.Seller, &order.Quantity, &order.Product, &order.OrderDate, &order.ShippingAddress, &order.ShippingMethod}, amounts.dest()...)
+	dest = append(append(dest, &order.Carrier, &order.TrackingNumber, &order.ShippedAt, &order.DeliveredAt, &order.Status, &order.CreatedAt, &order.UpdatedAt), address.dest()...)
+	if err := s.db.QueryRow(query, orderID).Scan(dest...); err != nil {
+		return Order{}, err
+	}
+	if err := amounts.apply(&order); err != nil {
+		return Order{}, err
+	}
+	order.ShipTo = address.address()
+
+	taxLines, err := orderTaxLines(s.db, orderID, order.Total.Currency)
 	if err != nil {
 		return Order{}, err
 	}
+	order.TaxLines = taxLines
 	return order, nil
 }
 
-func (s *service) CreateOrder(order OrderRequest) error {
-	query := `INSERT INTO orders (buyer_id, seller_id, product_id, quantity, order_date, shipping_address, shipping_cost, total_amount, tracking_number, shipped_at, delivered_at, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
-	_, err := s.db.Exec(query, order.BuyerID, order.SellerID, order.ProductID, order.Quantity, order.OrderDate, order.ShippingAddress, order.ShippingCost, order.Total, order.TrackingNumber, order.ShippedAt, order.DeliveredAt, order.Status)
+var (
+	// ErrInsufficientStock is returned when an order asks for more than the
+	// quantity left of a product.
+	ErrInsufficientStock = errors.New("insufficient stock")
+	// ErrProductUnavailable is returned when stock is reserved for a sale of a
+	// product that is not for sale.
+	ErrProductUnavailable = errors.New("product is not for sale")
+)
+
+// CreateOrder places an order for a product of the given seller, shipped to
+// one of the buyer's addresses which is copied onto the order. The ordered
+// quantity is taken out of the product's stock. The shipping
+// cost is calculated from the seller's rate table for the chosen shipping
+// method, and tax from the seller's and the destination's jurisdictions. The
+// total is the items, shipping and tax together.
+func (s *service) CreateOrder(order OrderRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	product, err := reserveStock(tx, order.ProductID, order.SellerID, order.Quantity)
+	if err != nil {
+		return 0, err
+	}
+
+	orderID, err := insertOrder(tx, order, product.price, product.originCountryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return orderID, tx.Commit()
+}
+
+// reservedProduct is what an order needs to know about the product it takes
+// stock from.
+type reservedProduct struct {
+	price           currency.Money
+	originCountryID int
+}
+
+// reserveStock locks a product of the seller and takes quantity out of its
+// stock. The quantity stays reserved until the order is cancelled. Only
+// products that are for sale can be reserved, and nothing is reserved while
+// the seller is on vacation.
+func reserveStock(tx queryer, productID int, sellerID int, quantity int) (reservedProduct, error) {
+	return takeStock(tx, productID, sellerID, quantity, true)
+}
+
+// reserveTradeStock reserves an item of a trade. Unlike reserveStock it also
+// takes items that are not for sale, which are the owner's collection.
+func reserveTradeStock(tx queryer, productID int, ownerID int, quantity int) error {
+	_, err := takeStock(tx, productID, ownerID, quantity, false)
 	return err
 }
 
-func (s *service) UpdateOrder(orderID int, order OrderRequest) error {
-	query := `UPDATE orders SET buyer_id = $1, seller_id = $2, product_id = $3, quantity = $4, order_date = $5, shipping_address = $6, shipping_cost = $7, total_amount = $8, tracking_number = $9, shipped_at = $10, delivered_at = $11, status = $12, updated_at = CURRENT_TIMESTAMP WHERE order_id = $13`
+func takeStock(tx queryer, productID int, sellerID int, quantity int, forSale bool) (reservedProduct, error) {
+	var (
+		product     reservedProduct
+		price, code string
+		stock       int
+		available   bool
+		onVacation  bool
+	)
+	query := `SELECT p.price, p.currency, p.quantity, p.is_available, sellers.country_id, sellers.on_vacation FROM products p JOIN users sellers ON p.seller_id = sellers.user_id WHERE p.product_id = $1 AND p.seller_id = $2 FOR UPDATE OF p`
+	err := tx.QueryRow(query, productID, sellerID).Scan(&price, &code, &stock, &available, &product.originCountryID, &onVacation)
+	if err != nil {
+		return reservedProduct{}, err
+	}
+	if forSale && !available {
+		return reservedProduct{}, ErrProductUnavailable
+	}
+	if onVacation {
+		return reservedProduct{}, ErrSellerOnVacation
+	}
+	if product.price, err = currency.Parse(price, code); err != nil {
+		return reservedProduct{}, err
+	}
+	if stock < quantity {
+		return reservedProduct{}, ErrInsufficientStock
+	}
+
+	_, err = tx.Exec(`UPDATE products SET quantity = quantity - $2, updated_at = CURRENT_TIMESTAMP WHERE product_id = $1`, productID, quantity)
+	if err != nil {
+		return reservedProduct{}, err
+	}
+	return product, nil
+}
+
+// insertOrder stores a pending order at the given unit price, shipped from the
+// seller's country to the buyer's address. It does not touch the product's
+// stock.
+func insertOrder(tx queryer, order OrderRequest, unitPrice currency.Money, originCountryID int) (int, error) {
+	shipTo, destCountryID, err := checkoutAddress(tx, order.BuyerID, order.AddressID)
+	if err != nil {
+		return 0, err
+	}
 
-	result, err := s.db.Exec(query, order.BuyerID, order.SellerID, order.ProductID, order.Quantity, order.OrderDate, order.ShippingAddress, order.ShippingCost, order.Total, order.TrackingNumber, order.ShippedAt, order.DeliveredAt, order.Status, orderID)
+	shippingCost, err := quoteOrderShipping(tx, order.SellerID, order.ShippingMethodID, originCountryID, destCountryID, order.Quantity, unitPrice.Mul(order.Quantity))
+	if err != nil {
+		return 0, err
+	}
+	sale, rates, err := saleTaxRates(tx, order.SellerID, destCountryID, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	totals, err := settlement.Checkout(unitPrice, order.Quantity, shippingCost, sale, rates)
+	if err != nil {
+		return 0, err
+	}
 
+	var orderID int
+	query := `INSERT INTO orders (buyer_id, seller_id, product_id, quantity, order_date, shipping_address, shipping_name, shipping_street_name, shipping_street_number, shipping_city, shipping_state, shipping_zip_code, shipping_country_id, shipping_method_id, unit_price, shipping_cost, tax_amount, total_amount, currency, status) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, 'pending') RETURNING order_id`
+	err = tx.QueryRow(query, order.BuyerID, order.SellerID, order.ProductID, order.Quantity, order.OrderDate, shipTo.String(), shipTo.Name, shipTo.StreetName, shipTo.StreetNumber, shipTo.City, shipTo.State, shipTo.ZipCode, destCountryID, order.ShippingMethodID, unitPrice, totals.Shipping, totals.Taxes.Total, totals.Total, totals.Total.Currency).Scan(&orderID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if err := insertOrderTaxLines(tx, orderID, totals.Taxes.Lines); err != nil {
+		return 0, err
+	}
+	return orderID, nil
+}
+
+var (
+	// ErrOrderFixed is returned when an update tries to change the product,
+	// quantity or parties of an order after checkout.
+	ErrOrderFixed = errors.New("ordered product, quantity and parties cannot be changed")
+	// ErrOrderStatusChange is returned when the user may not move the order to
+	// the requested status, such as anything out of cancelled.
+	ErrOrderStatusChange = errors.New("order status change not allowed")
+	// ErrNotOrderSeller is returned when someone other than the seller adds
+	// shipping details to an order.
+	ErrNotOrderSeller = errors.New("user is not the seller of the order")
+	// ErrOrderNotShippable is returned when shipping details are added to an
+	// order that is not paid or no longer open.
+	ErrOrderNotShippable = errors.New("order is not paid or no longer open")
+)
+
+// Parties of an order, as a bit set of who may make a status change.
+const (
+	orderBuyer = 1 << iota
+	orderSeller
+)
+
+// orderTransitions lists the status changes users can make and who may make
+// them. The buyer pays and confirms receipt; either party can call off an
+// unpaid order. Delivery comes from the carrier and the remaining changes from
+// the scheduler and disputes. Nothing leaves cancelled, so stock is only ever
+// put back once.
+var orderTransitions = map[[2]string]int{
+	{"pending", "processing"}:   orderBuyer,
+	{"pending", "cancelled"}:    orderBuyer | orderSeller,
+	{"processing", "completed"}: orderBuyer,
+	{"delivered", "completed"}:  orderBuyer,
+}
+
+// OrderChange is what an update did to an order, for notifying its parties.
+type OrderChange struct {
+	OrderSummary
+	PreviousStatus string
+	Status         string
+	// Shipped is set when the update added the first tracking number.
+	Shipped        bool
+	TrackingNumber *string
+}
 
+// UpdateOrder changes the status or shipping details of an order on behalf of
+// its buyer or seller. Moving it to processing records when it was paid, the
+// first tracking number marks it as shipped, and cancelling it puts its
+// quantity back in stock. Delivery is only recorded by the tracking poller. The product, quantity and
+// amounts set at checkout are not changed here; refunds go through disputes.
+func (s *service) UpdateOrder(orderID int, userID int, order OrderRequest) (OrderChange, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return err
+		return OrderChange{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		change    = OrderChange{OrderSummary: OrderSummary{OrderID: orderID}}
+		productID int
+		quantity  int
+		shippedAt *time.Time
+	)
+	err = tx.QueryRow(`SELECT status, buyer_id, seller_id, product_id, quantity, shipped_at FROM orders WHERE order_id = $1 FOR UPDATE`, orderID).Scan(&change.PreviousStatus, &change.BuyerID, &change.SellerID, &productID, &quantity, &shippedAt)
+	if err != nil {
+		return OrderChange{}, err
 	}
 
-	if rowsAffected == 0 {
-		return sql.ErrNoRows
+	var party int
+	if userID == change.BuyerID {
+		party |= orderBuyer
+	}
+	if userID == change.SellerID {
+		party |= orderSeller
+	}
+	if party == 0 {
+		return OrderChange{}, ErrNotOrderParticipant
+	}
+	if changed(order.ProductID, productID) || changed(order.Quantity, quantity) || changed(order.BuyerID, change.BuyerID) || changed(order.SellerID, change.SellerID) {
+		return OrderChange{}, ErrOrderFixed
 	}
 
-	return nil
+	change.Status = order.Status
+	if change.Status == "" {
+		change.Status = change.PreviousStatus
+	}
+	if change.Status != change.PreviousStatus && orderTransitions[[2]string{change.PreviousStatus, change.Status}]&party == 0 {
+		return OrderChange{}, ErrOrderStatusChange
+	}
+	if order.Carrier != nil || order.TrackingNumber != nil {
+		if party&orderSeller == 0 {
+			return OrderChange{}, ErrNotOrderSeller
+		}
+		if change.Status != "processing" {
+			return OrderChange{}, ErrOrderNotShippable
+		}
+	}
+
+	query := `UPDATE orders SET carrier = COALESCE($2, carrier), tracking_number = COALESCE($3, tracking_number), shipped_at = CASE WHEN $3::text IS NOT NULL THEN COALESCE(shipped_at, CURRENT_TIMESTAMP) ELSE shipped_at END, status = $4, paid_at = CASE WHEN $4 = 'processing' AND paid_at IS NULL THEN CURRENT_TIMESTAMP ELSE paid_at END, updated_at = CURRENT_TIMESTAMP WHERE order_id = $1 RETURNING tracking_number, shipped_at IS NOT NULL`
+	var shipped bool
+	err = tx.QueryRow(query, orderID, order.Carrier, order.TrackingNumber, change.Status).Scan(&change.TrackingNumber, &shipped)
+	if err != nil {
+		return OrderChange{}, err
+	}
+	change.Shipped = shippedAt == nil && shipped
+
+	if change.Status == "cancelled" && change.PreviousStatus != "cancelled" {
+		_, err = tx.Exec(`UPDATE products SET quantity = quantity + $2, updated_at = CURRENT_TIMESTAMP WHERE product_id = $1`, productID, quantity)
+		if err != nil {
+			return OrderChange{}, err
+		}
+	}
+	if change.Status == "completed" && change.PreviousStatus != "completed" {
+		if err := issueInvoice(tx, orderID); err != nil {
+			return OrderChange{}, err
+		}
+	}
+
+	return change, tx.Commit()
+}
+
+// changed reports whether a field given in a request differs from the stored
+// value. Fields left out of the request are zero and count as unchanged.
+func changed(requested int, stored int) bool {
+	return requested != 0 && requested != stored
 }
 
 func (s *service) DeleteOrder(orderID int) error {
@@ -498,7 +1031,7 @@ func (s *service) DeleteOrder(orderID int) error {
 }
 
 func (s *service) ListUsers() ([]User, error) {
-	rows, err := s.db.Query("SELECT u.user_id, u.username, u.email, u.first_name, u.last_name, u.street_name, u.street_number, u.city, u.state, u.zip_code, u.seller_type, c.country_name, l.language_name, u.created_at, u.updated_at FROM users u JOIN countries c ON u.country_id = c.country_id JOIN languages l ON u.language_id = l.language_id")
+	rows, err := s.db.Query("SELECT u.user_id, u.username, u.email, u.email_verified_at IS NOT NULL, u.first_name, u.last_name, u.street_name, u.street_number, u.city, u.state, u.zip_code, u.seller_type, c.country_name, l.language_name, COALESCE(r.review_count, 0), COALESCE(r.average_rating, 0), COALESCE(r.positive_percentage, 0), u.created_at, u.updated_at FROM users u JOIN countries c ON u.country_id = c.country_id JOIN languages l ON u.language_id = l.language_id LEFT JOIN user_reputations r ON u.user_id = r.user_id")
 	if err != nil {
 		return nil, err
 	}
@@ -506,7 +1039,7 @@ func (s *service) ListUsers() ([]User, error) {
 	var users []User
 	for rows.Next() {
 		var user User
-		if err := rows.Scan(&user.UserID, &user.Username, &user.Email, &user.FirstName, &user.LastName, &user.StreetName, &user.StreetNumber, &user.City, &user.State, &user.ZipCode, &user.SellerType, &user.Country, &user.Language, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		if err := rows.Scan(&user.UserID, &user.Username, &user.Email, &user.EmailVerified, &user.FirstName, &user.LastName, &user.StreetName, &user.StreetNumber, &user.City, &user.State, &user.ZipCode, &user.SellerType, &user.Country, &user.Language, &user.Reputation.ReviewCount, &user.Reputation.AverageRating, &user.Reputation.PositivePercentage, &user.CreatedAt, &user.UpdatedAt); err != nil {
 			return nil, err
 		}
 		users = append(users, user)
@@ -519,23 +1052,62 @@ func (s *service) ListUsers() ([]User, error) {
 
 func (s *service) GetUserByID(userID int) (User, error) {
 	var user User
-	err := s.db.QueryRow("SELECT u.user_id, u.username, u.email, u.first_name, u.last_name, u.street_name, u.street_number, u.city, u.state, u.zip_code, u.seller_type, c.country_name, l.language_name, u.created_at, u.updated_at FROM users u JOIN countries c ON u.country_id = c.country_id JOIN languages l ON u.language_id = l.language_id WHERE u.user_id = $1", userID).Scan(&user.UserID, &user.Username, &user.Email, &user.FirstName, &user.LastName, &user.StreetName, &user.StreetNumber, &user.City, &user.State, &user.ZipCode, &user.SellerType, &user.Country, &user.Language, &user.CreatedAt, &user.UpdatedAt)
+	err := s.db.QueryRow("SELECT u.user_id, u.username, u.email, u.email_verified_at IS NOT NULL, u.first_name, u.last_name, u.street_name, u.street_number, u.city, u.state, u.zip_code, u.seller_type, c.country_name, l.language_name, COALESCE(r.review_count, 0), COALESCE(r.average_rating, 0), COALESCE(r.positive_percentage, 0), u.created_at, u.updated_at FROM users u JOIN countries c ON u.country_id = c.country_id JOIN languages l ON u.language_id = l.language_id LEFT JOIN user_reputations r ON u.user_id = r.user_id WHERE u.user_id = $1", userID).Scan(&user.UserID, &user.Username, &user.Email, &user.EmailVerified, &user.FirstName, &user.LastName, &user.StreetName, &user.StreetNumber, &user.City, &user.State, &user.ZipCode, &user.SellerType, &user.Country, &user.Language, &user.Reputation.ReviewCount, &user.Reputation.AverageRating, &user.Reputation.PositivePercentage, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return User{}, err
 	}
 	return user, nil
 }
 
-func (s *service) CreateUser(user UserRequest) error {
-	query := `INSERT INTO users (username, email, password, first_name, last_name, street_name, street_number, city, state, zip_code, seller_type, country_id, language_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
-	_, err := s.db.Exec(query, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.StreetName, user.StreetNumber, user.City, user.State, user.ZipCode, user.SellerType, user.CountryID, user.LanguageID)
-	return err
+// CreateUser registers a user. The profile address also becomes the first,
+// default entry of the user's address book.
+func (s *service) CreateUser(user UserRequest) (int, error) {
+	hash, err := hashPassword(user.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if user.ZipCode, err = normalizeZipCode(tx, user.CountryID, user.ZipCode); err != nil {
+		return 0, err
+	}
+
+	var userID int
+	query := `INSERT INTO users (username, email, password_hash, first_name, last_name, street_name, street_number, city, state, zip_code, seller_type, country_id, language_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING user_id`
+	err = tx.QueryRow(query, user.Username, user.Email, hash, user.FirstName, user.LastName, user.StreetName, user.StreetNumber, user.City, user.State, user.ZipCode, user.SellerType, user.CountryID, user.LanguageID).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	query = `INSERT INTO addresses (user_id, recipient_name, street_name, street_number, city, state, zip_code, country_id, is_default) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, TRUE)`
+	_, err = tx.Exec(query, userID, user.FirstName+" "+user.LastName, user.StreetName, user.StreetNumber, user.City, user.State, user.ZipCode, user.CountryID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
 }
 
+// UpdateUser updates a user's profile. Changing the email address requires the
+// new address to be verified again.
 func (s *service) UpdateUser(userID int, user UserRequest) error {
-	query := `UPDATE users SET username = $1, email = $2, password = $3, first_name = $4, last_name = $5, street_name = $6, street_number = $7, city = $8, state = $9, zip_code = $10, seller_type = $11, country_id = $12, language_id = $13, updated_at = CURRENT_TIMESTAMP WHERE user_id = $14`
+	hash, err := hashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+
+	if user.ZipCode, err = normalizeZipCode(s.db, user.CountryID, user.ZipCode); err != nil {
+		return err
+	}
 
-	result, err := s.db.Exec(query, user.Username, user.Email, user.Password, user.FirstName, user.LastName, user.StreetName, user.StreetNumber, user.City, user.State, user.ZipCode, user.CountryID, user.LanguageID, userID)
+	query := `UPDATE users SET username = $1, email_verified_at = CASE WHEN email = $2 THEN email_verified_at END, email = $2, password_hash = $3, first_name = $4, last_name = $5, street_name = $6, street_number = $7, city = $8, state = $9, zip_code = $10, seller_type = $11, country_id = $12, language_id = $13, updated_at = CURRENT_TIMESTAMP WHERE user_id = $14`
+
+	result, err := s.db.Exec(query, user.Username, user.Email, hash, user.FirstName, user.LastName, user.StreetName, user.StreetNumber, user.City, user.State, user.ZipCode, user.SellerType, user.CountryID, user.LanguageID, userID)
 
 	if err != nil {
 		return err
@@ -575,3 +1147,13 @@ func (s *service) DeleteUser(userID int) error {
 
 	return nil
 }
+
+func (s *service) GetUserContact(userID int) (UserContact, error) {
+	contact := UserContact{UserID: userID}
+	query := `SELECT u.email, u.first_name, l.language_code FROM users u JOIN languages l ON u.language_id = l.language_id WHERE u.user_id = $1`
+	err := s.db.QueryRow(query, userID).Scan(&contact.Email, &contact.FirstName, &contact.LanguageCode)
+	if err != nil {
+		return UserContact{}, err
+	}
+	return contact, nil
+}
@@ -2,10 +2,21 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"cardmarket_backend/internal/auction"
+	"cardmarket_backend/internal/currency"
+
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
@@ -49,15 +60,153 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 
 	host = dbHost
 	port = dbPort.Port()
+	schema = "public"
 
 	return dbContainer.Terminate, err
 }
 
+// applyMigrations runs the up part of every migration in order.
+func applyMigrations() error {
+	db, err := sql.Open("pgx", testConnString())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := filepath.Glob("../../migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		if _, err := db.Exec(up); err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+func testConnString() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable&search_path=%s", username, password, host, port, database, schema)
+}
+
+// newTestService opens a connection of its own, so that tests do not depend
+// on the shared instance that TestClose closes.
+func newTestService(t *testing.T) *service {
+	t.Helper()
+	db, err := sql.Open("pgx", testConnString())
+	if err != nil {
+		t.Fatalf("could not open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &service{db: db}
+}
+
+// seededUserID returns the ID of a user from the seed data.
+func seededUserID(t *testing.T, s *service, username string) int {
+	t.Helper()
+	var userID int
+	if err := s.db.QueryRow(`SELECT user_id FROM users WHERE username = $1`, username).Scan(&userID); err != nil {
+		t.Fatalf("could not find user %s: %v", username, err)
+	}
+	return userID
+}
+
+// seededProductID returns the ID of the seeded listing of a card by a seller.
+func seededProductID(t *testing.T, s *service, sellerID int, card string) int {
+	t.Helper()
+	var productID int
+	err := s.db.QueryRow(`SELECT p.product_id FROM products p JOIN cards c ON p.card_id = c.card_id WHERE p.seller_id = $1 AND c.name = $2`, sellerID, card).Scan(&productID)
+	if err != nil {
+		t.Fatalf("could not find %s of seller %d: %v", card, sellerID, err)
+	}
+	return productID
+}
+
+// placeTestOrder orders one copy of a product with the first shipping method
+// the seller offers to the buyer's default address.
+func placeTestOrder(t *testing.T, s *service, buyerID int, sellerID int, productID int) int {
+	t.Helper()
+	quotes, err := s.QuoteShipping(productID, buyerID, 1)
+	if err != nil || len(quotes) == 0 {
+		t.Fatalf("could not quote shipping: %v", err)
+	}
+	orderID, err := s.CreateOrder(OrderRequest{
+		BuyerID:          buyerID,
+		SellerID:         sellerID,
+		ProductID:        productID,
+		Quantity:         1,
+		OrderDate:        time.Now(),
+		ShippingMethodID: quotes[0].ShippingMethodID,
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	return orderID
+}
+
+// startTestAuction auctions one copy of a product at 100.00 EUR and returns
+// the auction with a shipping method that reaches the bidders.
+func startTestAuction(t *testing.T, s *service, sellerID int, productID int, bidderID int) (int, int) {
+	t.Helper()
+	auctionID, err := s.CreateAuction(sellerID, AuctionRequest{
+		ProductID:  productID,
+		StartPrice: currency.New(10000, "EUR"),
+		EndsAt:     time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateAuction() error = %v", err)
+	}
+	quotes, err := s.QuoteShipping(productID, bidderID, 1)
+	if err != nil || len(quotes) == 0 {
+		t.Fatalf("could not quote shipping: %v", err)
+	}
+	return auctionID, quotes[0].ShippingMethodID
+}
+
+// endTestAuction moves the end of an auction into the past.
+func endTestAuction(t *testing.T, s *service, auctionID int) {
+	t.Helper()
+	if _, err := s.db.Exec(`UPDATE auctions SET ends_at = $2 WHERE auction_id = $1`, auctionID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// closeTestAuction closes the ended auctions and returns the result of one.
+func closeTestAuction(t *testing.T, s *service, auctionID int) (AuctionResult, error) {
+	t.Helper()
+	results, err := s.CloseEndedAuctions(time.Now())
+	for _, result := range results {
+		if result.AuctionID == auctionID {
+			return result, err
+		}
+	}
+	t.Fatalf("expected auction %d to be closed; got %+v, error %v", auctionID, results, err)
+	return AuctionResult{}, nil
+}
+
+func productQuantity(t *testing.T, s *service, productID int) int {
+	t.Helper()
+	var quantity int
+	if err := s.db.QueryRow(`SELECT quantity FROM products WHERE product_id = $1`, productID).Scan(&quantity); err != nil {
+		t.Fatal(err)
+	}
+	return quantity
+}
+
 func TestMain(m *testing.M) {
 	teardown, err := mustStartPostgresContainer()
 	if err != nil {
 		log.Fatalf("could not start postgres container: %v", err)
 	}
+	if err := applyMigrations(); err != nil {
+		log.Fatalf("could not apply migrations: %v", err)
+	}
 
 	m.Run()
 
@@ -98,3 +247,525 @@ func TestClose(t *testing.T) {
 		t.Fatalf("expected Close() to return nil")
 	}
 }
+
+func TestCheckoutFromJapaneseSeller(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "rarefinds")
+	buyerID := seededUserID(t, s, "cardcollector")
+
+	var productID int
+	var price string
+	err := s.db.QueryRow(`SELECT p.product_id, p.price FROM products p JOIN cards c ON p.card_id = c.card_id WHERE p.seller_id = $1 AND c.name = 'Pikachu'`, sellerID).Scan(&productID, &price)
+	if err != nil {
+		t.Fatalf("could not find product: %v", err)
+	}
+	if price != "4063.00" {
+		t.Errorf("expected the seeded price to be converted to yen; got %s", price)
+	}
+
+	quotes, err := s.QuoteShipping(productID, buyerID, 1)
+	if err != nil {
+		t.Fatalf("QuoteShipping() error = %v", err)
+	}
+	if len(quotes) == 0 {
+		t.Fatal("expected shipping quotes")
+	}
+	for _, quote := range quotes {
+		if quote.Price.Currency != "JPY" || !quote.Price.IsPositive() {
+			t.Errorf("expected a price in yen for %s; got %v", quote.Code, quote.Price)
+		}
+	}
+
+	orderID, err := s.CreateOrder(OrderRequest{
+		BuyerID:          buyerID,
+		SellerID:         sellerID,
+		ProductID:        productID,
+		Quantity:         1,
+		OrderDate:        time.Now(),
+		ShippingMethodID: quotes[0].ShippingMethodID,
+	})
+	if err != nil {
+		t.Fatalf("CreateOrder() error = %v", err)
+	}
+	order, err := s.GetOrderByID(orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+	if order.Total.Currency != "JPY" || order.ShippingCost != quotes[0].Price {
+		t.Errorf("expected the order to settle in yen at the quoted shipping cost %v; got shipping %v, total %v", quotes[0].Price, order.ShippingCost, order.Total)
+	}
+}
+
+func TestCreateTaxRateResumesOpenRate(t *testing.T) {
+	s := newTestService(t)
+	from := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, err := s.CreateTaxRate(TaxRateRequest{CountryCode: "NL", RateBasisPoints: 1900, EffectiveFrom: from, EffectiveTo: &to}); err != nil {
+		t.Fatalf("CreateTaxRate() error = %v", err)
+	}
+
+	rates, err := s.ListTaxRates()
+	if err != nil {
+		t.Fatalf("ListTaxRates() error = %v", err)
+	}
+	var got []string
+	for _, rate := range rates {
+		if rate.CountryCode != "NL" {
+			continue
+		}
+		end := "open"
+		if rate.EffectiveTo != nil {
+			end = rate.EffectiveTo.Format(time.DateOnly)
+		}
+		got = append(got, fmt.Sprintf("%d %s %s", rate.RateBasisPoints, rate.EffectiveFrom.Format(time.DateOnly), end))
+	}
+	want := []string{"2100 2012-10-01 2030-07-01", "1900 2030-07-01 2031-01-01", "2100 2031-01-01 open"}
+	if strings.Join(got, ", ") != strings.Join(want, ", ") {
+		t.Errorf("expected rates %v; got %v", want, got)
+	}
+}
+
+func TestIssuedInvoiceKeepsAddresses(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "magicdealer")
+	buyerID := seededUserID(t, s, "casualplayer")
+	productID := seededProductID(t, s, sellerID, "Dark Magician")
+	orderID := placeTestOrder(t, s, buyerID, sellerID, productID)
+
+	if _, err := s.GetInvoice(orderID, buyerID); !errors.Is(err, ErrOrderNotInvoiceable) {
+		t.Fatalf("expected no invoice before completion; got %v", err)
+	}
+	for _, status := range []string{"processing", "completed"} {
+		if _, err := s.UpdateOrder(orderID, buyerID, OrderRequest{Status: status}); err != nil {
+			t.Fatalf("UpdateOrder(%s) error = %v", status, err)
+		}
+	}
+
+	issued, err := s.GetInvoice(orderID, buyerID)
+	if err != nil {
+		t.Fatalf("GetInvoice() error = %v", err)
+	}
+	var price, code string
+	if err := s.db.QueryRow(`SELECT price, currency FROM products WHERE product_id = $1`, productID).Scan(&price, &code); err != nil {
+		t.Fatal(err)
+	}
+	want, err := currency.Parse(price, code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issued.UnitPrice != want || issued.Subtotal != want {
+		t.Errorf("expected the unit price and subtotal %v; got %v and %v", want, issued.UnitPrice, issued.Subtotal)
+	}
+
+	if _, err := s.db.Exec(`UPDATE users SET city = 'Hamburg', last_name = 'Smith' WHERE user_id = $1`, sellerID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.Exec(`UPDATE users SET city = 'Sevilla' WHERE user_id = $1`, buyerID); err != nil {
+		t.Fatal(err)
+	}
+
+	again, err := s.GetInvoice(orderID, sellerID)
+	if err != nil {
+		t.Fatalf("GetInvoice() error = %v", err)
+	}
+	if again.Number != issued.Number || again.Seller != issued.Seller || again.Buyer != issued.Buyer {
+		t.Errorf("expected the issued invoice to be unchanged; got %+v, then %+v", issued, again)
+	}
+	if again.Seller.City != "Berlin" || again.Seller.Name != "Thomas Wilson" || again.Buyer.City != "Madrid" {
+		t.Errorf("expected the addresses at issue time; got seller %+v, buyer %+v", again.Seller, again.Buyer)
+	}
+}
+
+func TestConcurrentBids(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "magicdealer")
+	bidders := []int{seededUserID(t, s, "cardcollector"), seededUserID(t, s, "casualplayer")}
+	auctionID, methodID := startTestAuction(t, s, sellerID, seededProductID(t, s, sellerID, "Blue-Eyes White Dragon"), bidders[0])
+
+	const bids = 20
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for i := range bids {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.PlaceBid(auctionID, bidders[i%2], BidRequest{MaxAmount: currency.New(int64(15000+i*1000), "EUR"), ShippingMethodID: methodID})
+			if err != nil && !errors.Is(err, auction.ErrBidTooLow) {
+				t.Errorf("PlaceBid() error = %v", err)
+				return
+			}
+			if err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	var (
+		bidCount, rows, leaderID int
+		maxBid                   string
+	)
+	if err := s.db.QueryRow(`SELECT bid_count, leader_id, max_bid, (SELECT COUNT(*) FROM bids WHERE auction_id = $1) FROM auctions WHERE auction_id = $1`, auctionID).Scan(&bidCount, &leaderID, &maxBid, &rows); err != nil {
+		t.Fatal(err)
+	}
+	if bidCount != accepted || rows != accepted {
+		t.Errorf("expected %d accepted bids to be counted; got bid_count %d and %d bids", accepted, bidCount, rows)
+	}
+	// The highest maximum is accepted whatever order the bids arrive in.
+	if leaderID != bidders[(bids-1)%2] || maxBid != "340.00" {
+		t.Errorf("expected user %d to lead with 340.00; got user %d with %s", bidders[(bids-1)%2], leaderID, maxBid)
+	}
+}
+
+func TestClosedAuctionBecomesOrder(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "magicdealer")
+	bidderID := seededUserID(t, s, "cardcollector")
+	productID := seededProductID(t, s, sellerID, "Black Lotus")
+	auctionID, methodID := startTestAuction(t, s, sellerID, productID, bidderID)
+
+	if _, err := s.PlaceBid(auctionID, bidderID, BidRequest{MaxAmount: currency.New(12000, "EUR"), ShippingMethodID: methodID}); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	endTestAuction(t, s, auctionID)
+
+	result, err := closeTestAuction(t, s, auctionID)
+	if err != nil {
+		t.Fatalf("CloseEndedAuctions() error = %v", err)
+	}
+	if result.WinnerID != bidderID || result.OrderID == 0 || result.Failed {
+		t.Fatalf("expected an order for the winner; got %+v", result)
+	}
+	var (
+		buyerID, orderProductID, quantity int
+		status, total                     string
+	)
+	err = s.db.QueryRow(`SELECT buyer_id, product_id, quantity, status, total_amount FROM orders WHERE order_id = $1`, result.OrderID).Scan(&buyerID, &orderProductID, &quantity, &status, &total)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buyerID != bidderID || orderProductID != productID || quantity != 1 || status != "pending" {
+		t.Errorf("unexpected order of user %d for product %d: %d, %s", buyerID, orderProductID, quantity, status)
+	}
+	if result.Price != currency.New(10000, "EUR") {
+		t.Errorf("expected the sole bidder to win at the start price; got %v, total %s", result.Price, total)
+	}
+	closed, err := s.GetAuction(auctionID)
+	if err != nil {
+		t.Fatalf("GetAuction() error = %v", err)
+	}
+	if closed.Status != AuctionStatusSold {
+		t.Errorf("expected the auction to be sold; got %s", closed.Status)
+	}
+}
+
+func TestClosedAuctionFailsWithoutOrder(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "magicdealer")
+	bidderID := seededUserID(t, s, "casualplayer")
+	productID := seededProductID(t, s, sellerID, "Blue-Eyes White Dragon")
+	quantity := productQuantity(t, s, productID)
+	auctionID, methodID := startTestAuction(t, s, sellerID, productID, bidderID)
+
+	if _, err := s.PlaceBid(auctionID, bidderID, BidRequest{MaxAmount: currency.New(12000, "EUR"), ShippingMethodID: methodID}); err != nil {
+		t.Fatalf("PlaceBid() error = %v", err)
+	}
+	// The seller stops offering the winner's shipping method before the end.
+	query := `INSERT INTO seller_shipping_methods (seller_id, shipping_method_id) SELECT $1, shipping_method_id FROM shipping_methods WHERE shipping_method_id <> $2`
+	if _, err := s.db.Exec(query, sellerID, methodID); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.db.Exec(`DELETE FROM seller_shipping_methods WHERE seller_id = $1`, sellerID) })
+	endTestAuction(t, s, auctionID)
+
+	result, err := closeTestAuction(t, s, auctionID)
+	if !errors.Is(err, ErrShippingMethodUnavailable) {
+		t.Errorf("expected the failed order to be reported; got %v", err)
+	}
+	if !result.Failed || result.WinnerID != bidderID || result.OrderID != 0 {
+		t.Errorf("expected a failed auction without order; got %+v", result)
+	}
+	closed, err := s.GetAuction(auctionID)
+	if err != nil {
+		t.Fatalf("GetAuction() error = %v", err)
+	}
+	if closed.Status != AuctionStatusFailed {
+		t.Errorf("expected the auction to fail; got %s", closed.Status)
+	}
+	if got := productQuantity(t, s, productID); got != quantity {
+		t.Errorf("expected the copy back in stock; got quantity %d, want %d", got, quantity)
+	}
+}
+
+func TestCancelledOrderIsRestockedOnce(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "rarefinds")
+	buyerID := seededUserID(t, s, "cardcollector")
+	productID := seededProductID(t, s, sellerID, "Pikachu")
+	orderID := placeTestOrder(t, s, buyerID, sellerID, productID)
+	stock := productQuantity(t, s, productID)
+
+	if _, err := s.UpdateOrder(orderID, seededUserID(t, s, "casualplayer"), OrderRequest{Status: "cancelled"}); !errors.Is(err, ErrNotOrderParticipant) {
+		t.Errorf("expected only the parties to change the order; got %v", err)
+	}
+	if _, err := s.UpdateOrder(orderID, buyerID, OrderRequest{Quantity: 2}); !errors.Is(err, ErrOrderFixed) {
+		t.Errorf("expected the quantity to be fixed at checkout; got %v", err)
+	}
+	change, err := s.UpdateOrder(orderID, sellerID, OrderRequest{Status: "cancelled"})
+	if err != nil {
+		t.Fatalf("UpdateOrder() error = %v", err)
+	}
+	if change.PreviousStatus != "pending" || change.BuyerID != buyerID {
+		t.Errorf("unexpected change %+v", change)
+	}
+	for _, status := range []string{"pending", "processing"} {
+		if _, err := s.UpdateOrder(orderID, buyerID, OrderRequest{Status: status}); !errors.Is(err, ErrOrderStatusChange) {
+			t.Errorf("expected a cancelled order to stay cancelled; moving to %s got %v", status, err)
+		}
+	}
+	if got := productQuantity(t, s, productID); got != stock+1 {
+		t.Errorf("expected the copy to be put back once; stock went from %d to %d", stock, got)
+	}
+}
+
+func TestOrderIsShippedOnceAfterPayment(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "rarefinds")
+	buyerID := seededUserID(t, s, "casualplayer")
+	orderID := placeTestOrder(t, s, buyerID, sellerID, seededProductID(t, s, sellerID, "Pikachu"))
+	tracking := "1Z999AA10123456784"
+	ship := OrderRequest{TrackingNumber: &tracking}
+
+	if _, err := s.UpdateOrder(orderID, sellerID, ship); !errors.Is(err, ErrOrderNotShippable) {
+		t.Errorf("expected an unpaid order not to ship; got %v", err)
+	}
+	if _, err := s.UpdateOrder(orderID, sellerID, OrderRequest{Status: "processing"}); !errors.Is(err, ErrOrderStatusChange) {
+		t.Errorf("expected only the buyer to pay; got %v", err)
+	}
+	if _, err := s.UpdateOrder(orderID, buyerID, OrderRequest{Status: "processing"}); err != nil {
+		t.Fatalf("UpdateOrder() error = %v", err)
+	}
+	if _, err := s.UpdateOrder(orderID, buyerID, ship); !errors.Is(err, ErrNotOrderSeller) {
+		t.Errorf("expected only the seller to ship; got %v", err)
+	}
+	for i, want := range []bool{true, false} {
+		change, err := s.UpdateOrder(orderID, sellerID, ship)
+		if err != nil {
+			t.Fatalf("UpdateOrder() error = %v", err)
+		}
+		if change.Shipped != want {
+			t.Errorf("update %d: expected shipped %v; got %v", i, want, change.Shipped)
+		}
+	}
+	order, err := s.GetOrderByID(orderID)
+	if err != nil {
+		t.Fatalf("GetOrderByID() error = %v", err)
+	}
+	if order.ShippedAt == nil || order.DeliveredAt != nil || order.Status != "processing" {
+		t.Errorf("expected a shipped order awaiting delivery; got %+v", order)
+	}
+}
+
+func TestConcurrentReservationsOfLastCopy(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "powertcg")
+	productID := seededProductID(t, s, sellerID, "Mox Ruby")
+	var code string
+	if err := s.db.QueryRow(`SELECT currency FROM products WHERE product_id = $1`, productID).Scan(&code); err != nil {
+		t.Fatal(err)
+	}
+
+	const attempts = 5
+	errs := make(chan error, attempts)
+	for range attempts {
+		go func() {
+			_, err := s.CreateAuction(sellerID, AuctionRequest{ProductID: productID, StartPrice: currency.New(100000, code), EndsAt: time.Now().Add(time.Hour)})
+			errs <- err
+		}()
+	}
+	var created int
+	for range attempts {
+		err := <-errs
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrInsufficientStock):
+			t.Errorf("CreateAuction() error = %v", err)
+		}
+	}
+	if created != 1 || productQuantity(t, s, productID) != 0 {
+		t.Errorf("expected the only copy to be auctioned once; got %d auctions", created)
+	}
+}
+
+func TestCollectionItemsAreTradedButNotSold(t *testing.T) {
+	s := newTestService(t)
+	ownerID := seededUserID(t, s, "rarefinds")
+	buyerID := seededUserID(t, s, "cardcollector")
+	productID := seededProductID(t, s, ownerID, "Briar")
+	if _, err := s.db.Exec(`UPDATE products SET is_available = false WHERE product_id = $1`, productID); err != nil {
+		t.Fatal(err)
+	}
+	stock := productQuantity(t, s, productID)
+
+	quotes, err := s.QuoteShipping(productID, buyerID, 1)
+	if err != nil || len(quotes) == 0 {
+		t.Fatalf("could not quote shipping: %v", err)
+	}
+	_, err = s.CreateOrder(OrderRequest{BuyerID: buyerID, SellerID: ownerID, ProductID: productID, Quantity: 1, OrderDate: time.Now(), ShippingMethodID: quotes[0].ShippingMethodID})
+	if !errors.Is(err, ErrProductUnavailable) {
+		t.Errorf("expected a collection item not to be sold; got %v", err)
+	}
+
+	trade, err := s.ProposeTrade(buyerID, TradeRequest{
+		RecipientID: ownerID,
+		Requested:   []TradeItemRequest{{ProductID: productID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("ProposeTrade() error = %v", err)
+	}
+	if _, err := s.AcceptTrade(trade.TradeID, ownerID, 0); err != nil {
+		t.Fatalf("AcceptTrade() error = %v", err)
+	}
+	if got := productQuantity(t, s, productID); got != stock-1 {
+		t.Errorf("expected the traded copy to leave the collection; stock went from %d to %d", stock, got)
+	}
+}
+
+func TestTradeCashIsPaidBeforeShipping(t *testing.T) {
+	s := newTestService(t)
+	sellerID := seededUserID(t, s, "magicdealer")
+	buyerID := seededUserID(t, s, "cardcollector")
+	cash := currency.New(5000, "EUR")
+
+	trade, err := s.ProposeTrade(buyerID, TradeRequest{
+		RecipientID: sellerID,
+		Requested:   []TradeItemRequest{{ProductID: seededProductID(t, s, sellerID, "Dark Magician"), Quantity: 1}},
+		Cash:        &cash,
+		CashPayer:   TradeCashFromProposer,
+	})
+	if err != nil {
+		t.Fatalf("ProposeTrade() error = %v", err)
+	}
+	if _, err := s.PayTradeCash(trade.TradeID, buyerID); !errors.Is(err, ErrNoCashDue) {
+		t.Errorf("expected no cash to be due before the trade is accepted; got %v", err)
+	}
+	if _, err := s.AcceptTrade(trade.TradeID, sellerID, 0); err != nil {
+		t.Fatalf("AcceptTrade() error = %v", err)
+	}
+
+	if _, err := s.ShipTrade(trade.TradeID, sellerID, nil, nil); !errors.Is(err, ErrInvalidShipmentState) {
+		t.Errorf("expected the cards to wait for the cash; got %v", err)
+	}
+	if _, err := s.PayTradeCash(trade.TradeID, sellerID); !errors.Is(err, ErrNoCashDue) {
+		t.Errorf("expected the payee not to pay; got %v", err)
+	}
+	paid, err := s.PayTradeCash(trade.TradeID, buyerID)
+	if err != nil {
+		t.Fatalf("PayTradeCash() error = %v", err)
+	}
+	if paid.CashPaidAt == nil {
+		t.Error("expected the payment to be recorded")
+	}
+	if _, err := s.PayTradeCash(trade.TradeID, buyerID); !errors.Is(err, ErrNoCashDue) {
+		t.Errorf("expected the cash to be paid once; got %v", err)
+	}
+
+	if _, err := s.ShipTrade(trade.TradeID, sellerID, nil, nil); err != nil {
+		t.Fatalf("ShipTrade() error = %v", err)
+	}
+	received, err := s.ReceiveTrade(trade.TradeID, buyerID)
+	if err != nil {
+		t.Fatalf("ReceiveTrade() error = %v", err)
+	}
+	if received.Status != TradeStatusCompleted {
+		t.Errorf("expected the trade to complete; got %s", received.Status)
+	}
+}
+
+func TestStalledTradeIsCancelledAndRestocked(t *testing.T) {
+	s := newTestService(t)
+	ownerID := seededUserID(t, s, "rarefinds")
+	proposerID := seededUserID(t, s, "cardcollector")
+	productID := seededProductID(t, s, ownerID, "Pikachu")
+	stock := productQuantity(t, s, productID)
+
+	trade, err := s.ProposeTrade(proposerID, TradeRequest{
+		RecipientID: ownerID,
+		Requested:   []TradeItemRequest{{ProductID: productID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("ProposeTrade() error = %v", err)
+	}
+	if _, err := s.AcceptTrade(trade.TradeID, ownerID, 0); err != nil {
+		t.Fatalf("AcceptTrade() error = %v", err)
+	}
+	if _, err := s.db.Exec(`UPDATE trades SET accepted_at = $2 WHERE trade_id = $1`, trade.TradeID, time.Now().Add(-48*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	trades, err := s.CancelStalledTrades(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("CancelStalledTrades() error = %v", err)
+	}
+	if len(trades) != 1 || trades[0].TradeID != trade.TradeID || trades[0].Status != TradeStatusCancelled {
+		t.Fatalf("expected the trade to be cancelled; got %+v", trades)
+	}
+	if got := productQuantity(t, s, productID); got != stock {
+		t.Errorf("expected the card to be put back; stock went from %d to %d", stock, got)
+	}
+	if _, err := s.ShipTrade(trade.TradeID, ownerID, nil, nil); !errors.Is(err, ErrInvalidShipmentState) {
+		t.Errorf("expected a cancelled trade not to ship; got %v", err)
+	}
+}
+
+func TestStoredNotificationsAreAnnounced(t *testing.T) {
+	s := newTestService(t)
+	userID := seededUserID(t, s, "casualplayer")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	delivered := make(chan Notification, 16)
+	go s.ListenNotifications(ctx, func(n Notification) { delivered <- n })
+
+	// The listener may not be subscribed yet when the first ones are stored.
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case n := <-delivered:
+			if n.UserID != userID || n.Type != NotificationNewMessage {
+				t.Errorf("unexpected notification %+v", n)
+			}
+			return
+		case <-ticker.C:
+			if _, err := s.CreateNotification(userID, NotificationNewMessage, map[string]any{"conversation_id": 1}); err != nil {
+				t.Fatalf("CreateNotification() error = %v", err)
+			}
+		case <-ctx.Done():
+			t.Fatal("expected a stored notification to be announced")
+		}
+	}
+}
+
+func TestEveryNotificationTypeIsStored(t *testing.T) {
+	s := newTestService(t)
+	userID := seededUserID(t, s, "casualplayer")
+	for _, notificationType := range []string{
+		NotificationOrderCreated, NotificationOrderStatusChanged, NotificationOrderDelivered, NotificationShippingReminder,
+		NotificationDisputeOpened, NotificationDisputeUpdated, NotificationDisputeResolved, NotificationNewMessage,
+		NotificationWantlistMatch, NotificationReviewReceived, NotificationOutbid, NotificationAuctionWon,
+		NotificationAuctionEnded, NotificationOfferReceived, NotificationOfferUpdated, NotificationTradeReceived,
+		NotificationTradeUpdated, NotificationVacationEnded, NotificationWatchlistAlert,
+	} {
+		if _, err := s.CreateNotification(userID, notificationType, map[string]int{"id": 1}); err != nil {
+			t.Errorf("CreateNotification(%s) error = %v", notificationType, err)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package database
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/settlement"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Dispute reasons, statuses and resolutions stored in the disputes table.
+const (
+	DisputeStatusOpen            = "open"
+	DisputeStatusSellerResponded = "seller_responded"
+	DisputeStatusEscalated       = "escalated"
+	DisputeStatusResolved        = "resolved"
+
+	DisputeFullRefund    = settlement.FullRefund
+	DisputePartialRefund = settlement.PartialRefund
+	DisputeNoRefund      = settlement.NoRefund
+)
+
+var (
+	// ErrOrderNotDisputable is returned when a dispute is opened on an order
+	// that was never paid or is already cancelled or refunded.
+	ErrOrderNotDisputable = errors.New("order cannot be disputed")
+	// ErrDisputeExists is returned when the order already has a dispute.
+	ErrDisputeExists = errors.New("order already has a dispute")
+	// ErrDisputeResolved is returned when a resolved dispute is changed.
+	ErrDisputeResolved = errors.New("dispute is already resolved")
+	// ErrInvalidRefund is returned when a refund amount is not positive or
+	// exceeds what is left to refund on the order.
+	ErrInvalidRefund = settlement.ErrInvalidRefund
+)
+
+type Dispute struct {
+	DisputeID    int               `json:"dispute_id"`
+	OrderID      int               `json:"order_id"`
+	BuyerID      int               `json:"buyer_id"`
+	SellerID     int               `json:"seller_id"`
+	Buyer        string            `json:"buyer"`
+	Seller       string            `json:"seller"`
+	Reason       string            `json:"reason"`
+	Description  string            `json:"description"`
+	Status       string            `json:"status"`
+	Resolution   *string           `json:"resolution,omitempty"`
+	RefundAmount *currency.Money   `json:"refund_amount,omitempty"`
+	Restocked    bool              `json:"restocked"`
+	ResolvedAt   *time.Time        `json:"resolved_at,omitempty"`
+	Evidence     []DisputeEvidence `json:"evidence,omitempty"`
+	Messages     []DisputeMessage  `json:"messages,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+type DisputeRequest struct {
+	Reason       string   `json:"reason"`
+	Description  string   `json:"description"`
+	EvidenceURLs []string `json:"evidence_urls"`
+}
+
+type DisputeEvidence struct {
+	DisputeEvidenceID int       `json:"dispute_evidence_id"`
+	UploadedBy        string    `json:"uploaded_by"`
+	ImageURL          string    `json:"image_url"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type DisputeMessage struct {
+	DisputeMessageID int       `json:"dispute_message_id"`
+	Sender           string    `json:"sender"`
+	Body             string    `json:"body"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// DisputeResolutionRequest settles a dispute. RefundAmount is only read for
+// partial refunds. Restock puts the ordered quantity back in stock when the
+// card was returned to the seller.
+type DisputeResolutionRequest struct {
+	Resolution   string         `json:"resolution"`
+	RefundAmount currency.Money `json:"refund_amount"`
+	Restock      bool           `json:"restock"`
+}
+
+const disputeSelect = `SELECT d.dispute_id, d.order_id, o.buyer_id, o.seller_id, buyers.username, sellers.username, d.reason, d.description, d.status, d.resolution, d.refund_amount, o.currency, d.restocked, d.resolved_at, d.created_at, d.updated_at FROM disputes d JOIN orders o ON d.order_id = o.order_id JOIN users buyers ON o.buyer_id = buyers.user_id JOIN users sellers ON o.seller_id = sellers.user_id`
+
+// ListUserDisputes returns the disputes on orders the user bought or sold,
+// most recent first.
+func (s *service) ListUserDisputes(userID int) ([]Dispute, error) {
+	return queryDisputes(s.db, disputeSelect+` WHERE o.buyer_id = $1 OR o.seller_id = $1 ORDER BY d.created_at DESC`, userID)
+}
+
+// ListDisputesByStatus returns the disputes with the given status, oldest
+// first so they are handled in order.
+func (s *service) ListDisputesByStatus(status string) ([]Dispute, error) {
+	return queryDisputes(s.db, disputeSelect+` WHERE d.status = $1 ORDER BY d.created_at`, status)
+}
+
+// GetDispute returns a dispute with its evidence and messages.
+func (s *service) GetDispute(disputeID int) (Dispute, error) {
+	disputes, err := queryDisputes(s.db, disputeSelect+` WHERE d.dispute_id = $1`, disputeID)
+	if err != nil {
+		return Dispute{}, err
+	}
+	if len(disputes) == 0 {
+		return Dispute{}, sql.ErrNoRows
+	}
+	dispute := disputes[0]
+
+	rows, err := s.db.Query(`SELECT e.dispute_evidence_id, u.username, e.image_url, e.created_at FROM dispute_evidence e JOIN users u ON e.uploaded_by = u.user_id WHERE e.dispute_id = $1 ORDER BY e.created_at`, disputeID)
+	if err != nil {
+		return Dispute{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var evidence DisputeEvidence
+		if err := rows.Scan(&evidence.DisputeEvidenceID, &evidence.UploadedBy, &evidence.ImageURL, &evidence.CreatedAt); err != nil {
+			return Dispute{}, err
+		}
+		dispute.Evidence = append(dispute.Evidence, evidence)
+	}
+	if err := rows.Err(); err != nil {
+		return Dispute{}, err
+	}
+
+	rows, err = s.db.Query(`SELECT m.dispute_message_id, u.username, m.body, m.created_at FROM dispute_messages m JOIN users u ON m.sender_id = u.user_id WHERE m.dispute_id = $1 ORDER BY m.created_at`, disputeID)
+	if err != nil {
+		return Dispute{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var message DisputeMessage
+		if err := rows.Scan(&message.DisputeMessageID, &message.Sender, &message.Body, &message.CreatedAt); err != nil {
+			return Dispute{}, err
+		}
+		dispute.Messages = append(dispute.Messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return Dispute{}, err
+	}
+
+	return dispute, nil
+}
+
+// CreateDispute opens a dispute on an order on behalf of its buyer and returns
+// the dispute ID.
+func (s *service) CreateDispute(orderID int, buyerID int, dispute DisputeRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		orderBuyerID int
+		status       string
+	)
+	err = tx.QueryRow(`SELECT buyer_id, status FROM orders WHERE order_id = $1`, orderID).Scan(&orderBuyerID, &status)
+	if err != nil {
+		return 0, err
+	}
+
+	if orderBuyerID != buyerID {
+		return 0, ErrNotOrderParticipant
+	}
+	if status == "pending" || status == "cancelled" || status == "refunded" {
+		return 0, ErrOrderNotDisputable
+	}
+
+	var disputeID int
+	query := `INSERT INTO disputes (order_id, reason, description) VALUES ($1, $2, $3) ON CONFLICT (order_id) DO NOTHING RETURNING dispute_id`
+	err = tx.QueryRow(query, orderID, dispute.Reason, dispute.Description).Scan(&disputeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrDisputeExists
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	for _, imageURL := range dispute.EvidenceURLs {
+		if err := insertDisputeEvidence(tx, disputeID, buyerID, imageURL); err != nil {
+			return 0, err
+		}
+	}
+
+	return disputeID, tx.Commit()
+}
+
+// CreateDisputeMessage posts a message on a dispute. The first message of the
+// seller on an open dispute marks it as responded.
+func (s *service) CreateDisputeMessage(disputeID int, senderID int, body string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, sellerID, err := lockDispute(tx, disputeID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`INSERT INTO dispute_messages (dispute_id, sender_id, body) VALUES ($1, $2, $3)`, disputeID, senderID, body); err != nil {
+		return err
+	}
+
+	if status == DisputeStatusOpen && senderID == sellerID {
+		status = DisputeStatusSellerResponded
+	}
+	if _, err := tx.Exec(`UPDATE disputes SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE dispute_id = $1`, disputeID, status); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddDisputeEvidence attaches an image to an unresolved dispute.
+func (s *service) AddDisputeEvidence(disputeID int, userID int, imageURL string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockDispute(tx, disputeID); err != nil {
+		return err
+	}
+
+	if err := insertDisputeEvidence(tx, disputeID, userID, imageURL); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EscalateDispute hands an unresolved dispute over to the administrators.
+func (s *service) EscalateDispute(disputeID int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockDispute(tx, disputeID); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`UPDATE disputes SET status = 'escalated', updated_at = CURRENT_TIMESTAMP WHERE dispute_id = $1`, disputeID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ResolveDispute settles a dispute. Refunds are added to the order's refunded
+// amount and a full refund moves the order to refunded. With Restock, the
+// ordered quantity is put back in stock.
+func (s *service) ResolveDispute(disputeID int, resolverID int, resolution DisputeResolutionRequest) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, err := lockDispute(tx, disputeID); err != nil {
+		return err
+	}
+
+	var (
+		orderID, productID, quantity int
+		total, refunded, code        string
+	)
+	query := `SELECT o.order_id, o.product_id, o.quantity, o.total_amount, o.refunded_amount, o.currency FROM orders o JOIN disputes d ON d.order_id = o.order_id WHERE d.dispute_id = $1 FOR UPDATE OF o`
+	if err := tx.QueryRow(query, disputeID).Scan(&orderID, &productID, &quantity, &total, &refunded, &code); err != nil {
+		return err
+	}
+
+	orderTotal, err := currency.Parse(total, code)
+	if err != nil {
+		return err
+	}
+	alreadyRefunded, err := currency.Parse(refunded, code)
+	if err != nil {
+		return err
+	}
+	refund, err := settlement.Refund(orderTotal, alreadyRefunded, resolution.Resolution, resolution.RefundAmount)
+	if err != nil {
+		return err
+	}
+	if resolution.Resolution == DisputeNoRefund {
+		resolution.Restock = false
+	}
+
+	query = `UPDATE orders SET refunded_amount = refunded_amount + $2, status = CASE WHEN $3 THEN 'refunded' ELSE status END, updated_at = CURRENT_TIMESTAMP WHERE order_id = $1`
+	if _, err := tx.Exec(query, orderID, refund, resolution.Resolution == DisputeFullRefund); err != nil {
+		return err
+	}
+
+	if resolution.Restock {
+		if _, err := tx.Exec(`UPDATE products SET quantity = quantity + $2, updated_at = CURRENT_TIMESTAMP WHERE product_id = $1`, productID, quantity); err != nil {
+			return err
+		}
+	}
+
+	query = `UPDATE disputes SET status = 'resolved', resolution = $2, refund_amount = $3, restocked = $4, resolved_by = $5, resolved_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE dispute_id = $1`
+	if _, err := tx.Exec(query, disputeID, resolution.Resolution, refund, resolution.Restock, resolverID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// lockDispute locks a dispute for the rest of the transaction and returns its
+// status and the seller of the order. Resolved disputes cannot be changed.
+func lockDispute(tx *sql.Tx, disputeID int) (string, int, error) {
+	var (
+		status   string
+		sellerID int
+	)
+	query := `SELECT d.status, o.seller_id FROM disputes d JOIN orders o ON d.order_id = o.order_id WHERE d.dispute_id = $1 FOR UPDATE OF d`
+	if err := tx.QueryRow(query, disputeID).Scan(&status, &sellerID); err != nil {
+		return "", 0, err
+	}
+	if status == DisputeStatusResolved {
+		return "", 0, ErrDisputeResolved
+	}
+	return status, sellerID, nil
+}
+
+func insertDisputeEvidence(tx *sql.Tx, disputeID int, userID int, imageURL string) error {
+	_, err := tx.Exec(`INSERT INTO dispute_evidence (dispute_id, uploaded_by, image_url) VALUES ($1, $2, $3)`, disputeID, userID, imageURL)
+	return err
+}
+
+func queryDisputes(q queryer, query string, args ...any) ([]Dispute, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	disputes := []Dispute{}
+	for rows.Next() {
+		var (
+			d      Dispute
+			refund sql.NullString
+			code   string
+		)
+		if err := rows.Scan(&d.DisputeID, &d.OrderID, &d.BuyerID, &d.SellerID, &d.Buyer, &d.Seller, &d.Reason, &d.Description, &d.Status, &d.Resolution, &refund, &code, &d.Restocked, &d.ResolvedAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if refund.Valid {
+			amount, err := currency.Parse(refund.String, code)
+			if err != nil {
+				return nil, err
+			}
+			d.RefundAmount = &amount
+		}
+		disputes = append(disputes, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return disputes, nil
+}
@@ -0,0 +1,210 @@
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxProductImages bounds the number of images of a product.
+const MaxProductImages = 10
+
+var (
+	// ErrNotProductSeller is returned when a user changes the images of a
+	// product sold by someone else.
+	ErrNotProductSeller = errors.New("user is not the seller of the product")
+	// ErrTooManyImages is returned when a product already has
+	// MaxProductImages images.
+	ErrTooManyImages = errors.New("product has too many images")
+	// ErrInvalidImageOrder is returned when a new order does not list every
+	// image of the product exactly once.
+	ErrInvalidImageOrder = errors.New("invalid image order")
+)
+
+// ProductImage is an image of a product. The URLs are filled in from the
+// storage keys by the caller.
+type ProductImage struct {
+	ImageID      int       `json:"image_id"`
+	ProductID    int       `json:"product_id"`
+	Key          string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int       `json:"size"`
+	Position     int       `json:"position"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ProductImageRequest describes an image already put into storage.
+type ProductImageRequest struct {
+	Key          string
+	ThumbnailKey string
+	ContentType  string
+	Width        int
+	Height       int
+	Size         int
+}
+
+func (s *service) ListProductImages(productID int) ([]ProductImage, error) {
+	return listProductImages(s.db, productID)
+}
+
+func listProductImages(q queryer, productID int) ([]ProductImage, error) {
+	query := `SELECT product_image_id, product_id, storage_key, thumbnail_key, content_type, width, height, size_bytes, position, created_at
+FROM product_images
+WHERE product_id = $1
+ORDER BY position`
+	rows, err := q.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	productImages := []ProductImage{}
+	for rows.Next() {
+		var img ProductImage
+		if err := rows.Scan(&img.ImageID, &img.ProductID, &img.Key, &img.ThumbnailKey, &img.ContentType, &img.Width, &img.Height, &img.Size, &img.Position, &img.CreatedAt); err != nil {
+			return nil, err
+		}
+		productImages = append(productImages, img)
+	}
+	return productImages, rows.Err()
+}
+
+// CheckProductImageUpload reports whether the seller may add an image to the
+// product, so that uploads can be rejected before they are processed.
+// AddProductImage checks again when the image is stored.
+func (s *service) CheckProductImageUpload(productID int, sellerID int) error {
+	var (
+		productSellerID int
+		count           int
+	)
+	query := `SELECT p.seller_id, (SELECT COUNT(*) FROM product_images i WHERE i.product_id = p.product_id) FROM products p WHERE p.product_id = $1`
+	if err := s.db.QueryRow(query, productID).Scan(&productSellerID, &count); err != nil {
+		return err
+	}
+	if productSellerID != sellerID {
+		return ErrNotProductSeller
+	}
+	if count >= MaxProductImages {
+		return ErrTooManyImages
+	}
+	return nil
+}
+
+// AddProductImage adds an image after the existing images of the seller's
+// product.
+func (s *service) AddProductImage(productID int, sellerID int, req ProductImageRequest) (ProductImage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return ProductImage{}, err
+	}
+	defer tx.Rollback()
+
+	if err := lockSellerProduct(tx, productID, sellerID); err != nil {
+		return ProductImage{}, err
+	}
+
+	var count int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM product_images WHERE product_id = $1`, productID).Scan(&count); err != nil {
+		return ProductImage{}, err
+	}
+	if count >= MaxProductImages {
+		return ProductImage{}, ErrTooManyImages
+	}
+
+	img := ProductImage{ProductID: productID, Key: req.Key, ThumbnailKey: req.ThumbnailKey, ContentType: req.ContentType, Width: req.Width, Height: req.Height, Size: req.Size, Position: count + 1}
+	query := `INSERT INTO product_images (product_id, storage_key, thumbnail_key, content_type, width, height, size_bytes, position) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING product_image_id, created_at`
+	err = tx.QueryRow(query, productID, req.Key, req.ThumbnailKey, req.ContentType, req.Width, req.Height, req.Size, img.Position).Scan(&img.ImageID, &img.CreatedAt)
+	if err != nil {
+		return ProductImage{}, err
+	}
+	return img, tx.Commit()
+}
+
+// DeleteProductImage removes an image of the seller's product and closes the
+// gap it leaves in the order. The deleted image is returned so that its files
+// can be removed from storage.
+func (s *service) DeleteProductImage(productID int, imageID int, sellerID int) (ProductImage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return ProductImage{}, err
+	}
+	defer tx.Rollback()
+
+	if err := lockSellerProduct(tx, productID, sellerID); err != nil {
+		return ProductImage{}, err
+	}
+
+	img := ProductImage{ImageID: imageID, ProductID: productID}
+	query := `DELETE FROM product_images WHERE product_image_id = $1 AND product_id = $2 RETURNING storage_key, thumbnail_key, content_type, width, height, size_bytes, position, created_at`
+	err = tx.QueryRow(query, imageID, productID).Scan(&img.Key, &img.ThumbnailKey, &img.ContentType, &img.Width, &img.Height, &img.Size, &img.Position, &img.CreatedAt)
+	if err != nil {
+		return ProductImage{}, err
+	}
+	if _, err := tx.Exec(`UPDATE product_images SET position = position - 1 WHERE product_id = $1 AND position > $2`, productID, img.Position); err != nil {
+		return ProductImage{}, err
+	}
+	return img, tx.Commit()
+}
+
+// ReorderProductImages puts the images of the seller's product in the given
+// order, which must list each of them once.
+func (s *service) ReorderProductImages(productID int, sellerID int, imageIDs []int) ([]ProductImage, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockSellerProduct(tx, productID, sellerID); err != nil {
+		return nil, err
+	}
+
+	current, err := listProductImages(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIDs) != len(current) {
+		return nil, ErrInvalidImageOrder
+	}
+	listed := make(map[int]bool, len(current))
+	for _, img := range current {
+		listed[img.ImageID] = false
+	}
+	for _, imageID := range imageIDs {
+		seen, ok := listed[imageID]
+		if !ok || seen {
+			return nil, ErrInvalidImageOrder
+		}
+		listed[imageID] = true
+	}
+
+	for i, imageID := range imageIDs {
+		if _, err := tx.Exec(`UPDATE product_images SET position = $2 WHERE product_image_id = $1`, imageID, i+1); err != nil {
+			return nil, err
+		}
+	}
+
+	reordered, err := listProductImages(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	return reordered, tx.Commit()
+}
+
+// lockSellerProduct locks a product while its images change, making sure it
+// is sold by the seller.
+func lockSellerProduct(tx queryer, productID int, sellerID int) error {
+	var productSellerID int
+	err := tx.QueryRow(`SELECT seller_id FROM products WHERE product_id = $1 FOR UPDATE`, productID).Scan(&productSellerID)
+	if err != nil {
+		return err
+	}
+	if productSellerID != sellerID {
+		return ErrNotProductSeller
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/tax"
+)
+
+// ErrOrderNotInvoiceable is returned when an invoice is requested for an
+// order that has not been completed.
+var ErrOrderNotInvoiceable = errors.New("order has not been invoiced")
+
+// Invoice holds everything printed on the invoice for an order, issued when
+// the order is completed. Seller and buyer are the addresses of their user
+// profiles when it was issued.
+type Invoice struct {
+	InvoiceID      int            `json:"invoice_id"`
+	Number         string         `json:"number"`
+	IssuedAt       time.Time      `json:"issued_at"`
+	OrderID        int            `json:"order_id"`
+	OrderDate      time.Time      `json:"order_date"`
+	BuyerID        int            `json:"buyer_id"`
+	SellerID       int            `json:"seller_id"`
+	SellerType     string         `json:"seller_type"`
+	Seller         OrderAddress   `json:"seller"`
+	Buyer          OrderAddress   `json:"buyer"`
+	ShipTo         *OrderAddress  `json:"ship_to,omitempty"`
+	Description    string         `json:"description"`
+	Quantity       int            `json:"quantity"`
+	UnitPrice      currency.Money `json:"unit_price"`
+	Subtotal       currency.Money `json:"subtotal"`
+	ShippingMethod *string        `json:"shipping_method,omitempty"`
+	ShippingCost   currency.Money `json:"shipping_cost"`
+	TaxLines       []tax.TaxLine  `json:"tax_lines"`
+	TaxAmount      currency.Money `json:"tax_amount"`
+	Total          currency.Money `json:"total"`
+}
+
+// invoiceNumber formats the number of a seller's invoice. The seller ID keeps
+// numbers unique across sellers.
+func invoiceNumber(sellerID int, number int) string {
+	return fmt.Sprintf("%d-%06d", sellerID, number)
+}
+
+// GetInvoice returns the invoice for an order. Only the buyer and seller may
+// request it.
+func (s *service) GetInvoice(orderID int, userID int) (Invoice, error) {
+	var buyerID, sellerID int
+	err := s.db.QueryRow(`SELECT buyer_id, seller_id FROM orders WHERE order_id = $1`, orderID).Scan(&buyerID, &sellerID)
+	if err != nil {
+		return Invoice{}, err
+	}
+	if userID != buyerID && userID != sellerID {
+		return Invoice{}, ErrNotOrderParticipant
+	}
+
+	invoice, err := loadInvoice(s.db, orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Invoice{}, ErrOrderNotInvoiceable
+	}
+	return invoice, err
+}
+
+// issueInvoice issues the invoice for a completed order with the seller's next
+// invoice number, unless it already has one. The caller must hold the lock on
+// the order row.
+func issueInvoice(tx queryer, orderID int) error {
+	var (
+		buyerID, sellerID int
+		issued            bool
+	)
+	err := tx.QueryRow(`SELECT buyer_id, seller_id, EXISTS (SELECT 1 FROM invoices WHERE order_id = $1) FROM orders WHERE order_id = $1`, orderID).Scan(&buyerID, &sellerID, &issued)
+	if err != nil || issued {
+		return err
+	}
+
+	var number int
+	query := `INSERT INTO invoice_counters (seller_id, last_number) VALUES ($1, 1) ON CONFLICT (seller_id) DO UPDATE SET last_number = invoice_counters.last_number + 1 RETURNING last_number`
+	if err := tx.QueryRow(query, sellerID).Scan(&number); err != nil {
+		return err
+	}
+	query = `INSERT INTO invoices (order_id, seller_id, invoice_number, seller_type,
+	seller_name, seller_street_name, seller_street_number, seller_city, seller_state, seller_zip_code, seller_country,
+	buyer_name, buyer_street_name, buyer_street_number, buyer_city, buyer_state, buyer_zip_code, buyer_country)
+SELECT $1, $2, $3, sellers.seller_type,
+	sellers.first_name || ' ' || sellers.last_name, sellers.street_name, sellers.street_number, sellers.city, sellers.state, sellers.zip_code, seller_countries.country_name,
+	buyers.first_name || ' ' || buyers.last_name, buyers.street_name, buyers.street_number, buyers.city, buyers.state, buyers.zip_code, buyer_countries.country_name
+FROM users sellers
+JOIN countries seller_countries ON sellers.country_id = seller_countries.country_id
+JOIN users buyers ON buyers.user_id = $4
+JOIN countries buyer_countries ON buyers.country_id = buyer_countries.country_id
+WHERE sellers.user_id = $2`
+	_, err = tx.Exec(query, orderID, sellerID, number, buyerID)
+	return err
+}
+
+const invoiceSelect = `SELECT i.invoice_id, i.invoice_number, i.issued_at, o.order_id, o.order_date, o.buyer_id, o.seller_id, i.seller_type, COALESCE(c.name, sp.name), p.condition, l.language_name, o.quantity, o.unit_price, sm.name, o.shipping_cost, o.tax_amount, o.total_amount, o.refunded_amount, o.currency,
+	i.seller_name, i.seller_street_name, i.seller_street_number, i.seller_city, i.seller_state, i.seller_zip_code, i.seller_country,
+	i.buyer_name, i.buyer_street_name, i.buyer_street_number, i.buyer_city, i.buyer_state, i.buyer_zip_code, i.buyer_country,
+	o.shipping_name, o.shipping_street_name, o.shipping_street_number, o.shipping_city, o.shipping_state, o.shipping_zip_code, sc.country_name
+FROM invoices i
+JOIN orders o ON i.order_id = o.order_id
+JOIN products p ON o.product_id = p.product_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+JOIN languages l ON p.language_id = l.language_id
+LEFT JOIN shipping_methods sm ON o.shipping_method_id = sm.shipping_method_id
+LEFT JOIN countries sc ON o.shipping_country_id = sc.country_id`
+
+func loadInvoice(q queryer, orderID int) (Invoice, error) {
+	var (
+		invoice                   Invoice
+		number                    int
+		card, condition, language string
+		unitPrice                 string
+		order                     Order
+		amounts                   orderAmountColumns
+		address                   orderAddressColumns
+	)
+	dest := []any{&invoice.InvoiceID, &number, &invoice.IssuedAt, &invoice.OrderID, &invoice.OrderDate, &invoice.BuyerID, &invoice.SellerID, &invoice.SellerType, &card, &condition, &language, &invoice.Quantity, &unitPrice, &invoice.ShippingMethod}
+	dest = append(dest, amounts.dest()...)
+	dest = append(dest, &invoice.Seller.Name, &invoice.Seller.StreetName, &invoice.Seller.StreetNumber, &invoice.Seller.City, &invoice.Seller.State, &invoice.Seller.ZipCode, &invoice.Seller.Country)
+	dest = append(dest, &invoice.Buyer.Name, &invoice.Buyer.StreetName, &invoice.Buyer.StreetNumber, &invoice.Buyer.City, &invoice.Buyer.State, &invoice.Buyer.ZipCode, &invoice.Buyer.Country)
+	dest = append(dest, address.dest()...)
+	if err := q.QueryRow(invoiceSelect+` WHERE i.order_id = $1`, orderID).Scan(dest...); err != nil {
+		return Invoice{}, err
+	}
+	if err := amounts.apply(&order); err != nil {
+		return Invoice{}, err
+	}
+
+	invoice.Number = invoiceNumber(invoice.SellerID, number)
+	invoice.Description = fmt.Sprintf("%s (%s, %s)", card, condition, language)
+	invoice.ShipTo = address.address()
+	invoice.ShippingCost = order.ShippingCost
+	invoice.TaxAmount = order.TaxAmount
+	invoice.Total = order.Total
+
+	var err error
+	if invoice.UnitPrice, err = currency.Parse(unitPrice, order.Total.Currency); err != nil {
+		return Invoice{}, err
+	}
+	invoice.Subtotal = invoice.UnitPrice.Mul(invoice.Quantity)
+
+	invoice.TaxLines, err = orderTaxLines(q, orderID, order.Total.Currency)
+	if err != nil {
+		return Invoice{}, err
+	}
+	return invoice, nil
+}
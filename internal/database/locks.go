@@ -0,0 +1,67 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// AdvisoryLock is a session-level Postgres advisory lock. The session holding
+// it is kept on a dedicated connection; the lock is released when that
+// connection closes, so a crashed replica never keeps it.
+type AdvisoryLock struct {
+	db  *sql.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func (s *service) AdvisoryLock(key int64) *AdvisoryLock {
+	return &AdvisoryLock{db: s.db, key: key}
+}
+
+// TryLock acquires the lock if it is free and reports whether it is held by
+// this process.
+func (l *AdvisoryLock) TryLock(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if err := l.conn.PingContext(ctx); err == nil {
+			return true, nil
+		}
+		// The session is gone and the lock with it.
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return false, err
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+// Unlock releases the lock if this process holds it.
+func (l *AdvisoryLock) Unlock() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+	l.conn = nil
+	return err
+}
@@ -0,0 +1,212 @@
+package database
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotConversationParticipant is returned when a user accesses a
+	// conversation they are not part of.
+	ErrNotConversationParticipant = errors.New("user is not a participant of the conversation")
+	// ErrInvalidRecipient is returned when a user tries to start a conversation
+	// with themselves.
+	ErrInvalidRecipient = errors.New("invalid conversation recipient")
+)
+
+type Conversation struct {
+	ConversationID int        `json:"conversation_id"`
+	UserOne        string     `json:"user_one"`
+	UserTwo        string     `json:"user_two"`
+	OrderID        *int       `json:"order_id,omitempty"`
+	ProductID      *int       `json:"product_id,omitempty"`
+	UnreadCount    int        `json:"unread_count"`
+	LastMessageAt  *time.Time `json:"last_message_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ConversationRequest struct {
+	RecipientID int    `json:"recipient_id"`
+	OrderID     *int   `json:"order_id,omitempty"`
+	ProductID   *int   `json:"product_id,omitempty"`
+	Body        string `json:"body"`
+}
+
+type Message struct {
+	MessageID      int        `json:"message_id"`
+	ConversationID int        `json:"conversation_id"`
+	Sender         string     `json:"sender"`
+	Body           string     `json:"body"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+// ListConversations returns the conversations of a user, most recently active
+// first, with the number of messages the user has not read yet.
+func (s *service) ListConversations(userID int) ([]Conversation, error) {
+	query := `SELECT cv.conversation_id, one.username, two.username, cv.order_id, cv.product_id,
+		(SELECT COUNT(*) FROM messages m WHERE m.conversation_id = cv.conversation_id AND m.sender_id <> $1 AND m.read_at IS NULL),
+		(SELECT MAX(m.created_at) FROM messages m WHERE m.conversation_id = cv.conversation_id),
+		cv.created_at, cv.updated_at
+		FROM conversations cv JOIN users one ON cv.user_one_id = one.user_id JOIN users two ON cv.user_two_id = two.user_id
+		WHERE cv.user_one_id = $1 OR cv.user_two_id = $1 ORDER BY cv.updated_at DESC`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var conversations []Conversation
+	for rows.Next() {
+		var conversation Conversation
+		if err := rows.Scan(&conversation.ConversationID, &conversation.UserOne, &conversation.UserTwo, &conversation.OrderID, &conversation.ProductID, &conversation.UnreadCount, &conversation.LastMessageAt, &conversation.CreatedAt, &conversation.UpdatedAt); err != nil {
+			return nil, err
+		}
+		conversations = append(conversations, conversation)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return conversations, nil
+}
+
+// CreateConversation opens a conversation between the sender and the recipient,
+// or reuses the existing one for the same order/product, and posts the first
+// message. It returns the conversation ID.
+func (s *service) CreateConversation(senderID int, conversation ConversationRequest) (int, error) {
+	if conversation.RecipientID == senderID {
+		return 0, ErrInvalidRecipient
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if conversation.OrderID != nil {
+		var buyerID, sellerID int
+		err := tx.QueryRow(`SELECT buyer_id, seller_id FROM orders WHERE order_id = $1`, *conversation.OrderID).Scan(&buyerID, &sellerID)
+		if err != nil {
+			return 0, err
+		}
+		if !(senderID == buyerID && conversation.RecipientID == sellerID) && !(senderID == sellerID && conversation.RecipientID == buyerID) {
+			return 0, ErrNotOrderParticipant
+		}
+	}
+
+	if conversation.ProductID != nil {
+		var sellerID int
+		err := tx.QueryRow(`SELECT seller_id FROM products WHERE product_id = $1`, *conversation.ProductID).Scan(&sellerID)
+		if err != nil {
+			return 0, err
+		}
+		if senderID != sellerID && conversation.RecipientID != sellerID {
+			return 0, ErrInvalidRecipient
+		}
+	}
+
+	userOneID, userTwoID := senderID, conversation.RecipientID
+	if userOneID > userTwoID {
+		userOneID, userTwoID = userTwoID, userOneID
+	}
+
+	var conversationID int
+	query := `INSERT INTO conversations (user_one_id, user_two_id, order_id, product_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_one_id, user_two_id, COALESCE(order_id, 0), COALESCE(product_id, 0)) DO UPDATE SET updated_at = CURRENT_TIMESTAMP
+		RETURNING conversation_id`
+	err = tx.QueryRow(query, userOneID, userTwoID, conversation.OrderID, conversation.ProductID).Scan(&conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`INSERT INTO messages (conversation_id, sender_id, body) VALUES ($1, $2, $3)`, conversationID, senderID, conversation.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	return conversationID, tx.Commit()
+}
+
+// ListMessages returns the messages of a conversation in chronological order
+// and marks the ones sent by the other participant as read.
+func (s *service) ListMessages(conversationID int, userID int) ([]Message, error) {
+	if _, err := s.checkConversationParticipant(conversationID, userID); err != nil {
+		return nil, err
+	}
+
+	_, err := s.db.Exec(`UPDATE messages SET read_at = CURRENT_TIMESTAMP WHERE conversation_id = $1 AND sender_id <> $2 AND read_at IS NULL`, conversationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT m.message_id, m.conversation_id, u.username AS sender, m.body, m.read_at, m.created_at FROM messages m JOIN users u ON m.sender_id = u.user_id WHERE m.conversation_id = $1 ORDER BY m.created_at, m.message_id`
+	rows, err := s.db.Query(query, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var message Message
+		if err := rows.Scan(&message.MessageID, &message.ConversationID, &message.Sender, &message.Body, &message.ReadAt, &message.CreatedAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// CreateMessage posts a message to a conversation and returns the ID of the
+// other participant.
+func (s *service) CreateMessage(conversationID int, senderID int, message MessageRequest) (int, error) {
+	recipientID, err := s.checkConversationParticipant(conversationID, senderID)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO messages (conversation_id, sender_id, body) VALUES ($1, $2, $3)`, conversationID, senderID, message.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`UPDATE conversations SET updated_at = CURRENT_TIMESTAMP WHERE conversation_id = $1`, conversationID)
+	if err != nil {
+		return 0, err
+	}
+
+	return recipientID, tx.Commit()
+}
+
+// checkConversationParticipant verifies that the user takes part in the
+// conversation and returns the ID of the other participant.
+func (s *service) checkConversationParticipant(conversationID int, userID int) (int, error) {
+	var userOneID, userTwoID int
+	err := s.db.QueryRow(`SELECT user_one_id, user_two_id FROM conversations WHERE conversation_id = $1`, conversationID).Scan(&userOneID, &userTwoID)
+	if err != nil {
+		return 0, err
+	}
+	switch userID {
+	case userOneID:
+		return userTwoID, nil
+	case userTwoID:
+		return userOneID, nil
+	default:
+		return 0, ErrNotConversationParticipant
+	}
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// notificationChannel is the Postgres channel on which the notifications
+// table announces the ID of every new row.
+const notificationChannel = "notifications"
+
+// Notification types stored in the notifications table.
+const (
+	NotificationOrderCreated       = "order_created"
+	NotificationOrderStatusChanged = "order_status_changed"
+	NotificationOrderDelivered     = "order_delivered"
+	NotificationShippingReminder   = "shipping_reminder"
+	NotificationDisputeOpened      = "dispute_opened"
+	NotificationDisputeUpdated     = "dispute_updated"
+	NotificationDisputeResolved    = "dispute_resolved"
+	NotificationNewMessage         = "new_message"
+	NotificationWantlistMatch      = "wantlist_match"
+	NotificationReviewReceived     = "review_received"
+	NotificationOutbid             = "outbid"
+	NotificationAuctionWon         = "auction_won"
+	NotificationAuctionEnded       = "auction_ended"
+	NotificationOfferReceived      = "offer_received"
+	NotificationOfferUpdated       = "offer_updated"
+	NotificationTradeReceived      = "trade_received"
+	NotificationTradeUpdated       = "trade_updated"
+	NotificationVacationEnded      = "vacation_ended"
+	NotificationWatchlistAlert     = "watchlist_alert"
+)
+
+type Notification struct {
+	NotificationID int             `json:"notification_id"`
+	UserID         int             `json:"user_id"`
+	Type           string          `json:"type"`
+	Payload        json.RawMessage `json:"payload"`
+	ReadAt         *time.Time      `json:"read_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// CreateNotification records an event for a user. The payload is stored as JSON.
+func (s *service) CreateNotification(userID int, notificationType string, payload any) (Notification, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Notification{}, err
+	}
+
+	notification := Notification{UserID: userID, Type: notificationType, Payload: data}
+	query := `INSERT INTO notifications (user_id, type, payload) VALUES ($1, $2, $3) RETURNING notification_id, created_at`
+	err = s.db.QueryRow(query, userID, notificationType, data).Scan(&notification.NotificationID, &notification.CreatedAt)
+	if err != nil {
+		return Notification{}, err
+	}
+	return notification, nil
+}
+
+// ListenNotifications delivers every notification stored from now on, by any
+// server process, until ctx is done or the connection is lost.
+func (s *service) ListenNotifications(ctx context.Context, deliver func(Notification)) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		listener := driverConn.(*stdlib.Conn).Conn()
+		// The connection stays subscribed, so it is closed rather than going
+		// back to the pool.
+		defer listener.Close(context.Background())
+
+		if _, err := listener.Exec(ctx, "LISTEN "+notificationChannel); err != nil {
+			return err
+		}
+		for {
+			announced, err := listener.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			notificationID, err := strconv.Atoi(announced.Payload)
+			if err != nil {
+				return err
+			}
+
+			var notification Notification
+			query := `SELECT notification_id, user_id, type, payload, read_at, created_at FROM notifications WHERE notification_id = $1`
+			err = s.db.QueryRowContext(ctx, query, notificationID).Scan(&notification.NotificationID, &notification.UserID, &notification.Type, &notification.Payload, &notification.ReadAt, &notification.CreatedAt)
+			if errors.Is(err, sql.ErrNoRows) {
+				// Deleted with its user in the meantime.
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			deliver(notification)
+		}
+	})
+}
+
+func (s *service) ListNotifications(userID int, unreadOnly bool) ([]Notification, error) {
+	query := `SELECT notification_id, user_id, type, payload, read_at, created_at FROM notifications WHERE user_id = $1 AND ($2 = FALSE OR read_at IS NULL) ORDER BY created_at DESC, notification_id DESC`
+	rows, err := s.db.Query(query, userID, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []Notification
+	for rows.Next() {
+		var notification Notification
+		if err := rows.Scan(&notification.NotificationID, &notification.UserID, &notification.Type, &notification.Payload, &notification.ReadAt, &notification.CreatedAt); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (s *service) MarkNotificationRead(notificationID int, userID int) error {
+	query := `UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE notification_id = $1 AND user_id = $2`
+
+	result, err := s.db.Exec(query, notificationID, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (s *service) MarkAllNotificationsRead(userID int) error {
+	_, err := s.db.Exec(`UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL`, userID)
+	return err
+}
@@ -0,0 +1,370 @@
+package database
+
+import (
+	"cardmarket_backend/internal/currency"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Offer statuses stored in the offers table.
+const (
+	OfferStatusPending   = "pending"
+	OfferStatusAccepted  = "accepted"
+	OfferStatusDeclined  = "declined"
+	OfferStatusCountered = "countered"
+	OfferStatusExpired   = "expired"
+)
+
+// Offers expire after defaultOfferLifetime unless the offer says otherwise,
+// and after maxOfferLifetime at the latest.
+const (
+	defaultOfferLifetime = 48 * time.Hour
+	maxOfferLifetime     = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidOffer is returned when an offer is not below the asking
+	// price, or its quantity or expiry is out of range.
+	ErrInvalidOffer = errors.New("invalid offer")
+	// ErrOwnProduct is returned when sellers make an offer on their own
+	// product.
+	ErrOwnProduct = errors.New("cannot make an offer on your own product")
+	// ErrNotOfferRecipient is returned when someone other than the party the
+	// offer was made to responds to it.
+	ErrNotOfferRecipient = errors.New("offer was not made to this user")
+	// ErrOfferClosed is returned when responding to an offer that was
+	// already answered or has expired.
+	ErrOfferClosed = errors.New("offer is no longer open")
+)
+
+type Offer struct {
+	OfferID       int            `json:"offer_id"`
+	ProductID     int            `json:"product_id"`
+	Card          string         `json:"card"`
+	BuyerID       int            `json:"buyer_id"`
+	SellerID      int            `json:"seller_id"`
+	Buyer         string         `json:"buyer"`
+	Seller        string         `json:"seller"`
+	OfferedBy     string         `json:"offered_by"`
+	ParentOfferID *int           `json:"parent_offer_id,omitempty"`
+	Amount        currency.Money `json:"amount"`
+	AskingPrice   currency.Money `json:"asking_price"`
+	Quantity      int            `json:"quantity"`
+	Status        string         `json:"status"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	OrderID       *int           `json:"order_id,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// RecipientID returns the user who may accept, decline or counter the offer.
+func (o Offer) RecipientID() int {
+	if o.OfferedBy == "seller" {
+		return o.BuyerID
+	}
+	return o.SellerID
+}
+
+// OfferRequest is a buyer's offer: the price per card, and how the cards are
+// shipped if the offer is accepted. Without an address, the buyer's default
+// address is used.
+type OfferRequest struct {
+	Amount           currency.Money `json:"amount"`
+	Quantity         int            `json:"quantity"`
+	AddressID        int            `json:"address_id"`
+	ShippingMethodID int            `json:"shipping_method_id"`
+	ExpiresAt        *time.Time     `json:"expires_at,omitempty"`
+}
+
+// CounterOfferRequest answers an offer with another price. Without a
+// quantity, the quantity of the countered offer is kept.
+type CounterOfferRequest struct {
+	Amount    currency.Money `json:"amount"`
+	Quantity  int            `json:"quantity"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+}
+
+const offerSelect = `SELECT o.offer_id, o.product_id, COALESCE(c.name, sp.name), o.buyer_id, o.seller_id, buyers.username, sellers.username, o.offered_by, o.parent_offer_id, o.amount, p.price, o.currency, o.quantity, o.status, o.expires_at, o.order_id, o.created_at
+FROM offers o
+JOIN products p ON o.product_id = p.product_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+JOIN users buyers ON o.buyer_id = buyers.user_id
+JOIN users sellers ON o.seller_id = sellers.user_id`
+
+func queryOffers(q queryer, query string, args ...any) ([]Offer, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := []Offer{}
+	for rows.Next() {
+		var (
+			o                   Offer
+			amount, price, code string
+		)
+		if err := rows.Scan(&o.OfferID, &o.ProductID, &o.Card, &o.BuyerID, &o.SellerID, &o.Buyer, &o.Seller, &o.OfferedBy, &o.ParentOfferID, &amount, &price, &code, &o.Quantity, &o.Status, &o.ExpiresAt, &o.OrderID, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		if o.Amount, err = currency.Parse(amount, code); err != nil {
+			return nil, err
+		}
+		if o.AskingPrice, err = currency.Parse(price, code); err != nil {
+			return nil, err
+		}
+		offers = append(offers, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return offers, nil
+}
+
+func getOffer(q queryer, offerID int) (Offer, error) {
+	offers, err := queryOffers(q, offerSelect+` WHERE o.offer_id = $1`, offerID)
+	if err != nil {
+		return Offer{}, err
+	}
+	if len(offers) == 0 {
+		return Offer{}, sql.ErrNoRows
+	}
+	return offers[0], nil
+}
+
+// ListUserOffers returns the offers a user made or received, newest first.
+func (s *service) ListUserOffers(userID int) ([]Offer, error) {
+	return queryOffers(s.db, offerSelect+` WHERE o.buyer_id = $1 OR o.seller_id = $1 ORDER BY o.created_at DESC, o.offer_id DESC`, userID)
+}
+
+// CreateOffer makes an offer on an available product below its asking price.
+// The buyer's address must be served by the chosen shipping method. Stock is
+// only reserved once the offer is accepted.
+func (s *service) CreateOffer(productID int, buyerID int, req OfferRequest) (Offer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Offer{}, err
+	}
+	defer tx.Rollback()
+
+	var (
+		sellerID, stock, originCountryID int
+		available, onVacation            bool
+		price, code                      string
+	)
+	query := `SELECT p.seller_id, p.quantity, p.is_available, p.price, p.currency, sellers.country_id, sellers.on_vacation FROM products p JOIN users sellers ON p.seller_id = sellers.user_id WHERE p.product_id = $1`
+	err = tx.QueryRow(query, productID).Scan(&sellerID, &stock, &available, &price, &code, &originCountryID, &onVacation)
+	if err != nil {
+		return Offer{}, err
+	}
+	if !available {
+		return Offer{}, sql.ErrNoRows
+	}
+	if onVacation {
+		return Offer{}, ErrSellerOnVacation
+	}
+	if sellerID == buyerID {
+		return Offer{}, ErrOwnProduct
+	}
+	askingPrice, err := currency.Parse(price, code)
+	if err != nil {
+		return Offer{}, err
+	}
+	expiresAt, err := validateOffer(req.Amount, askingPrice, req.Quantity, req.ExpiresAt)
+	if err != nil {
+		return Offer{}, err
+	}
+	if stock < req.Quantity {
+		return Offer{}, ErrInsufficientStock
+	}
+
+	_, destCountryID, err := checkoutAddress(tx, buyerID, req.AddressID)
+	if err != nil {
+		return Offer{}, err
+	}
+	if _, err := quoteOrderShipping(tx, sellerID, req.ShippingMethodID, originCountryID, destCountryID, req.Quantity, req.Amount.Mul(req.Quantity)); err != nil {
+		return Offer{}, err
+	}
+
+	var offerID int
+	query = `INSERT INTO offers (product_id, buyer_id, seller_id, offered_by, amount, currency, quantity, address_id, shipping_method_id, expires_at) VALUES ($1, $2, $3, 'buyer', $4, $5, $6, NULLIF($7, 0), $8, $9) RETURNING offer_id`
+	err = tx.QueryRow(query, productID, buyerID, sellerID, req.Amount, req.Amount.Currency, req.Quantity, req.AddressID, req.ShippingMethodID, expiresAt).Scan(&offerID)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	offer, err := getOffer(tx, offerID)
+	if err != nil {
+		return Offer{}, err
+	}
+	return offer, tx.Commit()
+}
+
+// validateOffer checks the price and quantity of an offer and returns when it
+// expires.
+func validateOffer(amount currency.Money, askingPrice currency.Money, quantity int, expiresAt *time.Time) (time.Time, error) {
+	if amount.Currency != askingPrice.Currency {
+		return time.Time{}, currency.ErrCurrencyMismatch
+	}
+	if !amount.IsPositive() || amount.Units >= askingPrice.Units || quantity < 1 {
+		return time.Time{}, ErrInvalidOffer
+	}
+	now := time.Now()
+	if expiresAt == nil {
+		return now.Add(defaultOfferLifetime), nil
+	}
+	if !expiresAt.After(now) || expiresAt.Sub(now) > maxOfferLifetime {
+		return time.Time{}, ErrInvalidOffer
+	}
+	return *expiresAt, nil
+}
+
+// lockOpenOffer locks a pending offer made to userID.
+func lockOpenOffer(tx queryer, offerID int, userID int) (Offer, error) {
+	var id int
+	if err := tx.QueryRow(`SELECT offer_id FROM offers WHERE offer_id = $1 FOR UPDATE`, offerID).Scan(&id); err != nil {
+		return Offer{}, err
+	}
+	offer, err := getOffer(tx, id)
+	if err != nil {
+		return Offer{}, err
+	}
+	if offer.RecipientID() != userID {
+		return Offer{}, ErrNotOfferRecipient
+	}
+	if offer.Status != OfferStatusPending || !offer.ExpiresAt.After(time.Now()) {
+		return Offer{}, ErrOfferClosed
+	}
+	return offer, nil
+}
+
+// AcceptOffer accepts an offer and places the order at the offered price.
+// The stock is reserved as at checkout; the order is pending payment like
+// any other.
+func (s *service) AcceptOffer(offerID int, userID int) (Offer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Offer{}, err
+	}
+	defer tx.Rollback()
+
+	offer, err := lockOpenOffer(tx, offerID, userID)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	order := OrderRequest{
+		BuyerID:   offer.BuyerID,
+		SellerID:  offer.SellerID,
+		ProductID: offer.ProductID,
+		Quantity:  offer.Quantity,
+		OrderDate: time.Now(),
+	}
+	var addressID sql.NullInt64
+	err = tx.QueryRow(`SELECT address_id, shipping_method_id FROM offers WHERE offer_id = $1`, offerID).Scan(&addressID, &order.ShippingMethodID)
+	if err != nil {
+		return Offer{}, err
+	}
+	order.AddressID = int(addressID.Int64)
+
+	product, err := reserveStock(tx, order.ProductID, order.SellerID, order.Quantity)
+	if err != nil {
+		return Offer{}, err
+	}
+	orderID, err := insertOrder(tx, order, offer.Amount, product.originCountryID)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	_, err = tx.Exec(`UPDATE offers SET status = 'accepted', order_id = $2, updated_at = CURRENT_TIMESTAMP WHERE offer_id = $1`, offerID, orderID)
+	if err != nil {
+		return Offer{}, err
+	}
+	offer.Status, offer.OrderID = OfferStatusAccepted, &orderID
+	return offer, tx.Commit()
+}
+
+func (s *service) DeclineOffer(offerID int, userID int) (Offer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Offer{}, err
+	}
+	defer tx.Rollback()
+
+	offer, err := lockOpenOffer(tx, offerID, userID)
+	if err != nil {
+		return Offer{}, err
+	}
+	_, err = tx.Exec(`UPDATE offers SET status = 'declined', updated_at = CURRENT_TIMESTAMP WHERE offer_id = $1`, offerID)
+	if err != nil {
+		return Offer{}, err
+	}
+	offer.Status = OfferStatusDeclined
+	return offer, tx.Commit()
+}
+
+// CounterOffer answers an offer with a new one from the other party, shipped
+// the same way. The countered offer is closed.
+func (s *service) CounterOffer(offerID int, userID int, req CounterOfferRequest) (Offer, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Offer{}, err
+	}
+	defer tx.Rollback()
+
+	offer, err := lockOpenOffer(tx, offerID, userID)
+	if err != nil {
+		return Offer{}, err
+	}
+	if req.Quantity == 0 {
+		req.Quantity = offer.Quantity
+	}
+	expiresAt, err := validateOffer(req.Amount, offer.AskingPrice, req.Quantity, req.ExpiresAt)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	offeredBy := "seller"
+	if userID == offer.BuyerID {
+		offeredBy = "buyer"
+	}
+	_, err = tx.Exec(`UPDATE offers SET status = 'countered', updated_at = CURRENT_TIMESTAMP WHERE offer_id = $1`, offerID)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	var counterID int
+	query := `INSERT INTO offers (product_id, buyer_id, seller_id, offered_by, parent_offer_id, amount, currency, quantity, address_id, shipping_method_id, expires_at)
+		SELECT product_id, buyer_id, seller_id, $2, offer_id, $3, $4, $5, address_id, shipping_method_id, $6 FROM offers WHERE offer_id = $1
+		RETURNING offer_id`
+	err = tx.QueryRow(query, offerID, offeredBy, req.Amount, req.Amount.Currency, req.Quantity, expiresAt).Scan(&counterID)
+	if err != nil {
+		return Offer{}, err
+	}
+
+	counter, err := getOffer(tx, counterID)
+	if err != nil {
+		return Offer{}, err
+	}
+	return counter, tx.Commit()
+}
+
+// ExpireOffers closes the pending offers that expired before now.
+func (s *service) ExpireOffers(now time.Time) ([]Offer, error) {
+	query := `WITH expired AS (
+			UPDATE offers SET status = 'expired', updated_at = CURRENT_TIMESTAMP
+			WHERE status = 'pending' AND expires_at <= $1
+			RETURNING offer_id
+		)
+		` + offerSelect + ` JOIN expired e ON o.offer_id = e.offer_id`
+	offers, err := queryOffers(s.db, query, now)
+	if err != nil {
+		return nil, err
+	}
+	// The select sees the offers as they were before the update.
+	for i := range offers {
+		offers[i].Status = OfferStatusExpired
+	}
+	return offers, nil
+}
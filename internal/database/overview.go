@@ -0,0 +1,224 @@
+package database
+
+import (
+	"database/sql"
+
+	"cardmarket_backend/internal/currency"
+)
+
+// priceGuideDays is the period of sales the price guide averages over.
+const priceGuideDays = 30
+
+// CardOverview gathers what the card page shows: the card with its set, the
+// available listings, the price guide and the reputations of the sellers.
+type CardOverview struct {
+	Card Card    `json:"card"`
+	Set  CardSet `json:"set"`
+	// Listings groups the available listings by condition and language,
+	// cheapest group first.
+	Listings   []ListingGroup `json:"listings"`
+	PriceGuide PriceGuide     `json:"price_guide"`
+	Sellers    []SellerRating `json:"sellers"`
+}
+
+type CardSet struct {
+	Name      string `json:"name"`
+	TCGGame   string `json:"tcg_game"`
+	CardCount int    `json:"card_count"`
+}
+
+// ListingGroup holds the listings of one condition and language, cheapest
+// first. Listings in different currencies are compared in euros.
+type ListingGroup struct {
+	Condition    string         `json:"condition"`
+	Language     string         `json:"language"`
+	ListingCount int            `json:"listing_count"`
+	Quantity     int            `json:"quantity"`
+	LowestPrice  currency.Money `json:"lowest_price"`
+	// ConvertedLowestPrice is the lowest price in the currency requested by
+	// the client.
+	ConvertedLowestPrice *currency.Money `json:"converted_lowest_price,omitempty"`
+	Listings             []CardListing   `json:"listings"`
+}
+
+type CardListing struct {
+	ProductID int            `json:"product_id"`
+	SellerID  int            `json:"seller_id"`
+	Seller    string         `json:"seller"`
+	Price     currency.Money `json:"price"`
+	// ConvertedPrice is the price in the currency requested by the client.
+	ConvertedPrice *currency.Money `json:"converted_price,omitempty"`
+	Quantity       int             `json:"quantity"`
+
+	condition, language string
+	euroPrice           currency.Money
+}
+
+// PriceGuide summarises the prices of a card in euros: the available
+// listings, and the copies sold over the last priceGuideDays days.
+type PriceGuide struct {
+	LowestPrice         *currency.Money `json:"lowest_price"`
+	AverageListingPrice *currency.Money `json:"average_listing_price"`
+	AverageSellPrice    *currency.Money `json:"average_sell_price"`
+	SoldCopies          int             `json:"sold_copies"`
+	Days                int             `json:"days"`
+}
+
+// SellerRating is the reputation of a seller listing the card.
+type SellerRating struct {
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	CountryCode string     `json:"country_code"`
+	SellerType  string     `json:"seller_type"`
+	Reputation  Reputation `json:"reputation"`
+}
+
+// GetCardOverview loads the overview of a card with one query each for the
+// card, its listings, its sales and its sellers. Listings of sellers on
+// vacation are left out.
+func (s *service) GetCardOverview(cardID int) (CardOverview, error) {
+	var overview CardOverview
+	card := &overview.Card
+	query := `SELECT c.card_id, c.name, c.image_url, c.description, c.set_name, c.card_number, c.rarity, tcg.name, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM cards s WHERE s.tcg_game_id = c.tcg_game_id AND s.set_name = c.set_name)
+FROM cards c
+JOIN tcg_games tcg ON c.tcg_game_id = tcg.tcg_game_id
+WHERE c.card_id = $1`
+	err := s.db.QueryRow(query, cardID).Scan(&card.ID, &card.Name, &card.ImageURL, &card.Description, &card.SetName, &card.CardNumber, &card.Rarity, &card.TCGGame, &card.CreatedAt, &card.UpdatedAt, &overview.Set.CardCount)
+	if err != nil {
+		return CardOverview{}, err
+	}
+	overview.Set.Name, overview.Set.TCGGame = card.SetName, card.TCGGame
+	overview.PriceGuide.Days = priceGuideDays
+
+	listings, err := s.listCardListings(cardID)
+	if err != nil {
+		return CardOverview{}, err
+	}
+	overview.Listings = groupListings(listings)
+
+	if len(listings) > 0 {
+		var sum int64
+		for _, listing := range listings {
+			sum += listing.euroPrice.Units
+		}
+		lowest := listings[0].euroPrice
+		average := currency.New((sum+int64(len(listings))/2)/int64(len(listings)), currency.Base)
+		overview.PriceGuide.LowestPrice, overview.PriceGuide.AverageListingPrice = &lowest, &average
+	}
+
+	// The sold price of a copy is the order total without shipping and tax.
+	var averageSellPrice sql.NullString
+	query = `SELECT COALESCE(SUM(o.quantity), 0), ROUND(SUM((o.total_amount - o.shipping_cost - o.tax_amount) / COALESCE(er.rate, 1)) / NULLIF(SUM(o.quantity), 0), 2)
+FROM orders o
+JOIN products p ON o.product_id = p.product_id
+LEFT JOIN exchange_rates er ON er.currency_code = o.currency
+WHERE p.card_id = $1 AND o.status IN ('processing', 'delivered', 'completed') AND o.order_date >= CURRENT_TIMESTAMP - make_interval(days => $2)`
+	if err := s.db.QueryRow(query, cardID, priceGuideDays).Scan(&overview.PriceGuide.SoldCopies, &averageSellPrice); err != nil {
+		return CardOverview{}, err
+	}
+	if averageSellPrice.Valid {
+		price, err := currency.Parse(averageSellPrice.String, currency.Base)
+		if err != nil {
+			return CardOverview{}, err
+		}
+		overview.PriceGuide.AverageSellPrice = &price
+	}
+
+	overview.Sellers, err = s.listSellerRatings(listings)
+	if err != nil {
+		return CardOverview{}, err
+	}
+	return overview, nil
+}
+
+// listCardListings returns the available listings of a card, cheapest in
+// euros first.
+func (s *service) listCardListings(cardID int) ([]CardListing, error) {
+	query := `SELECT p.product_id, p.seller_id, sellers.username, p.condition, l.language_name, p.price, p.currency, ROUND(p.price / COALESCE(er.rate, 1), 2), p.quantity
+FROM products p
+JOIN users sellers ON p.seller_id = sellers.user_id
+JOIN languages l ON p.language_id = l.language_id
+LEFT JOIN exchange_rates er ON er.currency_code = p.currency
+WHERE p.card_id = $1 AND p.is_available AND p.quantity > 0 AND NOT sellers.on_vacation
+ORDER BY p.price / COALESCE(er.rate, 1), p.product_id`
+	rows, err := s.db.Query(query, cardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := []CardListing{}
+	for rows.Next() {
+		var (
+			listing                CardListing
+			price, code, euroPrice string
+		)
+		if err := rows.Scan(&listing.ProductID, &listing.SellerID, &listing.Seller, &listing.condition, &listing.language, &price, &code, &euroPrice, &listing.Quantity); err != nil {
+			return nil, err
+		}
+		if listing.Price, err = currency.Parse(price, code); err != nil {
+			return nil, err
+		}
+		if listing.euroPrice, err = currency.Parse(euroPrice, currency.Base); err != nil {
+			return nil, err
+		}
+		listings = append(listings, listing)
+	}
+	return listings, rows.Err()
+}
+
+// groupListings groups listings sorted cheapest first by condition and
+// language. The groups keep that order, so the cheapest group comes first.
+func groupListings(listings []CardListing) []ListingGroup {
+	type groupKey struct{ condition, language string }
+	index := map[groupKey]int{}
+	groups := []ListingGroup{}
+	for _, listing := range listings {
+		key := groupKey{listing.condition, listing.language}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, ListingGroup{Condition: listing.condition, Language: listing.language, LowestPrice: listing.Price, Listings: []CardListing{}})
+		}
+		group := &groups[i]
+		group.ListingCount++
+		group.Quantity += listing.Quantity
+		group.Listings = append(group.Listings, listing)
+	}
+	return groups
+}
+
+// listSellerRatings loads the reputations of the sellers of the listings.
+func (s *service) listSellerRatings(listings []CardListing) ([]SellerRating, error) {
+	sellers := []SellerRating{}
+	if len(listings) == 0 {
+		return sellers, nil
+	}
+	sellerIDs := make([]int, 0, len(listings))
+	for _, listing := range listings {
+		sellerIDs = append(sellerIDs, listing.SellerID)
+	}
+
+	query := `SELECT u.user_id, u.username, c.country_code, u.seller_type, COALESCE(r.review_count, 0), COALESCE(r.average_rating, 0), COALESCE(r.positive_percentage, 0)
+FROM users u
+JOIN countries c ON u.country_id = c.country_id
+LEFT JOIN user_reputations r ON u.user_id = r.user_id
+WHERE u.user_id = ANY($1)
+ORDER BY u.username`
+	rows, err := s.db.Query(query, sellerIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seller SellerRating
+		if err := rows.Scan(&seller.UserID, &seller.Username, &seller.CountryCode, &seller.SellerType, &seller.Reputation.ReviewCount, &seller.Reputation.AverageRating, &seller.Reputation.PositivePercentage); err != nil {
+			return nil, err
+		}
+		sellers = append(sellers, seller)
+	}
+	return sellers, rows.Err()
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PackingSlip holds what a seller needs to pack and label one order.
+type PackingSlip struct {
+	OrderID        int           `json:"order_id"`
+	OrderDate      time.Time     `json:"order_date"`
+	Buyer          string        `json:"buyer"`
+	ShippingMethod *string       `json:"shipping_method,omitempty"`
+	Sender         OrderAddress  `json:"sender"`
+	ShipTo         *OrderAddress `json:"ship_to,omitempty"`
+	// ShippingAddress is the free-text address of orders placed before
+	// addresses were stored structurally.
+	ShippingAddress string            `json:"shipping_address"`
+	Items           []PackingSlipItem `json:"items"`
+}
+
+type PackingSlipItem struct {
+	Quantity   int    `json:"quantity"`
+	Card       string `json:"card"`
+	SetName    string `json:"set_name"`
+	CardNumber string `json:"card_number"`
+	Condition  string `json:"condition"`
+	Language   string `json:"language"`
+}
+
+const packingSlipSelect = `SELECT o.order_id, o.order_date, buyers.username, sm.name, o.shipping_address, o.quantity, COALESCE(c.name, sp.name), COALESCE(c.set_name, sp.set_name), c.card_number, p.condition, l.language_name,
+	sellers.first_name || ' ' || sellers.last_name, sellers.street_name, sellers.street_number, sellers.city, sellers.state, sellers.zip_code, seller_countries.country_name,
+	o.shipping_name, o.shipping_street_name, o.shipping_street_number, o.shipping_city, o.shipping_state, o.shipping_zip_code, sc.country_name
+FROM orders o
+JOIN products p ON o.product_id = p.product_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+JOIN languages l ON p.language_id = l.language_id
+JOIN users sellers ON o.seller_id = sellers.user_id
+JOIN countries seller_countries ON sellers.country_id = seller_countries.country_id
+JOIN users buyers ON o.buyer_id = buyers.user_id
+LEFT JOIN shipping_methods sm ON o.shipping_method_id = sm.shipping_method_id
+LEFT JOIN countries sc ON o.shipping_country_id = sc.country_id`
+
+// ListPackingSlips returns the packing slips of the seller's paid, not yet
+// shipped orders among orderIDs, by order ID. Other orders are left out.
+func (s *service) ListPackingSlips(sellerID int, orderIDs []int) ([]PackingSlip, error) {
+	rows, err := s.db.Query(packingSlipSelect+` WHERE o.seller_id = $1 AND o.order_id = ANY($2) AND o.status = 'processing' ORDER BY o.order_id`, sellerID, orderIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slips := []PackingSlip{}
+	for rows.Next() {
+		var (
+			slip     PackingSlip
+			item     PackingSlipItem
+			address  orderAddressColumns
+			setName  sql.NullString
+			cardCode sql.NullString
+		)
+		dest := []any{&slip.OrderID, &slip.OrderDate, &slip.Buyer, &slip.ShippingMethod, &slip.ShippingAddress, &item.Quantity, &item.Card, &setName, &cardCode, &item.Condition, &item.Language}
+		dest = append(dest, &slip.Sender.Name, &slip.Sender.StreetName, &slip.Sender.StreetNumber, &slip.Sender.City, &slip.Sender.State, &slip.Sender.ZipCode, &slip.Sender.Country)
+		dest = append(dest, address.dest()...)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		item.SetName, item.CardNumber = setName.String, cardCode.String
+		slip.ShipTo = address.address()
+		slip.Items = []PackingSlipItem{item}
+		slips = append(slips, slip)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return slips, nil
+}
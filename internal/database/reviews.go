@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrOrderNotReviewable is returned when a review is submitted for an order
+	// that has not been delivered or completed yet.
+	ErrOrderNotReviewable = errors.New("order is not delivered or completed")
+	// ErrNotOrderParticipant is returned when the user is neither the buyer nor
+	// the seller of the order.
+	ErrNotOrderParticipant = errors.New("user is not a participant of the order")
+	// ErrAlreadyReviewed is returned when the reviewer already rated the order.
+	ErrAlreadyReviewed = errors.New("order already reviewed by this user")
+)
+
+// positiveRating is the lowest overall rating that counts as positive in the
+// reputation summary.
+const positiveRating = 4
+
+type Review struct {
+	ReviewID        int       `json:"review_id"`
+	OrderID         int       `json:"order_id"`
+	Reviewer        string    `json:"reviewer"`
+	Reviewee        string    `json:"reviewee"`
+	Overall         int       `json:"overall"`
+	ItemDescription int       `json:"item_description"`
+	Packaging       int       `json:"packaging"`
+	ShippingSpeed   int       `json:"shipping_speed"`
+	Comment         string    `json:"comment"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type ReviewRequest struct {
+	Overall         int    `json:"overall"`
+	ItemDescription int    `json:"item_description"`
+	Packaging       int    `json:"packaging"`
+	ShippingSpeed   int    `json:"shipping_speed"`
+	Comment         string `json:"comment"`
+}
+
+// Reputation is the cached review summary of a user over the last 12 months.
+// It is refreshed when the user is reviewed and by RefreshReputations as
+// reviews age out of the window.
+type Reputation struct {
+	ReviewCount        int     `json:"review_count"`
+	AverageRating      float64 `json:"average_rating"`
+	PositivePercentage float64 `json:"positive_percentage"`
+}
+
+const reviewSelect = `SELECT r.review_id, r.order_id, reviewers.username AS reviewer, reviewees.username AS reviewee, r.overall, r.item_description, r.packaging, r.shipping_speed, r.comment, r.created_at, r.updated_at FROM reviews r JOIN users reviewers ON r.reviewer_id = reviewers.user_id JOIN users reviewees ON r.reviewee_id = reviewees.user_id`
+
+func (s *service) ListOrderReviews(orderID int) ([]Review, error) {
+	return s.queryReviews(reviewSelect+` WHERE r.order_id = $1 ORDER BY r.created_at`, orderID)
+}
+
+func (s *service) ListUserReviews(userID int) ([]Review, error) {
+	return s.queryReviews(reviewSelect+` WHERE r.reviewee_id = $1 ORDER BY r.created_at DESC`, userID)
+}
+
+func (s *service) queryReviews(query string, args ...any) ([]Review, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reviews []Review
+	for rows.Next() {
+		var review Review
+		if err := rows.Scan(&review.ReviewID, &review.OrderID, &review.Reviewer, &review.Reviewee, &review.Overall, &review.ItemDescription, &review.Packaging, &review.ShippingSpeed, &review.Comment, &review.CreatedAt, &review.UpdatedAt); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// CreateReview stores the reviewer's review of the other party of the order
+// and refreshes the reviewee's cached reputation in the same transaction. It
+// returns the ID of the reviewed user.
+func (s *service) CreateReview(orderID int, reviewerID int, review ReviewRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		buyerID, sellerID int
+		status            string
+		deliveredAt       *time.Time
+	)
+	err = tx.QueryRow(`SELECT buyer_id, seller_id, status, delivered_at FROM orders WHERE order_id = $1`, orderID).Scan(&buyerID, &sellerID, &status, &deliveredAt)
+	if err != nil {
+		return 0, err
+	}
+
+	if status != "completed" && deliveredAt == nil {
+		return 0, ErrOrderNotReviewable
+	}
+
+	var revieweeID int
+	switch reviewerID {
+	case buyerID:
+		revieweeID = sellerID
+	case sellerID:
+		revieweeID = buyerID
+	default:
+		return 0, ErrNotOrderParticipant
+	}
+
+	query := `INSERT INTO reviews (order_id, reviewer_id, reviewee_id, overall, item_description, packaging, shipping_speed, comment) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (order_id, reviewer_id) DO NOTHING`
+	result, err := tx.Exec(query, orderID, reviewerID, revieweeID, review.Overall, review.ItemDescription, review.Packaging, review.ShippingSpeed, review.Comment)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if rowsAffected == 0 {
+		return 0, ErrAlreadyReviewed
+	}
+
+	if err := refreshReputation(tx, revieweeID); err != nil {
+		return 0, err
+	}
+
+	return revieweeID, tx.Commit()
+}
+
+// refreshReputation recomputes the cached reputation of a user from the reviews
+// received in the last 12 months.
+func refreshReputation(tx *sql.Tx, userID int) error {
+	query := `INSERT INTO user_reputations (user_id, review_count, average_rating, positive_percentage, updated_at)
+		SELECT $1, COUNT(*), COALESCE(AVG(overall), 0), COALESCE(100.0 * COUNT(*) FILTER (WHERE overall >= $2) / NULLIF(COUNT(*), 0), 0), CURRENT_TIMESTAMP
+		FROM reviews WHERE reviewee_id = $1 AND created_at >= CURRENT_TIMESTAMP - INTERVAL '12 months'
+		ON CONFLICT (user_id) DO UPDATE SET review_count = EXCLUDED.review_count, average_rating = EXCLUDED.average_rating, positive_percentage = EXCLUDED.positive_percentage, updated_at = EXCLUDED.updated_at`
+	_, err := tx.Exec(query, userID, positiveRating)
+	return err
+}
+
+// RefreshReputations recomputes the cached reputations that still count
+// reviews which have left the 12-month window by now, and returns how many
+// were refreshed.
+func (s *service) RefreshReputations(now time.Time) (int, error) {
+	query := `WITH stale AS (
+	SELECT rep.user_id FROM user_reputations rep
+	WHERE EXISTS (SELECT 1 FROM reviews r WHERE r.reviewee_id = rep.user_id AND r.created_at >= rep.updated_at - INTERVAL '12 months' AND r.created_at < $1::timestamptz - INTERVAL '12 months')
+)
+UPDATE user_reputations rep
+SET review_count = agg.review_count, average_rating = agg.average_rating, positive_percentage = agg.positive_percentage, updated_at = $1
+FROM (
+	SELECT stale.user_id, COUNT(r.review_id) AS review_count, COALESCE(AVG(r.overall), 0) AS average_rating, COALESCE(100.0 * COUNT(r.review_id) FILTER (WHERE r.overall >= $2) / NULLIF(COUNT(r.review_id), 0), 0) AS positive_percentage
+	FROM stale
+	LEFT JOIN reviews r ON r.reviewee_id = stale.user_id AND r.created_at >= $1::timestamptz - INTERVAL '12 months'
+	GROUP BY stale.user_id
+) agg
+WHERE rep.user_id = agg.user_id`
+	result, err := s.db.Exec(query, now, positiveRating)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	return int(rowsAffected), err
+}
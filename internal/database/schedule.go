@@ -0,0 +1,83 @@
+package database
+
+import "time"
+
+// OrderSummary identifies an order and its parties, for notifying them of
+// changes made by background jobs.
+type OrderSummary struct {
+	OrderID  int
+	BuyerID  int
+	SellerID int
+}
+
+// CancelUnpaidOrders cancels the orders still pending payment that were placed
+// before the given time and puts their quantity back in stock.
+func (s *service) CancelUnpaidOrders(placedBefore time.Time) ([]OrderSummary, error) {
+	query := `WITH cancelled AS (
+			UPDATE orders SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+			WHERE status = 'pending' AND created_at < $1
+			RETURNING order_id, buyer_id, seller_id, product_id, quantity
+		), restocked AS (
+			UPDATE products p SET quantity = p.quantity + r.quantity, updated_at = CURRENT_TIMESTAMP
+			FROM (SELECT product_id, SUM(quantity) AS quantity FROM cancelled GROUP BY product_id) r
+			WHERE p.product_id = r.product_id
+		)
+		SELECT order_id, buyer_id, seller_id FROM cancelled`
+	return queryOrderSummaries(s.db, query, placedBefore)
+}
+
+// RemindUnshippedOrders flags the orders paid before the given time that are
+// not shipped yet and returns the ones whose seller has not been reminded.
+func (s *service) RemindUnshippedOrders(paidBefore time.Time) ([]OrderSummary, error) {
+	query := `UPDATE orders SET seller_reminded_at = CURRENT_TIMESTAMP
+		WHERE status = 'processing' AND shipped_at IS NULL AND seller_reminded_at IS NULL AND paid_at < $1
+		RETURNING order_id, buyer_id, seller_id`
+	return queryOrderSummaries(s.db, query, paidBefore)
+}
+
+// CompleteShippedOrders completes the orders shipped before the given time
+// that the buyer never confirmed. Orders with an unresolved dispute are left
+// alone. Each completed order is invoiced.
+func (s *service) CompleteShippedOrders(shippedBefore time.Time) ([]OrderSummary, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE orders SET status = 'completed', updated_at = CURRENT_TIMESTAMP
+		WHERE status IN ('processing', 'delivered') AND shipped_at < $1
+		AND NOT EXISTS (SELECT 1 FROM disputes d WHERE d.order_id = orders.order_id AND d.status <> 'resolved')
+		RETURNING order_id, buyer_id, seller_id`
+	orders, err := queryOrderSummaries(tx, query, shippedBefore)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if err := issueInvoice(tx, order.OrderID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, tx.Commit()
+}
+
+func queryOrderSummaries(q queryer, query string, args ...any) ([]OrderSummary, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []OrderSummary
+	for rows.Next() {
+		var order OrderSummary
+		if err := rows.Scan(&order.OrderID, &order.BuyerID, &order.SellerID); err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
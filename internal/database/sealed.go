@@ -0,0 +1,168 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrInvalidProduct is returned when a product references neither or both
+	// of a card and a sealed product.
+	ErrInvalidProduct = errors.New("product must reference either a card or a sealed product")
+	// ErrInvalidCondition is returned when a condition does not apply to the
+	// kind of item a product lists.
+	ErrInvalidCondition = errors.New("condition does not apply to this product")
+	// ErrInvalidSealedProduct is returned when a sealed product has no name,
+	// game or known product type.
+	ErrInvalidSealedProduct = errors.New("invalid sealed product")
+)
+
+// CardConditions are the conditions a single card can be listed in.
+var CardConditions = []string{"mint", "near mint", "excellent", "good", "light_played", "played", "poor"}
+
+// SealedConditions are the conditions a sealed product can be listed in.
+// "damaged box" is still sealed, but the packaging is not collector grade.
+var SealedConditions = []string{"sealed", "opened", "damaged box"}
+
+// SealedProductTypes are the kinds of sealed products in the catalog.
+var SealedProductTypes = []string{"booster", "booster_box", "elite_trainer_box", "precon_deck", "bundle", "other"}
+
+type SealedProduct struct {
+	SealedProductID int       `json:"sealed_product_id"`
+	Name            string    `json:"name"`
+	ProductType     string    `json:"product_type"`
+	SetName         *string   `json:"set_name,omitempty"`
+	ImageURL        *string   `json:"image_url,omitempty"`
+	Description     *string   `json:"description,omitempty"`
+	TCGGameID       int       `json:"tcg_game_id"`
+	TCGGame         string    `json:"tcg_game"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+type SealedProductRequest struct {
+	Name        string  `json:"name"`
+	ProductType string  `json:"product_type"`
+	SetName     *string `json:"set_name,omitempty"`
+	ImageURL    *string `json:"image_url,omitempty"`
+	Description *string `json:"description,omitempty"`
+	TCGGameID   int     `json:"tcg_game_id"`
+}
+
+// SealedProductFilter narrows the sealed product catalog. Zero values match
+// every entry.
+type SealedProductFilter struct {
+	TCGGameID int
+	SetName   string
+}
+
+const sealedProductSelect = `SELECT sp.sealed_product_id, sp.name, sp.product_type, sp.set_name, sp.image_url, sp.description, sp.tcg_game_id, tcg.name, sp.created_at, sp.updated_at
+FROM sealed_products sp
+JOIN tcg_games tcg ON sp.tcg_game_id = tcg.tcg_game_id`
+
+func scanSealedProduct(row interface{ Scan(...any) error }) (SealedProduct, error) {
+	var product SealedProduct
+	err := row.Scan(&product.SealedProductID, &product.Name, &product.ProductType, &product.SetName, &product.ImageURL, &product.Description, &product.TCGGameID, &product.TCGGame, &product.CreatedAt, &product.UpdatedAt)
+	return product, err
+}
+
+func (s *service) ListSealedProducts(filter SealedProductFilter) ([]SealedProduct, error) {
+	rows, err := s.db.Query(sealedProductSelect+` WHERE ($1 = 0 OR sp.tcg_game_id = $1) AND ($2 = '' OR sp.set_name = $2) ORDER BY tcg.name, sp.set_name, sp.name`, filter.TCGGameID, filter.SetName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []SealedProduct{}
+	for rows.Next() {
+		product, err := scanSealedProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, product)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (s *service) GetSealedProduct(sealedProductID int) (SealedProduct, error) {
+	return scanSealedProduct(s.db.QueryRow(sealedProductSelect+` WHERE sp.sealed_product_id = $1`, sealedProductID))
+}
+
+func (s *service) CreateSealedProduct(product SealedProductRequest) (int, error) {
+	if err := product.validate(); err != nil {
+		return 0, err
+	}
+
+	var sealedProductID int
+	query := `INSERT INTO sealed_products (name, product_type, set_name, image_url, description, tcg_game_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING sealed_product_id`
+	err := s.db.QueryRow(query, product.Name, product.ProductType, product.SetName, product.ImageURL, product.Description, product.TCGGameID).Scan(&sealedProductID)
+	return sealedProductID, err
+}
+
+func (s *service) UpdateSealedProduct(sealedProductID int, product SealedProductRequest) error {
+	if err := product.validate(); err != nil {
+		return err
+	}
+
+	query := `UPDATE sealed_products SET name = $1, product_type = $2, set_name = $3, image_url = $4, description = $5, tcg_game_id = $6, updated_at = CURRENT_TIMESTAMP WHERE sealed_product_id = $7`
+	result, err := s.db.Exec(query, product.Name, product.ProductType, product.SetName, product.ImageURL, product.Description, product.TCGGameID, sealedProductID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (s *service) DeleteSealedProduct(sealedProductID int) error {
+	result, err := s.db.Exec(`DELETE FROM sealed_products WHERE sealed_product_id = $1`, sealedProductID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r SealedProductRequest) validate() error {
+	if r.Name == "" || r.TCGGameID == 0 || !slices.Contains(SealedProductTypes, r.ProductType) {
+		return ErrInvalidSealedProduct
+	}
+	return nil
+}
+
+// validateItem checks that a product lists exactly one card or sealed product
+// in a condition that applies to it.
+func (r ProductRequest) validateItem() error {
+	if (r.CardID == 0) == (r.SealedProductID == 0) {
+		return ErrInvalidProduct
+	}
+	conditions := CardConditions
+	if r.SealedProductID != 0 {
+		conditions = SealedConditions
+	}
+	if !slices.Contains(conditions, r.Condition) {
+		return ErrInvalidCondition
+	}
+	return nil
+}
+
+// nullID stores the zero ID as NULL.
+func nullID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
@@ -0,0 +1,95 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrSellerOnVacation is returned when ordering from, or making an offer to,
+// a seller on vacation.
+var ErrSellerOnVacation = errors.New("seller is on vacation")
+
+// ErrInvalidVacation is returned when a return date is not in the future, or
+// is given without going on vacation.
+var ErrInvalidVacation = errors.New("invalid vacation")
+
+// SellerProfile is the public profile of a user's shop. It leaves out the
+// user's name, address and email.
+type SellerProfile struct {
+	UserID      int        `json:"user_id"`
+	Username    string     `json:"username"`
+	Country     string     `json:"country"`
+	CountryCode string     `json:"country_code"`
+	SellerType  string     `json:"seller_type"`
+	MemberSince time.Time  `json:"member_since"`
+	Reputation  Reputation `json:"reputation"`
+	// ShippingMethods are the shipping policies of the seller.
+	ShippingMethods []ShippingMethod `json:"shipping_methods"`
+	// ProductCount counts the available products, zero while on vacation.
+	ProductCount int  `json:"product_count"`
+	OnVacation   bool `json:"on_vacation"`
+	// VacationUntil is the announced return date, if any.
+	VacationUntil *time.Time `json:"vacation_until,omitempty"`
+}
+
+func (s *service) GetSellerProfile(username string) (SellerProfile, error) {
+	var profile SellerProfile
+	query := `SELECT u.user_id, u.username, c.country_name, c.country_code, u.seller_type, u.created_at, COALESCE(r.review_count, 0), COALESCE(r.average_rating, 0), COALESCE(r.positive_percentage, 0), u.on_vacation, u.vacation_until,
+	(SELECT COUNT(*) FROM products p WHERE p.seller_id = u.user_id AND p.is_available AND p.quantity > 0 AND NOT u.on_vacation)
+FROM users u
+JOIN countries c ON u.country_id = c.country_id
+LEFT JOIN user_reputations r ON u.user_id = r.user_id
+WHERE u.username = $1`
+	err := s.db.QueryRow(query, username).Scan(&profile.UserID, &profile.Username, &profile.Country, &profile.CountryCode, &profile.SellerType, &profile.MemberSince, &profile.Reputation.ReviewCount, &profile.Reputation.AverageRating, &profile.Reputation.PositivePercentage, &profile.OnVacation, &profile.VacationUntil, &profile.ProductCount)
+	if err != nil {
+		return SellerProfile{}, err
+	}
+
+	profile.ShippingMethods, err = s.ListSellerShippingMethods(profile.UserID)
+	if err != nil {
+		return SellerProfile{}, err
+	}
+	return profile, nil
+}
+
+// SetVacation turns vacation mode of a seller on or off. While on vacation,
+// the seller's products are hidden from listings but kept. With a return date,
+// EndVacations turns vacation mode off once it has passed.
+func (s *service) SetVacation(userID int, onVacation bool, until *time.Time) error {
+	if until != nil && (!onVacation || !until.After(time.Now())) {
+		return ErrInvalidVacation
+	}
+	result, err := s.db.Exec(`UPDATE users SET on_vacation = $2, vacation_until = $3, updated_at = CURRENT_TIMESTAMP WHERE user_id = $1`, userID, onVacation, until)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// EndVacations turns vacation mode off for sellers whose return date has
+// passed by now and returns their user IDs.
+func (s *service) EndVacations(now time.Time) ([]int, error) {
+	rows, err := s.db.Query(`UPDATE users SET on_vacation = FALSE, vacation_until = NULL, updated_at = CURRENT_TIMESTAMP WHERE on_vacation AND vacation_until <= $1 RETURNING user_id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
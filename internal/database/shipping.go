@@ -0,0 +1,194 @@
+package database
+
+import (
+	"errors"
+
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/shipping"
+)
+
+// ErrShippingMethodUnavailable is returned when the seller does not offer the
+// shipping method or it has no rate covering the order.
+var ErrShippingMethodUnavailable = errors.New("shipping method not available for this order")
+
+type ShippingMethod struct {
+	ShippingMethodID int    `json:"shipping_method_id"`
+	Code             string `json:"code"`
+	Name             string `json:"name"`
+	IsTracked        bool   `json:"is_tracked"`
+	IsInsured        bool   `json:"is_insured"`
+}
+
+type ShippingQuote struct {
+	ShippingMethod
+	Price currency.Money `json:"price"`
+}
+
+type SellerShippingMethodsRequest struct {
+	ShippingMethodIDs []int `json:"shipping_method_ids"`
+}
+
+const shippingMethodSelect = `SELECT m.shipping_method_id, m.code, m.name, m.is_tracked, m.is_insured FROM shipping_methods m`
+
+// sellerShippingMethodsWhere restricts shipping methods to the ones offered by
+// the seller in $1. Sellers without configuration offer every method.
+const sellerShippingMethodsWhere = ` WHERE NOT EXISTS (SELECT 1 FROM seller_shipping_methods ssm WHERE ssm.seller_id = $1) OR EXISTS (SELECT 1 FROM seller_shipping_methods ssm WHERE ssm.seller_id = $1 AND ssm.shipping_method_id = m.shipping_method_id)`
+
+func (s *service) ListShippingMethods() ([]ShippingMethod, error) {
+	return queryShippingMethods(s.db, shippingMethodSelect+` ORDER BY m.shipping_method_id`)
+}
+
+func (s *service) ListSellerShippingMethods(sellerID int) ([]ShippingMethod, error) {
+	return queryShippingMethods(s.db, shippingMethodSelect+sellerShippingMethodsWhere+` ORDER BY m.shipping_method_id`, sellerID)
+}
+
+// SetSellerShippingMethods replaces the shipping methods offered by a seller.
+func (s *service) SetSellerShippingMethods(sellerID int, methodIDs []int) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM seller_shipping_methods WHERE seller_id = $1`, sellerID); err != nil {
+		return err
+	}
+
+	for _, methodID := range methodIDs {
+		_, err := tx.Exec(`INSERT INTO seller_shipping_methods (seller_id, shipping_method_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, sellerID, methodID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// QuoteShipping returns the price of every shipping method the seller of the
+// product offers to the buyer for the given quantity, shipped to the buyer's
+// default address.
+func (s *service) QuoteShipping(productID int, buyerID int, quantity int) ([]ShippingQuote, error) {
+	var (
+		price, code     string
+		sellerID        int
+		originCountryID int
+		destCountryID   int
+	)
+	query := `SELECT p.price, p.currency, p.seller_id, sellers.country_id, COALESCE((SELECT a.country_id FROM addresses a WHERE a.user_id = buyers.user_id AND a.is_default), buyers.country_id) FROM products p JOIN users sellers ON p.seller_id = sellers.user_id JOIN users buyers ON buyers.user_id = $2 WHERE p.product_id = $1`
+	err := s.db.QueryRow(query, productID, buyerID).Scan(&price, &code, &sellerID, &originCountryID, &destCountryID)
+	if err != nil {
+		return nil, err
+	}
+	unitPrice, err := currency.Parse(price, code)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := queryShippingMethods(s.db, shippingMethodSelect+sellerShippingMethodsWhere+` ORDER BY m.shipping_method_id`, sellerID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := []ShippingQuote{}
+	for _, method := range methods {
+		cost, err := shippingCost(s.db, method.ShippingMethodID, originCountryID, destCountryID, quantity, unitPrice.Mul(quantity))
+		if errors.Is(err, ErrShippingMethodUnavailable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, ShippingQuote{ShippingMethod: method, Price: cost})
+	}
+	return quotes, nil
+}
+
+// quoteOrderShipping returns the shipping cost of an order with the given
+// method, checking that the seller offers it.
+func quoteOrderShipping(q queryer, sellerID int, methodID int, originCountryID int, destCountryID int, items int, value currency.Money) (currency.Money, error) {
+	var offered bool
+	err := q.QueryRow(`SELECT EXISTS (`+shippingMethodSelect+sellerShippingMethodsWhere+` AND m.shipping_method_id = $2)`, sellerID, methodID).Scan(&offered)
+	if err != nil {
+		return currency.Money{}, err
+	}
+	if !offered {
+		return currency.Money{}, ErrShippingMethodUnavailable
+	}
+	return shippingCost(q, methodID, originCountryID, destCountryID, items, value)
+}
+
+// shippingCost picks the rate band for the route. Rates specific to the
+// destination take precedence over the origin's fallback rates. Only rates in
+// the currency of the order value apply.
+func shippingCost(q queryer, methodID int, originCountryID int, destCountryID int, items int, value currency.Money) (currency.Money, error) {
+	query := `SELECT max_items, max_order_value, price FROM shipping_rates WHERE shipping_method_id = $1 AND origin_country_id = $2 AND destination_country_id IS NOT DISTINCT FROM $3 AND currency = $4`
+
+	for _, destination := range []*int{&destCountryID, nil} {
+		rates, err := queryShippingRates(q, query, value.Currency, methodID, originCountryID, destination, value.Currency)
+		if err != nil {
+			return currency.Money{}, err
+		}
+
+		if len(rates) == 0 {
+			continue
+		}
+
+		rate, err := shipping.SelectRate(rates, items, value)
+		if err != nil {
+			return currency.Money{}, ErrShippingMethodUnavailable
+		}
+		return rate.Price, nil
+	}
+	return currency.Money{}, ErrShippingMethodUnavailable
+}
+
+func queryShippingRates(q queryer, query string, code string, args ...any) ([]shipping.Rate, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rates []shipping.Rate
+	for rows.Next() {
+		var (
+			rate            shipping.Rate
+			maxValue, price string
+		)
+		if err := rows.Scan(&rate.MaxItems, &maxValue, &price); err != nil {
+			return nil, err
+		}
+		if rate.MaxValue, err = currency.Parse(maxValue, code); err != nil {
+			return nil, err
+		}
+		if rate.Price, err = currency.Parse(price, code); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+func queryShippingMethods(q queryer, query string, args ...any) ([]ShippingMethod, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	methods := []ShippingMethod{}
+	for rows.Next() {
+		var method ShippingMethod
+		if err := rows.Scan(&method.ShippingMethodID, &method.Code, &method.Name, &method.IsTracked, &method.IsInsured); err != nil {
+			return nil, err
+		}
+		methods = append(methods, method)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
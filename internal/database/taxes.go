@@ -0,0 +1,211 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/tax"
+)
+
+var (
+	// ErrTaxRateOverlap is returned when a new tax rate overlaps a rate of the
+	// same country that is already scheduled.
+	ErrTaxRateOverlap = errors.New("tax rate overlaps an existing rate")
+	// ErrTaxRateGap is returned when a rate with an end date follows earlier
+	// rates of the country but no rate follows it, so that sales after it
+	// would go untaxed.
+	ErrTaxRateGap = errors.New("tax rate leaves the country without a rate")
+)
+
+type TaxRate struct {
+	TaxRateID       int        `json:"tax_rate_id"`
+	CountryCode     string     `json:"country_code"`
+	Country         string     `json:"country"`
+	RateBasisPoints int64      `json:"rate_basis_points"`
+	EffectiveFrom   time.Time  `json:"effective_from"`
+	EffectiveTo     *time.Time `json:"effective_to,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// TaxRateRequest schedules a tax rate for a country. Without EffectiveTo the
+// rate applies until a later rate replaces it.
+type TaxRateRequest struct {
+	CountryCode     string     `json:"country_code"`
+	RateBasisPoints int64      `json:"rate_basis_points"`
+	EffectiveFrom   time.Time  `json:"effective_from"`
+	EffectiveTo     *time.Time `json:"effective_to,omitempty"`
+}
+
+func (s *service) ListTaxRates() ([]TaxRate, error) {
+	query := `SELECT r.tax_rate_id, c.country_code, c.country_name, r.rate_basis_points, r.effective_from, r.effective_to, r.created_at FROM tax_rates r JOIN countries c ON r.country_id = c.country_id ORDER BY c.country_code, r.effective_from`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := []TaxRate{}
+	for rows.Next() {
+		var rate TaxRate
+		if err := rows.Scan(&rate.TaxRateID, &rate.CountryCode, &rate.Country, &rate.RateBasisPoints, &rate.EffectiveFrom, &rate.EffectiveTo, &rate.CreatedAt); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// CreateTaxRate schedules a tax rate. A rate without end date that started
+// earlier ends where the new rate starts. When the new rate has an end date
+// too, the earlier rate resumes after it. Any other overlap is rejected, as
+// is a rate with an end date that nothing follows.
+func (s *service) CreateTaxRate(rate TaxRateRequest) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Locking the country serializes changes to its rate table.
+	var countryID int
+	err = tx.QueryRow(`SELECT country_id FROM countries WHERE country_code = $1 FOR UPDATE`, rate.CountryCode).Scan(&countryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrUnknownCountry
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		openRateID      int
+		openBasisPoints int64
+	)
+	err = tx.QueryRow(`SELECT tax_rate_id, rate_basis_points FROM tax_rates WHERE country_id = $1 AND effective_to IS NULL AND effective_from < $2`, countryID, rate.EffectiveFrom).Scan(&openRateID, &openBasisPoints)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if openRateID != 0 {
+		if _, err := tx.Exec(`UPDATE tax_rates SET effective_to = $2 WHERE tax_rate_id = $1`, openRateID, rate.EffectiveFrom); err != nil {
+			return 0, err
+		}
+	}
+
+	var overlaps bool
+	query := `SELECT EXISTS (SELECT 1 FROM tax_rates WHERE country_id = $1 AND effective_from < COALESCE($3::DATE, 'infinity'::DATE) AND COALESCE(effective_to, 'infinity'::DATE) > $2::DATE)`
+	if err := tx.QueryRow(query, countryID, rate.EffectiveFrom, rate.EffectiveTo).Scan(&overlaps); err != nil {
+		return 0, err
+	}
+	if overlaps {
+		return 0, ErrTaxRateOverlap
+	}
+
+	var taxRateID int
+	query = `INSERT INTO tax_rates (country_id, rate_basis_points, effective_from, effective_to) VALUES ($1, $2, $3, $4) RETURNING tax_rate_id`
+	if err := tx.QueryRow(query, countryID, rate.RateBasisPoints, rate.EffectiveFrom, rate.EffectiveTo).Scan(&taxRateID); err != nil {
+		return 0, err
+	}
+
+	if rate.EffectiveTo != nil {
+		if openRateID != 0 {
+			_, err := tx.Exec(`INSERT INTO tax_rates (country_id, rate_basis_points, effective_from) VALUES ($1, $2, $3)`, countryID, openBasisPoints, rate.EffectiveTo)
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		var gap bool
+		query = `SELECT EXISTS (SELECT 1 FROM tax_rates WHERE country_id = $1 AND effective_from < $2::DATE) AND NOT EXISTS (SELECT 1 FROM tax_rates WHERE country_id = $1 AND effective_from = $3::DATE)`
+		if err := tx.QueryRow(query, countryID, rate.EffectiveFrom, rate.EffectiveTo).Scan(&gap); err != nil {
+			return 0, err
+		}
+		if gap {
+			return 0, ErrTaxRateGap
+		}
+	}
+
+	return taxRateID, tx.Commit()
+}
+
+// saleTaxRates describes a sale by the seller to the destination country at
+// the given time, with the rates of the country whose tax applies to it.
+func saleTaxRates(q queryer, sellerID int, destCountryID int, at time.Time) (tax.Sale, []tax.Rate, error) {
+	sale := tax.Sale{Date: at}
+	query := `SELECT u.seller_type, sc.country_code, sc.is_eu, dc.country_code, dc.is_eu FROM users u JOIN countries sc ON u.country_id = sc.country_id JOIN countries dc ON dc.country_id = $2 WHERE u.user_id = $1`
+	err := q.QueryRow(query, sellerID, destCountryID).Scan(&sale.SellerType, &sale.Seller.Country, &sale.Seller.EU, &sale.Buyer.Country, &sale.Buyer.EU)
+	if err != nil {
+		return tax.Sale{}, nil, err
+	}
+
+	country := tax.Jurisdiction(sale)
+	if country == "" {
+		return sale, nil, nil
+	}
+
+	rows, err := q.Query(`SELECT c.country_code, r.rate_basis_points, r.effective_from, r.effective_to FROM tax_rates r JOIN countries c ON r.country_id = c.country_id WHERE c.country_code = $1`, country)
+	if err != nil {
+		return tax.Sale{}, nil, err
+	}
+	defer rows.Close()
+
+	var rates []tax.Rate
+	for rows.Next() {
+		var rate tax.Rate
+		if err := rows.Scan(&rate.Country, &rate.BasisPoints, &rate.EffectiveFrom, &rate.EffectiveTo); err != nil {
+			return tax.Sale{}, nil, err
+		}
+		rates = append(rates, rate)
+	}
+	if err := rows.Err(); err != nil {
+		return tax.Sale{}, nil, err
+	}
+
+	return sale, rates, nil
+}
+
+func insertOrderTaxLines(q queryer, orderID int, lines []tax.TaxLine) error {
+	query := `INSERT INTO order_tax_lines (order_id, kind, country_id, rate_basis_points, taxable_amount, tax_amount) SELECT $1, $2, country_id, $4, $5, $6 FROM countries WHERE country_code = $3`
+	for _, line := range lines {
+		if _, err := q.Exec(query, orderID, line.Kind, line.Country, line.BasisPoints, line.Taxable, line.Tax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderTaxLines returns the tax breakdown stored on an order whose amounts
+// are in the given currency.
+func orderTaxLines(q queryer, orderID int, code string) ([]tax.TaxLine, error) {
+	query := `SELECT l.kind, c.country_code, l.rate_basis_points, l.taxable_amount, l.tax_amount FROM order_tax_lines l JOIN countries c ON l.country_id = c.country_id WHERE l.order_id = $1 ORDER BY l.order_tax_line_id`
+	rows, err := q.Query(query, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []tax.TaxLine{}
+	for rows.Next() {
+		var (
+			line              tax.TaxLine
+			taxable, taxValue string
+		)
+		if err := rows.Scan(&line.Kind, &line.Country, &line.BasisPoints, &taxable, &taxValue); err != nil {
+			return nil, err
+		}
+		if line.Taxable, err = currency.Parse(taxable, code); err != nil {
+			return nil, err
+		}
+		if line.Tax, err = currency.Parse(taxValue, code); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
@@ -0,0 +1,48 @@
+package database
+
+import (
+	"time"
+
+	"cardmarket_backend/internal/tracking"
+)
+
+// ListTrackedShipments returns the shipped orders with a tracking number that
+// have not been delivered yet.
+func (s *service) ListTrackedShipments() ([]tracking.Shipment, error) {
+	query := `SELECT order_id, buyer_id, seller_id, carrier, tracking_number, shipped_at FROM orders WHERE tracking_number IS NOT NULL AND carrier IS NOT NULL AND shipped_at IS NOT NULL AND delivered_at IS NULL AND status <> 'cancelled' ORDER BY shipped_at`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []tracking.Shipment
+	for rows.Next() {
+		var shipment tracking.Shipment
+		if err := rows.Scan(&shipment.OrderID, &shipment.BuyerID, &shipment.SellerID, &shipment.Carrier, &shipment.TrackingNumber, &shipment.ShippedAt); err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, shipment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return shipments, nil
+}
+
+// MarkOrderDelivered records the delivery of an order. Completed orders keep
+// their status. It reports false when the order was already delivered or has
+// been cancelled in the meantime.
+func (s *service) MarkOrderDelivered(orderID int, deliveredAt time.Time) (bool, error) {
+	query := `UPDATE orders SET delivered_at = $2, status = CASE WHEN status = 'completed' THEN status ELSE 'delivered' END, updated_at = CURRENT_TIMESTAMP WHERE order_id = $1 AND delivered_at IS NULL AND status <> 'cancelled'`
+	result, err := s.db.Exec(query, orderID, deliveredAt)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
@@ -0,0 +1,593 @@
+package database
+
+import (
+	"cardmarket_backend/internal/currency"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Trade statuses stored in the trades table.
+const (
+	TradeStatusPending   = "pending"
+	TradeStatusAccepted  = "accepted"
+	TradeStatusDeclined  = "declined"
+	TradeStatusCountered = "countered"
+	TradeStatusCompleted = "completed"
+	TradeStatusCancelled = "cancelled"
+)
+
+// Who pays the cash of a trade, relative to the proposal.
+const (
+	TradeCashFromProposer  = "proposer"
+	TradeCashFromRecipient = "recipient"
+)
+
+var (
+	// ErrInvalidTrade is returned when a proposal has no cards, lists a card
+	// twice or lists cards its party does not own, or has a cash part that is
+	// not positive.
+	ErrInvalidTrade = errors.New("invalid trade")
+	// ErrNotTradeParticipant is returned when a user who is not a party to a
+	// trade accesses it.
+	ErrNotTradeParticipant = errors.New("user is not a party to this trade")
+	// ErrNotTradeRecipient is returned when someone other than the recipient
+	// of a proposal responds to it.
+	ErrNotTradeRecipient = errors.New("trade was not proposed to this user")
+	// ErrTradeClosed is returned when responding to a proposal that was
+	// already answered.
+	ErrTradeClosed = errors.New("trade is no longer open")
+	// ErrInvalidShipmentState is returned when a trade shipment is shipped
+	// twice, shipped for cash that was not paid yet, or received before it
+	// was shipped.
+	ErrInvalidShipmentState = errors.New("invalid shipment state")
+	// ErrNoCashDue is returned when paying the cash of a trade that is not
+	// accepted, has no cash, is paid by the other party or was paid already.
+	ErrNoCashDue = errors.New("no cash is due on this trade")
+)
+
+type Trade struct {
+	TradeID       int    `json:"trade_id"`
+	ProposerID    int    `json:"proposer_id"`
+	RecipientID   int    `json:"recipient_id"`
+	Proposer      string `json:"proposer"`
+	Recipient     string `json:"recipient"`
+	ParentTradeID *int   `json:"parent_trade_id,omitempty"`
+	Status        string `json:"status"`
+	// Offered are the proposer's cards, Requested the recipient's.
+	Offered     []TradeItem     `json:"offered"`
+	Requested   []TradeItem     `json:"requested"`
+	Cash        *currency.Money `json:"cash,omitempty"`
+	CashPayerID *int            `json:"cash_payer_id,omitempty"`
+	CashPaidAt  *time.Time      `json:"cash_paid_at,omitempty"`
+	Shipments   []TradeShipment `json:"shipments,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type TradeItem struct {
+	ProductID int    `json:"product_id"`
+	Card      string `json:"card"`
+	Condition string `json:"condition"`
+	Language  string `json:"language"`
+	Quantity  int    `json:"quantity"`
+}
+
+// TradeShipment carries the cards of one party of an accepted trade to the
+// other.
+type TradeShipment struct {
+	TradeShipmentID int          `json:"trade_shipment_id"`
+	SenderID        int          `json:"sender_id"`
+	ReceiverID      int          `json:"receiver_id"`
+	ShipTo          OrderAddress `json:"ship_to"`
+	Carrier         *string      `json:"carrier,omitempty"`
+	TrackingNumber  *string      `json:"tracking_number,omitempty"`
+	ShippedAt       *time.Time   `json:"shipped_at,omitempty"`
+	ReceivedAt      *time.Time   `json:"received_at,omitempty"`
+}
+
+// TradeRequest proposes a trade. Offered are cards of the proposer and
+// Requested cards of the recipient; either may be empty but not both. Cash is
+// in the currency of the party receiving it, and paid by CashPayer. Without
+// an address, the proposer's cards are sent back to their default address.
+type TradeRequest struct {
+	RecipientID int                `json:"recipient_id"`
+	Offered     []TradeItemRequest `json:"offered"`
+	Requested   []TradeItemRequest `json:"requested"`
+	Cash        *currency.Money    `json:"cash,omitempty"`
+	CashPayer   string             `json:"cash_payer,omitempty"`
+	AddressID   int                `json:"address_id"`
+}
+
+type TradeItemRequest struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+const tradeSelect = `SELECT t.trade_id, t.proposer_id, t.recipient_id, proposers.username, recipients.username, t.parent_trade_id, t.status, t.cash_amount, t.cash_currency, t.cash_payer_id, t.cash_paid_at, t.created_at, t.updated_at
+FROM trades t
+JOIN users proposers ON t.proposer_id = proposers.user_id
+JOIN users recipients ON t.recipient_id = recipients.user_id`
+
+// queryTrades loads trades with their cards and shipments.
+func queryTrades(q queryer, query string, args ...any) ([]Trade, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trades := []Trade{}
+	for rows.Next() {
+		var (
+			t          Trade
+			cash, code sql.NullString
+		)
+		if err := rows.Scan(&t.TradeID, &t.ProposerID, &t.RecipientID, &t.Proposer, &t.Recipient, &t.ParentTradeID, &t.Status, &cash, &code, &t.CashPayerID, &t.CashPaidAt, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if cash.Valid {
+			amount, err := currency.Parse(cash.String, code.String)
+			if err != nil {
+				return nil, err
+			}
+			t.Cash = &amount
+		}
+		t.Offered, t.Requested = []TradeItem{}, []TradeItem{}
+		trades = append(trades, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if len(trades) == 0 {
+		return trades, nil
+	}
+	return trades, loadTradeDetails(q, trades)
+}
+
+func loadTradeDetails(q queryer, trades []Trade) error {
+	index := make(map[int]*Trade, len(trades))
+	tradeIDs := make([]int, len(trades))
+	for i := range trades {
+		index[trades[i].TradeID] = &trades[i]
+		tradeIDs[i] = trades[i].TradeID
+	}
+
+	rows, err := q.Query(`SELECT ti.trade_id, ti.owner_id, ti.product_id, COALESCE(c.name, sp.name), p.condition, l.language_name, ti.quantity FROM trade_items ti JOIN products p ON ti.product_id = p.product_id LEFT JOIN cards c ON p.card_id = c.card_id LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id JOIN languages l ON p.language_id = l.language_id WHERE ti.trade_id = ANY($1) ORDER BY ti.trade_item_id`, tradeIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tradeID, ownerID int
+			item             TradeItem
+		)
+		if err := rows.Scan(&tradeID, &ownerID, &item.ProductID, &item.Card, &item.Condition, &item.Language, &item.Quantity); err != nil {
+			return err
+		}
+		t := index[tradeID]
+		if ownerID == t.ProposerID {
+			t.Offered = append(t.Offered, item)
+		} else {
+			t.Requested = append(t.Requested, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	rows, err = q.Query(`SELECT s.trade_id, s.trade_shipment_id, s.sender_id, s.receiver_id, s.shipping_name, s.shipping_street_name, s.shipping_street_number, s.shipping_city, s.shipping_state, s.shipping_zip_code, sc.country_name, s.carrier, s.tracking_number, s.shipped_at, s.received_at FROM trade_shipments s JOIN countries sc ON s.shipping_country_id = sc.country_id WHERE s.trade_id = ANY($1) ORDER BY s.trade_shipment_id`, tradeIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tradeID int
+			s       TradeShipment
+		)
+		if err := rows.Scan(&tradeID, &s.TradeShipmentID, &s.SenderID, &s.ReceiverID, &s.ShipTo.Name, &s.ShipTo.StreetName, &s.ShipTo.StreetNumber, &s.ShipTo.City, &s.ShipTo.State, &s.ShipTo.ZipCode, &s.ShipTo.Country, &s.Carrier, &s.TrackingNumber, &s.ShippedAt, &s.ReceivedAt); err != nil {
+			return err
+		}
+		index[tradeID].Shipments = append(index[tradeID].Shipments, s)
+	}
+	return rows.Err()
+}
+
+func getTrade(q queryer, tradeID int) (Trade, error) {
+	trades, err := queryTrades(q, tradeSelect+` WHERE t.trade_id = $1`, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if len(trades) == 0 {
+		return Trade{}, sql.ErrNoRows
+	}
+	return trades[0], nil
+}
+
+// ListUserTrades returns the trade history of a user, newest first.
+func (s *service) ListUserTrades(userID int) ([]Trade, error) {
+	return queryTrades(s.db, tradeSelect+` WHERE t.proposer_id = $1 OR t.recipient_id = $1 ORDER BY t.created_at DESC, t.trade_id DESC`, userID)
+}
+
+// GetTrade returns a trade to one of its parties.
+func (s *service) GetTrade(tradeID int, userID int) (Trade, error) {
+	trade, err := getTrade(s.db, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if trade.ProposerID != userID && trade.RecipientID != userID {
+		return Trade{}, ErrNotTradeParticipant
+	}
+	return trade, nil
+}
+
+// ProposeTrade proposes a trade to another user. Stock is only reserved once
+// the trade is accepted.
+func (s *service) ProposeTrade(proposerID int, req TradeRequest) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	tradeID, err := insertTrade(tx, proposerID, req.RecipientID, nil, req)
+	if err != nil {
+		return Trade{}, err
+	}
+	trade, err := getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	return trade, tx.Commit()
+}
+
+// insertTrade validates and stores a proposal.
+func insertTrade(tx queryer, proposerID int, recipientID int, parentID *int, req TradeRequest) (int, error) {
+	if proposerID == recipientID || len(req.Offered)+len(req.Requested) == 0 {
+		return 0, ErrInvalidTrade
+	}
+	var exists bool
+	if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1)`, recipientID).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, sql.ErrNoRows
+	}
+	if _, _, err := checkoutAddress(tx, proposerID, req.AddressID); err != nil {
+		return 0, err
+	}
+
+	var cashPayerID *int
+	if req.Cash != nil {
+		payer, payee := proposerID, recipientID
+		switch req.CashPayer {
+		case TradeCashFromProposer:
+		case TradeCashFromRecipient:
+			payer, payee = recipientID, proposerID
+		default:
+			return 0, ErrInvalidTrade
+		}
+		if !req.Cash.IsPositive() {
+			return 0, ErrInvalidTrade
+		}
+		var code string
+		if err := tx.QueryRow(`SELECT c.currency_code FROM users u JOIN countries c ON u.country_id = c.country_id WHERE u.user_id = $1`, payee).Scan(&code); err != nil {
+			return 0, err
+		}
+		if req.Cash.Currency != code {
+			return 0, currency.ErrCurrencyMismatch
+		}
+		cashPayerID = &payer
+	}
+
+	var tradeID int
+	query := `INSERT INTO trades (proposer_id, recipient_id, parent_trade_id, proposer_address_id, cash_amount, cash_currency, cash_payer_id) VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7) RETURNING trade_id`
+	var cashCurrency *string
+	if req.Cash != nil {
+		cashCurrency = &req.Cash.Currency
+	}
+	if err := tx.QueryRow(query, proposerID, recipientID, parentID, req.AddressID, req.Cash, cashCurrency, cashPayerID).Scan(&tradeID); err != nil {
+		return 0, err
+	}
+
+	seen := map[int]bool{}
+	for _, side := range []struct {
+		ownerID int
+		items   []TradeItemRequest
+	}{{proposerID, req.Offered}, {recipientID, req.Requested}} {
+		for _, item := range side.items {
+			if item.Quantity < 1 || seen[item.ProductID] {
+				return 0, ErrInvalidTrade
+			}
+			seen[item.ProductID] = true
+
+			var stock int
+			err := tx.QueryRow(`SELECT quantity FROM products WHERE product_id = $1 AND seller_id = $2`, item.ProductID, side.ownerID).Scan(&stock)
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrInvalidTrade
+			}
+			if err != nil {
+				return 0, err
+			}
+			if stock < item.Quantity {
+				return 0, ErrInsufficientStock
+			}
+
+			_, err = tx.Exec(`INSERT INTO trade_items (trade_id, product_id, owner_id, quantity) VALUES ($1, $2, $3, $4)`, tradeID, item.ProductID, side.ownerID, item.Quantity)
+			if err != nil {
+				return 0, err
+			}
+		}
+	}
+	return tradeID, nil
+}
+
+// lockOpenTrade locks a pending trade proposed to userID.
+func lockOpenTrade(tx queryer, tradeID int, userID int) (Trade, error) {
+	var id int
+	if err := tx.QueryRow(`SELECT trade_id FROM trades WHERE trade_id = $1 FOR UPDATE`, tradeID).Scan(&id); err != nil {
+		return Trade{}, err
+	}
+	trade, err := getTrade(tx, id)
+	if err != nil {
+		return Trade{}, err
+	}
+	if trade.RecipientID != userID {
+		return Trade{}, ErrNotTradeRecipient
+	}
+	if trade.Status != TradeStatusPending {
+		return Trade{}, ErrTradeClosed
+	}
+	return trade, nil
+}
+
+// AcceptTrade accepts a proposal. The traded cards are taken out of stock as
+// at checkout, and a shipment is created for each party that sends cards:
+// the recipient's go to the proposer's address and the proposer's to the
+// given address of the recipient. Cash is paid afterwards with PayTradeCash.
+func (s *service) AcceptTrade(tradeID int, userID int, addressID int) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	trade, err := lockOpenTrade(tx, tradeID, userID)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	var proposerAddressID sql.NullInt64
+	if err := tx.QueryRow(`SELECT proposer_address_id FROM trades WHERE trade_id = $1`, tradeID).Scan(&proposerAddressID); err != nil {
+		return Trade{}, err
+	}
+	proposerAddress, proposerCountryID, err := checkoutAddress(tx, trade.ProposerID, int(proposerAddressID.Int64))
+	if err != nil {
+		return Trade{}, err
+	}
+	recipientAddress, recipientCountryID, err := checkoutAddress(tx, trade.RecipientID, addressID)
+	if err != nil {
+		return Trade{}, err
+	}
+
+	for _, side := range []struct {
+		senderID, receiverID int
+		items                []TradeItem
+		shipTo               OrderAddress
+		countryID            int
+	}{
+		{trade.ProposerID, trade.RecipientID, trade.Offered, recipientAddress, recipientCountryID},
+		{trade.RecipientID, trade.ProposerID, trade.Requested, proposerAddress, proposerCountryID},
+	} {
+		if len(side.items) == 0 {
+			continue
+		}
+		for _, item := range side.items {
+			if err := reserveTradeStock(tx, item.ProductID, side.senderID, item.Quantity); err != nil {
+				return Trade{}, err
+			}
+		}
+		query := `INSERT INTO trade_shipments (trade_id, sender_id, receiver_id, shipping_name, shipping_street_name, shipping_street_number, shipping_city, shipping_state, shipping_zip_code, shipping_country_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		_, err := tx.Exec(query, tradeID, side.senderID, side.receiverID, side.shipTo.Name, side.shipTo.StreetName, side.shipTo.StreetNumber, side.shipTo.City, side.shipTo.State, side.shipTo.ZipCode, side.countryID)
+		if err != nil {
+			return Trade{}, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE trades SET status = 'accepted', accepted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE trade_id = $1`, tradeID); err != nil {
+		return Trade{}, err
+	}
+	trade, err = getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	return trade, tx.Commit()
+}
+
+func (s *service) DeclineTrade(tradeID int, userID int) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	trade, err := lockOpenTrade(tx, tradeID, userID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if err := setTradeStatus(tx, tradeID, TradeStatusDeclined); err != nil {
+		return Trade{}, err
+	}
+	trade.Status = TradeStatusDeclined
+	return trade, tx.Commit()
+}
+
+// CounterTrade answers a proposal with a new one from its recipient, who
+// becomes the proposer. The countered proposal is closed.
+func (s *service) CounterTrade(tradeID int, userID int, req TradeRequest) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	trade, err := lockOpenTrade(tx, tradeID, userID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if err := setTradeStatus(tx, tradeID, TradeStatusCountered); err != nil {
+		return Trade{}, err
+	}
+	counterID, err := insertTrade(tx, userID, trade.ProposerID, &tradeID, req)
+	if err != nil {
+		return Trade{}, err
+	}
+	counter, err := getTrade(tx, counterID)
+	if err != nil {
+		return Trade{}, err
+	}
+	return counter, tx.Commit()
+}
+
+// PayTradeCash records that the user paid the cash of an accepted trade. A
+// trade whose cards were all received already is completed.
+func (s *service) PayTradeCash(tradeID int, userID int) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	if err := tx.QueryRow(`SELECT trade_id FROM trades WHERE trade_id = $1 FOR UPDATE`, tradeID).Scan(&id); err != nil {
+		return Trade{}, err
+	}
+	trade, err := getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if trade.ProposerID != userID && trade.RecipientID != userID {
+		return Trade{}, ErrNotTradeParticipant
+	}
+	if trade.Status != TradeStatusAccepted || trade.CashPayerID == nil || *trade.CashPayerID != userID || trade.CashPaidAt != nil {
+		return Trade{}, ErrNoCashDue
+	}
+
+	if _, err := tx.Exec(`UPDATE trades SET cash_paid_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE trade_id = $1`, tradeID); err != nil {
+		return Trade{}, err
+	}
+	if err := completeTrade(tx, tradeID); err != nil {
+		return Trade{}, err
+	}
+	trade, err = getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	return trade, tx.Commit()
+}
+
+// ShipTrade marks the user's shipment of an accepted trade as sent. A party
+// receiving cash ships once it is paid.
+func (s *service) ShipTrade(tradeID int, userID int, carrier *string, trackingNumber *string) (Trade, error) {
+	query := `UPDATE trade_shipments s SET carrier = $3, tracking_number = $4, shipped_at = CURRENT_TIMESTAMP
+		FROM trades t WHERE s.trade_id = t.trade_id AND t.trade_id = $1 AND s.sender_id = $2 AND t.status = 'accepted' AND s.shipped_at IS NULL
+		AND (t.cash_payer_id IS NULL OR t.cash_payer_id = $2 OR t.cash_paid_at IS NOT NULL)`
+	return s.updateTradeShipment(tradeID, userID, query, carrier, trackingNumber)
+}
+
+// ReceiveTrade confirms that the user received the cards sent to them. The
+// trade is completed once both parties have received theirs and its cash is
+// paid.
+func (s *service) ReceiveTrade(tradeID int, userID int) (Trade, error) {
+	query := `UPDATE trade_shipments s SET received_at = CURRENT_TIMESTAMP
+		FROM trades t WHERE s.trade_id = t.trade_id AND t.trade_id = $1 AND s.receiver_id = $2 AND t.status = 'accepted' AND s.shipped_at IS NOT NULL AND s.received_at IS NULL`
+	return s.updateTradeShipment(tradeID, userID, query)
+}
+
+func (s *service) updateTradeShipment(tradeID int, userID int, query string, args ...any) (Trade, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Trade{}, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	if err := tx.QueryRow(`SELECT trade_id FROM trades WHERE trade_id = $1 FOR UPDATE`, tradeID).Scan(&id); err != nil {
+		return Trade{}, err
+	}
+	trade, err := getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	if trade.ProposerID != userID && trade.RecipientID != userID {
+		return Trade{}, ErrNotTradeParticipant
+	}
+
+	result, err := tx.Exec(query, append([]any{tradeID, userID}, args...)...)
+	if err != nil {
+		return Trade{}, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return Trade{}, err
+	}
+	if updated == 0 {
+		return Trade{}, ErrInvalidShipmentState
+	}
+
+	if err := completeTrade(tx, tradeID); err != nil {
+		return Trade{}, err
+	}
+
+	trade, err = getTrade(tx, tradeID)
+	if err != nil {
+		return Trade{}, err
+	}
+	return trade, tx.Commit()
+}
+
+// completeTrade completes an accepted trade once every shipment is received
+// and the cash, if any, is paid.
+func completeTrade(q queryer, tradeID int) error {
+	query := `UPDATE trades SET status = 'completed', updated_at = CURRENT_TIMESTAMP
+		WHERE trade_id = $1 AND status = 'accepted' AND (cash_payer_id IS NULL OR cash_paid_at IS NOT NULL)
+		AND NOT EXISTS (SELECT 1 FROM trade_shipments WHERE trade_id = $1 AND received_at IS NULL)`
+	_, err := q.Exec(query, tradeID)
+	return err
+}
+
+// CancelStalledTrades cancels the trades accepted before the given time on
+// which nothing was shipped yet, and puts the cards of both parties back in
+// stock.
+func (s *service) CancelStalledTrades(acceptedBefore time.Time) ([]Trade, error) {
+	query := `WITH cancelled AS (
+			UPDATE trades t SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+			WHERE t.status = 'accepted' AND t.accepted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM trade_shipments s WHERE s.trade_id = t.trade_id AND s.shipped_at IS NOT NULL)
+			RETURNING t.trade_id
+		), restocked AS (
+			UPDATE products p SET quantity = p.quantity + r.quantity, updated_at = CURRENT_TIMESTAMP
+			FROM (SELECT ti.product_id, SUM(ti.quantity) AS quantity FROM trade_items ti JOIN cancelled c ON ti.trade_id = c.trade_id GROUP BY ti.product_id) r
+			WHERE p.product_id = r.product_id
+		)
+		` + tradeSelect + ` JOIN cancelled c ON t.trade_id = c.trade_id`
+	trades, err := queryTrades(s.db, query, acceptedBefore)
+	if err != nil {
+		return nil, err
+	}
+	// The select sees the trades as they were before the update.
+	for i := range trades {
+		trades[i].Status = TradeStatusCancelled
+	}
+	return trades, nil
+}
+
+func setTradeStatus(q queryer, tradeID int, status string) error {
+	_, err := q.Exec(`UPDATE trades SET status = $2, updated_at = CURRENT_TIMESTAMP WHERE trade_id = $1`, tradeID, status)
+	return err
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+// ErrInvalidWantlist is returned when a wantlist has no name or cards, a
+// quantity that is not positive, or cards of another game.
+var ErrInvalidWantlist = errors.New("invalid wantlist")
+
+type Wantlist struct {
+	WantlistID int            `json:"wantlist_id"`
+	UserID     int            `json:"user_id"`
+	Name       string         `json:"name"`
+	TCGGameID  int            `json:"tcg_game_id"`
+	TCGGame    string         `json:"tcg_game"`
+	Items      []WantlistItem `json:"items"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+type WantlistItem struct {
+	CardID   int    `json:"card_id"`
+	Card     string `json:"card"`
+	SetName  string `json:"set_name"`
+	Quantity int    `json:"quantity"`
+}
+
+// WantlistRequest creates a wantlist. Items of the same card are added up.
+type WantlistRequest struct {
+	Name      string                `json:"name"`
+	TCGGameID int                   `json:"tcg_game_id"`
+	Items     []WantlistItemRequest `json:"items"`
+}
+
+type WantlistItemRequest struct {
+	CardID   int `json:"card_id"`
+	Quantity int `json:"quantity"`
+}
+
+// WantlistMatch tells a user that a card of one of their wantlists was
+// listed.
+type WantlistMatch struct {
+	UserID       int            `json:"-"`
+	WantlistID   int            `json:"wantlist_id"`
+	WantlistName string         `json:"wantlist"`
+	ProductID    int            `json:"product_id"`
+	CardID       int            `json:"card_id"`
+	Card         string         `json:"card"`
+	Price        currency.Money `json:"price"`
+}
+
+const wantlistSelect = `SELECT w.wantlist_id, w.user_id, w.name, w.tcg_game_id, tcg.name, w.created_at, w.updated_at
+FROM wantlists w
+JOIN tcg_games tcg ON w.tcg_game_id = tcg.tcg_game_id`
+
+// queryWantlists loads wantlists with their cards.
+func queryWantlists(q queryer, query string, args ...any) ([]Wantlist, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wantlists := []Wantlist{}
+	for rows.Next() {
+		w := Wantlist{Items: []WantlistItem{}}
+		if err := rows.Scan(&w.WantlistID, &w.UserID, &w.Name, &w.TCGGameID, &w.TCGGame, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		wantlists = append(wantlists, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(wantlists) == 0 {
+		return wantlists, nil
+	}
+
+	index := make(map[int]*Wantlist, len(wantlists))
+	wantlistIDs := make([]int, len(wantlists))
+	for i := range wantlists {
+		index[wantlists[i].WantlistID] = &wantlists[i]
+		wantlistIDs[i] = wantlists[i].WantlistID
+	}
+
+	rows, err = q.Query(`SELECT wi.wantlist_id, wi.card_id, c.name, COALESCE(c.set_name, ''), wi.quantity FROM wantlist_items wi JOIN cards c ON wi.card_id = c.card_id WHERE wi.wantlist_id = ANY($1) ORDER BY c.name, wi.wantlist_item_id`, wantlistIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			wantlistID int
+			item       WantlistItem
+		)
+		if err := rows.Scan(&wantlistID, &item.CardID, &item.Card, &item.SetName, &item.Quantity); err != nil {
+			return nil, err
+		}
+		index[wantlistID].Items = append(index[wantlistID].Items, item)
+	}
+	return wantlists, rows.Err()
+}
+
+func (s *service) ListWantlists(userID int) ([]Wantlist, error) {
+	return queryWantlists(s.db, wantlistSelect+` WHERE w.user_id = $1 ORDER BY w.created_at DESC`, userID)
+}
+
+// GetWantlist returns a wantlist of the user. Wantlists of other users are
+// reported as not found.
+func (s *service) GetWantlist(wantlistID int, userID int) (Wantlist, error) {
+	return getWantlist(s.db, wantlistID, userID)
+}
+
+func getWantlist(q queryer, wantlistID int, userID int) (Wantlist, error) {
+	wantlists, err := queryWantlists(q, wantlistSelect+` WHERE w.wantlist_id = $1 AND w.user_id = $2`, wantlistID, userID)
+	if err != nil {
+		return Wantlist{}, err
+	}
+	if len(wantlists) == 0 {
+		return Wantlist{}, sql.ErrNoRows
+	}
+	return wantlists[0], nil
+}
+
+func (s *service) CreateWantlist(userID int, wantlist WantlistRequest) (Wantlist, error) {
+	if wantlist.Name == "" || len(wantlist.Items) == 0 {
+		return Wantlist{}, ErrInvalidWantlist
+	}
+	cardIDs := make([]int, 0, len(wantlist.Items))
+	for _, item := range wantlist.Items {
+		if item.Quantity <= 0 {
+			return Wantlist{}, ErrInvalidWantlist
+		}
+		cardIDs = append(cardIDs, item.CardID)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return Wantlist{}, err
+	}
+	defer tx.Rollback()
+
+	var foreign bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM unnest($1::int[]) AS w(card_id) LEFT JOIN cards c ON c.card_id = w.card_id AND c.tcg_game_id = $2 WHERE c.card_id IS NULL)`, cardIDs, wantlist.TCGGameID).Scan(&foreign)
+	if err != nil {
+		return Wantlist{}, err
+	}
+	if foreign {
+		return Wantlist{}, ErrInvalidWantlist
+	}
+
+	var wantlistID int
+	err = tx.QueryRow(`INSERT INTO wantlists (user_id, name, tcg_game_id) VALUES ($1, $2, $3) RETURNING wantlist_id`, userID, wantlist.Name, wantlist.TCGGameID).Scan(&wantlistID)
+	if err != nil {
+		return Wantlist{}, err
+	}
+	for _, item := range wantlist.Items {
+		_, err := tx.Exec(`INSERT INTO wantlist_items (wantlist_id, card_id, quantity) VALUES ($1, $2, $3) ON CONFLICT (wantlist_id, card_id) DO UPDATE SET quantity = wantlist_items.quantity + EXCLUDED.quantity`, wantlistID, item.CardID, item.Quantity)
+		if err != nil {
+			return Wantlist{}, err
+		}
+	}
+
+	created, err := getWantlist(tx, wantlistID, userID)
+	if err != nil {
+		return Wantlist{}, err
+	}
+	return created, tx.Commit()
+}
+
+func (s *service) DeleteWantlist(wantlistID int, userID int) error {
+	result, err := s.db.Exec(`DELETE FROM wantlists WHERE wantlist_id = $1 AND user_id = $2`, wantlistID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MatchWantlists finds the users who want the card of a product that was
+// listed or changed. Each user is told about a listing once, naming the
+// oldest of their wantlists with the card. Products that cannot be bought
+// match no wantlists, and sellers are not told about their own products.
+func (s *service) MatchWantlists(productID int) ([]WantlistMatch, error) {
+	query := `WITH listing AS (
+	SELECT p.product_id, p.card_id, p.seller_id, p.price, p.currency, c.name
+	FROM products p
+	JOIN users sellers ON p.seller_id = sellers.user_id
+	JOIN cards c ON p.card_id = c.card_id
+	WHERE p.product_id = $1 AND p.is_available AND p.quantity > 0 AND NOT sellers.on_vacation
+), matches AS (
+	SELECT DISTINCT ON (w.user_id) w.user_id, w.wantlist_id, w.name
+	FROM wantlists w
+	JOIN wantlist_items wi ON wi.wantlist_id = w.wantlist_id
+	JOIN listing l ON wi.card_id = l.card_id
+	WHERE w.user_id <> l.seller_id
+	ORDER BY w.user_id, w.created_at, w.wantlist_id
+), notified AS (
+	INSERT INTO wantlist_notified_products (user_id, product_id)
+	SELECT m.user_id, $1 FROM matches m
+	ON CONFLICT DO NOTHING
+	RETURNING user_id
+)
+SELECT m.user_id, m.wantlist_id, m.name, l.product_id, l.card_id, l.name, l.price, l.currency
+FROM notified n
+JOIN matches m ON m.user_id = n.user_id
+CROSS JOIN listing l`
+	rows, err := s.db.Query(query, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []WantlistMatch{}
+	for rows.Next() {
+		var (
+			match       WantlistMatch
+			price, code string
+		)
+		if err := rows.Scan(&match.UserID, &match.WantlistID, &match.WantlistName, &match.ProductID, &match.CardID, &match.Card, &price, &code); err != nil {
+			return nil, err
+		}
+		if match.Price, err = currency.Parse(price, code); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
@@ -0,0 +1,346 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+// ErrInvalidWatchlistEntry is returned when an entry does not watch exactly
+// one product or card, or has a price threshold that is not positive.
+var ErrInvalidWatchlistEntry = errors.New("invalid watchlist entry")
+
+// Watchlist alert kinds.
+const (
+	// WatchAlertPriceDrop: a watched product became cheaper.
+	WatchAlertPriceDrop = "price_drop"
+	// WatchAlertNewListing: a watched card was listed.
+	WatchAlertNewListing = "new_listing"
+)
+
+type WatchlistEntry struct {
+	EntryID   int  `json:"entry_id"`
+	ProductID *int `json:"product_id,omitempty"`
+	CardID    *int `json:"card_id,omitempty"`
+	// Name is the name of the watched card, or of the item of the watched
+	// product.
+	Name string `json:"name"`
+	// Price is the current price of a watched product.
+	Price *currency.Money `json:"price,omitempty"`
+	// MaxPrice limits alerts to prices at or below it.
+	MaxPrice  *currency.Money `json:"max_price,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// WatchlistEntryRequest watches either a product or a card. Watching the same
+// item again replaces its price threshold.
+type WatchlistEntryRequest struct {
+	ProductID int             `json:"product_id,omitempty"`
+	CardID    int             `json:"card_id,omitempty"`
+	MaxPrice  *currency.Money `json:"max_price,omitempty"`
+}
+
+// WatchlistAlert tells a user about a price drop of a watched product or a
+// new listing of a watched card.
+type WatchlistAlert struct {
+	UserID    int            `json:"-"`
+	EntryID   int            `json:"entry_id"`
+	Kind      string         `json:"kind"`
+	ProductID int            `json:"product_id"`
+	CardID    *int           `json:"card_id,omitempty"`
+	Name      string         `json:"name"`
+	Price     currency.Money `json:"price"`
+	// PreviousPrice is the price before a drop.
+	PreviousPrice *currency.Money `json:"previous_price,omitempty"`
+}
+
+const watchlistEntrySelect = `SELECT w.watchlist_entry_id, w.product_id, w.card_id, COALESCE(c.name, pc.name, sp.name), p.price, p.currency, w.max_price, w.max_price_currency, w.created_at
+FROM watchlist_entries w
+LEFT JOIN cards c ON w.card_id = c.card_id
+LEFT JOIN products p ON w.product_id = p.product_id
+LEFT JOIN cards pc ON p.card_id = pc.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id`
+
+func queryWatchlistEntries(q queryer, query string, args ...any) ([]WatchlistEntry, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []WatchlistEntry{}
+	for rows.Next() {
+		var (
+			entry                                   WatchlistEntry
+			price, code, maxPrice, maxPriceCurrency sql.NullString
+		)
+		if err := rows.Scan(&entry.EntryID, &entry.ProductID, &entry.CardID, &entry.Name, &price, &code, &maxPrice, &maxPriceCurrency, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		if entry.Price, err = parseNullMoney(price, code); err != nil {
+			return nil, err
+		}
+		if entry.MaxPrice, err = parseNullMoney(maxPrice, maxPriceCurrency); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (s *service) ListWatchlist(userID int) ([]WatchlistEntry, error) {
+	return queryWatchlistEntries(s.db, watchlistEntrySelect+` WHERE w.user_id = $1 ORDER BY w.created_at DESC`, userID)
+}
+
+// AddWatchlistEntry watches a product or card for the user. Listings of a
+// card that already match the threshold are not announced again.
+func (s *service) AddWatchlistEntry(userID int, req WatchlistEntryRequest) (WatchlistEntry, error) {
+	if (req.ProductID > 0) == (req.CardID > 0) || req.ProductID < 0 || req.CardID < 0 {
+		return WatchlistEntry{}, ErrInvalidWatchlistEntry
+	}
+	var maxPrice, maxPriceCurrency sql.NullString
+	if req.MaxPrice != nil {
+		if !req.MaxPrice.IsPositive() {
+			return WatchlistEntry{}, ErrInvalidWatchlistEntry
+		}
+		maxPrice = sql.NullString{String: req.MaxPrice.Decimal(), Valid: true}
+		maxPriceCurrency = sql.NullString{String: req.MaxPrice.Currency, Valid: true}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return WatchlistEntry{}, err
+	}
+	defer tx.Rollback()
+
+	var entryID int
+	if req.ProductID > 0 {
+		query := `INSERT INTO watchlist_entries (user_id, product_id, max_price, max_price_currency, last_price)
+SELECT $1, p.product_id, $3, $4, p.price FROM products p WHERE p.product_id = $2
+ON CONFLICT (user_id, product_id) DO UPDATE SET max_price = EXCLUDED.max_price, max_price_currency = EXCLUDED.max_price_currency, last_price = EXCLUDED.last_price
+RETURNING watchlist_entry_id`
+		err = tx.QueryRow(query, userID, req.ProductID, maxPrice, maxPriceCurrency).Scan(&entryID)
+	} else {
+		query := `INSERT INTO watchlist_entries (user_id, card_id, max_price, max_price_currency)
+SELECT $1, c.card_id, $3, $4 FROM cards c WHERE c.card_id = $2
+ON CONFLICT (user_id, card_id) DO UPDATE SET max_price = EXCLUDED.max_price, max_price_currency = EXCLUDED.max_price_currency
+RETURNING watchlist_entry_id`
+		err = tx.QueryRow(query, userID, req.CardID, maxPrice, maxPriceCurrency).Scan(&entryID)
+	}
+	if err != nil {
+		return WatchlistEntry{}, err
+	}
+
+	if req.CardID > 0 {
+		// Prices are compared in euros, like the cheapest listings of a cart.
+		query := `INSERT INTO watchlist_seen_products (watchlist_entry_id, product_id)
+SELECT $1, p.product_id
+FROM products p
+LEFT JOIN exchange_rates er ON er.currency_code = p.currency
+LEFT JOIN exchange_rates tr ON tr.currency_code = $4::text
+WHERE p.card_id = $2 AND ($3::numeric IS NULL OR p.price / COALESCE(er.rate, 1) <= $3::numeric / COALESCE(tr.rate, 1))
+ON CONFLICT DO NOTHING`
+		if _, err := tx.Exec(query, entryID, req.CardID, maxPrice, maxPriceCurrency); err != nil {
+			return WatchlistEntry{}, err
+		}
+	}
+
+	entries, err := queryWatchlistEntries(tx, watchlistEntrySelect+` WHERE w.watchlist_entry_id = $1`, entryID)
+	if err != nil {
+		return WatchlistEntry{}, err
+	}
+	if len(entries) == 0 {
+		return WatchlistEntry{}, sql.ErrNoRows
+	}
+	return entries[0], tx.Commit()
+}
+
+func (s *service) RemoveWatchlistEntry(userID int, entryID int) error {
+	result, err := s.db.Exec(`DELETE FROM watchlist_entries WHERE watchlist_entry_id = $1 AND user_id = $2`, entryID, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CheckWatchlist compares a product that was listed or changed against the
+// watchlists. Watchers of the product are alerted when its price dropped,
+// watchers of its card the first time it is listed within their threshold.
+// Products that cannot be bought raise no alerts, and sellers are not
+// alerted about their own products.
+func (s *service) CheckWatchlist(productID int) ([]WatchlistAlert, error) {
+	rates, err := s.ListExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		alert       WatchlistAlert
+		price, code string
+		sellerID    int
+		listed      bool
+	)
+	query := `SELECT p.price, p.currency, p.card_id, COALESCE(c.name, sp.name), p.seller_id, p.is_available AND p.quantity > 0 AND NOT sellers.on_vacation
+FROM products p
+JOIN users sellers ON p.seller_id = sellers.user_id
+LEFT JOIN cards c ON p.card_id = c.card_id
+LEFT JOIN sealed_products sp ON p.sealed_product_id = sp.sealed_product_id
+WHERE p.product_id = $1`
+	err = tx.QueryRow(query, productID).Scan(&price, &code, &alert.CardID, &alert.Name, &sellerID, &listed)
+	if err != nil {
+		return nil, err
+	}
+	alert.ProductID = productID
+	if alert.Price, err = currency.Parse(price, code); err != nil {
+		return nil, err
+	}
+
+	alerts, err := productPriceAlerts(tx, alert, sellerID, listed, rates)
+	if err != nil {
+		return nil, err
+	}
+	if alert.CardID != nil && listed {
+		listings, err := newListingAlerts(tx, alert, sellerID, rates)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, listings...)
+	}
+	return alerts, tx.Commit()
+}
+
+// productPriceAlerts alerts the watchers of a product whose price dropped and
+// remembers the price for the next check.
+func productPriceAlerts(tx queryer, product WatchlistAlert, sellerID int, listed bool, rates currency.Rates) ([]WatchlistAlert, error) {
+	query := `SELECT watchlist_entry_id, user_id, max_price, max_price_currency, last_price FROM watchlist_entries WHERE product_id = $1 FOR UPDATE`
+	rows, err := tx.Query(query, product.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []WatchlistAlert{}
+	for rows.Next() {
+		var (
+			alert                                 = product
+			maxPrice, maxPriceCurrency, lastPrice sql.NullString
+		)
+		if err := rows.Scan(&alert.EntryID, &alert.UserID, &maxPrice, &maxPriceCurrency, &lastPrice); err != nil {
+			return nil, err
+		}
+		if !listed || alert.UserID == sellerID || !lastPrice.Valid {
+			continue
+		}
+		previous, err := currency.Parse(lastPrice.String, product.Price.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if product.Price.Units >= previous.Units {
+			continue
+		}
+		threshold, err := parseNullMoney(maxPrice, maxPriceCurrency)
+		if err != nil {
+			return nil, err
+		}
+		if !withinThreshold(product.Price, threshold, rates) {
+			continue
+		}
+		alert.Kind = WatchAlertPriceDrop
+		alert.PreviousPrice = &previous
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	if _, err := tx.Exec(`UPDATE watchlist_entries SET last_price = $2 WHERE product_id = $1`, product.ProductID, product.Price); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// newListingAlerts alerts the watchers of a card about a listing they have
+// not been told about yet.
+func newListingAlerts(tx queryer, product WatchlistAlert, sellerID int, rates currency.Rates) ([]WatchlistAlert, error) {
+	query := `SELECT w.watchlist_entry_id, w.user_id, w.max_price, w.max_price_currency
+FROM watchlist_entries w
+WHERE w.card_id = $1 AND w.user_id <> $2
+AND NOT EXISTS (SELECT 1 FROM watchlist_seen_products seen WHERE seen.watchlist_entry_id = w.watchlist_entry_id AND seen.product_id = $3)`
+	rows, err := tx.Query(query, *product.CardID, sellerID, product.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []WatchlistAlert{}
+	for rows.Next() {
+		var (
+			alert                      = product
+			maxPrice, maxPriceCurrency sql.NullString
+		)
+		if err := rows.Scan(&alert.EntryID, &alert.UserID, &maxPrice, &maxPriceCurrency); err != nil {
+			return nil, err
+		}
+		threshold, err := parseNullMoney(maxPrice, maxPriceCurrency)
+		if err != nil {
+			return nil, err
+		}
+		if !withinThreshold(product.Price, threshold, rates) {
+			continue
+		}
+		alert.Kind = WatchAlertNewListing
+		alerts = append(alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, alert := range alerts {
+		if _, err := tx.Exec(`INSERT INTO watchlist_seen_products (watchlist_entry_id, product_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, alert.EntryID, alert.ProductID); err != nil {
+			return nil, err
+		}
+	}
+	return alerts, nil
+}
+
+// withinThreshold reports whether price is at or below the threshold, if
+// there is one. Prices that cannot be converted are never within it.
+func withinThreshold(price currency.Money, threshold *currency.Money, rates currency.Rates) bool {
+	if threshold == nil {
+		return true
+	}
+	converted, err := rates.Convert(price, threshold.Currency)
+	if err != nil {
+		return false
+	}
+	return converted.Units <= threshold.Units
+}
+
+// parseNullMoney parses an optional amount.
+func parseNullMoney(amount, code sql.NullString) (*currency.Money, error) {
+	if !amount.Valid || !code.Valid {
+		return nil, nil
+	}
+	m, err := currency.Parse(amount.String, code.String)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
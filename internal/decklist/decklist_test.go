@@ -0,0 +1,132 @@
+package decklist
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseArena(t *testing.T) {
+	text := "About\nName Burn\n\nDeck\n4 Lightning Bolt (M10) 146\n20 Mountain\n4x Lava Spike *F*\n\nSideboard\n2 Smash to Smithereens\nnot a card\n"
+	lines, unparsed := Parse(GameMagic, text)
+
+	want := []Line{
+		{Number: 5, Text: "4 Lightning Bolt (M10) 146", Section: SectionMain, Quantity: 4, Name: "Lightning Bolt", SetCode: "M10", CardNumber: "146"},
+		{Number: 6, Text: "20 Mountain", Section: SectionMain, Quantity: 20, Name: "Mountain"},
+		{Number: 7, Text: "4x Lava Spike *F*", Section: SectionMain, Quantity: 4, Name: "Lava Spike"},
+		{Number: 10, Text: "2 Smash to Smithereens", Section: SectionSideboard, Quantity: 2, Name: "Smash to Smithereens"},
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("expected %+v; got %+v", want, lines)
+	}
+	if len(unparsed) != 1 || unparsed[0].Number != 11 {
+		t.Errorf("expected line 11 to be unparsed; got %+v", unparsed)
+	}
+}
+
+func TestParseMTGOSideboard(t *testing.T) {
+	lines, _ := Parse(GameMagic, "4 Lightning Bolt\n// burn\n\n2 Duress\nSB: 1 Pyroblast")
+	sections := []string{}
+	for _, line := range lines {
+		sections = append(sections, line.Section)
+	}
+	if !slices.Equal(sections, []string{SectionMain, SectionSideboard, SectionSideboard}) {
+		t.Errorf("unexpected sections %v", sections)
+	}
+}
+
+func TestParsePokemonTCGLive(t *testing.T) {
+	text := "Pokémon: 2\n4 Pikachu ex SVI 57\n\nTrainer: 1\n4 Professor's Research PR-SV 190\n\nEnergy: 1\n10 Basic Lightning Energy\n\nTotal Cards: 18"
+	lines, unparsed := Parse(GamePokemon, text)
+
+	want := []Line{
+		{Number: 2, Text: "4 Pikachu ex SVI 57", Section: SectionPokemon, Quantity: 4, Name: "Pikachu ex", SetCode: "SVI", CardNumber: "57"},
+		{Number: 5, Text: "4 Professor's Research PR-SV 190", Section: SectionTrainer, Quantity: 4, Name: "Professor's Research", SetCode: "PR-SV", CardNumber: "190"},
+		{Number: 8, Text: "10 Basic Lightning Energy", Section: SectionEnergy, Quantity: 10, Name: "Basic Lightning Energy"},
+	}
+	if !slices.Equal(lines, want) || len(unparsed) != 0 {
+		t.Errorf("expected %+v; got %+v, unparsed %+v", want, lines, unparsed)
+	}
+}
+
+func TestParseYuGiOhSections(t *testing.T) {
+	lines, _ := Parse(GameYuGiOh, "Main Deck:\n3x Ash Blossom & Joyous Spring\nExtra Deck:\n1 Accesscode Talker\nSide Deck:\n2 Droll & Lock Bird")
+	sections := []string{}
+	for _, line := range lines {
+		sections = append(sections, line.Section)
+	}
+	if !slices.Equal(sections, []string{SectionMain, SectionExtra, SectionSideboard}) {
+		t.Errorf("unexpected sections %v", sections)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	idx := NewIndex([]Card{
+		{ID: 1, Name: "Lightning Bolt", SetName: "M10", CardNumber: "146"},
+		{ID: 2, Name: "Lightning Bolt", SetName: "Alpha", CardNumber: "161"},
+		{ID: 3, Name: "Fire // Ice", SetName: "Apocalypse", CardNumber: "128"},
+		{ID: 4, Name: "Lim-Dûl's Vault", SetName: "Alliances", CardNumber: "105"},
+		{ID: 5, Name: "Shock", SetName: "M19", CardNumber: "156"},
+		{ID: 6, Name: "Smash", SetName: "Apocalypse", CardNumber: "111"},
+		{ID: 7, Name: "Swash", SetName: "Legends", CardNumber: "2"},
+	})
+
+	matches, unresolved := idx.Resolve([]Line{
+		{Number: 1, Quantity: 4, Name: "Lightning Bolt", SetCode: "M10", CardNumber: "146"},
+		{Number: 2, Quantity: 4, Name: "lightning bolt"},
+		{Number: 3, Quantity: 2, Name: "Fire"},
+		{Number: 4, Quantity: 1, Name: "Lim-Dul's Vault"},
+		{Number: 5, Quantity: 4, Name: "Lightnign Bolt"},
+		{Number: 6, Quantity: 1, Name: "Shk"},
+		{Number: 7, Quantity: 1, Name: "Sash"},
+		{Number: 8, Quantity: 1, Name: "Counterspell"},
+	})
+
+	type result struct {
+		line, cardID int
+		cardIDs      []int
+		fuzzy        bool
+	}
+	want := []result{
+		{1, 1, []int{1}, false},
+		{2, 1, []int{1, 2}, false},
+		{3, 3, []int{3}, false},
+		{4, 4, []int{4}, false},
+		{5, 1, []int{1, 2}, true},
+	}
+	if len(matches) != len(want) {
+		t.Fatalf("expected %d matches; got %+v", len(want), matches)
+	}
+	for i, m := range matches {
+		if m.Number != want[i].line || m.CardID != want[i].cardID || !slices.Equal(m.CardIDs, want[i].cardIDs) || m.Fuzzy != want[i].fuzzy {
+			t.Errorf("line %d: expected %+v; got %+v", want[i].line, want[i], m)
+		}
+	}
+
+	reasons := map[int]string{}
+	for _, u := range unresolved {
+		reasons[u.Number] = u.Reason
+	}
+	// Names shorter than four characters are not corrected, "Sash" is as
+	// close to "Smash" as to "Swash".
+	if reasons[6] != ReasonNotFound || reasons[7] != ReasonAmbiguous || reasons[8] != ReasonNotFound || len(reasons) != 3 {
+		t.Errorf("unexpected unresolved lines %+v", unresolved)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"lightning bolt", "lightning bolt", 3, 0},
+		{"lightnign bolt", "lightning bolt", 3, 2},
+		{"shock", "shok", 3, 1},
+		{"counterspell", "shock", 3, 3},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b, tt.limit); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d; want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
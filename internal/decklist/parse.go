@@ -0,0 +1,164 @@
+// Package decklist parses deck lists exported from deck builders and
+// resolves their entries against the card catalog by name.
+package decklist
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Games with their own deck list formats, by their name in tcg_games. Other
+// games are parsed with the plain "quantity name" format only.
+const (
+	GameMagic   = "Magic: The Gathering"
+	GamePokemon = "Pokemon"
+	GameYuGiOh  = "Yu-Gi-Oh!"
+)
+
+// Sections of a deck. Which ones occur depends on the game.
+const (
+	SectionMain      = "main"
+	SectionSideboard = "sideboard"
+	SectionCommander = "commander"
+	SectionCompanion = "companion"
+	SectionExtra     = "extra"
+	SectionPokemon   = "pokemon"
+	SectionTrainer   = "trainer"
+	SectionEnergy    = "energy"
+)
+
+// sectionIgnored marks sections whose lines are not cards, such as the
+// "About" block of an Arena export.
+const sectionIgnored = "ignored"
+
+// Line is a card entry of a deck list.
+type Line struct {
+	// Number is the 1-based line number in the pasted text.
+	Number   int    `json:"line"`
+	Text     string `json:"text"`
+	Section  string `json:"section"`
+	Quantity int    `json:"quantity"`
+	Name     string `json:"name"`
+	// SetCode and CardNumber identify the printing when the format names
+	// one, like "(M10) 146" on Arena or "SVI 57" on Pokemon TCG Live.
+	SetCode    string `json:"set_code,omitempty"`
+	CardNumber string `json:"card_number,omitempty"`
+}
+
+// format describes the deck list conventions of a game.
+type format struct {
+	// headers maps lower-cased section headers to sections. Headers may be
+	// followed by a colon and a card count.
+	headers map[string]string
+	// printing splits a trailing printing off a card name into the name, the
+	// set code and the card number.
+	printing *regexp.Regexp
+	// blankStartsSideboard is set for formats (MTGO) that separate the
+	// sideboard from the main deck with a blank line only.
+	blankStartsSideboard bool
+}
+
+var formats = map[string]format{
+	GameMagic: {
+		headers: map[string]string{
+			"deck":      SectionMain,
+			"main":      SectionMain,
+			"maindeck":  SectionMain,
+			"mainboard": SectionMain,
+			"sideboard": SectionSideboard,
+			"commander": SectionCommander,
+			"companion": SectionCompanion,
+			"about":     sectionIgnored,
+		},
+		printing:             regexp.MustCompile(`^(.+?)\s+\(([A-Za-z0-9]{2,6})\)(?:\s+([A-Za-z0-9-]+))?$`),
+		blankStartsSideboard: true,
+	},
+	GamePokemon: {
+		headers: map[string]string{
+			"pokemon": SectionPokemon,
+			"pokémon": SectionPokemon,
+			"trainer": SectionTrainer,
+			"energy":  SectionEnergy,
+		},
+		printing: regexp.MustCompile(`^(.+?)\s+([A-Z][A-Z0-9]{1,4}(?:-[A-Z0-9]{1,4})?)\s+([A-Z]{0,4}\d{1,4}[a-z]?)$`),
+	},
+	GameYuGiOh: {
+		headers: map[string]string{
+			"main deck":  SectionMain,
+			"#main":      SectionMain,
+			"extra deck": SectionExtra,
+			"#extra":     SectionExtra,
+			"side deck":  SectionSideboard,
+			"!side":      SectionSideboard,
+		},
+	},
+}
+
+var (
+	// entryFormat matches "4 Lightning Bolt" and "4x Lightning Bolt".
+	entryFormat = regexp.MustCompile(`^(\d{1,3})\s*[xX]?\s+(\S.*)$`)
+	// headerCount matches the card count after a section header, like
+	// "Pokémon: 12" on Pokemon TCG Live.
+	headerCount = regexp.MustCompile(`\s*:?\s*\d*$`)
+	// foilTag matches the finish tags deck builders append, like "*F*".
+	foilTag = regexp.MustCompile(`\s+\*[A-Za-z]+\*$`)
+)
+
+// Parse splits a deck list of the given game into card entries. Lines that
+// are neither entries, section headers nor comments are returned as
+// unparsed.
+func Parse(game string, text string) (lines []Line, unparsed []Line) {
+	f := formats[game]
+	section := SectionMain
+	seenHeader, seenCards := false, false
+
+	for i, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		text := strings.TrimSpace(raw)
+		switch {
+		case text == "":
+			if f.blankStartsSideboard && !seenHeader && seenCards && section == SectionMain {
+				section = SectionSideboard
+			}
+			continue
+		case strings.HasPrefix(text, "//"), strings.HasPrefix(text, "#") && f.headers[strings.ToLower(text)] == "":
+			continue
+		case strings.HasPrefix(strings.ToLower(text), "total cards"):
+			continue
+		}
+
+		if s, ok := f.headers[strings.ToLower(headerCount.ReplaceAllString(text, ""))]; ok && !entryFormat.MatchString(text) {
+			section, seenHeader = s, true
+			continue
+		}
+		if section == sectionIgnored {
+			continue
+		}
+
+		line := Line{Number: i + 1, Text: text, Section: section}
+		entry := strings.TrimPrefix(text, "* ")
+		if rest, ok := strings.CutPrefix(entry, "SB:"); ok {
+			entry, line.Section = strings.TrimSpace(rest), SectionSideboard
+		}
+
+		m := entryFormat.FindStringSubmatch(entry)
+		if m == nil {
+			unparsed = append(unparsed, line)
+			continue
+		}
+		line.Quantity, _ = strconv.Atoi(m[1])
+		line.Name = foilTag.ReplaceAllString(strings.TrimSpace(m[2]), "")
+		if f.printing != nil {
+			if p := f.printing.FindStringSubmatch(line.Name); p != nil {
+				line.Name, line.SetCode, line.CardNumber = p[1], p[2], p[3]
+			}
+		}
+		if line.Quantity == 0 {
+			unparsed = append(unparsed, line)
+			continue
+		}
+		lines = append(lines, line)
+		seenCards = true
+	}
+	return lines, unparsed
+}
@@ -0,0 +1,268 @@
+package decklist
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Reasons a line could not be resolved to a card.
+const (
+	ReasonUnparsed  = "unparsed"
+	ReasonNotFound  = "not_found"
+	ReasonAmbiguous = "ambiguous"
+)
+
+// Card is a catalog card that deck list entries are matched against.
+type Card struct {
+	ID         int
+	Name       string
+	SetName    string
+	CardNumber string
+}
+
+// Match is a deck list entry resolved to a card.
+type Match struct {
+	Line
+	// CardID is the printing that best fits the entry. CardIDs holds every
+	// printing the entry may be bought as: only CardID when the entry names
+	// a printing that exists, otherwise all printings of the card.
+	CardID  int    `json:"card_id"`
+	CardIDs []int  `json:"card_ids"`
+	Card    string `json:"card"`
+	// Fuzzy is set when the name was matched despite a misspelling.
+	Fuzzy bool `json:"fuzzy"`
+}
+
+// Unresolved is a deck list line that matched no card.
+type Unresolved struct {
+	Line
+	Reason      string   `json:"reason"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Index looks up catalog cards by their normalized name.
+type Index struct {
+	byName map[string][]Card
+	names  []string
+}
+
+// NewIndex indexes the cards of a game. Cards with two faces, named
+// "Front // Back", can also be found by their front face.
+func NewIndex(cards []Card) *Index {
+	idx := &Index{byName: map[string][]Card{}}
+	add := func(key string, card Card) {
+		if key == "" {
+			return
+		}
+		if _, ok := idx.byName[key]; !ok {
+			idx.names = append(idx.names, key)
+		}
+		idx.byName[key] = append(idx.byName[key], card)
+	}
+	for _, card := range cards {
+		add(normalize(card.Name), card)
+		if front, _, ok := strings.Cut(card.Name, "//"); ok {
+			add(normalize(front), card)
+		}
+	}
+	slices.Sort(idx.names)
+	return idx
+}
+
+// Resolve matches deck list entries to cards. Entries with the same card
+// and section are not merged, so every match keeps its line.
+func (idx *Index) Resolve(lines []Line) (matches []Match, unresolved []Unresolved) {
+	for _, line := range lines {
+		key := normalize(line.Name)
+		printings, fuzzy := idx.byName[key], false
+		if len(printings) == 0 {
+			names := idx.closest(key)
+			switch {
+			case len(names) == 0:
+				unresolved = append(unresolved, Unresolved{Line: line, Reason: ReasonNotFound, Suggestions: idx.suggest(key)})
+				continue
+			case len(names) > 1 && !sameCard(idx.byName, names):
+				unresolved = append(unresolved, Unresolved{Line: line, Reason: ReasonAmbiguous, Suggestions: cardNames(idx.byName, names)})
+				continue
+			}
+			printings, fuzzy = idx.byName[names[0]], true
+		}
+
+		best := printings[0]
+		for _, card := range printings[1:] {
+			if printingScore(card, line) > printingScore(best, line) {
+				best = card
+			}
+		}
+		match := Match{Line: line, CardID: best.ID, Card: best.Name, Fuzzy: fuzzy}
+		if printingScore(best, line) > 0 {
+			match.CardIDs = []int{best.ID}
+		} else {
+			for _, card := range printings {
+				match.CardIDs = append(match.CardIDs, card.ID)
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches, unresolved
+}
+
+// printingScore rates how well a card's printing fits the set code and card
+// number of an entry. Catalog sets are stored by name, so the set code only
+// matches sets named by their code.
+func printingScore(card Card, line Line) int {
+	score := 0
+	if line.SetCode != "" && strings.EqualFold(card.SetName, line.SetCode) {
+		score += 2
+	}
+	if line.CardNumber != "" {
+		number, _, _ := strings.Cut(card.CardNumber, "/")
+		if strings.EqualFold(card.CardNumber, line.CardNumber) || strings.TrimLeft(number, "0") == strings.TrimLeft(line.CardNumber, "0") {
+			score++
+		}
+	}
+	return score
+}
+
+// closest returns the indexed names within the misspelling tolerance of key
+// that are nearest to it.
+func (idx *Index) closest(key string) []string {
+	limit := maxDistance(key)
+	if limit == 0 {
+		return nil
+	}
+	var names []string
+	best := limit
+	for _, name := range idx.names {
+		d := distance(key, name, best+1)
+		if d > best {
+			continue
+		}
+		if d < best {
+			best, names = d, nil
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// suggest returns up to three names close to key, for lines that matched no
+// card.
+func (idx *Index) suggest(key string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+	limit := 2*maxDistance(key) + 1
+	var candidates []candidate
+	for _, name := range idx.names {
+		if d := distance(key, name, limit+1); d <= limit {
+			candidates = append(candidates, candidate{name, d})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int { return a.distance - b.distance })
+
+	var suggestions []string
+	for _, c := range candidates {
+		name := idx.byName[c.name][0].Name
+		if !slices.Contains(suggestions, name) {
+			suggestions = append(suggestions, name)
+		}
+		if len(suggestions) == 3 {
+			break
+		}
+	}
+	return suggestions
+}
+
+// sameCard reports whether the names all belong to the same card, as the
+// front face and full name of a two-faced card do.
+func sameCard(byName map[string][]Card, names []string) bool {
+	return len(cardNames(byName, names)) == 1
+}
+
+func cardNames(byName map[string][]Card, names []string) []string {
+	var cards []string
+	for _, name := range names {
+		if card := byName[name][0].Name; !slices.Contains(cards, card) {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// maxDistance is the number of typos tolerated in a name: one per five
+// characters, and none in names shorter than four.
+func maxDistance(key string) int {
+	n := len([]rune(key))
+	if n < 4 {
+		return 0
+	}
+	return max(1, n/5)
+}
+
+// distance returns the Levenshtein distance between a and b, or limit once
+// it is certain to be at least limit.
+func distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d >= limit || -d >= limit {
+		return limit
+	}
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin >= limit {
+			return limit
+		}
+		prev, curr = curr, prev
+	}
+	return min(prev[len(rb)], limit)
+}
+
+// folds replaces letters that deck builders and catalogs spell differently.
+var folds = strings.NewReplacer(
+	"æ", "ae", "œ", "oe", "ß", "ss",
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c",
+)
+
+// normalize reduces a card name to lower-case letters and digits separated
+// by single spaces. Apostrophes are dropped and other punctuation separates
+// words, so "Blue-Eyes" matches "Blue Eyes" and "Professor's" "Professors".
+func normalize(name string) string {
+	name = folds.Replace(strings.ToLower(name))
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		case r == '\'' || r == '’':
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,146 @@
+// Package images validates uploaded images and renders their thumbnails.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+const (
+	// MaxSize bounds the size of an uploaded image file.
+	MaxSize = 8 << 20
+	// MaxPixels bounds the decoded size of an image. A 1200 dpi scan of a
+	// card stays well below it.
+	MaxPixels = 24_000_000
+	// ThumbnailSize is the longest side of a thumbnail in pixels.
+	ThumbnailSize = 320
+)
+
+var (
+	// ErrTooLarge is returned for files above MaxSize or images above
+	// MaxPixels.
+	ErrTooLarge = errors.New("image too large")
+	// ErrUnsupportedType is returned for files that are not JPEG or PNG
+	// images.
+	ErrUnsupportedType = errors.New("unsupported image type")
+	// ErrInvalid is returned for files that cannot be decoded.
+	ErrInvalid = errors.New("invalid image")
+)
+
+// Extensions maps the accepted content types to their file extensions.
+var Extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// Image is a validated upload.
+type Image struct {
+	// ContentType is sniffed from the data rather than taken from the client.
+	ContentType string
+	Width       int
+	Height      int
+	image       image.Image
+}
+
+// Decode validates an uploaded file and decodes it. The dimensions are
+// checked before decoding, so oversized images are rejected without being
+// held in memory.
+func Decode(data []byte) (Image, error) {
+	if len(data) > MaxSize {
+		return Image{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if _, ok := Extensions[contentType]; !ok {
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, err := decodeConfig(contentType, data)
+	if err != nil {
+		return Image{}, ErrInvalid
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return Image{}, ErrInvalid
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	img, err := decode(contentType, data)
+	if err != nil {
+		return Image{}, ErrInvalid
+	}
+	return Image{ContentType: contentType, Width: config.Width, Height: config.Height, image: img}, nil
+}
+
+// Thumbnail renders the image as a JPEG whose longest side is at most size
+// pixels. Smaller images keep their size. Transparent areas become white.
+func (img Image) Thumbnail(size int) ([]byte, error) {
+	width, height := img.Width, img.Height
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/width)
+		} else {
+			width, height = max(1, width*size/height), size
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scale(img.image, width, height), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeConfig(contentType string, data []byte) (image.Config, error) {
+	if contentType == "image/png" {
+		return png.DecodeConfig(bytes.NewReader(data))
+	}
+	return jpeg.DecodeConfig(bytes.NewReader(data))
+}
+
+func decode(contentType string, data []byte) (image.Image, error) {
+	if contentType == "image/png" {
+		return png.Decode(bytes.NewReader(data))
+	}
+	return jpeg.Decode(bytes.NewReader(data))
+}
+
+// scale resizes src to width x height over a white background, averaging the
+// source pixels covered by each target pixel.
+func scale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+
+	sw, sh := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		y0 := y * sh / height
+		y1 := max((y+1)*sh/height, y0+1)
+		for x := range width {
+			x0 := x * sw / width
+			x1 := max((x+1)*sw/width, x0+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := flat.Pix[sy*flat.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+			i := y*dst.Stride + x*4
+			dst.Pix[i], dst.Pix[i+1], dst.Pix[i+2], dst.Pix[i+3] = uint8(r/n), uint8(g/n), uint8(b/n), 0xff
+		}
+	}
+	return dst
+}
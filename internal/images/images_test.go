@@ -0,0 +1,96 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			img.Set(x, y, color.NRGBA{R: 200, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img, err := Decode(encodePNG(t, 63, 88))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if img.ContentType != "image/png" || img.Width != 63 || img.Height != 88 {
+		t.Errorf("unexpected image %+v", img)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"text", []byte("not an image at all"), ErrUnsupportedType},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), ErrUnsupportedType},
+		{"truncated png", encodePNG(t, 10, 10)[:40], ErrInvalid},
+		{"too big", append(encodePNG(t, 1, 1), make([]byte, MaxSize)...), ErrTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := Decode(tt.data); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v; got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestDecodeRejectsHugeDimensions(t *testing.T) {
+	// A PNG header claiming 10000 x 10000 pixels is refused before decoding.
+	data := encodePNG(t, 1, 1)
+	header := append([]byte(nil), data[:33]...)
+	copy(header[16:24], []byte{0, 0, 0x27, 0x10, 0, 0, 0x27, 0x10})
+	binary.BigEndian.PutUint32(header[29:33], crc32.ChecksumIEEE(header[12:29]))
+	if _, err := Decode(header); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("expected ErrTooLarge; got %v", err)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		width, height int
+		wantW, wantH  int
+	}{
+		{630, 880, 229, 320},
+		{880, 630, 320, 229},
+		{100, 140, 100, 140},
+	}
+	for _, tt := range tests {
+		img, err := Decode(encodePNG(t, tt.width, tt.height))
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		data, err := img.Thumbnail(ThumbnailSize)
+		if err != nil {
+			t.Fatalf("thumbnail: %v", err)
+		}
+		thumb, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("thumbnail is not a JPEG: %v", err)
+		}
+		if b := thumb.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH {
+			t.Errorf("%dx%d: expected %dx%d thumbnail; got %dx%d", tt.width, tt.height, tt.wantW, tt.wantH, b.Dx(), b.Dy())
+		}
+		if r, g, _, _ := thumb.At(tt.wantW/2, tt.wantH/2).RGBA(); r>>8 < 180 || g>>8 > 30 {
+			t.Errorf("expected the thumbnail to keep the colour; got r=%d g=%d", r>>8, g>>8)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package mailer
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned when the dispatcher cannot accept more emails.
+	ErrQueueFull = errors.New("mail queue is full")
+	// ErrClosed is returned when an email is enqueued after Close.
+	ErrClosed = errors.New("mail dispatcher is closed")
+)
+
+// maxAttempts is the number of delivery attempts before an email is dropped.
+const maxAttempts = 3
+
+// Email is a request to render and send a template to a recipient.
+type Email struct {
+	To       string
+	Language string
+	Template string
+	Data     any
+}
+
+// Dispatcher renders and sends emails from background workers so handlers
+// never wait on the mail server.
+type Dispatcher struct {
+	sender  Sender
+	queue   chan Email
+	backoff time.Duration
+
+	mu     sync.RWMutex
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewDispatcher(sender Sender, queueSize int) *Dispatcher {
+	return &Dispatcher{
+		sender:  sender,
+		queue:   make(chan Email, queueSize),
+		backoff: time.Second,
+	}
+}
+
+// Start launches the given number of workers.
+func (d *Dispatcher) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+}
+
+// Enqueue schedules an email for delivery without blocking.
+func (d *Dispatcher) Enqueue(email Email) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrClosed
+	}
+
+	select {
+	case d.queue <- email:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting emails and waits for the queued ones to be sent.
+func (d *Dispatcher) Close() {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+
+	for email := range d.queue {
+		if err := d.deliver(email); err != nil {
+			log.Printf("failed to send %s email to %s: %v", email.Template, email.To, err)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(email Email) error {
+	subject, body, err := Render(email.Template, email.Language, email.Data)
+	if err != nil {
+		return err
+	}
+
+	msg := Message{To: email.To, Subject: subject, Body: body}
+	for attempt := 1; ; attempt++ {
+		err = d.sender.Send(msg)
+		if err == nil || attempt == maxAttempts {
+			return err
+		}
+		time.Sleep(time.Duration(attempt) * d.backoff)
+	}
+}
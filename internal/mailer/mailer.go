@@ -0,0 +1,49 @@
+// Package mailer renders transactional emails and delivers them through a
+// pluggable Sender from a background worker.
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Message is a rendered email ready to be delivered.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers rendered messages.
+type Sender interface {
+	Send(msg Message) error
+}
+
+// NewSenderFromEnv builds the sender selected by MAIL_DRIVER. "smtp" delivers
+// through the SMTP_* settings, "file" (the default) writes every message to
+// MAIL_DIR so local development needs no mail server.
+func NewSenderFromEnv() (Sender, error) {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case "smtp":
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		return &SMTPSender{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		}, nil
+	case "", "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return &FileSender{Dir: dir, From: os.Getenv("MAIL_FROM")}, nil
+	default:
+		return nil, fmt.Errorf("unknown MAIL_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,126 @@
+package mailer
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderAllTemplates(t *testing.T) {
+	data := map[string]any{
+		"FirstName":      "Marie",
+		"OrderID":        42,
+		"Product":        "Black Lotus",
+		"Quantity":       1,
+		"Total":          "25085.00",
+		"TrackingNumber": "TRK7829456321",
+		"Link":           "https://example.com/verify?token=abc",
+		"ExpiresIn":      "24h",
+	}
+	templates := []string{TemplateOrderConfirmation, TemplateOrderShipped, TemplatePasswordReset, TemplateEmailVerification}
+
+	for _, lang := range []string{"EN", "DE", "FR"} {
+		for _, name := range templates {
+			subject, body, err := Render(name, lang, data)
+			if err != nil {
+				t.Fatalf("render %s/%s: %v", lang, name, err)
+			}
+			if subject == "" || strings.Contains(subject, "\n") {
+				t.Errorf("%s/%s: expected single line subject; got %q", lang, name, subject)
+			}
+			if !strings.Contains(body, "Marie") {
+				t.Errorf("%s/%s: expected body to greet the user; got %q", lang, name, body)
+			}
+		}
+	}
+}
+
+func TestRenderFallsBackToEnglish(t *testing.T) {
+	subject, _, err := Render(TemplateOrderShipped, "JP", map[string]any{"OrderID": 7})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if subject != "Your order #7 has shipped" {
+		t.Errorf("expected english subject; got %q", subject)
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	if _, _, err := Render("unknown", "EN", nil); err == nil {
+		t.Error("expected error for unknown template")
+	}
+}
+
+func TestDispatcherSendsQueuedEmails(t *testing.T) {
+	sender := &MemorySender{}
+	dispatcher := NewDispatcher(sender, 10)
+	dispatcher.Start(2)
+
+	for i := 0; i < 3; i++ {
+		err := dispatcher.Enqueue(Email{To: "buyer@example.com", Language: "EN", Template: TemplateOrderShipped, Data: map[string]any{"OrderID": i}})
+		if err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	dispatcher.Close()
+
+	if got := len(sender.Messages()); got != 3 {
+		t.Fatalf("expected 3 messages; got %d", got)
+	}
+	if err := dispatcher.Enqueue(Email{}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after Close; got %v", err)
+	}
+}
+
+type failingSender struct {
+	failures int
+	calls    int
+}
+
+func (s *failingSender) Send(msg Message) error {
+	s.calls++
+	if s.calls <= s.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestDispatcherRetries(t *testing.T) {
+	sender := &failingSender{failures: 2}
+	dispatcher := NewDispatcher(sender, 1)
+	dispatcher.backoff = time.Millisecond
+
+	err := dispatcher.deliver(Email{To: "buyer@example.com", Template: TemplateOrderShipped, Data: map[string]any{}})
+	if err != nil {
+		t.Fatalf("expected delivery to succeed on the third attempt; got %v", err)
+	}
+	if sender.calls != 3 {
+		t.Errorf("expected 3 attempts; got %d", sender.calls)
+	}
+}
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender := &FileSender{Dir: dir, From: "noreply@example.com"}
+
+	if err := sender.Send(Message{To: "buyer@example.com", Subject: "Hello", Body: "Body\n"}); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected one file; got %d", len(entries))
+	}
+	content, err := os.ReadFile(dir + "/" + entries[0].Name())
+	if err != nil {
+		t.Fatalf("read file: %v", err)
+	}
+	if !strings.Contains(string(content), "To: buyer@example.com") || !strings.Contains(string(content), "Subject: Hello") {
+		t.Errorf("unexpected message content: %s", content)
+	}
+}
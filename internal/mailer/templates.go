@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Templates available for every language. Each template defines a "subject"
+// and a "body" block.
+const (
+	TemplateOrderConfirmation = "order_confirmation"
+	TemplateOrderShipped      = "order_shipped"
+	TemplatePasswordReset     = "password_reset"
+	TemplateEmailVerification = "email_verification"
+)
+
+// defaultLanguage is used when no template exists for the user's language.
+const defaultLanguage = "en"
+
+//go:embed templates/*/*.tmpl
+var templateFS embed.FS
+
+// Render executes the template for the given language code (as stored in
+// languages.language_code) and returns the subject and body.
+func Render(name string, language string, data any) (string, string, error) {
+	tmpl, err := lookup(name, strings.ToLower(strings.TrimSpace(language)))
+	if err != nil {
+		return "", "", err
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), strings.TrimSpace(body.String()) + "\n", nil
+}
+
+func lookup(name string, language string) (*template.Template, error) {
+	for _, lang := range []string{language, defaultLanguage} {
+		path := fmt.Sprintf("templates/%s/%s.tmpl", lang, name)
+		if _, err := templateFS.Open(path); err != nil {
+			continue
+		}
+		return template.ParseFS(templateFS, path)
+	}
+	return nil, fmt.Errorf("mail template %q not found", name)
+}
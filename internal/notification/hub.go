@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"cardmarket_backend/internal/database"
+)
+
+// subscriberBuffer is the number of notifications queued per connected client
+// before new ones are dropped. Dropped notifications stay available through
+// the notifications endpoint.
+const subscriberBuffer = 16
+
+// listenRetryDelay is how long the hub waits before listening again after its
+// source failed. Notifications stored meanwhile are not pushed but stay
+// available through the notifications endpoint.
+const listenRetryDelay = 5 * time.Second
+
+// Source announces the notifications stored by every server process.
+type Source interface {
+	ListenNotifications(ctx context.Context, deliver func(database.Notification)) error
+}
+
+// Hub fans out notifications to the clients connected to this server process.
+// Once started, it receives the notifications stored by every replica from
+// its source, so clients get them whichever replica they are connected to.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[int]map[chan database.Notification]struct{}
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[int]map[chan database.Notification]struct{}),
+	}
+}
+
+// Subscribe registers a client of the given user. The returned function must be
+// called once the client disconnects.
+func (h *Hub) Subscribe(userID int) (<-chan database.Notification, func()) {
+	ch := make(chan database.Notification, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan database.Notification]struct{})
+	}
+	h.subscribers[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[userID], ch)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+}
+
+// Publish delivers the notification to every connected client of its user
+// without blocking on slow clients.
+func (h *Hub) Publish(notification database.Notification) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for ch := range h.subscribers[notification.UserID] {
+		select {
+		case ch <- notification:
+		default:
+		}
+	}
+}
+
+// Start publishes the notifications announced by the source in the background
+// until Close is called.
+func (h *Hub) Start(source Source) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		for {
+			err := source.ListenNotifications(ctx, h.Publish)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("stopped listening for notifications: %v", err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(listenRetryDelay):
+			}
+		}
+	}()
+}
+
+// Close stops listening to the source.
+func (h *Hub) Close() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+}
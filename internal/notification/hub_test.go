@@ -0,0 +1,95 @@
+package notification
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cardmarket_backend/internal/database"
+)
+
+func TestHubPublishesToUserSubscribers(t *testing.T) {
+	hub := NewHub()
+
+	mine, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+	other, unsubscribeOther := hub.Subscribe(2)
+	defer unsubscribeOther()
+
+	hub.Publish(database.Notification{NotificationID: 7, UserID: 1, Type: database.NotificationNewMessage})
+
+	select {
+	case n := <-mine:
+		if n.NotificationID != 7 {
+			t.Errorf("expected notification 7; got %v", n.NotificationID)
+		}
+	default:
+		t.Fatal("expected notification for user 1")
+	}
+
+	select {
+	case n := <-other:
+		t.Errorf("expected no notification for user 2; got %v", n)
+	default:
+	}
+}
+
+func TestHubUnsubscribe(t *testing.T) {
+	hub := NewHub()
+
+	ch, unsubscribe := hub.Subscribe(1)
+	unsubscribe()
+
+	hub.Publish(database.Notification{UserID: 1})
+
+	select {
+	case n := <-ch:
+		t.Errorf("expected no notification after unsubscribe; got %v", n)
+	default:
+	}
+
+	if len(hub.subscribers) != 0 {
+		t.Errorf("expected no subscribers left; got %v", len(hub.subscribers))
+	}
+}
+
+func TestHubDoesNotBlockOnFullBuffer(t *testing.T) {
+	hub := NewHub()
+
+	_, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	for i := 0; i < subscriberBuffer*2; i++ {
+		hub.Publish(database.Notification{NotificationID: i, UserID: 1})
+	}
+}
+
+// announcingSource announces its notifications once and then waits to be
+// stopped.
+type announcingSource []database.Notification
+
+func (s announcingSource) ListenNotifications(ctx context.Context, deliver func(database.Notification)) error {
+	for _, n := range s {
+		deliver(n)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestHubPublishesAnnouncedNotifications(t *testing.T) {
+	hub := NewHub()
+	mine, unsubscribe := hub.Subscribe(1)
+	defer unsubscribe()
+
+	hub.Start(announcingSource{{NotificationID: 7, UserID: 1, Type: database.NotificationNewMessage}})
+	defer hub.Close()
+
+	select {
+	case n := <-mine:
+		if n.NotificationID != 7 {
+			t.Errorf("expected notification 7; got %v", n.NotificationID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the announced notification to be pushed")
+	}
+}
@@ -0,0 +1,178 @@
+// Package pdf writes simple text documents as PDF files, using the standard
+// Helvetica and Courier fonts so that no font files need to be embedded.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Page sizes in points.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// Font selects one of the standard fonts.
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+	// Mono is Courier; every glyph is 0.6 em wide, which makes it possible to
+	// right-align text.
+	Mono
+)
+
+var fontNames = []string{"Helvetica", "Helvetica-Bold", "Courier"}
+
+// Document is a PDF document made of pages.
+type Document struct {
+	pages []*Page
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// Page is one page of a document. Coordinates are in points from the bottom
+// left corner.
+type Page struct {
+	width, height float64
+	content       bytes.Buffer
+}
+
+// AddPage appends a page of the given size.
+func (d *Document) AddPage(width, height float64) *Page {
+	page := &Page{width: width, height: height}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// Pages returns the number of pages.
+func (d *Document) Pages() int {
+	return len(d.pages)
+}
+
+// Text writes a line of text with its baseline starting at x, y. Characters
+// outside Windows-1252 are replaced with '?'.
+func (p *Page) Text(x, y float64, font Font, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /F%d %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font+1, size, x, y, escape(text))
+}
+
+// TextRight writes a line of monospaced text ending at x.
+func (p *Page) TextRight(x, y float64, size float64, text string) {
+	p.Text(x-MonoWidth(text, size), y, Mono, size, text)
+}
+
+// MonoWidth returns the width of text set in Mono at the given size.
+func MonoWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * 0.6 * size
+}
+
+// Line draws a straight line.
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Rect draws the outline of a rectangle.
+func (p *Page) Rect(x, y, width, height float64) {
+	fmt.Fprintf(&p.content, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, y, width, height)
+}
+
+// WriteTo writes the document to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 and 2 are the catalog and the page tree, followed by the
+	// fonts and then a page and a content stream per page.
+	fontObject := 3
+	firstPage := fontObject + len(fontNames)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, name := range fontNames {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+
+	var fonts strings.Builder
+	for i := range fontNames {
+		fmt.Fprintf(&fonts, "/F%d %d 0 R ", i+1, fontObject+i)
+	}
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << %s>> >> /Contents %d 0 R >>", page.width, page.height, fonts.String(), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.WriteTo(w)
+}
+
+// Bytes returns the encoded document.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	d.WriteTo(&buf)
+	return buf.Bytes()
+}
+
+// escape encodes text as a PDF string in Windows-1252.
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// winAnsiExtras maps the characters Windows-1252 places in 0x80-0x9f.
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r == '\t':
+		return ' ', true
+	case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+		return byte(r), true
+	}
+	c, ok := winAnsiExtras[r]
+	return c, ok
+}
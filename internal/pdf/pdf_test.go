@@ -0,0 +1,69 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDocumentStructure(t *testing.T) {
+	doc := New()
+	first := doc.AddPage(A4Width, A4Height)
+	first.Text(50, 800, Bold, 16, "Invoice")
+	first.TextRight(545, 780, 10, "12.50 EUR")
+	first.Line(50, 770, 545, 770)
+	doc.AddPage(A4Width, A4Height).Text(50, 800, Regular, 10, "Page two")
+
+	data := doc.Bytes()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(data, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	if !bytes.Contains(data, []byte("/Count 2")) {
+		t.Errorf("expected two pages")
+	}
+
+	// Every xref entry must point at the object it lists.
+	start := bytes.LastIndex(data, []byte("startxref\n"))
+	xref, err := strconv.Atoi(strings.Fields(string(data[start+len("startxref\n"):]))[0])
+	if err != nil {
+		t.Fatalf("invalid startxref: %v", err)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 objects; got %d", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, data[offset:offset+10])
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"Black Lotus (Alpha)": `Black Lotus \(Alpha\)`,
+		`C:\cards`:            `C:\\cards`,
+		"Müller":              `M\374ller`,
+		"12,50 €":             `12,50 \200`,
+		"ドラゴン":                `????`,
+	}
+	for in, want := range tests {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q) = %q; want %q", in, got, want)
+		}
+	}
+}
+
+func TestTextRight(t *testing.T) {
+	page := New().AddPage(A4Width, A4Height)
+	page.TextRight(100, 50, 10, "12.50")
+
+	if !strings.Contains(page.content.String(), "70.00 50.00 Td") {
+		t.Errorf("expected text to end at x=100; got %s", page.content.String())
+	}
+}
@@ -0,0 +1,101 @@
+// Package scheduler runs periodic background jobs in the server process. When
+// several replicas run, only the one holding the leader lock runs the jobs.
+package scheduler
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Locker elects the leader among replicas.
+type Locker interface {
+	// TryLock acquires the lock if it is free and reports whether this
+	// process holds it. It is called before every run, so a process that
+	// already holds the lock must keep it.
+	TryLock(ctx context.Context) (bool, error)
+	// Unlock releases the lock if it is held.
+	Unlock() error
+}
+
+// Job is a unit of periodic work.
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Scheduler runs its jobs one after the other at a fixed interval while it is
+// the leader.
+type Scheduler struct {
+	locker   Locker
+	interval time.Duration
+	jobs     []Job
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(locker Locker, interval time.Duration, jobs ...Job) *Scheduler {
+	return &Scheduler{
+		locker:   locker,
+		interval: interval,
+		jobs:     jobs,
+	}
+}
+
+// Start runs the jobs in the background until Close is called.
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops the scheduler, waits for running jobs and gives up leadership.
+func (s *Scheduler) Close() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	if err := s.locker.Unlock(); err != nil {
+		log.Printf("failed to release scheduler lock: %v", err)
+	}
+}
+
+// RunOnce runs every job if this process is the leader. A failing job is
+// logged and does not prevent the others from running.
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	leader, err := s.locker.TryLock(ctx)
+	if err != nil {
+		log.Printf("failed to acquire scheduler lock: %v", err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := job.Run(ctx); err != nil {
+			log.Printf("scheduled job %s failed: %v", job.Name, err)
+		}
+	}
+}
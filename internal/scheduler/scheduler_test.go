@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sharedLock simulates an advisory lock shared by several replicas.
+type sharedLock struct {
+	holder *replicaLock
+}
+
+type replicaLock struct {
+	shared   *sharedLock
+	unlocked bool
+}
+
+func (l *replicaLock) TryLock(ctx context.Context) (bool, error) {
+	if l.shared.holder == nil {
+		l.shared.holder = l
+	}
+	return l.shared.holder == l, nil
+}
+
+func (l *replicaLock) Unlock() error {
+	if l.shared.holder == l {
+		l.shared.holder = nil
+	}
+	l.unlocked = true
+	return nil
+}
+
+func TestRunOnceOnlyOnLeader(t *testing.T) {
+	shared := &sharedLock{}
+	first := &replicaLock{shared: shared}
+	second := &replicaLock{shared: shared}
+
+	var runs []string
+	job := func(name string) Job {
+		return Job{Name: name, Run: func(ctx context.Context) error {
+			runs = append(runs, name)
+			return nil
+		}}
+	}
+
+	leader := New(first, time.Minute, job("first"))
+	follower := New(second, time.Minute, job("second"))
+
+	leader.RunOnce(context.Background())
+	follower.RunOnce(context.Background())
+	if len(runs) != 1 || runs[0] != "first" {
+		t.Fatalf("expected only the leader to run; got %v", runs)
+	}
+
+	leader.Close()
+	if !first.unlocked {
+		t.Errorf("expected the leader to release the lock on close")
+	}
+
+	follower.RunOnce(context.Background())
+	if len(runs) != 2 || runs[1] != "second" {
+		t.Errorf("expected the follower to take over; got %v", runs)
+	}
+}
+
+func TestRunOnceContinuesAfterFailingJob(t *testing.T) {
+	ran := false
+	s := New(&replicaLock{shared: &sharedLock{}}, time.Minute,
+		Job{Name: "failing", Run: func(ctx context.Context) error { return errors.New("boom") }},
+		Job{Name: "next", Run: func(ctx context.Context) error { ran = true; return nil }},
+	)
+
+	s.RunOnce(context.Background())
+
+	if !ran {
+		t.Errorf("expected the remaining jobs to run")
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"cardmarket_backend/internal/address"
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListAddressesHandler(c *fiber.Ctx) error {
+	addresses, err := s.db.ListAddresses(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch addresses",
+		})
+	}
+	return c.JSON(fiber.Map{"addresses": addresses})
+}
+
+func (s *FiberServer) CreateAddressHandler(c *fiber.Ctx) error {
+	var req database.AddressRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !addressComplete(req) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Recipient, street, city, postal code and country are required",
+		})
+	}
+
+	addressID, err := s.db.CreateAddress(currentUserID(c), req)
+	if err != nil {
+		return addressError(c, err, "Failed to create address")
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "address created",
+		"address_id": addressID,
+	})
+}
+
+func (s *FiberServer) UpdateAddressHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	addressID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid address ID",
+		})
+	}
+
+	var req database.AddressRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !addressComplete(req) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Recipient, street, city, postal code and country are required",
+		})
+	}
+
+	if err := s.db.UpdateAddress(addressID, currentUserID(c), req); err != nil {
+		return addressError(c, err, "Failed to update address")
+	}
+	return c.JSON(fiber.Map{"message": "address updated"})
+}
+
+func (s *FiberServer) DeleteAddressHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	addressID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid address ID",
+		})
+	}
+
+	if err := s.db.DeleteAddress(addressID, currentUserID(c)); err != nil {
+		return addressError(c, err, "Failed to delete address")
+	}
+	return c.JSON(fiber.Map{"message": "address deleted"})
+}
+
+// addressComplete reports whether all mandatory address fields are set. The
+// state is optional as many countries do not use one.
+func addressComplete(req database.AddressRequest) bool {
+	for _, field := range []string{req.RecipientName, req.StreetName, req.City, req.ZipCode} {
+		if strings.TrimSpace(field) == "" {
+			return false
+		}
+	}
+	return req.CountryID > 0
+}
+
+// invalidAddress reports whether err was caused by an address that failed
+// validation.
+func invalidAddress(err error) bool {
+	return errors.Is(err, address.ErrInvalidPostalCode) || errors.Is(err, database.ErrUnknownCountry)
+}
+
+// addressError maps errors from the address book queries to a response.
+func addressError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Address not found",
+		})
+	case invalidAddress(err):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid postal code or country",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"cardmarket_backend/internal/address"
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateAddressHandler(t *testing.T) {
+	valid := database.AddressRequest{RecipientName: "Marie Dubois", StreetName: "Rue de Seine", StreetNumber: "15", City: "Paris", ZipCode: "75006", CountryID: 3}
+
+	tests := []struct {
+		name           string
+		address        database.AddressRequest
+		dbErr          error
+		expectedStatus int
+	}{
+		{
+			name:           "created",
+			address:        valid,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "missing city",
+			address:        database.AddressRequest{RecipientName: "Marie Dubois", StreetName: "Rue de Seine", ZipCode: "75006", CountryID: 3},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid postal code",
+			address:        valid,
+			dbErr:          address.ErrInvalidPostalCode,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown country",
+			address:        valid,
+			dbErr:          database.ErrUnknownCountry,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var owner int
+			mockDB := MockDBService{
+				CreateAddressFunc: func(userID int, address database.AddressRequest) (int, error) {
+					owner = userID
+					return 1, tt.dbErr
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/addresses", requireUser, s.CreateAddressHandler)
+
+			addressBytes, err := json.Marshal(tt.address)
+			if err != nil {
+				t.Fatalf("error marshalling address request. Err: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/api/addresses", strings.NewReader(string(addressBytes)))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusCreated && owner != 2 {
+				t.Errorf("expected address to be added for user 2; got %v", owner)
+			}
+		})
+	}
+}
+
+func TestDeleteAddressHandlerNotFound(t *testing.T) {
+	mockDB := MockDBService{
+		DeleteAddressFunc: func(addressID int, userID int) error {
+			return sql.ErrNoRows
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Delete("/api/addresses/:id", requireUser, s.DeleteAddressHandler)
+
+	req, err := http.NewRequest("DELETE", "/api/addresses/7", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status Not Found; got %v", resp.Status)
+	}
+}
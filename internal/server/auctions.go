@@ -0,0 +1,226 @@
+package server
+
+import (
+	"cardmarket_backend/internal/auction"
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListAuctionsHandler(c *fiber.Ctx) error {
+	auctions, err := s.db.ListAuctions()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch auctions",
+		})
+	}
+	return c.JSON(fiber.Map{"auctions": auctions})
+}
+
+func (s *FiberServer) GetAuctionHandler(c *fiber.Ctx) error {
+	auctionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid auction ID",
+		})
+	}
+
+	a, err := s.db.GetAuction(auctionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Auction not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch auction",
+		})
+	}
+	return c.JSON(fiber.Map{"auction": a})
+}
+
+// CreateAuctionHandler puts one copy of a product of the current user up for
+// auction.
+func (s *FiberServer) CreateAuctionHandler(c *fiber.Ctx) error {
+	var req database.AuctionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	auctionID, err := s.db.CreateAuction(currentUserID(c), req)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	case errors.Is(err, database.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is out of stock",
+		})
+	case errors.Is(err, database.ErrProductUnavailable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is not for sale",
+		})
+	case errors.Is(err, database.ErrSellerOnVacation):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Seller is on vacation",
+		})
+	case errors.Is(err, currency.ErrCurrencyMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Prices must be in the currency of the product",
+		})
+	case errors.Is(err, database.ErrInvalidAuction):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Prices must be positive, the reserve at least the start price, and the end within 30 days",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create auction",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"auction_id": auctionID})
+}
+
+// CancelAuctionHandler withdraws an auction of the current user that has no
+// bids yet.
+func (s *FiberServer) CancelAuctionHandler(c *fiber.Ctx) error {
+	auctionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid auction ID",
+		})
+	}
+
+	err = s.db.CancelAuction(auctionID, currentUserID(c))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Auction not found",
+		})
+	case errors.Is(err, auction.ErrEnded):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Auction has ended",
+		})
+	case errors.Is(err, database.ErrAuctionHasBids):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Auctions with bids cannot be cancelled",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel auction",
+		})
+	}
+
+	return c.JSON(fiber.Map{"message": "auction cancelled"})
+}
+
+// PlaceBidHandler places a proxy bid of the current user and tells the
+// bidder who lost the lead.
+func (s *FiberServer) PlaceBidHandler(c *fiber.Ctx) error {
+	auctionID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid auction ID",
+		})
+	}
+
+	var req database.BidRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if !req.MaxAmount.IsPositive() || req.ShippingMethodID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Maximum amount and shipping method are required",
+		})
+	}
+
+	result, err := s.db.PlaceBid(auctionID, currentUserID(c), req)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Auction not found",
+		})
+	case errors.Is(err, auction.ErrEnded):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Auction has ended",
+		})
+	case errors.Is(err, auction.ErrBidTooLow):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Bid is below the minimum bid",
+		})
+	case errors.Is(err, database.ErrOwnAuction):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot bid on your own auction",
+		})
+	case errors.Is(err, currency.ErrCurrencyMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Bids must be in the currency of the auction",
+		})
+	case errors.Is(err, database.ErrAddressNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping address not found",
+		})
+	case errors.Is(err, database.ErrShippingMethodUnavailable):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping method not available for this auction",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to place bid",
+		})
+	}
+
+	if result.OutbidID != 0 {
+		s.notify(result.OutbidID, database.NotificationOutbid, fiber.Map{
+			"auction_id":    auctionID,
+			"current_price": result.Auction.CurrentPrice,
+			"ends_at":       result.Auction.EndsAt,
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"auction": result.Auction})
+}
+
+// closeAuctionsJob closes ended auctions. Winning bids become pending orders
+// that go through payment and shipping like any other order. When that order
+// cannot be created, the winner and the seller are both told the auction
+// ended without a sale.
+func (s *FiberServer) closeAuctionsJob() scheduler.Job {
+	return scheduler.Job{Name: "close_auctions", Run: func(ctx context.Context) error {
+		results, err := s.db.CloseEndedAuctions(time.Now())
+		for _, result := range results {
+			payload := fiber.Map{
+				"auction_id": result.AuctionID,
+				"sold":       result.OrderID != 0,
+			}
+			if result.Failed {
+				payload["failed"] = true
+				s.notify(result.WinnerID, database.NotificationAuctionEnded, payload)
+			}
+			if result.OrderID != 0 {
+				payload["order_id"] = result.OrderID
+				payload["price"] = result.Price
+				s.notify(result.WinnerID, database.NotificationAuctionWon, payload)
+				s.announceOrder(result.OrderID, database.OrderRequest{
+					BuyerID:   result.WinnerID,
+					SellerID:  result.SellerID,
+					ProductID: result.ProductID,
+					Quantity:  1,
+				})
+			}
+			s.notify(result.SellerID, database.NotificationAuctionEnded, payload)
+		}
+		return err
+	}}
+}
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"cardmarket_backend/internal/auction"
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateAuctionHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"created", nil, http.StatusCreated},
+		{"not the seller's product", sql.ErrNoRows, http.StatusNotFound},
+		{"out of stock", database.ErrInsufficientStock, http.StatusConflict},
+		{"not for sale", database.ErrProductUnavailable, http.StatusConflict},
+		{"other currency", currency.ErrCurrencyMismatch, http.StatusBadRequest},
+		{"reserve below start price", database.ErrInvalidAuction, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				sellerID int
+				received database.AuctionRequest
+			)
+			mockDB := MockDBService{
+				CreateAuctionFunc: func(id int, req database.AuctionRequest) (int, error) {
+					sellerID, received = id, req
+					return 7, tt.err
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/auctions", requireUser, s.CreateAuctionHandler)
+
+			body := `{"product_id":3,"start_price":{"amount":"10.00","currency":"EUR"},"reserve_price":{"amount":"50.00","currency":"EUR"},"ends_at":"2025-11-20T18:00:00Z"}`
+			req, err := http.NewRequest("POST", "/api/auctions", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 1)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if sellerID != 1 {
+				t.Errorf("expected the auction to be created for seller 1; got %v", sellerID)
+			}
+			if received.StartPrice != currency.New(1000, "EUR") || received.ReservePrice == nil || *received.ReservePrice != currency.New(5000, "EUR") || received.BidIncrement != nil {
+				t.Errorf("unexpected auction request %+v", received)
+			}
+		})
+	}
+}
+
+func TestPlaceBidHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		result         database.BidResult
+		err            error
+		expectedStatus int
+		outbid         bool
+	}{
+		{"takes the lead", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{Auction: database.Auction{AuctionID: 5}, OutbidID: 3}, nil, http.StatusCreated, true},
+		{"first bid", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{Auction: database.Auction{AuctionID: 5}}, nil, http.StatusCreated, false},
+		{"too low", `{"max_amount":{"amount":"1.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{}, auction.ErrBidTooLow, http.StatusConflict, false},
+		{"ended", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{}, auction.ErrEnded, http.StatusConflict, false},
+		{"own auction", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{}, database.ErrOwnAuction, http.StatusForbidden, false},
+		{"shipping unavailable", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":9}`, database.BidResult{}, database.ErrShippingMethodUnavailable, http.StatusBadRequest, false},
+		{"unknown auction", `{"max_amount":{"amount":"40.00","currency":"EUR"},"shipping_method_id":1}`, database.BidResult{}, sql.ErrNoRows, http.StatusNotFound, false},
+		{"missing shipping method", `{"max_amount":{"amount":"40.00","currency":"EUR"}}`, database.BidResult{}, nil, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				bidderID int
+				notified []int
+			)
+			mockDB := MockDBService{
+				PlaceBidFunc: func(auctionID int, id int, bid database.BidRequest) (database.BidResult, error) {
+					bidderID = id
+					return tt.result, tt.err
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					if notificationType == database.NotificationOutbid {
+						notified = append(notified, userID)
+					}
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/auctions/:id/bids", requireUser, s.PlaceBidHandler)
+
+			req, err := http.NewRequest("POST", "/api/auctions/5/bids", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus != http.StatusBadRequest && bidderID != 2 {
+				t.Errorf("expected the bid to be placed by user 2; got %v", bidderID)
+			}
+			if tt.outbid != (len(notified) == 1 && notified[0] == 3) {
+				t.Errorf("expected outbid notification %v; got %v", tt.outbid, notified)
+			}
+		})
+	}
+}
+
+func TestCloseAuctionsJob(t *testing.T) {
+	var (
+		closedBefore time.Time
+		notified     = map[string][]int{}
+	)
+	mockDB := MockDBService{
+		CloseEndedAuctionsFunc: func(now time.Time) ([]database.AuctionResult, error) {
+			closedBefore = now
+			return []database.AuctionResult{
+				{AuctionID: 1, ProductID: 4, SellerID: 1, WinnerID: 2, OrderID: 9, Price: currency.New(4500, "EUR")},
+				{AuctionID: 2, ProductID: 5, SellerID: 3, Price: currency.New(1000, "EUR")},
+				{AuctionID: 3, ProductID: 6, SellerID: 5, WinnerID: 7, Price: currency.New(2000, "EUR"), Failed: true},
+			}, errors.New("auction 3: shipping method not available for this order")
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			notified[notificationType] = append(notified[notificationType], userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	if err := s.closeAuctionsJob().Run(context.Background()); err == nil {
+		t.Errorf("expected the failed auction to be reported")
+	}
+
+	if since := time.Since(closedBefore); since < 0 || since > time.Minute {
+		t.Errorf("expected auctions ended by now to be closed; got cutoff %v ago", since)
+	}
+	if got := notified[database.NotificationAuctionWon]; len(got) != 1 || got[0] != 2 {
+		t.Errorf("expected the winner to be notified; got %v", got)
+	}
+	if got := notified[database.NotificationOrderCreated]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("expected the seller to be notified of the order; got %v", got)
+	}
+	if got := notified[database.NotificationAuctionEnded]; !slices.Equal(got, []int{1, 3, 7, 5}) {
+		t.Errorf("expected the sellers and the winner of the failed auction to be notified; got %v", got)
+	}
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/mailer"
+	"cardmarket_backend/internal/token"
+	"database/sql"
+	"errors"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const userIDLocalKey = "userID"
+
+// streamTokenParam carries the session token of clients that cannot set the
+// Authorization header, such as browser EventSource connections.
+const streamTokenParam = "access_token"
+
+// Lifetimes of the tokens sent by email and of sessions. Sessions are not
+// recorded, so they stay valid until they expire.
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+	sessionTTL           = 7 * 24 * time.Hour
+)
+
+// minPasswordLength is the shortest password accepted on reset.
+const minPasswordLength = 8
+
+var (
+	// tokenSecret signs the tokens sent by email and session tokens.
+	tokenSecret = []byte(os.Getenv("APP_SECRET"))
+	// appURL is the base URL of the frontend used in email links.
+	appURL = os.Getenv("APP_URL")
+)
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// requireUser rejects requests without a valid session token in the
+// Authorization header and stores the user ID in the request locals for the
+// handlers down the chain.
+func requireUser(c *fiber.Ctx) error {
+	return authenticateSession(c, bearerToken(c))
+}
+
+// requireStreamUser is requireUser for event streams, whose browser clients
+// cannot set headers. It also accepts the token as a query parameter.
+func requireStreamUser(c *fiber.Ctx) error {
+	session := bearerToken(c)
+	if session == "" {
+		session = c.Query(streamTokenParam)
+	}
+	return authenticateSession(c, session)
+}
+
+func bearerToken(c *fiber.Ctx) string {
+	scheme, session, ok := strings.Cut(c.Get(fiber.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(session)
+}
+
+func authenticateSession(c *fiber.Ctx, session string) error {
+	claims, err := token.Verify(tokenSecret, session, token.PurposeSession)
+	if err != nil || claims.UserID <= 0 {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Missing or invalid session",
+		})
+	}
+	c.Locals(userIDLocalKey, claims.UserID)
+	return c.Next()
+}
+
+// requireAdmin rejects requests from users who are not administrators. It
+// must run after requireUser.
+func (s *FiberServer) requireAdmin(c *fiber.Ctx) error {
+	isAdmin, err := s.db.IsAdmin(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check permissions",
+		})
+	}
+	if !isAdmin {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Administrator access required",
+		})
+	}
+	return c.Next()
+}
+
+// currentUserID returns the user ID stored by requireUser.
+func currentUserID(c *fiber.Ctx) int {
+	userID, _ := c.Locals(userIDLocalKey).(int)
+	return userID
+}
+
+// LoginHandler checks a user's email and password and returns a session token
+// to send as a bearer token.
+func (s *FiberServer) LoginHandler(c *fiber.Ctx) error {
+	var req loginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	userID, err := s.db.Authenticate(strings.TrimSpace(req.Email), req.Password)
+	if errors.Is(err, database.ErrInvalidCredentials) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid email or password",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+
+	claims, err := token.New(userID, token.PurposeSession, sessionTTL)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+	signed, err := token.Sign(tokenSecret, claims)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sign in",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"user_id":    userID,
+		"token":      signed,
+		"expires_at": claims.ExpiresAt,
+	})
+}
+
+func (s *FiberServer) VerifyEmailHandler(c *fiber.Ctx) error {
+	var req verifyEmailRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	claims, err := token.Verify(tokenSecret, req.Token, token.PurposeEmailVerification)
+	if err != nil {
+		return tokenError(c, err)
+	}
+
+	if err := s.db.VerifyEmail(claims.ID, claims.UserID); err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "email verified"})
+}
+
+func (s *FiberServer) ForgotPasswordHandler(c *fiber.Ctx) error {
+	var req forgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	// The response is the same whether the address is registered or not so it
+	// cannot be used to discover accounts.
+	resp := fiber.Map{"message": "if the email is registered, a reset link has been sent"}
+
+	userID, err := s.db.GetUserIDByEmail(strings.TrimSpace(req.Email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(resp)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to request password reset",
+		})
+	}
+
+	link, err := s.issueTokenLink(userID, token.PurposePasswordReset, passwordResetTTL, "/reset-password")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to request password reset",
+		})
+	}
+
+	s.sendEmail(userID, mailer.TemplatePasswordReset, fiber.Map{
+		"Link":      link,
+		"ExpiresIn": passwordResetTTL.String(),
+	})
+	return c.JSON(resp)
+}
+
+func (s *FiberServer) ResetPasswordHandler(c *fiber.Ctx) error {
+	var req resetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.Password) < minPasswordLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password must be at least 8 characters long",
+		})
+	}
+
+	claims, err := token.Verify(tokenSecret, req.Token, token.PurposePasswordReset)
+	if err != nil {
+		return tokenError(c, err)
+	}
+
+	if err := s.db.ResetPassword(claims.ID, claims.UserID, req.Password); err != nil {
+		return tokenError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "password updated"})
+}
+
+// sendVerificationEmail issues a verification token and emails the link to the
+// user.
+func (s *FiberServer) sendVerificationEmail(userID int) {
+	link, err := s.issueTokenLink(userID, token.PurposeEmailVerification, emailVerificationTTL, "/verify-email")
+	if err != nil {
+		log.Printf("failed to issue verification token for user %d: %v", userID, err)
+		return
+	}
+
+	s.sendEmail(userID, mailer.TemplateEmailVerification, fiber.Map{
+		"Link":      link,
+		"ExpiresIn": emailVerificationTTL.String(),
+	})
+}
+
+// issueTokenLink records a new single-use token and returns the frontend link
+// carrying it.
+func (s *FiberServer) issueTokenLink(userID int, purpose string, ttl time.Duration, path string) (string, error) {
+	claims, err := token.New(userID, purpose, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.db.CreateUserToken(claims.ID, userID, purpose, claims.ExpiresAt); err != nil {
+		return "", err
+	}
+
+	signed, err := token.Sign(tokenSecret, claims)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(appURL, "/") + path + "?token=" + url.QueryEscape(signed), nil
+}
+
+// tokenError maps token verification and consumption errors to a response.
+func tokenError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, token.ErrExpired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token expired",
+		})
+	case errors.Is(err, token.ErrInvalid), errors.Is(err, database.ErrTokenUsed):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or already used token",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process token",
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/mailer"
+	"cardmarket_backend/internal/token"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func signedToken(t *testing.T, userID int, purpose string, ttl time.Duration) (token.Claims, string) {
+	t.Helper()
+	claims, err := token.New(userID, purpose, ttl)
+	if err != nil {
+		t.Fatalf("error creating token. Err: %v", err)
+	}
+	signed, err := token.Sign(tokenSecret, claims)
+	if err != nil {
+		t.Fatalf("error signing token. Err: %v", err)
+	}
+	return claims, signed
+}
+
+// setSession signs a test request in as the user.
+func setSession(t *testing.T, req *http.Request, userID int) {
+	t.Helper()
+	_, signed := signedToken(t, userID, token.PurposeSession, time.Hour)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer "+signed)
+}
+
+func postJSON(t *testing.T, app *fiber.App, path string, body any) *http.Response {
+	t.Helper()
+	return postJSONAs(t, app, path, body, 0)
+}
+
+// postJSONAs posts the body signed in as the user, or anonymously for user 0.
+func postJSONAs(t *testing.T, app *fiber.App, path string, body any, userID int) *http.Response {
+	t.Helper()
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		t.Fatalf("error marshalling request. Err: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", path, strings.NewReader(string(bodyBytes)))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if userID != 0 {
+		setSession(t, req, userID)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	return resp
+}
+
+func TestVerifyEmailHandler(t *testing.T) {
+	claims, signed := signedToken(t, 3, token.PurposeEmailVerification, time.Hour)
+
+	var verifiedToken string
+	var verifiedUser int
+	mockDB := MockDBService{
+		VerifyEmailFunc: func(tokenID string, userID int) error {
+			verifiedToken, verifiedUser = tokenID, userID
+			return nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/auth/verify", s.VerifyEmailHandler)
+
+	resp := postJSON(t, app, "/api/auth/verify", verifyEmailRequest{Token: signed})
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if verifiedToken != claims.ID || verifiedUser != 3 {
+		t.Errorf("expected token %v of user 3 to be consumed; got %v of user %v", claims.ID, verifiedToken, verifiedUser)
+	}
+}
+
+func TestVerifyEmailHandlerRejectsTokens(t *testing.T) {
+	_, resetToken := signedToken(t, 3, token.PurposePasswordReset, time.Hour)
+	_, expiredToken := signedToken(t, 3, token.PurposeEmailVerification, -time.Minute)
+	_, usedToken := signedToken(t, 3, token.PurposeEmailVerification, time.Hour)
+
+	mockDB := MockDBService{
+		VerifyEmailFunc: func(tokenID string, userID int) error {
+			return database.ErrTokenUsed
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/auth/verify", s.VerifyEmailHandler)
+
+	for name, tok := range map[string]string{"wrong purpose": resetToken, "expired": expiredToken, "already used": usedToken, "garbage": "abc"} {
+		t.Run(name, func(t *testing.T) {
+			resp := postJSON(t, app, "/api/auth/verify", verifyEmailRequest{Token: tok})
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status Bad Request; got %v", resp.Status)
+			}
+		})
+	}
+}
+
+func TestForgotPasswordHandler(t *testing.T) {
+	var issuedFor int
+	mockDB := MockDBService{
+		GetUserIDByEmailFunc: func(email string) (int, error) {
+			if email != "dealer@example.com" {
+				return 0, sql.ErrNoRows
+			}
+			return 1, nil
+		},
+		CreateUserTokenFunc: func(tokenID string, userID int, purpose string, expiresAt time.Time) error {
+			if purpose != token.PurposePasswordReset {
+				t.Errorf("expected password reset token; got %v", purpose)
+			}
+			issuedFor = userID
+			return nil
+		},
+		GetUserContactFunc: func(userID int) (database.UserContact, error) {
+			return database.UserContact{UserID: userID, Email: "dealer@example.com", FirstName: "Thomas", LanguageCode: "EN"}, nil
+		},
+	}
+	sender := &mailer.MemorySender{}
+	dispatcher := mailer.NewDispatcher(sender, 2)
+	dispatcher.Start(1)
+
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB, mailer: dispatcher}
+	app.Post("/api/auth/forgot-password", s.ForgotPasswordHandler)
+
+	unknown := postJSON(t, app, "/api/auth/forgot-password", forgotPasswordRequest{Email: "nobody@example.com"})
+	known := postJSON(t, app, "/api/auth/forgot-password", forgotPasswordRequest{Email: "dealer@example.com"})
+	dispatcher.Close()
+
+	if unknown.StatusCode != http.StatusOK || known.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK for both addresses; got %v and %v", unknown.Status, known.Status)
+	}
+	if issuedFor != 1 {
+		t.Errorf("expected a token for user 1; got %v", issuedFor)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected one email; got %d", len(messages))
+	}
+	if !strings.Contains(messages[0].Body, "/reset-password?token=") {
+		t.Errorf("expected reset link in body; got %v", messages[0].Body)
+	}
+}
+
+func TestResetPasswordHandler(t *testing.T) {
+	_, signed := signedToken(t, 4, token.PurposePasswordReset, time.Hour)
+
+	var newPassword string
+	mockDB := MockDBService{
+		ResetPasswordFunc: func(tokenID string, userID int, password string) error {
+			newPassword = password
+			return nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/auth/reset-password", s.ResetPasswordHandler)
+
+	short := postJSON(t, app, "/api/auth/reset-password", resetPasswordRequest{Token: signed, Password: "short"})
+	if short.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request for a short password; got %v", short.Status)
+	}
+
+	resp := postJSON(t, app, "/api/auth/reset-password", resetPasswordRequest{Token: signed, Password: "correct horse battery"})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if newPassword != "correct horse battery" {
+		t.Errorf("expected new password to be stored; got %q", newPassword)
+	}
+}
+
+func TestCreateProductHandlerRequiresVerifiedEmail(t *testing.T) {
+	mockDB := MockDBService{
+		CreateProductFunc: func(product database.ProductRequest) (int, error) {
+			return 0, database.ErrEmailNotVerified
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/products", requireUser, s.CreateProductHandler)
+
+	product := database.ProductRequest{CardID: 1, Price: currency.New(1000, "EUR"), Condition: "mint", Quantity: 1, LanguageID: 1}
+	if resp := postJSON(t, app, "/api/products", product); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected anonymous listings to be rejected; got %v", resp.Status)
+	}
+	if resp := postJSONAs(t, app, "/api/products", product, 1); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", resp.Status)
+	}
+}
+
+func TestUpdateProductHandlerRejectsOtherSellers(t *testing.T) {
+	mockDB := MockDBService{
+		UpdateProductFunc: func(productID int, product database.ProductRequest) error {
+			if product.SellerID != 1 {
+				return database.ErrNotProductSeller
+			}
+			return nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Put("/api/products/:id", requireUser, s.UpdateProductHandler)
+
+	for userID, expectedStatus := range map[int]int{0: http.StatusUnauthorized, 1: http.StatusOK, 2: http.StatusForbidden} {
+		req, err := http.NewRequest("PUT", "/api/products/4", strings.NewReader(`{"card_id":1,"price":{"amount":"10.00","currency":"EUR"},"condition":"mint","quantity":1,"seller_id":1,"language_id":1}`))
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if userID != 0 {
+			setSession(t, req, userID)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("user %d: expected status %v; got %v", userID, expectedStatus, resp.Status)
+		}
+	}
+}
+
+func TestLoginHandler(t *testing.T) {
+	mockDB := MockDBService{
+		AuthenticateFunc: func(email string, password string) (int, error) {
+			if email != "dealer@example.com" || password != "correct horse" {
+				return 0, database.ErrInvalidCredentials
+			}
+			return 1, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/auth/login", s.LoginHandler)
+
+	resp := postJSON(t, app, "/api/auth/login", loginRequest{Email: " dealer@example.com ", Password: "correct horse"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", resp.Status)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		t.Fatalf("error decoding response. Err: %v", err)
+	}
+	claims, err := token.Verify(tokenSecret, session.Token, token.PurposeSession)
+	if err != nil || claims.UserID != 1 {
+		t.Errorf("expected a session of user 1; got %+v, %v", claims, err)
+	}
+
+	resp = postJSON(t, app, "/api/auth/login", loginRequest{Email: "dealer@example.com", Password: "wrong"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status Unauthorized; got %v", resp.Status)
+	}
+}
+
+func TestRequireUser(t *testing.T) {
+	_, session := signedToken(t, 4, token.PurposeSession, time.Hour)
+	_, expired := signedToken(t, 4, token.PurposeSession, -time.Minute)
+	_, reset := signedToken(t, 4, token.PurposePasswordReset, time.Hour)
+
+	app := fiber.New()
+	whoami := func(c *fiber.Ctx) error { return c.JSON(fiber.Map{"user_id": currentUserID(c)}) }
+	app.Get("/api/me", requireUser, whoami)
+	app.Get("/api/stream", requireStreamUser, whoami)
+
+	tests := []struct {
+		name           string
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"bearer token", "/api/me", "Bearer " + session, http.StatusOK},
+		{"no token", "/api/me", "", http.StatusUnauthorized},
+		{"expired session", "/api/me", "Bearer " + expired, http.StatusUnauthorized},
+		{"email token", "/api/me", "Bearer " + reset, http.StatusUnauthorized},
+		{"forged user ID", "/api/me", "Bearer 4", http.StatusUnauthorized},
+		{"query token on a regular route", "/api/me?access_token=" + session, "", http.StatusUnauthorized},
+		{"query token on a stream", "/api/stream?access_token=" + session, "", http.StatusOK},
+		{"bearer token on a stream", "/api/stream", "Bearer " + session, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", tt.path, nil)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.authorization)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusOK {
+				var body struct {
+					UserID int `json:"user_id"`
+				}
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.UserID != 4 {
+					t.Errorf("expected user 4; got %v, %v", body.UserID, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) GetCartHandler(c *fiber.Ctx) error {
+	items, err := s.db.ListCart(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cart",
+		})
+	}
+	return c.JSON(fiber.Map{"cart": items})
+}
+
+// RemoveCartItemHandler removes a product, by its product ID, from the
+// current user's cart.
+func (s *FiberServer) RemoveCartItemHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	if err := s.db.RemoveCartItem(currentUserID(c), productID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Product is not in the cart",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update cart",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "product removed from cart"})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// errConversionUnavailable is returned when an amount is stored in a
+// currency that has no exchange rate.
+var errConversionUnavailable = errors.New("no exchange rate for requested currency")
+
+// displayRates returns the currency requested with ?currency= and the rates
+// needed to convert into it. An empty code means no conversion was asked for.
+func (s *FiberServer) displayRates(c *fiber.Ctx) (string, currency.Rates, error) {
+	code := strings.ToUpper(c.Query("currency"))
+	if code == "" {
+		return "", nil, nil
+	}
+	if !currency.ValidCode(code) {
+		return "", nil, currency.ErrUnknownCurrency
+	}
+
+	rates, err := s.db.ListExchangeRates()
+	if err != nil {
+		return "", nil, err
+	}
+	if _, ok := rates[code]; !ok {
+		return "", nil, currency.ErrUnknownCurrency
+	}
+	return code, rates, nil
+}
+
+// currencyError writes the response for a failed currency conversion.
+func currencyError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, currency.ErrUnknownCurrency) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unsupported currency",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to convert currency",
+	})
+}
+
+// convertProduct fills in the product's price in the display currency.
+func convertProduct(product *database.Product, rates currency.Rates, to string) error {
+	price, err := rates.Convert(product.Price, to)
+	if err != nil {
+		return errConversionUnavailable
+	}
+	product.ConvertedPrice = &price
+	return nil
+}
+
+// convertCardOverview fills in the listing prices in the display currency and
+// states the price guide in it.
+func convertCardOverview(overview *database.CardOverview, rates currency.Rates, to string) error {
+	for i := range overview.Listings {
+		group := &overview.Listings[i]
+		lowest, err := rates.Convert(group.LowestPrice, to)
+		if err != nil {
+			return errConversionUnavailable
+		}
+		group.ConvertedLowestPrice = &lowest
+		for j := range group.Listings {
+			price, err := rates.Convert(group.Listings[j].Price, to)
+			if err != nil {
+				return errConversionUnavailable
+			}
+			group.Listings[j].ConvertedPrice = &price
+		}
+	}
+
+	guide := &overview.PriceGuide
+	var err error
+	if guide.LowestPrice, err = convertOptional(guide.LowestPrice, rates, to); err != nil {
+		return err
+	}
+	if guide.AverageListingPrice, err = convertOptional(guide.AverageListingPrice, rates, to); err != nil {
+		return err
+	}
+	if guide.AverageSellPrice, err = convertOptional(guide.AverageSellPrice, rates, to); err != nil {
+		return err
+	}
+	return nil
+}
+
+// convertOptional converts an amount that may be missing.
+func convertOptional(amount *currency.Money, rates currency.Rates, to string) (*currency.Money, error) {
+	if amount == nil {
+		return nil, nil
+	}
+	converted, err := rates.Convert(*amount, to)
+	if err != nil {
+		return nil, errConversionUnavailable
+	}
+	return &converted, nil
+}
+
+// convertOrder fills in the order's amounts in the display currency. The
+// order itself still settles in the seller's currency.
+func convertOrder(order *database.Order, rates currency.Rates, to string) error {
+	total, err := rates.Convert(order.Total, to)
+	if err != nil {
+		return errConversionUnavailable
+	}
+	shipping, err := rates.Convert(order.ShippingCost, to)
+	if err != nil {
+		return errConversionUnavailable
+	}
+	order.ConvertedTotal = &total
+	order.ConvertedShippingCost = &shipping
+	return nil
+}
+
+// exchangeRateJob refreshes the stored exchange rates from source.
+func (s *FiberServer) exchangeRateJob(source currency.Source) scheduler.Job {
+	return scheduler.Job{Name: "import_exchange_rates", Run: func(ctx context.Context) error {
+		rates, err := source.Fetch(ctx)
+		if err != nil {
+			return err
+		}
+		return s.db.SaveExchangeRates(rates, source.Name())
+	}}
+}
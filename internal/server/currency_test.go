@@ -0,0 +1,166 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type stubRateSource struct {
+	rates currency.Rates
+}
+
+func (s stubRateSource) Name() string { return "stub" }
+
+func (s stubRateSource) Fetch(ctx context.Context) (currency.Rates, error) {
+	return s.rates, nil
+}
+
+func TestGetProductByIDHandlerCurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedPrice  *currency.Money
+	}{
+		{name: "seller currency only", query: "", expectedStatus: http.StatusOK},
+		{name: "converted", query: "?currency=usd", expectedStatus: http.StatusOK, expectedPrice: &currency.Money{Units: 1080, Currency: "USD"}},
+		{name: "zero decimal currency", query: "?currency=JPY", expectedStatus: http.StatusOK, expectedPrice: &currency.Money{Units: 1625, Currency: "JPY"}},
+		{name: "no rate", query: "?currency=CHF", expectedStatus: http.StatusBadRequest},
+		{name: "malformed", query: "?currency=euro", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				GetProductByIDFunc: func(productID int) (database.Product, error) {
+					return database.Product{ProductID: productID, Price: currency.New(1000, "EUR")}, nil
+				},
+				ListExchangeRatesFunc: func() (currency.Rates, error) {
+					return currency.Rates{"EUR": 1, "USD": 1.08, "JPY": 162.5}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Get("/api/products/:id", s.GetProductByIDHandler)
+
+			req, err := http.NewRequest("GET", "/api/products/1"+tt.query, nil)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("error reading response body. Err: %v", err)
+			}
+			var payload struct {
+				Product database.Product `json:"product"`
+			}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("error decoding response body. Err: %v", err)
+			}
+
+			if payload.Product.Price != currency.New(1000, "EUR") {
+				t.Errorf("expected price in the seller's currency; got %v", payload.Product.Price)
+			}
+			got := payload.Product.ConvertedPrice
+			if tt.expectedPrice == nil {
+				if got != nil {
+					t.Errorf("expected no converted price; got %+v", got)
+				}
+				return
+			}
+			if got == nil || *got != *tt.expectedPrice {
+				t.Errorf("expected converted price %+v; got %+v", tt.expectedPrice, got)
+			}
+		})
+	}
+}
+
+func TestGetOrderByIDHandlerCurrency(t *testing.T) {
+	mockDB := MockDBService{
+		GetOrderByIDFunc: func(orderID int) (database.Order, error) {
+			return database.Order{OrderID: orderID, ShippingCost: currency.New(425, "GBP"), Total: currency.New(5425, "GBP")}, nil
+		},
+		ListExchangeRatesFunc: func() (currency.Rates, error) {
+			return currency.Rates{"EUR": 1, "GBP": 0.85}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/orders/:id", s.GetOrderByIDHandler)
+
+	req, err := http.NewRequest("GET", "/api/orders/1?currency=EUR", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200; got %v", resp.Status)
+	}
+
+	var payload struct {
+		Order database.Order `json:"order"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		t.Fatalf("error decoding response body. Err: %v", err)
+	}
+
+	if payload.Order.Total != currency.New(5425, "GBP") {
+		t.Errorf("expected settlement in 54.25 GBP; got %v", payload.Order.Total)
+	}
+	if got := payload.Order.ConvertedTotal; got == nil || *got != currency.New(6382, "EUR") {
+		t.Errorf("expected converted total EUR 63.82; got %+v", got)
+	}
+	if got := payload.Order.ConvertedShippingCost; got == nil || *got != currency.New(500, "EUR") {
+		t.Errorf("expected converted shipping EUR 5; got %+v", got)
+	}
+}
+
+func TestExchangeRateJob(t *testing.T) {
+	var (
+		saved  currency.Rates
+		source string
+	)
+	mockDB := MockDBService{
+		SaveExchangeRatesFunc: func(rates currency.Rates, name string) error {
+			saved, source = rates, name
+			return nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	job := s.exchangeRateJob(stubRateSource{rates: currency.Rates{"EUR": 1, "USD": 1.1}})
+	if err := job.Run(context.Background()); err != nil {
+		t.Fatalf("job failed: %v", err)
+	}
+
+	if source != "stub" {
+		t.Errorf("expected source stub; got %v", source)
+	}
+	if saved["USD"] != 1.1 {
+		t.Errorf("expected USD rate 1.1; got %v", saved["USD"])
+	}
+}
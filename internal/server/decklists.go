@@ -0,0 +1,162 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/decklist"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxDeckListLength bounds the text of a pasted deck list. Even a 250 card
+// cube list stays far below it.
+const maxDeckListLength = 64 << 10
+
+type deckListRequest struct {
+	TCGGameID int    `json:"tcg_game_id"`
+	Text      string `json:"text"`
+	// Name names the wantlist created from the deck list.
+	Name string `json:"name,omitempty"`
+	// ExcludeOwned leaves out copies among the user's own products when
+	// filling the cart.
+	ExcludeOwned bool `json:"exclude_owned,omitempty"`
+}
+
+// missingCard is a deck list entry that could not be filled completely.
+type missingCard struct {
+	decklist.Match
+	Missing int `json:"missing"`
+}
+
+// ParseDeckListHandler resolves a pasted deck list against the cards of a
+// game without saving anything.
+func (s *FiberServer) ParseDeckListHandler(c *fiber.Ctx) error {
+	_, matches, unresolved, err := s.resolveDeckList(c)
+	if err != nil || matches == nil {
+		return err
+	}
+	return c.JSON(fiber.Map{"cards": matches, "unresolved": unresolved})
+}
+
+// ImportDeckListWantlistHandler creates a wantlist of the current user from
+// a deck list. Each card is wanted in the printing the list names, or in the
+// first printing found.
+func (s *FiberServer) ImportDeckListWantlistHandler(c *fiber.Ctx) error {
+	req, matches, unresolved, err := s.resolveDeckList(c)
+	if err != nil || matches == nil {
+		return err
+	}
+	if len(matches) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "No card of the deck list was found",
+			"unresolved": unresolved,
+		})
+	}
+
+	wantlist := database.WantlistRequest{Name: req.Name, TCGGameID: req.TCGGameID}
+	if wantlist.Name == "" {
+		wantlist.Name = "Imported deck"
+	}
+	for _, m := range matches {
+		wantlist.Items = append(wantlist.Items, database.WantlistItemRequest{CardID: m.CardID, Quantity: m.Quantity})
+	}
+
+	created, err := s.db.CreateWantlist(currentUserID(c), wantlist)
+	if err != nil {
+		return wantlistError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"wantlist": created, "unresolved": unresolved})
+}
+
+// ImportDeckListCartHandler adds the cheapest listings of every card of a
+// deck list to the current user's cart. Cards without enough listings are
+// reported as missing.
+func (s *FiberServer) ImportDeckListCartHandler(c *fiber.Ctx) error {
+	req, matches, unresolved, err := s.resolveDeckList(c)
+	if err != nil || matches == nil {
+		return err
+	}
+
+	wants := make([]database.CartWant, len(matches))
+	for i, m := range matches {
+		wants[i] = database.CartWant{CardIDs: m.CardIDs, Quantity: m.Quantity}
+	}
+	fills, err := s.db.FillCart(currentUserID(c), wants, req.ExcludeOwned)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fill cart",
+		})
+	}
+
+	added, missing := []database.CartAddition{}, []missingCard{}
+	for i, fill := range fills {
+		added = append(added, fill.Added...)
+		if fill.Missing > 0 {
+			missing = append(missing, missingCard{Match: matches[i], Missing: fill.Missing})
+		}
+	}
+
+	cart, err := s.db.ListCart(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cart",
+		})
+	}
+	return c.JSON(fiber.Map{"added": added, "missing": missing, "unresolved": unresolved, "cart": cart})
+}
+
+// resolveDeckList parses the deck list of the request and matches it against
+// the cards of its game. Lines that could not be parsed are reported as
+// unresolved along with unknown cards. When it fails, the response has been
+// sent and the matches are nil.
+func (s *FiberServer) resolveDeckList(c *fiber.Ctx) (deckListRequest, []decklist.Match, []decklist.Unresolved, error) {
+	var req deckListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return req, nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Text == "" || len(req.Text) > maxDeckListLength {
+		return req, nil, nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Deck list must not be empty or longer than 64 KiB",
+		})
+	}
+
+	game, err := s.db.GetTCGGame(req.TCGGameID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return req, nil, nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Game not found",
+			})
+		}
+		return req, nil, nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch game",
+		})
+	}
+	cards, err := s.db.ListCardsByGame(game.ID)
+	if err != nil {
+		return req, nil, nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch cards",
+		})
+	}
+
+	catalog := make([]decklist.Card, len(cards))
+	for i, card := range cards {
+		catalog[i] = decklist.Card{ID: card.ID, Name: card.Name, SetName: card.SetName, CardNumber: card.CardNumber}
+	}
+	lines, unparsed := decklist.Parse(game.Name, req.Text)
+	matches, unresolved := decklist.NewIndex(catalog).Resolve(lines)
+	for _, line := range unparsed {
+		unresolved = append(unresolved, decklist.Unresolved{Line: line, Reason: decklist.ReasonUnparsed})
+	}
+	slices.SortStableFunc(unresolved, func(a, b decklist.Unresolved) int { return a.Number - b.Number })
+	if matches == nil {
+		matches = []decklist.Match{}
+	}
+	if unresolved == nil {
+		unresolved = []decklist.Unresolved{}
+	}
+	return req, matches, unresolved, nil
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/decklist"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const burnList = `{"tcg_game_id":1,"text":"4 Lightning Bolt\n4 Lava Spkie\n4 Goblin Guide\nnot a card","exclude_owned":true}`
+
+func deckListMockDB() MockDBService {
+	return MockDBService{
+		GetTCGGameFunc: func(tcgGameID int) (database.TCGGame, error) {
+			if tcgGameID != 1 {
+				return database.TCGGame{}, sql.ErrNoRows
+			}
+			return database.TCGGame{ID: 1, Name: decklist.GameMagic}, nil
+		},
+		ListCardsByGameFunc: func(tcgGameID int) ([]database.Card, error) {
+			return []database.Card{
+				{ID: 10, Name: "Lightning Bolt", SetName: "M10", CardNumber: "146"},
+				{ID: 11, Name: "Lightning Bolt", SetName: "Alpha", CardNumber: "161"},
+				{ID: 12, Name: "Lava Spike", SetName: "Champions of Kamigawa", CardNumber: "178"},
+			}, nil
+		},
+	}
+}
+
+func TestParseDeckListHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"resolved", burnList, http.StatusOK},
+		{"unknown game", `{"tcg_game_id":9,"text":"4 Lightning Bolt"}`, http.StatusNotFound},
+		{"empty list", `{"tcg_game_id":1,"text":""}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := deckListMockDB()
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/decklists/parse", s.ParseDeckListHandler)
+
+			req, err := http.NewRequest("POST", "/api/decklists/parse", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Cards      []decklist.Match      `json:"cards"`
+				Unresolved []decklist.Unresolved `json:"unresolved"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("error decoding response. Err: %v", err)
+			}
+			if len(body.Cards) != 2 || body.Cards[1].Card != "Lava Spike" || !body.Cards[1].Fuzzy {
+				t.Errorf("unexpected cards %+v", body.Cards)
+			}
+			if len(body.Unresolved) != 2 || body.Unresolved[0].Reason != decklist.ReasonNotFound || body.Unresolved[1].Reason != decklist.ReasonUnparsed {
+				t.Errorf("unexpected unresolved lines %+v", body.Unresolved)
+			}
+		})
+	}
+}
+
+func TestImportDeckListWantlistHandler(t *testing.T) {
+	var (
+		userID   int
+		received database.WantlistRequest
+	)
+	mockDB := deckListMockDB()
+	mockDB.CreateWantlistFunc = func(id int, wantlist database.WantlistRequest) (database.Wantlist, error) {
+		userID, received = id, wantlist
+		return database.Wantlist{WantlistID: 1, UserID: id, Name: wantlist.Name}, nil
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/decklists/wantlist", requireUser, s.ImportDeckListWantlistHandler)
+
+	req, err := http.NewRequest("POST", "/api/decklists/wantlist", strings.NewReader(burnList))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status Created; got %v", resp.Status)
+	}
+	want := []database.WantlistItemRequest{{CardID: 10, Quantity: 4}, {CardID: 12, Quantity: 4}}
+	if userID != 2 || received.Name != "Imported deck" || received.TCGGameID != 1 || !slices.Equal(received.Items, want) {
+		t.Errorf("unexpected wantlist %+v for user %v", received, userID)
+	}
+}
+
+func TestImportDeckListCartHandler(t *testing.T) {
+	var (
+		wants        []database.CartWant
+		excludeOwned bool
+	)
+	mockDB := deckListMockDB()
+	mockDB.FillCartFunc = func(userID int, w []database.CartWant, exclude bool) ([]database.CartFill, error) {
+		wants, excludeOwned = w, exclude
+		return []database.CartFill{
+			{Owned: 1, Added: []database.CartAddition{{ProductID: 5, Quantity: 3}}},
+			{Added: []database.CartAddition{{ProductID: 6, Quantity: 1}}, Missing: 3},
+		}, nil
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/decklists/cart", requireUser, s.ImportDeckListCartHandler)
+
+	req, err := http.NewRequest("POST", "/api/decklists/cart", strings.NewReader(burnList))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", resp.Status)
+	}
+	if !excludeOwned || len(wants) != 2 || !slices.Equal(wants[0].CardIDs, []int{10, 11}) || wants[1].Quantity != 4 {
+		t.Errorf("unexpected wants %+v", wants)
+	}
+
+	var body struct {
+		Added   []database.CartAddition `json:"added"`
+		Missing []struct {
+			Card    string `json:"card"`
+			Missing int    `json:"missing"`
+		} `json:"missing"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatalf("error decoding response. Err: %v", err)
+	}
+	if len(body.Added) != 2 || len(body.Missing) != 1 || body.Missing[0].Card != "Lava Spike" || body.Missing[0].Missing != 3 {
+		t.Errorf("unexpected response %+v", body)
+	}
+}
@@ -0,0 +1,358 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxDisputeEvidence limits the images attached when opening a dispute.
+const maxDisputeEvidence = 10
+
+var disputeReasons = map[string]bool{
+	"not_received":     true,
+	"not_as_described": true,
+	"damaged":          true,
+	"wrong_item":       true,
+	"other":            true,
+}
+
+type disputeMessageRequest struct {
+	Body string `json:"body"`
+}
+
+type disputeEvidenceRequest struct {
+	ImageURL string `json:"image_url"`
+}
+
+func (s *FiberServer) ListDisputesHandler(c *fiber.Ctx) error {
+	disputes, err := s.db.ListUserDisputes(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch disputes",
+		})
+	}
+	return c.JSON(fiber.Map{"disputes": disputes})
+}
+
+func (s *FiberServer) GetDisputeHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	dispute, err := s.participantDispute(disputeID, currentUserID(c))
+	if errors.Is(err, database.ErrNotOrderParticipant) {
+		// Administrators may read every dispute.
+		if isAdmin, _ := s.db.IsAdmin(currentUserID(c)); isAdmin {
+			dispute, err = s.db.GetDispute(disputeID)
+		}
+	}
+	if err != nil {
+		return disputeError(c, err, "Failed to fetch dispute")
+	}
+	return c.JSON(fiber.Map{"dispute": dispute})
+}
+
+func (s *FiberServer) CreateDisputeHandler(c *fiber.Ctx) error {
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	var req database.DisputeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !disputeReasons[req.Reason] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute reason",
+		})
+	}
+	if strings.TrimSpace(req.Description) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Description is required",
+		})
+	}
+	if len(req.EvidenceURLs) > maxDisputeEvidence {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Too many evidence images",
+		})
+	}
+	for _, imageURL := range req.EvidenceURLs {
+		if !validImageURL(imageURL) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid evidence image URL",
+			})
+		}
+	}
+
+	disputeID, err := s.db.CreateDispute(orderID, currentUserID(c), req)
+	if err != nil {
+		return disputeError(c, err, "Failed to open dispute")
+	}
+
+	if dispute, err := s.db.GetDispute(disputeID); err == nil {
+		s.notify(dispute.SellerID, database.NotificationDisputeOpened, fiber.Map{
+			"dispute_id": disputeID,
+			"order_id":   orderID,
+			"reason":     req.Reason,
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "dispute opened",
+		"dispute_id": disputeID,
+	})
+}
+
+func (s *FiberServer) CreateDisputeMessageHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	var req disputeMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(req.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Message body is required",
+		})
+	}
+
+	dispute, err := s.participantDispute(disputeID, currentUserID(c))
+	if err != nil {
+		return disputeError(c, err, "Failed to send message")
+	}
+
+	if err := s.db.CreateDisputeMessage(disputeID, currentUserID(c), req.Body); err != nil {
+		return disputeError(c, err, "Failed to send message")
+	}
+
+	s.notifyDisputeCounterpart(dispute, currentUserID(c), "message")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "message sent"})
+}
+
+func (s *FiberServer) AddDisputeEvidenceHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	var req disputeEvidenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !validImageURL(req.ImageURL) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid evidence image URL",
+		})
+	}
+
+	dispute, err := s.participantDispute(disputeID, currentUserID(c))
+	if err != nil {
+		return disputeError(c, err, "Failed to add evidence")
+	}
+
+	if err := s.db.AddDisputeEvidence(disputeID, currentUserID(c), req.ImageURL); err != nil {
+		return disputeError(c, err, "Failed to add evidence")
+	}
+
+	s.notifyDisputeCounterpart(dispute, currentUserID(c), "evidence")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "evidence added"})
+}
+
+func (s *FiberServer) EscalateDisputeHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	dispute, err := s.participantDispute(disputeID, currentUserID(c))
+	if err != nil {
+		return disputeError(c, err, "Failed to escalate dispute")
+	}
+
+	if err := s.db.EscalateDispute(disputeID); err != nil {
+		return disputeError(c, err, "Failed to escalate dispute")
+	}
+
+	s.notifyDisputeCounterpart(dispute, currentUserID(c), "escalated")
+	return c.JSON(fiber.Map{"message": "dispute escalated"})
+}
+
+// RefundDisputeHandler lets the seller settle a dispute by refunding the
+// buyer. Rejecting a claim is left to the administrators.
+func (s *FiberServer) RefundDisputeHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	var req database.DisputeResolutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Resolution != database.DisputeFullRefund && req.Resolution != database.DisputePartialRefund {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Sellers can only resolve a dispute with a full or partial refund",
+		})
+	}
+
+	dispute, err := s.participantDispute(disputeID, currentUserID(c))
+	if err != nil {
+		return disputeError(c, err, "Failed to resolve dispute")
+	}
+	if dispute.SellerID != currentUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only the seller can refund the order",
+		})
+	}
+
+	return s.resolveDispute(c, dispute, req)
+}
+
+func (s *FiberServer) ListAdminDisputesHandler(c *fiber.Ctx) error {
+	disputes, err := s.db.ListDisputesByStatus(c.Query("status", database.DisputeStatusEscalated))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch disputes",
+		})
+	}
+	return c.JSON(fiber.Map{"disputes": disputes})
+}
+
+func (s *FiberServer) ResolveDisputeHandler(c *fiber.Ctx) error {
+	disputeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid dispute ID",
+		})
+	}
+
+	var req database.DisputeResolutionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	dispute, err := s.db.GetDispute(disputeID)
+	if err != nil {
+		return disputeError(c, err, "Failed to resolve dispute")
+	}
+
+	return s.resolveDispute(c, dispute, req)
+}
+
+func (s *FiberServer) resolveDispute(c *fiber.Ctx, dispute database.Dispute, req database.DisputeResolutionRequest) error {
+	if err := s.db.ResolveDispute(dispute.DisputeID, currentUserID(c), req); err != nil {
+		return disputeError(c, err, "Failed to resolve dispute")
+	}
+
+	payload := fiber.Map{
+		"dispute_id": dispute.DisputeID,
+		"order_id":   dispute.OrderID,
+		"resolution": req.Resolution,
+	}
+	s.notify(dispute.BuyerID, database.NotificationDisputeResolved, payload)
+	s.notify(dispute.SellerID, database.NotificationDisputeResolved, payload)
+	return c.JSON(fiber.Map{"message": "dispute resolved"})
+}
+
+// participantDispute loads a dispute and checks that the user is the buyer or
+// the seller of the disputed order.
+func (s *FiberServer) participantDispute(disputeID int, userID int) (database.Dispute, error) {
+	dispute, err := s.db.GetDispute(disputeID)
+	if err != nil {
+		return database.Dispute{}, err
+	}
+	if userID != dispute.BuyerID && userID != dispute.SellerID {
+		return database.Dispute{}, database.ErrNotOrderParticipant
+	}
+	return dispute, nil
+}
+
+// notifyDisputeCounterpart tells the other party of the dispute about an
+// action of the user.
+func (s *FiberServer) notifyDisputeCounterpart(dispute database.Dispute, userID int, event string) {
+	recipientID := dispute.SellerID
+	if userID == dispute.SellerID {
+		recipientID = dispute.BuyerID
+	}
+	s.notify(recipientID, database.NotificationDisputeUpdated, fiber.Map{
+		"dispute_id": dispute.DisputeID,
+		"order_id":   dispute.OrderID,
+		"event":      event,
+	})
+}
+
+// validImageURL accepts absolute http(s) URLs.
+func validImageURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// disputeError maps errors from the dispute queries to a response.
+func disputeError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dispute or order not found",
+		})
+	case errors.Is(err, database.ErrNotOrderParticipant):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You are not a participant of this order",
+		})
+	case errors.Is(err, database.ErrOrderNotDisputable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This order cannot be disputed",
+		})
+	case errors.Is(err, database.ErrDisputeExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This order already has a dispute",
+		})
+	case errors.Is(err, database.ErrDisputeResolved):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "This dispute is already resolved",
+		})
+	case errors.Is(err, database.ErrInvalidRefund):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid resolution or refund amount",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateDisputeHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		dbErr          error
+		expectedStatus int
+	}{
+		{
+			name:           "opened",
+			body:           `{"reason":"damaged","description":"Corner is bent","evidence_urls":["https://img.example.com/1.jpg"]}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "unknown reason",
+			body:           `{"reason":"changed_mind","description":"No longer needed"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid evidence url",
+			body:           `{"reason":"damaged","description":"Corner is bent","evidence_urls":["file:///etc/passwd"]}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "already disputed",
+			body:           `{"reason":"not_received","description":"Still waiting"}`,
+			dbErr:          database.ErrDisputeExists,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "not the buyer",
+			body:           `{"reason":"not_received","description":"Still waiting"}`,
+			dbErr:          database.ErrNotOrderParticipant,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var notified []int
+			mockDB := MockDBService{
+				CreateDisputeFunc: func(orderID int, buyerID int, dispute database.DisputeRequest) (int, error) {
+					return 1, tt.dbErr
+				},
+				GetDisputeFunc: func(disputeID int) (database.Dispute, error) {
+					return database.Dispute{DisputeID: disputeID, OrderID: 4, BuyerID: 2, SellerID: 1}, nil
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					notified = append(notified, userID)
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/orders/:id/disputes", requireUser, s.CreateDisputeHandler)
+
+			req, err := http.NewRequest("POST", "/api/orders/4/disputes", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusCreated && (len(notified) != 1 || notified[0] != 1) {
+				t.Errorf("expected the seller to be notified; got %v", notified)
+			}
+		})
+	}
+}
+
+func TestRefundDisputeHandlerOnlySeller(t *testing.T) {
+	resolved := false
+	mockDB := MockDBService{
+		GetDisputeFunc: func(disputeID int) (database.Dispute, error) {
+			return database.Dispute{DisputeID: disputeID, OrderID: 4, BuyerID: 2, SellerID: 1, Status: database.DisputeStatusOpen}, nil
+		},
+		ResolveDisputeFunc: func(disputeID int, resolverID int, resolution database.DisputeResolutionRequest) error {
+			resolved = true
+			return nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/disputes/:id/refund", requireUser, s.RefundDisputeHandler)
+
+	req, err := http.NewRequest("POST", "/api/disputes/1/refund", strings.NewReader(`{"resolution":"full_refund","restock":true}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", resp.Status)
+	}
+	if resolved {
+		t.Errorf("expected the buyer not to be able to refund")
+	}
+}
+
+func TestResolveDisputeHandlerRequiresAdmin(t *testing.T) {
+	tests := []struct {
+		name           string
+		isAdmin        bool
+		expectedStatus int
+	}{
+		{"admin", true, http.StatusOK},
+		{"not admin", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resolution database.DisputeResolutionRequest
+			mockDB := MockDBService{
+				IsAdminFunc: func(userID int) (bool, error) {
+					return tt.isAdmin, nil
+				},
+				GetDisputeFunc: func(disputeID int) (database.Dispute, error) {
+					return database.Dispute{DisputeID: disputeID, OrderID: 4, BuyerID: 2, SellerID: 1, Status: database.DisputeStatusEscalated}, nil
+				},
+				ResolveDisputeFunc: func(disputeID int, resolverID int, req database.DisputeResolutionRequest) error {
+					resolution = req
+					return nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			admin := app.Group("/api/admin", requireUser, s.requireAdmin)
+			admin.Post("/disputes/:id/resolve", s.ResolveDisputeHandler)
+
+			req, err := http.NewRequest("POST", "/api/admin/disputes/1/resolve", strings.NewReader(`{"resolution":"partial_refund","refund_amount":{"amount":"5.50","currency":"EUR"}}`))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 9)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.isAdmin && (resolution.Resolution != database.DisputePartialRefund || resolution.RefundAmount != currency.New(550, "EUR")) {
+				t.Errorf("expected partial refund of 5.50 EUR; got %+v", resolution)
+			}
+		})
+	}
+}
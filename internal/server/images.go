@@ -0,0 +1,244 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/images"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type reorderImagesRequest struct {
+	ImageIDs []int `json:"image_ids"`
+}
+
+// ListProductImagesHandler lists the images of a product in their order.
+func (s *FiberServer) ListProductImagesHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	productImages, err := s.db.ListProductImages(productID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch images",
+		})
+	}
+	s.imageURLs(productImages)
+	return c.JSON(fiber.Map{"images": productImages})
+}
+
+// UploadProductImageHandler adds the JPEG or PNG image in the "image" form
+// field to a product of the current user, along with a thumbnail.
+func (s *FiberServer) UploadProductImageHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	// Checking the product first spares reading and decoding uploads that
+	// would be rejected anyway.
+	if err := s.db.CheckProductImageUpload(productID, currentUserID(c)); err != nil {
+		return productImageError(c, err)
+	}
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "An image file is required",
+		})
+	}
+	if header.Size > images.MaxSize {
+		return productImageError(c, images.ErrTooLarge)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "An image file is required",
+		})
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, images.MaxSize+1))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "An image file is required",
+		})
+	}
+
+	img, err := images.Decode(data)
+	if err != nil {
+		return productImageError(c, err)
+	}
+	thumbnail, err := img.Thumbnail(images.ThumbnailSize)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create thumbnail",
+		})
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image",
+		})
+	}
+	req := database.ProductImageRequest{
+		Key:          fmt.Sprintf("products/%d/%s%s", productID, name, images.Extensions[img.ContentType]),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.jpg", productID, name),
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+		Size:         len(data),
+	}
+	ctx := c.UserContext()
+	if err := s.storage.Put(ctx, req.Key, data, img.ContentType); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image",
+		})
+	}
+	if err := s.storage.Put(ctx, req.ThumbnailKey, thumbnail, "image/jpeg"); err != nil {
+		s.deleteStoredImage(ctx, database.ProductImage{Key: req.Key})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store image",
+		})
+	}
+
+	productImage, err := s.db.AddProductImage(productID, currentUserID(c), req)
+	if err != nil {
+		s.deleteStoredImage(ctx, database.ProductImage{Key: req.Key, ThumbnailKey: req.ThumbnailKey})
+		return productImageError(c, err)
+	}
+	uploaded := []database.ProductImage{productImage}
+	s.imageURLs(uploaded)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"image": uploaded[0]})
+}
+
+// ReorderProductImagesHandler puts the images of a product of the current
+// user in the order of the given image IDs.
+func (s *FiberServer) ReorderProductImagesHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	var req reorderImagesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	productImages, err := s.db.ReorderProductImages(productID, currentUserID(c), req.ImageIDs)
+	if err != nil {
+		return productImageError(c, err)
+	}
+	s.imageURLs(productImages)
+	return c.JSON(fiber.Map{"images": productImages})
+}
+
+// DeleteProductImageHandler removes an image from a product of the current
+// user and deletes its files.
+func (s *FiberServer) DeleteProductImageHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+	imageID, err := strconv.Atoi(c.Params("imageID"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid image ID",
+		})
+	}
+
+	productImage, err := s.db.DeleteProductImage(productID, imageID, currentUserID(c))
+	if err != nil {
+		return productImageError(c, err)
+	}
+	s.deleteStoredImage(c.UserContext(), productImage)
+	return c.JSON(fiber.Map{"message": "image deleted"})
+}
+
+// imageURLs fills in where the images and their thumbnails are served from.
+func (s *FiberServer) imageURLs(productImages []database.ProductImage) {
+	for i := range productImages {
+		productImages[i].URL = s.storage.URL(productImages[i].Key)
+		productImages[i].ThumbnailURL = s.storage.URL(productImages[i].ThumbnailKey)
+	}
+}
+
+// deleteStoredImage removes the files of an image. Failures only leave
+// unreferenced files behind, so they are logged.
+func (s *FiberServer) deleteStoredImage(ctx context.Context, productImage database.ProductImage) {
+	for _, key := range []string{productImage.Key, productImage.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete stored image %s: %v", key, err)
+		}
+	}
+}
+
+// randomName returns a name for stored files that cannot be guessed from the
+// product.
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func productImageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product or image not found",
+		})
+	case errors.Is(err, database.ErrNotProductSeller):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only change the images of your own products",
+		})
+	case errors.Is(err, database.ErrTooManyImages):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Products can have at most %d images", database.MaxProductImages),
+		})
+	case errors.Is(err, database.ErrInvalidImageOrder):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The order must list every image of the product once",
+		})
+	case errors.Is(err, images.ErrTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": fmt.Sprintf("Images must be at most %d MiB and %d megapixels", images.MaxSize>>20, images.MaxPixels/1_000_000),
+		})
+	case errors.Is(err, images.ErrUnsupportedType):
+		return c.Status(fiber.StatusUnsupportedMediaType).JSON(fiber.Map{
+			"error": "Images must be JPEG or PNG",
+		})
+	case errors.Is(err, images.ErrInvalid):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "The image could not be read",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update images",
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"bytes"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/storage"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// multipartImage builds an upload form with data in the "image" field.
+func multipartImage(t *testing.T, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("image", "scan.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &body, w.FormDataContentType()
+}
+
+func pngScan(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 630, 880))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// storedFiles lists the files below dir relative to it.
+func storedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files := []string{}
+	filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, path)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return files
+}
+
+func TestUploadProductImageHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		data           []byte
+		checkErr       error
+		dbErr          error
+		expectedStatus int
+	}{
+		{"uploaded", pngScan(t), nil, nil, http.StatusCreated},
+		{"not an image", []byte("%PDF-1.4 scan"), nil, nil, http.StatusUnsupportedMediaType},
+		{"another seller's product", []byte("%PDF-1.4 scan"), database.ErrNotProductSeller, nil, http.StatusForbidden},
+		{"too many images", []byte("%PDF-1.4 scan"), database.ErrTooManyImages, nil, http.StatusConflict},
+		{"unknown product", []byte("%PDF-1.4 scan"), sql.ErrNoRows, nil, http.StatusNotFound},
+		{"images added meanwhile", pngScan(t), nil, database.ErrTooManyImages, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received database.ProductImageRequest
+			mockDB := MockDBService{
+				CheckProductImageUploadFunc: func(productID int, sellerID int) error {
+					if productID != 4 || sellerID != 2 {
+						t.Errorf("unexpected product %v or seller %v", productID, sellerID)
+					}
+					return tt.checkErr
+				},
+				AddProductImageFunc: func(productID int, sellerID int, img database.ProductImageRequest) (database.ProductImage, error) {
+					if tt.checkErr != nil {
+						t.Error("expected a rejected upload not to be added")
+					}
+					if productID != 4 || sellerID != 2 {
+						t.Errorf("unexpected product %v or seller %v", productID, sellerID)
+					}
+					received = img
+					if tt.dbErr != nil {
+						return database.ProductImage{}, tt.dbErr
+					}
+					return database.ProductImage{ImageID: 1, ProductID: productID, Key: img.Key, ThumbnailKey: img.ThumbnailKey, Position: 1}, nil
+				},
+			}
+			store := &storage.LocalStorage{Dir: t.TempDir(), URLPrefix: "/uploads"}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB, storage: store}
+			app.Post("/api/products/:id/images", requireUser, s.UploadProductImageHandler)
+
+			body, contentType := multipartImage(t, tt.data)
+			req, err := http.NewRequest("POST", "/api/products/4/images", body)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", contentType)
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+
+			files := storedFiles(t, store.Dir)
+			if tt.expectedStatus != http.StatusCreated {
+				if len(files) != 0 {
+					t.Errorf("expected no stored files; got %v", files)
+				}
+				return
+			}
+
+			if received.ContentType != "image/png" || received.Width != 630 || received.Height != 880 || received.Size != len(tt.data) {
+				t.Errorf("unexpected image %+v", received)
+			}
+			if !strings.HasPrefix(received.Key, "products/4/") || !strings.HasSuffix(received.Key, ".png") || !strings.HasSuffix(received.ThumbnailKey, "_thumb.jpg") {
+				t.Errorf("unexpected keys %q and %q", received.Key, received.ThumbnailKey)
+			}
+			slices.Sort(files)
+			if want := []string{received.Key, received.ThumbnailKey}; !slices.Equal(files, want) {
+				t.Errorf("expected stored files %v; got %v", want, files)
+			}
+
+			var response struct {
+				Image database.ProductImage `json:"image"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("error decoding response. Err: %v", err)
+			}
+			if response.Image.URL != "/uploads/"+received.Key || response.Image.ThumbnailURL != "/uploads/"+received.ThumbnailKey {
+				t.Errorf("unexpected URLs %q and %q", response.Image.URL, response.Image.ThumbnailURL)
+			}
+		})
+	}
+}
+
+func TestReorderProductImagesHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		dbErr          error
+		expectedStatus int
+	}{
+		{"reordered", `{"image_ids":[3,1,2]}`, nil, http.StatusOK},
+		{"incomplete order", `{"image_ids":[3,1]}`, database.ErrInvalidImageOrder, http.StatusBadRequest},
+		{"another seller's product", `{"image_ids":[3,1,2]}`, database.ErrNotProductSeller, http.StatusForbidden},
+		{"invalid body", `{"image_ids":`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				ReorderProductImagesFunc: func(productID int, sellerID int, imageIDs []int) ([]database.ProductImage, error) {
+					if tt.dbErr != nil {
+						return nil, tt.dbErr
+					}
+					reordered := make([]database.ProductImage, len(imageIDs))
+					for i, imageID := range imageIDs {
+						reordered[i] = database.ProductImage{ImageID: imageID, ProductID: productID, Position: i + 1}
+					}
+					return reordered, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB, storage: &storage.LocalStorage{Dir: t.TempDir(), URLPrefix: "/uploads"}}
+			app.Put("/api/products/:id/images", requireUser, s.ReorderProductImagesHandler)
+
+			req, err := http.NewRequest("PUT", "/api/products/4/images", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+		})
+	}
+}
+
+func TestDeleteProductImageHandler(t *testing.T) {
+	store := &storage.LocalStorage{Dir: t.TempDir(), URLPrefix: "/uploads"}
+	for _, key := range []string{"products/4/a.png", "products/4/a_thumb.jpg", "products/4/b.png"} {
+		if err := store.Put(context.Background(), key, []byte("image"), "image/png"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mockDB := MockDBService{
+		DeleteProductImageFunc: func(productID int, imageID int, sellerID int) (database.ProductImage, error) {
+			if imageID != 1 {
+				return database.ProductImage{}, sql.ErrNoRows
+			}
+			return database.ProductImage{ImageID: 1, ProductID: productID, Key: "products/4/a.png", ThumbnailKey: "products/4/a_thumb.jpg"}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB, storage: store}
+	app.Delete("/api/products/:id/images/:imageID", requireUser, s.DeleteProductImageHandler)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/api/products/4/images/1", http.StatusOK},
+		{"/api/products/4/images/9", http.StatusNotFound},
+		{"/api/products/4/images/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("DELETE", tt.path, nil)
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		setSession(t, req, 2)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != tt.expectedStatus {
+			t.Errorf("%s: expected status %v; got %v", tt.path, tt.expectedStatus, resp.Status)
+		}
+	}
+
+	if files := storedFiles(t, store.Dir); !slices.Equal(files, []string{"products/4/b.png"}) {
+		t.Errorf("expected only the other image to remain; got %v", files)
+	}
+}
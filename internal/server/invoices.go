@@ -0,0 +1,165 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/pdf"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetInvoiceHandler returns the invoice of an order as a PDF. Invoices are
+// issued when the order is completed.
+func (s *FiberServer) GetInvoiceHandler(c *fiber.Ctx) error {
+	orderID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	invoice, err := s.db.GetInvoice(orderID, currentUserID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Order not found",
+			})
+		case errors.Is(err, database.ErrNotOrderParticipant):
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You are not a participant of this order",
+			})
+		case errors.Is(err, database.ErrOrderNotInvoiceable):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Only completed orders are invoiced",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to load invoice",
+			})
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`inline; filename="invoice-%s.pdf"`, invoice.Number))
+	return c.Send(renderInvoice(invoice))
+}
+
+// Layout of the A4 documents, in points.
+const (
+	marginLeft  = 50.0
+	marginRight = pdf.A4Width - 50
+	lineHeight  = 14.0
+)
+
+func renderInvoice(invoice database.Invoice) []byte {
+	doc := pdf.New()
+	page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+
+	y := pdf.A4Height - 60
+	page.Text(marginLeft, y, pdf.Bold, 18, "Invoice "+invoice.Number)
+	y -= 2 * lineHeight
+	page.Text(marginLeft, y, pdf.Regular, 10, "Invoice date: "+invoice.IssuedAt.Format("2006-01-02"))
+	y -= lineHeight
+	page.Text(marginLeft, y, pdf.Regular, 10, fmt.Sprintf("Order: #%d of %s", invoice.OrderID, invoice.OrderDate.Format("2006-01-02")))
+
+	y -= 2 * lineHeight
+	page.Text(marginLeft, y, pdf.Bold, 10, "Seller")
+	page.Text(300, y, pdf.Bold, 10, "Bill to")
+	sellerLines := addressLines(invoice.Seller)
+	buyerLines := addressLines(invoice.Buyer)
+	for i := 0; i < max(len(sellerLines), len(buyerLines)); i++ {
+		y -= lineHeight
+		if i < len(sellerLines) {
+			page.Text(marginLeft, y, pdf.Regular, 10, sellerLines[i])
+		}
+		if i < len(buyerLines) {
+			page.Text(300, y, pdf.Regular, 10, buyerLines[i])
+		}
+	}
+
+	if invoice.ShipTo != nil {
+		y -= 2 * lineHeight
+		page.Text(marginLeft, y, pdf.Bold, 10, "Ship to")
+		for _, line := range addressLines(*invoice.ShipTo) {
+			y -= lineHeight
+			page.Text(marginLeft, y, pdf.Regular, 10, line)
+		}
+	}
+
+	y -= 2 * lineHeight
+	page.Text(marginLeft, y, pdf.Bold, 10, "Description")
+	page.Text(330, y, pdf.Bold, 10, "Qty")
+	page.Text(380, y, pdf.Bold, 10, "Unit price")
+	page.Text(480, y, pdf.Bold, 10, "Amount")
+	y -= 6
+	page.Line(marginLeft, y, marginRight, y)
+
+	y -= lineHeight
+	page.Text(marginLeft, y, pdf.Regular, 10, invoice.Description)
+	page.TextRight(350, y, 10, strconv.Itoa(invoice.Quantity))
+	page.TextRight(460, y, 10, invoice.UnitPrice.String())
+	page.TextRight(marginRight, y, 10, invoice.Subtotal.String())
+
+	y -= lineHeight
+	shipping := "Shipping"
+	if invoice.ShippingMethod != nil {
+		shipping += " (" + *invoice.ShippingMethod + ")"
+	}
+	page.Text(marginLeft, y, pdf.Regular, 10, shipping)
+	page.TextRight(marginRight, y, 10, invoice.ShippingCost.String())
+
+	y -= 6
+	page.Line(marginLeft, y, marginRight, y)
+	for _, line := range invoice.TaxLines {
+		y -= lineHeight
+		label := fmt.Sprintf("VAT %s %s%% on %s %s", line.Country, basisPointsPercent(line.BasisPoints), line.Kind, line.Taxable.Decimal())
+		page.Text(marginLeft, y, pdf.Regular, 10, label)
+		page.TextRight(marginRight, y, 10, line.Tax.String())
+	}
+	y -= lineHeight
+	page.Text(marginLeft, y, pdf.Regular, 10, "Total tax")
+	page.TextRight(marginRight, y, 10, invoice.TaxAmount.String())
+
+	y -= lineHeight + 4
+	page.Text(marginLeft, y, pdf.Bold, 11, "Total")
+	page.TextRight(marginRight, y, 11, invoice.Total.String())
+
+	if len(invoice.TaxLines) == 0 {
+		y -= 2 * lineHeight
+		note := "No VAT charged."
+		if invoice.SellerType == "private" {
+			note = "Sold by a private seller; no VAT charged."
+		}
+		page.Text(marginLeft, y, pdf.Regular, 9, note)
+	}
+
+	return doc.Bytes()
+}
+
+// addressLines formats an address for printing, skipping empty lines.
+func addressLines(address database.OrderAddress) []string {
+	lines := []string{
+		address.Name,
+		strings.TrimSpace(address.StreetName + " " + address.StreetNumber),
+		strings.TrimSpace(address.ZipCode + " " + address.City),
+		address.State,
+		address.Country,
+	}
+	printed := lines[:0]
+	for _, line := range lines {
+		if line != "" {
+			printed = append(printed, line)
+		}
+	}
+	return printed
+}
+
+// basisPointsPercent formats a rate such as 1950 as "19.5".
+func basisPointsPercent(basisPoints int64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%d.%02d", basisPoints/100, basisPoints%100), "0"), ".")
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"bytes"
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/tax"
+	"database/sql"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestGetInvoiceHandler(t *testing.T) {
+	method := "Tracked letter"
+	invoice := database.Invoice{
+		InvoiceID:      1,
+		Number:         "1-000007",
+		IssuedAt:       time.Date(2025, time.November, 12, 10, 0, 0, 0, time.UTC),
+		OrderID:        4,
+		BuyerID:        2,
+		SellerID:       1,
+		SellerType:     "professional",
+		Seller:         database.OrderAddress{Name: "Hans Müller", StreetName: "Hauptstraße", StreetNumber: "1", City: "Berlin", ZipCode: "10115", Country: "Germany"},
+		Buyer:          database.OrderAddress{Name: "Marie Dubois", StreetName: "Rue de Rivoli", StreetNumber: "5", City: "Paris", ZipCode: "75001", Country: "France"},
+		Description:    "Black Lotus (near_mint, English)",
+		Quantity:       2,
+		UnitPrice:      currency.New(1000, "EUR"),
+		Subtotal:       currency.New(2000, "EUR"),
+		ShippingMethod: &method,
+		ShippingCost:   currency.New(350, "EUR"),
+		TaxLines: []tax.TaxLine{
+			{Kind: tax.KindItem, Country: "FR", BasisPoints: 2000, Taxable: currency.New(2000, "EUR"), Tax: currency.New(400, "EUR")},
+			{Kind: tax.KindShipping, Country: "FR", BasisPoints: 2000, Taxable: currency.New(350, "EUR"), Tax: currency.New(70, "EUR")},
+		},
+		TaxAmount: currency.New(470, "EUR"),
+		Total:     currency.New(2820, "EUR"),
+	}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"participant", nil, http.StatusOK},
+		{"not a participant", database.ErrNotOrderParticipant, http.StatusForbidden},
+		{"order not completed", database.ErrOrderNotInvoiceable, http.StatusConflict},
+		{"unknown order", sql.ErrNoRows, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestedBy int
+			mockDB := MockDBService{
+				GetInvoiceFunc: func(orderID int, userID int) (database.Invoice, error) {
+					requestedBy = userID
+					if tt.err != nil {
+						return database.Invoice{}, tt.err
+					}
+					return invoice, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Get("/api/orders/:id/invoice.pdf", requireUser, s.GetInvoiceHandler)
+
+			req, err := http.NewRequest("GET", "/api/orders/4/invoice.pdf", nil)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if requestedBy != 2 {
+				t.Errorf("expected the invoice to be requested by user 2; got %v", requestedBy)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			if got := resp.Header.Get("Content-Type"); got != "application/pdf" {
+				t.Errorf("expected a PDF; got %v", got)
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("error reading response body. Err: %v", err)
+			}
+			for _, want := range []string{"%PDF-", "Invoice 1-000007", "Hans M\\374ller", "VAT FR 20% on item 20.00", "28.20 EUR"} {
+				if !bytes.Contains(body, []byte(want)) {
+					t.Errorf("expected invoice to contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestBasisPointsPercent(t *testing.T) {
+	tests := map[int64]string{1900: "19", 550: "5.5", 725: "7.25", 0: "0"}
+	for basisPoints, want := range tests {
+		if got := basisPointsPercent(basisPoints); got != want {
+			t.Errorf("basisPointsPercent(%d) = %q; want %q", basisPoints, got, want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// orderJobsConfig holds the order timeouts enforced by the scheduler.
+type orderJobsConfig struct {
+	paymentWindow         time.Duration
+	shippingReminderAfter time.Duration
+	autoCompleteAfter     time.Duration
+}
+
+// orderJobs returns the scheduled jobs that move stuck orders along.
+func (s *FiberServer) orderJobs(cfg orderJobsConfig) []scheduler.Job {
+	return []scheduler.Job{
+		{Name: "cancel_unpaid_orders", Run: func(ctx context.Context) error {
+			orders, err := s.db.CancelUnpaidOrders(time.Now().Add(-cfg.paymentWindow))
+			if err != nil {
+				return err
+			}
+			for _, order := range orders {
+				s.notifyStatusChange(order, "pending", "cancelled", "payment_timeout")
+			}
+			return nil
+		}},
+		{Name: "remind_unshipped_orders", Run: func(ctx context.Context) error {
+			orders, err := s.db.RemindUnshippedOrders(time.Now().Add(-cfg.shippingReminderAfter))
+			if err != nil {
+				return err
+			}
+			for _, order := range orders {
+				s.notify(order.SellerID, database.NotificationShippingReminder, fiber.Map{
+					"order_id": order.OrderID,
+				})
+			}
+			return nil
+		}},
+		{Name: "complete_shipped_orders", Run: func(ctx context.Context) error {
+			orders, err := s.db.CompleteShippedOrders(time.Now().Add(-cfg.autoCompleteAfter))
+			if err != nil {
+				return err
+			}
+			for _, order := range orders {
+				s.notifyStatusChange(order, "", "completed", "auto_completed")
+			}
+			return nil
+		}},
+	}
+}
+
+// notifyStatusChange tells both parties that a background job changed the
+// status of their order.
+func (s *FiberServer) notifyStatusChange(order database.OrderSummary, previous string, status string, reason string) {
+	payload := fiber.Map{
+		"order_id": order.OrderID,
+		"status":   status,
+		"reason":   reason,
+	}
+	if previous != "" {
+		payload["previous_status"] = previous
+	}
+	s.notify(order.BuyerID, database.NotificationOrderStatusChanged, payload)
+	s.notify(order.SellerID, database.NotificationOrderStatusChanged, payload)
+}
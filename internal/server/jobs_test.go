@@ -0,0 +1,52 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestOrderJobs(t *testing.T) {
+	var (
+		cancelBefore time.Time
+		notified     = map[string][]int{}
+	)
+	mockDB := MockDBService{
+		CancelUnpaidOrdersFunc: func(placedBefore time.Time) ([]database.OrderSummary, error) {
+			cancelBefore = placedBefore
+			return []database.OrderSummary{{OrderID: 1, BuyerID: 2, SellerID: 1}}, nil
+		},
+		RemindUnshippedOrdersFunc: func(paidBefore time.Time) ([]database.OrderSummary, error) {
+			return []database.OrderSummary{{OrderID: 2, BuyerID: 3, SellerID: 1}}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			notified[notificationType] = append(notified[notificationType], userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	jobs := s.orderJobs(orderJobsConfig{
+		paymentWindow:         72 * time.Hour,
+		shippingReminderAfter: 48 * time.Hour,
+		autoCompleteAfter:     14 * 24 * time.Hour,
+	})
+	for _, job := range jobs {
+		if err := job.Run(context.Background()); err != nil {
+			t.Fatalf("job %s failed: %v", job.Name, err)
+		}
+	}
+
+	if window := time.Since(cancelBefore); window < 72*time.Hour || window > 73*time.Hour {
+		t.Errorf("expected orders placed over 72h ago to be cancelled; got cutoff %v ago", window)
+	}
+	if got := notified[database.NotificationOrderStatusChanged]; len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Errorf("expected buyer and seller of the cancelled order to be notified; got %v", got)
+	}
+	if got := notified[database.NotificationShippingReminder]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("expected the seller to be reminded; got %v", got)
+	}
+}
@@ -0,0 +1,34 @@
+package server
+
+import (
+	"log"
+
+	"cardmarket_backend/internal/mailer"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sendEmail queues a templated email for a user in their preferred language.
+// Failures are logged and never fail the request that triggered the email.
+func (s *FiberServer) sendEmail(userID int, template string, data fiber.Map) {
+	if s.mailer == nil {
+		return
+	}
+
+	contact, err := s.db.GetUserContact(userID)
+	if err != nil {
+		log.Printf("failed to load contact of user %d for %s email: %v", userID, template, err)
+		return
+	}
+
+	data["FirstName"] = contact.FirstName
+	err = s.mailer.Enqueue(mailer.Email{
+		To:       contact.Email,
+		Language: contact.LanguageCode,
+		Template: template,
+		Data:     data,
+	})
+	if err != nil {
+		log.Printf("failed to queue %s email for user %d: %v", template, userID, err)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/mailer"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateOrderHandlerSendsConfirmation(t *testing.T) {
+	mockDB := MockDBService{
+		CreateOrderFunc: func(order database.OrderRequest) (int, error) {
+			return 42, nil
+		},
+		GetOrderByIDFunc: func(orderID int) (database.Order, error) {
+			return database.Order{OrderID: orderID, Total: currency.New(2508500, "EUR")}, nil
+		},
+		GetProductByIDFunc: func(productID int) (database.Product, error) {
+			return database.Product{ProductID: productID, Card: "Black Lotus"}, nil
+		},
+		GetUserContactFunc: func(userID int) (database.UserContact, error) {
+			return database.UserContact{UserID: userID, Email: "collector@example.com", FirstName: "Marie", LanguageCode: "FR"}, nil
+		},
+	}
+	sender := &mailer.MemorySender{}
+	dispatcher := mailer.NewDispatcher(sender, 1)
+	dispatcher.Start(1)
+
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB, mailer: dispatcher}
+	app.Post("/api/orders", requireUser, s.CreateOrderHandler)
+
+	orderBytes, err := json.Marshal(database.OrderRequest{BuyerID: 2, SellerID: 1, ProductID: 1, Quantity: 1, ShippingMethodID: 1})
+	if err != nil {
+		t.Fatalf("error marshalling order request. Err: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", "/api/orders", strings.NewReader(string(orderBytes)))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	dispatcher.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status Created; got %v", resp.Status)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("expected one email; got %d", len(messages))
+	}
+	if messages[0].To != "collector@example.com" {
+		t.Errorf("expected email to the buyer; got %v", messages[0].To)
+	}
+	if messages[0].Subject != "Votre commande n°42 a été passée" {
+		t.Errorf("expected french confirmation subject; got %v", messages[0].Subject)
+	}
+	if !strings.Contains(messages[0].Body, "Black Lotus") || !strings.Contains(messages[0].Body, "25085.00") {
+		t.Errorf("expected order details in body; got %v", messages[0].Body)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListConversationsHandler(c *fiber.Ctx) error {
+	conversations, err := s.db.ListConversations(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch conversations",
+		})
+	}
+	return c.JSON(fiber.Map{"conversations": conversations})
+}
+
+func (s *FiberServer) CreateConversationHandler(c *fiber.Ctx) error {
+	var conversation database.ConversationRequest
+	if err := c.BodyParser(&conversation); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(conversation.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Message body is required",
+		})
+	}
+
+	conversationID, err := s.db.CreateConversation(currentUserID(c), conversation)
+	switch {
+	case err == nil:
+		s.notify(conversation.RecipientID, database.NotificationNewMessage, fiber.Map{
+			"conversation_id": conversationID,
+			"sender_id":       currentUserID(c),
+		})
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message":         "conversation created",
+			"conversation_id": conversationID,
+		})
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order or product not found",
+		})
+	case errors.Is(err, database.ErrInvalidRecipient), errors.Is(err, database.ErrNotOrderParticipant):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid recipient for this conversation",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create conversation",
+		})
+	}
+}
+
+func (s *FiberServer) ListMessagesHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	conversationID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid conversation ID",
+		})
+	}
+
+	messages, err := s.db.ListMessages(conversationID, currentUserID(c))
+	if err != nil {
+		return conversationError(c, err, "Failed to fetch messages")
+	}
+	return c.JSON(fiber.Map{"messages": messages})
+}
+
+func (s *FiberServer) CreateMessageHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	conversationID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid conversation ID",
+		})
+	}
+
+	var message database.MessageRequest
+	if err := c.BodyParser(&message); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if strings.TrimSpace(message.Body) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Message body is required",
+		})
+	}
+
+	recipientID, err := s.db.CreateMessage(conversationID, currentUserID(c), message)
+	if err != nil {
+		return conversationError(c, err, "Failed to send message")
+	}
+
+	s.notify(recipientID, database.NotificationNewMessage, fiber.Map{
+		"conversation_id": conversationID,
+		"sender_id":       currentUserID(c),
+	})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "message sent"})
+}
+
+// conversationError maps errors from the conversation queries to a response.
+func conversationError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Conversation not found",
+		})
+	case errors.Is(err, database.ErrNotConversationParticipant):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You are not a participant of this conversation",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fallback,
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestListConversationsHandler(t *testing.T) {
+	conversations := []database.Conversation{
+		{ConversationID: 1, UserOne: "magicdealer", UserTwo: "cardcollector", UnreadCount: 2, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	var requestedUserID int
+	mockDB := MockDBService{
+		ListConversationsFunc: func(userID int) ([]database.Conversation, error) {
+			requestedUserID = userID
+			return conversations, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/conversations", requireUser, s.ListConversationsHandler)
+
+	req, err := http.NewRequest("GET", "/api/conversations", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if requestedUserID != 2 {
+		t.Errorf("expected conversations of user 2; got %v", requestedUserID)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response body. Err: %v", err)
+	}
+	bytes, err := json.Marshal(conversations)
+	if err != nil {
+		t.Fatalf("error marshalling expected conversations. Err: %v", err)
+	}
+
+	expected := "{\"conversations\":" + string(bytes) + "}"
+	if expected != string(body) {
+		t.Errorf("expected response body to be %v; got %v", expected, string(body))
+	}
+}
+
+func TestListConversationsHandlerRequiresUser(t *testing.T) {
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &MockDBService{}}
+	app.Get("/api/conversations", requireUser, s.ListConversationsHandler)
+
+	req, err := http.NewRequest("GET", "/api/conversations", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected status Unauthorized; got %v", resp.Status)
+	}
+}
+
+func TestListMessagesHandlerForbidden(t *testing.T) {
+	mockDB := MockDBService{
+		ListMessagesFunc: func(conversationID int, userID int) ([]database.Message, error) {
+			return nil, database.ErrNotConversationParticipant
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/conversations/:id/messages", requireUser, s.ListMessagesHandler)
+
+	req, err := http.NewRequest("GET", "/api/conversations/1/messages", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	setSession(t, req, 5)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", resp.Status)
+	}
+}
+
+func TestCreateMessageHandler(t *testing.T) {
+	var sentBy int
+	mockDB := MockDBService{
+		CreateMessageFunc: func(conversationID int, senderID int, message database.MessageRequest) (int, error) {
+			sentBy = senderID
+			return 1, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/conversations/:id/messages", requireUser, s.CreateMessageHandler)
+
+	req, err := http.NewRequest("POST", "/api/conversations/1/messages", strings.NewReader(`{"body":"Is the card still sealed?"}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 3)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("expected status Created; got %v", resp.Status)
+	}
+	if sentBy != 3 {
+		t.Errorf("expected message to be sent by user 3; got %v", sentBy)
+	}
+}
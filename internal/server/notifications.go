@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// streamHeartbeatInterval keeps idle event streams open through proxies and
+// detects disconnected clients.
+const streamHeartbeatInterval = 30 * time.Second
+
+// notify records an event for a user. The hub of every server process picks it
+// up from the database and pushes it to the user's connected clients.
+// Failures are logged and never fail the request that triggered the event.
+func (s *FiberServer) notify(userID int, notificationType string, payload fiber.Map) {
+	if _, err := s.db.CreateNotification(userID, notificationType, payload); err != nil {
+		log.Printf("failed to create %s notification for user %d: %v", notificationType, userID, err)
+	}
+}
+
+func (s *FiberServer) ListNotificationsHandler(c *fiber.Ctx) error {
+	notifications, err := s.db.ListNotifications(currentUserID(c), c.QueryBool("unread"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch notifications",
+		})
+	}
+	return c.JSON(fiber.Map{"notifications": notifications})
+}
+
+func (s *FiberServer) MarkNotificationReadHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	notificationID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid notification ID",
+		})
+	}
+
+	err = s.db.MarkNotificationRead(notificationID, currentUserID(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Notification not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notification",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "notification marked as read"})
+}
+
+func (s *FiberServer) MarkAllNotificationsReadHandler(c *fiber.Ctx) error {
+	if err := s.db.MarkAllNotificationsRead(currentUserID(c)); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update notifications",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "notifications marked as read"})
+}
+
+// NotificationStreamHandler pushes new notifications of the current user as
+// Server-Sent Events until the client disconnects.
+func (s *FiberServer) NotificationStreamHandler(c *fiber.Ctx) error {
+	userID := currentUserID(c)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		events, unsubscribe := s.notifications.Subscribe(userID)
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(streamHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		// Flush the headers right away so clients see the stream as open.
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case notification := <-events:
+				data, err := json.Marshal(notification)
+				if err != nil {
+					log.Printf("failed to encode notification %d: %v", notification.NotificationID, err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", notification.NotificationID, notification.Type, data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
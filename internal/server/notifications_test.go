@@ -0,0 +1,116 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestListNotificationsHandler(t *testing.T) {
+	var unread bool
+	mockDB := MockDBService{
+		ListNotificationsFunc: func(userID int, unreadOnly bool) ([]database.Notification, error) {
+			unread = unreadOnly
+			return []database.Notification{}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/notifications", requireUser, s.ListNotificationsHandler)
+
+	req, err := http.NewRequest("GET", "/api/notifications?unread=true", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	setSession(t, req, 1)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if !unread {
+		t.Errorf("expected only unread notifications to be requested")
+	}
+}
+
+func TestMarkNotificationReadHandlerNotFound(t *testing.T) {
+	mockDB := MockDBService{
+		MarkNotificationReadFunc: func(notificationID int, userID int) error {
+			return sql.ErrNoRows
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Put("/api/notifications/:id/read", requireUser, s.MarkNotificationReadHandler)
+
+	req, err := http.NewRequest("PUT", "/api/notifications/3/read", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	setSession(t, req, 1)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected status Not Found; got %v", resp.Status)
+	}
+}
+
+func TestUpdateOrderHandlerNotifiesStatusChange(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		UpdateOrderFunc: func(orderID int, userID int, order database.OrderRequest) (database.OrderChange, error) {
+			return database.OrderChange{
+				OrderSummary:   database.OrderSummary{OrderID: orderID, BuyerID: 5, SellerID: 1},
+				PreviousStatus: "processing",
+				Status:         order.Status,
+			}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			if notificationType != database.NotificationOrderStatusChanged {
+				t.Errorf("expected %v notification; got %v", database.NotificationOrderStatusChanged, notificationType)
+			}
+			notified = append(notified, userID)
+			return database.Notification{NotificationID: len(notified), UserID: userID, Type: notificationType}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Put("/api/orders/:id", requireUser, s.UpdateOrderHandler)
+
+	orderBytes, err := json.Marshal(database.OrderRequest{Status: "completed"})
+	if err != nil {
+		t.Fatalf("error marshalling order request. Err: %v", err)
+	}
+
+	req, err := http.NewRequest("PUT", "/api/orders/1", strings.NewReader(string(orderBytes)))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 5)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if len(notified) != 2 || notified[0] != 5 || notified[1] != 1 {
+		t.Errorf("expected buyer and seller to be notified; got %v", notified)
+	}
+}
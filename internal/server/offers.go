@@ -0,0 +1,217 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListOffersHandler(c *fiber.Ctx) error {
+	offers, err := s.db.ListUserOffers(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch offers",
+		})
+	}
+	return c.JSON(fiber.Map{"offers": offers})
+}
+
+// CreateOfferHandler makes an offer of the current user on a product.
+func (s *FiberServer) CreateOfferHandler(c *fiber.Ctx) error {
+	productID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	var req database.OfferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Quantity <= 0 || req.ShippingMethodID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Quantity and shipping method are required",
+		})
+	}
+
+	offer, err := s.db.CreateOffer(productID, currentUserID(c), req)
+	if err != nil {
+		return offerError(c, err)
+	}
+
+	s.notifyOffer(offer.RecipientID(), database.NotificationOfferReceived, offer)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"offer": offer})
+}
+
+// AcceptOfferHandler accepts an offer made to the current user. The order is
+// placed at the offered price.
+func (s *FiberServer) AcceptOfferHandler(c *fiber.Ctx) error {
+	offerID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid offer ID",
+		})
+	}
+
+	offer, err := s.db.AcceptOffer(offerID, currentUserID(c))
+	if err != nil {
+		return offerError(c, err)
+	}
+
+	s.notifyOffer(offerMaker(offer), database.NotificationOfferUpdated, offer)
+	s.announceOrder(*offer.OrderID, database.OrderRequest{
+		BuyerID:   offer.BuyerID,
+		SellerID:  offer.SellerID,
+		ProductID: offer.ProductID,
+		Quantity:  offer.Quantity,
+	})
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"offer": offer})
+}
+
+func (s *FiberServer) DeclineOfferHandler(c *fiber.Ctx) error {
+	offerID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid offer ID",
+		})
+	}
+
+	offer, err := s.db.DeclineOffer(offerID, currentUserID(c))
+	if err != nil {
+		return offerError(c, err)
+	}
+
+	s.notifyOffer(offerMaker(offer), database.NotificationOfferUpdated, offer)
+	return c.JSON(fiber.Map{"offer": offer})
+}
+
+// CounterOfferHandler answers an offer made to the current user with another
+// price.
+func (s *FiberServer) CounterOfferHandler(c *fiber.Ctx) error {
+	offerID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid offer ID",
+		})
+	}
+
+	var req database.CounterOfferRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Quantity < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid quantity",
+		})
+	}
+
+	counter, err := s.db.CounterOffer(offerID, currentUserID(c), req)
+	if err != nil {
+		return offerError(c, err)
+	}
+
+	s.notifyOffer(counter.RecipientID(), database.NotificationOfferReceived, counter)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"offer": counter})
+}
+
+// offerError maps the errors of the offer endpoints to responses.
+func offerError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Offer or product not found",
+		})
+	case errors.Is(err, database.ErrNotOfferRecipient):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "This offer was not made to you",
+		})
+	case errors.Is(err, database.ErrOwnProduct):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You cannot make an offer on your own product",
+		})
+	case errors.Is(err, database.ErrOfferClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Offer is no longer open",
+		})
+	case errors.Is(err, database.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Not enough items in stock",
+		})
+	case errors.Is(err, database.ErrProductUnavailable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is no longer for sale",
+		})
+	case errors.Is(err, database.ErrSellerOnVacation):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Seller is on vacation",
+		})
+	case errors.Is(err, database.ErrInvalidOffer):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offers must be below the asking price and expire within 7 days",
+		})
+	case errors.Is(err, currency.ErrCurrencyMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Offers must be in the currency of the product",
+		})
+	case errors.Is(err, database.ErrAddressNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping address not found",
+		})
+	case errors.Is(err, database.ErrShippingMethodUnavailable):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping method not available for this order",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process offer",
+		})
+	}
+}
+
+// offerMaker returns the user who made the offer.
+func offerMaker(offer database.Offer) int {
+	if offer.RecipientID() == offer.SellerID {
+		return offer.BuyerID
+	}
+	return offer.SellerID
+}
+
+func (s *FiberServer) notifyOffer(userID int, notificationType string, offer database.Offer) {
+	payload := fiber.Map{
+		"offer_id":   offer.OfferID,
+		"product_id": offer.ProductID,
+		"amount":     offer.Amount,
+		"quantity":   offer.Quantity,
+		"status":     offer.Status,
+	}
+	if offer.OrderID != nil {
+		payload["order_id"] = *offer.OrderID
+	}
+	s.notify(userID, notificationType, payload)
+}
+
+// expireOffersJob closes expired offers and tells whoever made them.
+func (s *FiberServer) expireOffersJob() scheduler.Job {
+	return scheduler.Job{Name: "expire_offers", Run: func(ctx context.Context) error {
+		offers, err := s.db.ExpireOffers(time.Now())
+		if err != nil {
+			return err
+		}
+		for _, offer := range offers {
+			s.notifyOffer(offerMaker(offer), database.NotificationOfferUpdated, offer)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,195 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateOfferHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		err            error
+		expectedStatus int
+	}{
+		{"created", `{"amount":{"amount":"8.00","currency":"EUR"},"quantity":2,"shipping_method_id":1}`, nil, http.StatusCreated},
+		{"not below asking price", `{"amount":{"amount":"12.00","currency":"EUR"},"quantity":2,"shipping_method_id":1}`, database.ErrInvalidOffer, http.StatusBadRequest},
+		{"own product", `{"amount":{"amount":"8.00","currency":"EUR"},"quantity":2,"shipping_method_id":1}`, database.ErrOwnProduct, http.StatusForbidden},
+		{"more than in stock", `{"amount":{"amount":"8.00","currency":"EUR"},"quantity":20,"shipping_method_id":1}`, database.ErrInsufficientStock, http.StatusConflict},
+		{"unknown product", `{"amount":{"amount":"8.00","currency":"EUR"},"quantity":2,"shipping_method_id":1}`, sql.ErrNoRows, http.StatusNotFound},
+		{"missing quantity", `{"amount":{"amount":"8.00","currency":"EUR"},"shipping_method_id":1}`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				buyerID  int
+				received database.OfferRequest
+				notified []int
+			)
+			mockDB := MockDBService{
+				CreateOfferFunc: func(productID int, id int, req database.OfferRequest) (database.Offer, error) {
+					buyerID, received = id, req
+					if tt.err != nil {
+						return database.Offer{}, tt.err
+					}
+					return database.Offer{OfferID: 1, ProductID: productID, BuyerID: id, SellerID: 1, OfferedBy: "buyer", Amount: req.Amount, Quantity: req.Quantity, Status: database.OfferStatusPending}, nil
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					if notificationType == database.NotificationOfferReceived {
+						notified = append(notified, userID)
+					}
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/products/:id/offers", requireUser, s.CreateOfferHandler)
+
+			req, err := http.NewRequest("POST", "/api/products/3/offers", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusBadRequest && tt.err == nil {
+				return
+			}
+			if buyerID != 2 || received.ShippingMethodID != 1 || received.Amount.Currency != "EUR" {
+				t.Errorf("unexpected offer %+v by user %v", received, buyerID)
+			}
+			if wantNotified := tt.err == nil; wantNotified != (len(notified) == 1 && notified[0] == 1) {
+				t.Errorf("expected seller notification %v; got %v", wantNotified, notified)
+			}
+		})
+	}
+}
+
+func TestRespondToOfferHandlers(t *testing.T) {
+	orderID := 12
+	accepted := database.Offer{OfferID: 4, ProductID: 3, BuyerID: 2, SellerID: 1, OfferedBy: "buyer", Amount: currency.New(800, "EUR"), Quantity: 2, Status: database.OfferStatusAccepted, OrderID: &orderID}
+	counter := database.Offer{OfferID: 5, ProductID: 3, BuyerID: 2, SellerID: 1, OfferedBy: "seller", Amount: currency.New(900, "EUR"), Quantity: 2, Status: database.OfferStatusPending}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		err            error
+		expectedStatus int
+		notification   string
+		notifiedUser   int
+	}{
+		{"accept", "/api/offers/4/accept", "", nil, http.StatusCreated, database.NotificationOrderCreated, 1},
+		{"decline", "/api/offers/4/decline", "", nil, http.StatusOK, database.NotificationOfferUpdated, 2},
+		{"counter", "/api/offers/4/counter", `{"amount":{"amount":"9.00","currency":"EUR"}}`, nil, http.StatusCreated, database.NotificationOfferReceived, 2},
+		{"not the recipient", "/api/offers/4/accept", "", database.ErrNotOfferRecipient, http.StatusForbidden, "", 0},
+		{"already answered", "/api/offers/4/decline", "", database.ErrOfferClosed, http.StatusConflict, "", 0},
+		{"sold out meanwhile", "/api/offers/4/accept", "", database.ErrInsufficientStock, http.StatusConflict, "", 0},
+		{"delisted meanwhile", "/api/offers/4/accept", "", database.ErrProductUnavailable, http.StatusConflict, "", 0},
+		{"counter above asking price", "/api/offers/4/counter", `{"amount":{"amount":"15.00","currency":"EUR"}}`, database.ErrInvalidOffer, http.StatusBadRequest, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				respondent int
+				notified   = map[string][]int{}
+			)
+			mockDB := MockDBService{
+				AcceptOfferFunc: func(offerID int, userID int) (database.Offer, error) {
+					respondent = userID
+					return accepted, tt.err
+				},
+				DeclineOfferFunc: func(offerID int, userID int) (database.Offer, error) {
+					respondent = userID
+					declined := accepted
+					declined.Status, declined.OrderID = database.OfferStatusDeclined, nil
+					return declined, tt.err
+				},
+				CounterOfferFunc: func(offerID int, userID int, req database.CounterOfferRequest) (database.Offer, error) {
+					respondent = userID
+					return counter, tt.err
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					notified[notificationType] = append(notified[notificationType], userID)
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			offers := app.Group("/api/offers", requireUser)
+			offers.Post("/:id/accept", s.AcceptOfferHandler)
+			offers.Post("/:id/decline", s.DeclineOfferHandler)
+			offers.Post("/:id/counter", s.CounterOfferHandler)
+
+			req, err := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 1)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if respondent != 1 {
+				t.Errorf("expected user 1 to respond; got %v", respondent)
+			}
+			if tt.notification == "" {
+				if len(notified) != 0 {
+					t.Errorf("expected no notifications; got %v", notified)
+				}
+				return
+			}
+			if got := notified[tt.notification]; len(got) != 1 || got[0] != tt.notifiedUser {
+				t.Errorf("expected %s notification for user %d; got %v", tt.notification, tt.notifiedUser, got)
+			}
+		})
+	}
+}
+
+func TestExpireOffersJob(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		ExpireOffersFunc: func(now time.Time) ([]database.Offer, error) {
+			return []database.Offer{
+				{OfferID: 1, BuyerID: 2, SellerID: 1, OfferedBy: "buyer", Status: database.OfferStatusExpired},
+				{OfferID: 2, BuyerID: 3, SellerID: 1, OfferedBy: "seller", Status: database.OfferStatusExpired},
+			}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			notified = append(notified, userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	if err := s.expireOffersJob().Run(context.Background()); err != nil {
+		t.Fatalf("job failed: %v", err)
+	}
+	if len(notified) != 2 || notified[0] != 2 || notified[1] != 1 {
+		t.Errorf("expected the makers of the offers to be notified; got %v", notified)
+	}
+}
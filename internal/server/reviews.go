@@ -0,0 +1,113 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListOrderReviewsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	orderID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	reviews, err := s.db.ListOrderReviews(orderID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+	return c.JSON(fiber.Map{"reviews": reviews})
+}
+
+func (s *FiberServer) ListUserReviewsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	reviews, err := s.db.ListUserReviews(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+	return c.JSON(fiber.Map{"reviews": reviews})
+}
+
+func (s *FiberServer) CreateReviewHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	orderID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid order ID",
+		})
+	}
+
+	var review database.ReviewRequest
+	if err := c.BodyParser(&review); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	for _, rating := range []int{review.Overall, review.ItemDescription, review.Packaging, review.ShippingSpeed} {
+		if rating < 1 || rating > 5 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Ratings must be between 1 and 5",
+			})
+		}
+	}
+
+	revieweeID, err := s.db.CreateReview(orderID, currentUserID(c), review)
+	switch {
+	case err == nil:
+		s.notify(revieweeID, database.NotificationReviewReceived, fiber.Map{
+			"order_id": orderID,
+			"overall":  review.Overall,
+		})
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "review created"})
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	case errors.Is(err, database.ErrNotOrderParticipant):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only the buyer or seller can review this order",
+		})
+	case errors.Is(err, database.ErrOrderNotReviewable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Order must be delivered or completed before it can be reviewed",
+		})
+	case errors.Is(err, database.ErrAlreadyReviewed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Order already reviewed",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create review",
+		})
+	}
+}
+
+// refreshReputationsJob keeps the cached reputations current as reviews age
+// out of the 12-month window.
+func (s *FiberServer) refreshReputationsJob() scheduler.Job {
+	return scheduler.Job{Name: "refresh_reputations", Run: func(ctx context.Context) error {
+		_, err := s.db.RefreshReputations(time.Now())
+		return err
+	}}
+}
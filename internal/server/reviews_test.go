@@ -0,0 +1,158 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestListUserReviewsHandler(t *testing.T) {
+	reviews := []database.Review{
+		{ReviewID: 1, OrderID: 1, Reviewer: "cardcollector", Reviewee: "magicdealer", Overall: 5, ItemDescription: 5, Packaging: 4, ShippingSpeed: 5, Comment: "Great seller", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	mockDB := MockDBService{
+		ListUserReviewsFunc: func(userID int) ([]database.Review, error) {
+			return reviews, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/users/:id/reviews", s.ListUserReviewsHandler)
+
+	req, err := http.NewRequest("GET", "/api/users/1/reviews", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response body. Err: %v", err)
+	}
+	bytes, err := json.Marshal(reviews)
+	if err != nil {
+		t.Fatalf("error marshalling expected reviews. Err: %v", err)
+	}
+
+	expected := "{\"reviews\":" + string(bytes) + "}"
+	if expected != string(body) {
+		t.Errorf("expected response body to be %v; got %v", expected, string(body))
+	}
+}
+
+func TestCreateReviewHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		userID         int
+		review         database.ReviewRequest
+		dbErr          error
+		expectedStatus int
+	}{
+		{
+			name:           "created",
+			userID:         2,
+			review:         database.ReviewRequest{Overall: 5, ItemDescription: 5, Packaging: 5, ShippingSpeed: 4, Comment: "Fast shipping"},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "anonymous",
+			review:         database.ReviewRequest{Overall: 5, ItemDescription: 5, Packaging: 5, ShippingSpeed: 4},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "rating out of range",
+			userID:         2,
+			review:         database.ReviewRequest{Overall: 6, ItemDescription: 5, Packaging: 5, ShippingSpeed: 4},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not a participant",
+			userID:         9,
+			review:         database.ReviewRequest{Overall: 5, ItemDescription: 5, Packaging: 5, ShippingSpeed: 5},
+			dbErr:          database.ErrNotOrderParticipant,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "order not delivered",
+			userID:         2,
+			review:         database.ReviewRequest{Overall: 5, ItemDescription: 5, Packaging: 5, ShippingSpeed: 5},
+			dbErr:          database.ErrOrderNotReviewable,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reviewer int
+			mockDB := MockDBService{
+				CreateReviewFunc: func(orderID int, reviewerID int, review database.ReviewRequest) (int, error) {
+					reviewer = reviewerID
+					return 1, tt.dbErr
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/orders/:id/reviews", requireUser, s.CreateReviewHandler)
+
+			reviewBytes, err := json.Marshal(tt.review)
+			if err != nil {
+				t.Fatalf("error marshalling review request. Err: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/api/orders/1/reviews", strings.NewReader(string(reviewBytes)))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.userID != 0 {
+				setSession(t, req, tt.userID)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusCreated && reviewer != 2 {
+				t.Errorf("expected the review to be written by the current user; got reviewer %v", reviewer)
+			}
+		})
+	}
+}
+
+func TestRefreshReputationsJob(t *testing.T) {
+	now := time.Now()
+	var refreshedAt time.Time
+	mockDB := MockDBService{
+		RefreshReputationsFunc: func(at time.Time) (int, error) {
+			refreshedAt = at
+			return 2, nil
+		},
+	}
+	s := &FiberServer{db: &mockDB}
+
+	if err := s.refreshReputationsJob().Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if refreshedAt.Before(now) {
+		t.Errorf("expected reputations to be refreshed as of now; got %v", refreshedAt)
+	}
+}
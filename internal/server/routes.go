@@ -1,8 +1,14 @@
 package server
 
 import (
+	"cardmarket_backend/internal/currency"
 	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/mailer"
+	"cardmarket_backend/internal/storage"
+	"database/sql"
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -42,30 +48,144 @@ func (s *FiberServer) RegisterFiberRoutes() {
 
 	s.App.Get("/health", s.healthHandler)
 
+	if local, ok := s.storage.(*storage.LocalStorage); ok {
+		s.App.Static(local.URLPrefix, local.Dir)
+	}
+
 	api := s.App.Group("/api")
+	api.Post("/auth/login", s.LoginHandler)
+	api.Post("/auth/verify", s.VerifyEmailHandler)
+	api.Post("/auth/forgot-password", s.ForgotPasswordHandler)
+	api.Post("/auth/reset-password", s.ResetPasswordHandler)
+
 	api.Get("/cards", s.listCardsHandler)
 	api.Post("/cards", s.createCardHandler)
 	api.Get("/cards/:id", s.getCardByIDHandler)
+	api.Get("/cards/:id/overview", s.getCardOverviewHandler)
 	api.Put("/cards/:id", s.updateCardHandler)
 	api.Delete("/cards/:id", s.deleteCardHandler)
 
+	api.Get("/sealed-products", s.ListSealedProductsHandler)
+	api.Get("/sealed-products/:id", s.GetSealedProductHandler)
+
 	api.Get("/products", s.ListProductsHandler)
-	api.Post("/products", s.CreateProductHandler)
+	api.Post("/products", requireUser, s.CreateProductHandler)
 	api.Get("/products/:id", s.GetProductByIDHandler)
-	api.Put("/products/:id", s.UpdateProductHandler)
+	api.Get("/products/:id/shipping", s.QuoteShippingHandler)
+	api.Get("/products/:id/images", s.ListProductImagesHandler)
+	api.Post("/products/:id/images", requireUser, s.UploadProductImageHandler)
+	api.Put("/products/:id/images", requireUser, s.ReorderProductImagesHandler)
+	api.Delete("/products/:id/images/:imageID", requireUser, s.DeleteProductImageHandler)
+	api.Post("/products/:id/offers", requireUser, s.CreateOfferHandler)
+	api.Put("/products/:id", requireUser, s.UpdateProductHandler)
 	api.Delete("/products/:id", s.DeleteProductHandler)
 
 	api.Get("/orders", s.ListOrdersHandler)
 	api.Get("/orders/:id", s.GetOrderByIDHandler)
-	api.Post("/orders", s.CreateOrderHandler)
-	api.Put("/orders/:id", s.UpdateOrderHandler)
+	api.Post("/orders", requireUser, s.CreateOrderHandler)
+	api.Put("/orders/:id", requireUser, s.UpdateOrderHandler)
 	api.Delete("/orders/:id", s.DeleteOrderHandler)
+	api.Get("/orders/:id/reviews", s.ListOrderReviewsHandler)
+	api.Post("/orders/:id/reviews", requireUser, s.CreateReviewHandler)
+	api.Post("/orders/:id/disputes", requireUser, s.CreateDisputeHandler)
+	api.Get("/orders/:id/invoice.pdf", requireUser, s.GetInvoiceHandler)
 
 	api.Get("/users", s.ListUsersHandler)
 	api.Post("/users", s.CreateUserHandler)
 	api.Get("/users/:id", s.GetUserByIDHandler)
-	api.Put("/users/:id", s.UpdateUserHandler)
+	api.Put("/users/:id", requireUser, s.UpdateUserHandler)
 	api.Delete("/users/:id", s.DeleteUserHandler)
+	api.Get("/users/:id/reviews", s.ListUserReviewsHandler)
+	api.Get("/users/:id/shipping-methods", s.ListSellerShippingMethodsHandler)
+	api.Put("/users/:id/shipping-methods", requireUser, s.SetSellerShippingMethodsHandler)
+	api.Put("/users/:id/vacation", requireUser, s.SetVacationHandler)
+
+	api.Get("/sellers/:username", s.GetSellerHandler)
+	api.Get("/sellers/:username/products", s.ListSellerProductsHandler)
+
+	api.Get("/shipping-methods", s.ListShippingMethodsHandler)
+
+	api.Get("/auctions", s.ListAuctionsHandler)
+	api.Get("/auctions/:id", s.GetAuctionHandler)
+	api.Post("/auctions", requireUser, s.CreateAuctionHandler)
+	api.Delete("/auctions/:id", requireUser, s.CancelAuctionHandler)
+	api.Post("/auctions/:id/bids", requireUser, s.PlaceBidHandler)
+
+	offers := api.Group("/offers", requireUser)
+	offers.Get("/", s.ListOffersHandler)
+	offers.Post("/:id/accept", s.AcceptOfferHandler)
+	offers.Post("/:id/decline", s.DeclineOfferHandler)
+	offers.Post("/:id/counter", s.CounterOfferHandler)
+
+	trades := api.Group("/trades", requireUser)
+	trades.Get("/", s.ListTradesHandler)
+	trades.Post("/", s.ProposeTradeHandler)
+	trades.Get("/:id", s.GetTradeHandler)
+	trades.Post("/:id/accept", s.AcceptTradeHandler)
+	trades.Post("/:id/decline", s.DeclineTradeHandler)
+	trades.Post("/:id/counter", s.CounterTradeHandler)
+	trades.Post("/:id/pay", s.PayTradeCashHandler)
+	trades.Post("/:id/ship", s.ShipTradeHandler)
+	trades.Post("/:id/receive", s.ReceiveTradeHandler)
+
+	api.Post("/decklists/parse", s.ParseDeckListHandler)
+	api.Post("/decklists/wantlist", requireUser, s.ImportDeckListWantlistHandler)
+	api.Post("/decklists/cart", requireUser, s.ImportDeckListCartHandler)
+
+	wantlists := api.Group("/wantlists", requireUser)
+	wantlists.Get("/", s.ListWantlistsHandler)
+	wantlists.Get("/:id", s.GetWantlistHandler)
+	wantlists.Delete("/:id", s.DeleteWantlistHandler)
+
+	cart := api.Group("/cart", requireUser)
+	cart.Get("/", s.GetCartHandler)
+	cart.Delete("/:id", s.RemoveCartItemHandler)
+
+	watchlist := api.Group("/watchlist", requireUser)
+	watchlist.Get("/", s.ListWatchlistHandler)
+	watchlist.Post("/", s.AddWatchlistEntryHandler)
+	watchlist.Delete("/:id", s.RemoveWatchlistEntryHandler)
+
+	shipments := api.Group("/shipments", requireUser)
+	shipments.Post("/labels.pdf", s.ShippingLabelsHandler)
+	shipments.Post("/packing-slips.pdf", s.PackingSlipsHandler)
+
+	addresses := api.Group("/addresses", requireUser)
+	addresses.Get("/", s.ListAddressesHandler)
+	addresses.Post("/", s.CreateAddressHandler)
+	addresses.Put("/:id", s.UpdateAddressHandler)
+	addresses.Delete("/:id", s.DeleteAddressHandler)
+
+	disputes := api.Group("/disputes", requireUser)
+	disputes.Get("/", s.ListDisputesHandler)
+	disputes.Get("/:id", s.GetDisputeHandler)
+	disputes.Post("/:id/messages", s.CreateDisputeMessageHandler)
+	disputes.Post("/:id/evidence", s.AddDisputeEvidenceHandler)
+	disputes.Post("/:id/escalate", s.EscalateDisputeHandler)
+	disputes.Post("/:id/refund", s.RefundDisputeHandler)
+
+	admin := api.Group("/admin", requireUser, s.requireAdmin)
+	admin.Get("/disputes", s.ListAdminDisputesHandler)
+	admin.Post("/disputes/:id/resolve", s.ResolveDisputeHandler)
+	admin.Get("/tax-rates", s.ListTaxRatesHandler)
+	admin.Post("/tax-rates", s.CreateTaxRateHandler)
+	admin.Post("/sealed-products", s.CreateSealedProductHandler)
+	admin.Put("/sealed-products/:id", s.UpdateSealedProductHandler)
+	admin.Delete("/sealed-products/:id", s.DeleteSealedProductHandler)
+
+	conversations := api.Group("/conversations", requireUser)
+	conversations.Get("/", s.ListConversationsHandler)
+	conversations.Post("/", s.CreateConversationHandler)
+	conversations.Get("/:id/messages", s.ListMessagesHandler)
+	conversations.Post("/:id/messages", s.CreateMessageHandler)
+
+	// The stream is registered ahead of the group so that the token may come
+	// from the query string.
+	api.Get("/notifications/stream", requireStreamUser, s.NotificationStreamHandler)
+	notifications := api.Group("/notifications", requireUser)
+	notifications.Get("/", s.ListNotificationsHandler)
+	notifications.Put("/read", s.MarkAllNotificationsReadHandler)
+	notifications.Put("/:id/read", s.MarkNotificationReadHandler)
 
 }
 
@@ -123,6 +243,42 @@ func (s *FiberServer) getCardByIDHandler(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"card": card})
 }
 
+// getCardOverviewHandler returns everything the card page shows in one
+// response: the card and its set, the listings grouped by condition and
+// language, the price guide and the sellers' reputations.
+func (s *FiberServer) getCardOverviewHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	cardID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid card ID",
+		})
+	}
+	code, rates, err := s.displayRates(c)
+	if err != nil {
+		return currencyError(c, err)
+	}
+
+	overview, err := s.db.GetCardOverview(cardID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Card not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch card overview",
+		})
+	}
+
+	if code != "" {
+		if err := convertCardOverview(&overview, rates, code); err != nil {
+			return currencyError(c, err)
+		}
+	}
+	return c.JSON(fiber.Map{"overview": overview})
+}
+
 func (s *FiberServer) updateCardHandler(c *fiber.Ctx) error {
 	var card database.CardRequest
 	id := c.Params("id")
@@ -161,16 +317,77 @@ func (s *FiberServer) deleteCardHandler(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "card deleted"})
 }
 
+// ListProductsHandler lists products, filtered by the query parameters
+// described at productFilter.
 func (s *FiberServer) ListProductsHandler(c *fiber.Ctx) error {
-	products, err := s.db.ListProducts()
+	filter, err := productFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product filter",
+		})
+	}
+	return s.listProducts(c, filter)
+}
+
+// listProducts responds with the products matching the filter, with prices
+// converted to the currency requested by the client.
+func (s *FiberServer) listProducts(c *fiber.Ctx, filter database.ProductFilter) error {
+	code, rates, err := s.displayRates(c)
+	if err != nil {
+		return currencyError(c, err)
+	}
+
+	products, err := s.db.ListProducts(filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch products",
 		})
 	}
+
+	if code != "" {
+		for i := range products {
+			if err := convertProduct(&products[i], rates, code); err != nil {
+				return currencyError(c, err)
+			}
+		}
+	}
 	return c.JSON(fiber.Map{"products": products})
 }
 
+// errInvalidFilter is returned by productFilter for malformed parameters.
+var errInvalidFilter = errors.New("invalid product filter")
+
+// productFilter reads the product filters from the query parameters
+// tcg_game_id, card_id, sealed_product_id, condition, language_id and type
+// ("single" or "sealed").
+func productFilter(c *fiber.Ctx) (database.ProductFilter, error) {
+	filter := database.ProductFilter{
+		Condition: c.Query("condition"),
+		Kind:      c.Query("type"),
+	}
+	if filter.Kind != "" && filter.Kind != database.ProductKindSingle && filter.Kind != database.ProductKindSealed {
+		return filter, errInvalidFilter
+	}
+	for param, dest := range map[string]*int{
+		"tcg_game_id":       &filter.TCGGameID,
+		"card_id":           &filter.CardID,
+		"sealed_product_id": &filter.SealedProductID,
+		"language_id":       &filter.LanguageID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			return filter, errInvalidFilter
+		}
+		*dest = id
+	}
+	return filter, nil
+}
+
+// CreateProductHandler lists a product for the current user.
 func (s *FiberServer) CreateProductHandler(c *fiber.Ctx) error {
 	var product database.ProductRequest
 	if err := c.BodyParser(&product); err != nil {
@@ -178,15 +395,41 @@ func (s *FiberServer) CreateProductHandler(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
+	product.SellerID = currentUserID(c)
 
-	if err := s.db.CreateProduct(product); err != nil {
+	productID, err := s.db.CreateProduct(product)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidProduct) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Product must reference either a card or a sealed product",
+			})
+		}
+		if errors.Is(err, database.ErrInvalidCondition) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "Condition does not apply to this product",
+				"conditions": fiber.Map{"card": database.CardConditions, "sealed": database.SealedConditions},
+			})
+		}
+		if errors.Is(err, database.ErrEmailNotVerified) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Email address must be verified before selling",
+			})
+		}
+		if errors.Is(err, currency.ErrCurrencyMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Price must be in the seller's currency",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create product",
 		})
 	}
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "product created"})
+	s.checkWatchlist(productID)
+	s.matchWantlists(productID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "product created", "product_id": productID})
 }
 
+// UpdateProductHandler updates a product of the current user.
 func (s *FiberServer) UpdateProductHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
 	productID, err := strconv.Atoi(id)
@@ -202,12 +445,41 @@ func (s *FiberServer) UpdateProductHandler(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
+	product.SellerID = currentUserID(c)
 
 	if err := s.db.UpdateProduct(productID, product); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Product not found",
+			})
+		}
+		if errors.Is(err, database.ErrNotProductSeller) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "You can only change your own products",
+			})
+		}
+		if errors.Is(err, database.ErrInvalidProduct) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Product must reference either a card or a sealed product",
+			})
+		}
+		if errors.Is(err, database.ErrInvalidCondition) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "Condition does not apply to this product",
+				"conditions": fiber.Map{"card": database.CardConditions, "sealed": database.SealedConditions},
+			})
+		}
+		if errors.Is(err, currency.ErrCurrencyMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Price must be in the currency the product is listed in",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update product",
 		})
 	}
+	s.checkWatchlist(productID)
+	s.matchWantlists(productID)
 	return c.JSON(fiber.Map{"message": "product updated"})
 }
 
@@ -220,6 +492,13 @@ func (s *FiberServer) DeleteProductHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	productImages, err := s.db.ListProductImages(productID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete product",
+		})
+	}
+
 	err = s.db.DeleteProduct(productID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -227,6 +506,9 @@ func (s *FiberServer) DeleteProductHandler(c *fiber.Ctx) error {
 		})
 	}
 
+	for _, productImage := range productImages {
+		s.deleteStoredImage(c.UserContext(), productImage)
+	}
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "product deleted"})
 }
 
@@ -238,20 +520,51 @@ func (s *FiberServer) GetProductByIDHandler(c *fiber.Ctx) error {
 			"error": "Invalid product ID",
 		})
 	}
+	code, rates, err := s.displayRates(c)
+	if err != nil {
+		return currencyError(c, err)
+	}
+
 	product, err := s.db.GetProductByID(productID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Product not found",
 		})
 	}
+	product.Images, err = s.db.ListProductImages(productID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch images",
+		})
+	}
+	s.imageURLs(product.Images)
+
+	if code != "" {
+		if err := convertProduct(&product, rates, code); err != nil {
+			return currencyError(c, err)
+		}
+	}
 	return c.JSON(fiber.Map{"product": product})
 }
 
 func (s *FiberServer) ListOrdersHandler(c *fiber.Ctx) error {
+	code, rates, err := s.displayRates(c)
+	if err != nil {
+		return currencyError(c, err)
+	}
+
 	orders, err := s.db.ListOrders()
 	if err != nil {
 		return c.SendString(err.Error())
 	}
+
+	if code != "" {
+		for i := range orders {
+			if err := convertOrder(&orders[i], rates, code); err != nil {
+				return currencyError(c, err)
+			}
+		}
+	}
 	return c.JSON(fiber.Map{"orders": orders})
 }
 
@@ -263,12 +576,23 @@ func (s *FiberServer) GetOrderByIDHandler(c *fiber.Ctx) error {
 			"error": "Invalid order ID",
 		})
 	}
+	code, rates, err := s.displayRates(c)
+	if err != nil {
+		return currencyError(c, err)
+	}
+
 	order, err := s.db.GetOrderByID(orderID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Order not found",
 		})
 	}
+
+	if code != "" {
+		if err := convertOrder(&order, rates, code); err != nil {
+			return currencyError(c, err)
+		}
+	}
 	return c.JSON(fiber.Map{"order": order})
 }
 
@@ -280,14 +604,70 @@ func (s *FiberServer) CreateOrderHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.db.CreateOrder(order); err != nil {
+	if order.Quantity <= 0 || order.ShippingMethodID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Quantity and shipping method are required",
+		})
+	}
+	order.BuyerID = currentUserID(c)
+
+	orderID, err := s.db.CreateOrder(order)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product, seller or buyer not found",
+		})
+	case errors.Is(err, database.ErrAddressNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping address not found",
+		})
+	case errors.Is(err, database.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Not enough items in stock",
+		})
+	case errors.Is(err, database.ErrProductUnavailable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Product is not for sale",
+		})
+	case errors.Is(err, database.ErrSellerOnVacation):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Seller is on vacation",
+		})
+	case errors.Is(err, database.ErrShippingMethodUnavailable):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping method not available for this order",
+		})
+	case err != nil:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create order",
 		})
 	}
+
+	s.announceOrder(orderID, order)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "order accepted"})
 }
 
+// announceOrder tells the seller about a new order and sends the buyer the
+// order confirmation.
+func (s *FiberServer) announceOrder(orderID int, order database.OrderRequest) {
+	s.notify(order.SellerID, database.NotificationOrderCreated, fiber.Map{
+		"order_id":   orderID,
+		"product_id": order.ProductID,
+		"buyer_id":   order.BuyerID,
+		"quantity":   order.Quantity,
+	})
+
+	// The total is calculated when the order is stored.
+	created, _ := s.db.GetOrderByID(orderID)
+	product, _ := s.db.GetProductByID(order.ProductID)
+	s.sendEmail(order.BuyerID, mailer.TemplateOrderConfirmation, fiber.Map{
+		"OrderID":  orderID,
+		"Product":  product.Card,
+		"Quantity": order.Quantity,
+		"Total":    created.Total.String(),
+	})
+}
+
 func (s *FiberServer) UpdateOrderHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
 	orderID, err := strconv.Atoi(id)
@@ -304,11 +684,66 @@ func (s *FiberServer) UpdateOrderHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.db.UpdateOrder(orderID, order); err != nil {
+	if order.TrackingNumber != nil {
+		if err := normalizeTracking(&order); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid tracking number for carrier",
+			})
+		}
+	}
+
+	change, err := s.db.UpdateOrder(orderID, currentUserID(c), order)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Order not found",
+		})
+	case errors.Is(err, database.ErrNotOrderParticipant):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only change your own orders",
+		})
+	case errors.Is(err, database.ErrNotOrderSeller):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only the seller can add shipping details",
+		})
+	case errors.Is(err, database.ErrOrderFixed):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Product, quantity and parties cannot be changed after checkout",
+		})
+	case errors.Is(err, database.ErrOrderStatusChange):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Order status change not allowed",
+		})
+	case errors.Is(err, database.ErrOrderNotShippable):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Only paid orders can be shipped",
+		})
+	case err != nil:
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update order",
 		})
 	}
+
+	if change.PreviousStatus != change.Status {
+		payload := fiber.Map{
+			"order_id":        orderID,
+			"previous_status": change.PreviousStatus,
+			"status":          change.Status,
+		}
+		s.notify(change.BuyerID, database.NotificationOrderStatusChanged, payload)
+		s.notify(change.SellerID, database.NotificationOrderStatusChanged, payload)
+	}
+
+	if change.Shipped {
+		trackingNumber := ""
+		if change.TrackingNumber != nil {
+			trackingNumber = *change.TrackingNumber
+		}
+		s.sendEmail(change.BuyerID, mailer.TemplateOrderShipped, fiber.Map{
+			"OrderID":        orderID,
+			"TrackingNumber": trackingNumber,
+		})
+	}
 	return c.JSON(fiber.Map{"message": "order updated"})
 }
 
@@ -365,14 +800,23 @@ func (s *FiberServer) CreateUserHandler(c *fiber.Ctx) error {
 			"error": "Invalid request body",
 		})
 	}
-	if err := s.db.CreateUser(user); err != nil {
+	userID, err := s.db.CreateUser(user)
+	if invalidAddress(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid postal code or country",
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create user",
 		})
 	}
+
+	s.sendVerificationEmail(userID)
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": "user created"})
 }
 
+// UpdateUserHandler updates the account of the current user.
 func (s *FiberServer) UpdateUserHandler(c *fiber.Ctx) error {
 	id := c.Params("id")
 	userID, err := strconv.Atoi(id)
@@ -381,6 +825,11 @@ func (s *FiberServer) UpdateUserHandler(c *fiber.Ctx) error {
 			"error": "Invalid user ID",
 		})
 	}
+	if userID != currentUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only change your own account",
+		})
+	}
 
 	var user database.UserRequest
 	if err := c.BodyParser(&user); err != nil {
@@ -389,11 +838,28 @@ func (s *FiberServer) UpdateUserHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := s.db.UpdateUser(userID, user); err != nil {
+	previous, err := s.db.GetUserByID(userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	err = s.db.UpdateUser(userID, user)
+	if invalidAddress(err) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid postal code or country",
+		})
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update user",
 		})
 	}
+
+	if !strings.EqualFold(previous.Email, user.Email) {
+		s.sendVerificationEmail(userID)
+	}
 	return c.JSON(fiber.Map{"message": "user updated"})
 }
 
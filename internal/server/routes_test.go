@@ -1,7 +1,11 @@
 package server
 
 import (
+	"cardmarket_backend/internal/currency"
 	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/tracking"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -13,26 +17,123 @@ import (
 )
 
 type MockDBService struct {
-	ListCardsFunc      func() ([]database.Card, error)
-	GetCardByIDFunc    func() (database.Card, error)
-	CreateCardFunc     func(card database.CardRequest) error
-	UpdateCardFunc     func(cardID int, card database.CardRequest) error
-	DeleteCardFunc     func(cardID int) error
-	ListProductsFunc   func() ([]database.Product, error)
-	GetProductByIDFunc func(productID int) (database.Product, error)
-	CreateProductFunc  func(product database.ProductRequest) error
-	UpdateProductFunc  func(productID int, product database.ProductRequest) error
-	DeleteProductFunc  func(productID int) error
-	ListOrdersFunc     func() ([]database.Order, error)
-	GetOrderByIDFunc   func(orderID int) (database.Order, error)
-	CreateOrderFunc    func(order database.OrderRequest) error
-	UpdateOrderFunc    func(orderID int, order database.OrderRequest) error
-	DeleteOrderFunc    func(orderID int) error
-	ListUsersFunc      func() ([]database.User, error)
-	GetUserByIDFunc    func(userID int) (database.User, error)
-	CreateUserFunc     func(user database.UserRequest) error
-	UpdateUserFunc     func(userID int, user database.UserRequest) error
-	DeleteUserFunc     func(userID int) error
+	ListCardsFunc                 func() ([]database.Card, error)
+	GetCardByIDFunc               func() (database.Card, error)
+	CreateCardFunc                func(card database.CardRequest) error
+	UpdateCardFunc                func(cardID int, card database.CardRequest) error
+	DeleteCardFunc                func(cardID int) error
+	ListProductsFunc              func(filter database.ProductFilter) ([]database.Product, error)
+	GetProductByIDFunc            func(productID int) (database.Product, error)
+	CreateProductFunc             func(product database.ProductRequest) (int, error)
+	UpdateProductFunc             func(productID int, product database.ProductRequest) error
+	DeleteProductFunc             func(productID int) error
+	ListOrdersFunc                func() ([]database.Order, error)
+	GetOrderByIDFunc              func(orderID int) (database.Order, error)
+	CreateOrderFunc               func(order database.OrderRequest) (int, error)
+	UpdateOrderFunc               func(orderID int, userID int, order database.OrderRequest) (database.OrderChange, error)
+	DeleteOrderFunc               func(orderID int) error
+	ListUsersFunc                 func() ([]database.User, error)
+	GetUserByIDFunc               func(userID int) (database.User, error)
+	CreateUserFunc                func(user database.UserRequest) (int, error)
+	UpdateUserFunc                func(userID int, user database.UserRequest) error
+	DeleteUserFunc                func(userID int) error
+	GetUserContactFunc            func(userID int) (database.UserContact, error)
+	AuthenticateFunc              func(email string, password string) (int, error)
+	GetUserIDByEmailFunc          func(email string) (int, error)
+	CreateUserTokenFunc           func(tokenID string, userID int, purpose string, expiresAt time.Time) error
+	VerifyEmailFunc               func(tokenID string, userID int) error
+	ResetPasswordFunc             func(tokenID string, userID int, password string) error
+	ListOrderReviewsFunc          func(orderID int) ([]database.Review, error)
+	ListUserReviewsFunc           func(userID int) ([]database.Review, error)
+	CreateReviewFunc              func(orderID int, reviewerID int, review database.ReviewRequest) (int, error)
+	RefreshReputationsFunc        func(now time.Time) (int, error)
+	ListTrackedShipmentsFunc      func() ([]tracking.Shipment, error)
+	MarkOrderDeliveredFunc        func(orderID int, deliveredAt time.Time) (bool, error)
+	CancelUnpaidOrdersFunc        func(placedBefore time.Time) ([]database.OrderSummary, error)
+	RemindUnshippedOrdersFunc     func(paidBefore time.Time) ([]database.OrderSummary, error)
+	CompleteShippedOrdersFunc     func(shippedBefore time.Time) ([]database.OrderSummary, error)
+	IsAdminFunc                   func(userID int) (bool, error)
+	ListUserDisputesFunc          func(userID int) ([]database.Dispute, error)
+	ListDisputesByStatusFunc      func(status string) ([]database.Dispute, error)
+	GetDisputeFunc                func(disputeID int) (database.Dispute, error)
+	CreateDisputeFunc             func(orderID int, buyerID int, dispute database.DisputeRequest) (int, error)
+	CreateDisputeMessageFunc      func(disputeID int, senderID int, body string) error
+	AddDisputeEvidenceFunc        func(disputeID int, userID int, imageURL string) error
+	EscalateDisputeFunc           func(disputeID int) error
+	ResolveDisputeFunc            func(disputeID int, resolverID int, resolution database.DisputeResolutionRequest) error
+	ListPackingSlipsFunc          func(sellerID int, orderIDs []int) ([]database.PackingSlip, error)
+	ListAuctionsFunc              func() ([]database.Auction, error)
+	GetAuctionFunc                func(auctionID int) (database.Auction, error)
+	CreateAuctionFunc             func(sellerID int, auction database.AuctionRequest) (int, error)
+	CancelAuctionFunc             func(auctionID int, sellerID int) error
+	PlaceBidFunc                  func(auctionID int, bidderID int, bid database.BidRequest) (database.BidResult, error)
+	CloseEndedAuctionsFunc        func(now time.Time) ([]database.AuctionResult, error)
+	ListUserOffersFunc            func(userID int) ([]database.Offer, error)
+	CreateOfferFunc               func(productID int, buyerID int, offer database.OfferRequest) (database.Offer, error)
+	AcceptOfferFunc               func(offerID int, userID int) (database.Offer, error)
+	DeclineOfferFunc              func(offerID int, userID int) (database.Offer, error)
+	CounterOfferFunc              func(offerID int, userID int, counter database.CounterOfferRequest) (database.Offer, error)
+	ExpireOffersFunc              func(now time.Time) ([]database.Offer, error)
+	ListUserTradesFunc            func(userID int) ([]database.Trade, error)
+	GetTradeFunc                  func(tradeID int, userID int) (database.Trade, error)
+	ProposeTradeFunc              func(proposerID int, trade database.TradeRequest) (database.Trade, error)
+	AcceptTradeFunc               func(tradeID int, userID int, addressID int) (database.Trade, error)
+	DeclineTradeFunc              func(tradeID int, userID int) (database.Trade, error)
+	CounterTradeFunc              func(tradeID int, userID int, counter database.TradeRequest) (database.Trade, error)
+	PayTradeCashFunc              func(tradeID int, userID int) (database.Trade, error)
+	ShipTradeFunc                 func(tradeID int, userID int, carrier *string, trackingNumber *string) (database.Trade, error)
+	ReceiveTradeFunc              func(tradeID int, userID int) (database.Trade, error)
+	CancelStalledTradesFunc       func(acceptedBefore time.Time) ([]database.Trade, error)
+	ListSealedProductsFunc        func(filter database.SealedProductFilter) ([]database.SealedProduct, error)
+	GetSealedProductFunc          func(sealedProductID int) (database.SealedProduct, error)
+	CreateSealedProductFunc       func(product database.SealedProductRequest) (int, error)
+	UpdateSealedProductFunc       func(sealedProductID int, product database.SealedProductRequest) error
+	DeleteSealedProductFunc       func(sealedProductID int) error
+	GetTCGGameFunc                func(tcgGameID int) (database.TCGGame, error)
+	ListCardsByGameFunc           func(tcgGameID int) ([]database.Card, error)
+	ListWantlistsFunc             func(userID int) ([]database.Wantlist, error)
+	GetWantlistFunc               func(wantlistID int, userID int) (database.Wantlist, error)
+	CreateWantlistFunc            func(userID int, wantlist database.WantlistRequest) (database.Wantlist, error)
+	DeleteWantlistFunc            func(wantlistID int, userID int) error
+	MatchWantlistsFunc            func(productID int) ([]database.WantlistMatch, error)
+	ListCartFunc                  func(userID int) ([]database.CartItem, error)
+	FillCartFunc                  func(userID int, wants []database.CartWant, excludeOwned bool) ([]database.CartFill, error)
+	RemoveCartItemFunc            func(userID int, productID int) error
+	GetSellerProfileFunc          func(username string) (database.SellerProfile, error)
+	SetVacationFunc               func(userID int, onVacation bool, until *time.Time) error
+	EndVacationsFunc              func(now time.Time) ([]int, error)
+	ListProductImagesFunc         func(productID int) ([]database.ProductImage, error)
+	CheckProductImageUploadFunc   func(productID int, sellerID int) error
+	AddProductImageFunc           func(productID int, sellerID int, img database.ProductImageRequest) (database.ProductImage, error)
+	DeleteProductImageFunc        func(productID int, imageID int, sellerID int) (database.ProductImage, error)
+	ReorderProductImagesFunc      func(productID int, sellerID int, imageIDs []int) ([]database.ProductImage, error)
+	ListWatchlistFunc             func(userID int) ([]database.WatchlistEntry, error)
+	AddWatchlistEntryFunc         func(userID int, req database.WatchlistEntryRequest) (database.WatchlistEntry, error)
+	RemoveWatchlistEntryFunc      func(userID int, entryID int) error
+	CheckWatchlistFunc            func(productID int) ([]database.WatchlistAlert, error)
+	GetCardOverviewFunc           func(cardID int) (database.CardOverview, error)
+	GetInvoiceFunc                func(orderID int, userID int) (database.Invoice, error)
+	ListTaxRatesFunc              func() ([]database.TaxRate, error)
+	CreateTaxRateFunc             func(rate database.TaxRateRequest) (int, error)
+	ListExchangeRatesFunc         func() (currency.Rates, error)
+	SaveExchangeRatesFunc         func(rates currency.Rates, source string) error
+	ListAddressesFunc             func(userID int) ([]database.Address, error)
+	CreateAddressFunc             func(userID int, address database.AddressRequest) (int, error)
+	UpdateAddressFunc             func(addressID int, userID int, address database.AddressRequest) error
+	DeleteAddressFunc             func(addressID int, userID int) error
+	ListShippingMethodsFunc       func() ([]database.ShippingMethod, error)
+	ListSellerShippingMethodsFunc func(sellerID int) ([]database.ShippingMethod, error)
+	SetSellerShippingMethodsFunc  func(sellerID int, methodIDs []int) error
+	QuoteShippingFunc             func(productID int, buyerID int, quantity int) ([]database.ShippingQuote, error)
+	ListConversationsFunc         func(userID int) ([]database.Conversation, error)
+	CreateConversationFunc        func(senderID int, conversation database.ConversationRequest) (int, error)
+	ListMessagesFunc              func(conversationID int, userID int) ([]database.Message, error)
+	CreateMessageFunc             func(conversationID int, senderID int, message database.MessageRequest) (int, error)
+	CreateNotificationFunc        func(userID int, notificationType string, payload any) (database.Notification, error)
+	ListenNotificationsFunc       func(ctx context.Context, deliver func(database.Notification)) error
+	ListNotificationsFunc         func(userID int, unreadOnly bool) ([]database.Notification, error)
+	MarkNotificationReadFunc      func(notificationID int, userID int) error
+	MarkAllNotificationsReadFunc  func(userID int) error
 }
 
 func (m *MockDBService) Close() error {
@@ -78,9 +179,9 @@ func (m *MockDBService) DeleteCard(cardID int) error {
 	return nil
 }
 
-func (m *MockDBService) ListProducts() ([]database.Product, error) {
+func (m *MockDBService) ListProducts(filter database.ProductFilter) ([]database.Product, error) {
 	if m.ListProductsFunc != nil {
-		return m.ListProductsFunc()
+		return m.ListProductsFunc(filter)
 	}
 	return []database.Product{}, nil
 }
@@ -92,11 +193,11 @@ func (m *MockDBService) GetProductByID(productID int) (database.Product, error)
 	return database.Product{}, nil
 }
 
-func (m *MockDBService) CreateProduct(product database.ProductRequest) error {
+func (m *MockDBService) CreateProduct(product database.ProductRequest) (int, error) {
 	if m.CreateProductFunc != nil {
 		return m.CreateProductFunc(product)
 	}
-	return nil
+	return 1, nil
 }
 
 func (m *MockDBService) UpdateProduct(productID int, product database.ProductRequest) error {
@@ -127,18 +228,18 @@ func (m *MockDBService) GetOrderByID(orderID int) (database.Order, error) {
 	return database.Order{}, nil
 }
 
-func (m *MockDBService) CreateOrder(order database.OrderRequest) error {
+func (m *MockDBService) CreateOrder(order database.OrderRequest) (int, error) {
 	if m.CreateOrderFunc != nil {
 		return m.CreateOrderFunc(order)
 	}
-	return nil
+	return 0, nil
 }
 
-func (m *MockDBService) UpdateOrder(orderID int, order database.OrderRequest) error {
+func (m *MockDBService) UpdateOrder(orderID int, userID int, order database.OrderRequest) (database.OrderChange, error) {
 	if m.UpdateOrderFunc != nil {
-		return m.UpdateOrderFunc(orderID, order)
+		return m.UpdateOrderFunc(orderID, userID, order)
 	}
-	return nil
+	return database.OrderChange{}, nil
 }
 
 func (m *MockDBService) DeleteOrder(orderID int) error {
@@ -162,11 +263,11 @@ func (m *MockDBService) GetUserByID(userID int) (database.User, error) {
 	return database.User{}, nil
 }
 
-func (m *MockDBService) CreateUser(user database.UserRequest) error {
+func (m *MockDBService) CreateUser(user database.UserRequest) (int, error) {
 	if m.CreateUserFunc != nil {
 		return m.CreateUserFunc(user)
 	}
-	return nil
+	return 0, nil
 }
 
 func (m *MockDBService) UpdateUser(userID int, user database.UserRequest) error {
@@ -183,6 +284,657 @@ func (m *MockDBService) DeleteUser(userID int) error {
 	return nil
 }
 
+func (m *MockDBService) GetUserContact(userID int) (database.UserContact, error) {
+	if m.GetUserContactFunc != nil {
+		return m.GetUserContactFunc(userID)
+	}
+	return database.UserContact{UserID: userID}, nil
+}
+
+func (m *MockDBService) Authenticate(email string, password string) (int, error) {
+	return m.AuthenticateFunc(email, password)
+}
+
+func (m *MockDBService) GetUserIDByEmail(email string) (int, error) {
+	if m.GetUserIDByEmailFunc != nil {
+		return m.GetUserIDByEmailFunc(email)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) CreateUserToken(tokenID string, userID int, purpose string, expiresAt time.Time) error {
+	if m.CreateUserTokenFunc != nil {
+		return m.CreateUserTokenFunc(tokenID, userID, purpose, expiresAt)
+	}
+	return nil
+}
+
+func (m *MockDBService) VerifyEmail(tokenID string, userID int) error {
+	if m.VerifyEmailFunc != nil {
+		return m.VerifyEmailFunc(tokenID, userID)
+	}
+	return nil
+}
+
+func (m *MockDBService) ResetPassword(tokenID string, userID int, password string) error {
+	if m.ResetPasswordFunc != nil {
+		return m.ResetPasswordFunc(tokenID, userID, password)
+	}
+	return nil
+}
+
+func (m *MockDBService) ListOrderReviews(orderID int) ([]database.Review, error) {
+	if m.ListOrderReviewsFunc != nil {
+		return m.ListOrderReviewsFunc(orderID)
+	}
+	return []database.Review{}, nil
+}
+
+func (m *MockDBService) ListUserReviews(userID int) ([]database.Review, error) {
+	if m.ListUserReviewsFunc != nil {
+		return m.ListUserReviewsFunc(userID)
+	}
+	return []database.Review{}, nil
+}
+
+func (m *MockDBService) CreateReview(orderID int, reviewerID int, review database.ReviewRequest) (int, error) {
+	if m.CreateReviewFunc != nil {
+		return m.CreateReviewFunc(orderID, reviewerID, review)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) RefreshReputations(now time.Time) (int, error) {
+	if m.RefreshReputationsFunc != nil {
+		return m.RefreshReputationsFunc(now)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) ListTrackedShipments() ([]tracking.Shipment, error) {
+	if m.ListTrackedShipmentsFunc != nil {
+		return m.ListTrackedShipmentsFunc()
+	}
+	return []tracking.Shipment{}, nil
+}
+
+func (m *MockDBService) MarkOrderDelivered(orderID int, deliveredAt time.Time) (bool, error) {
+	if m.MarkOrderDeliveredFunc != nil {
+		return m.MarkOrderDeliveredFunc(orderID, deliveredAt)
+	}
+	return false, nil
+}
+
+func (m *MockDBService) CancelUnpaidOrders(placedBefore time.Time) ([]database.OrderSummary, error) {
+	if m.CancelUnpaidOrdersFunc != nil {
+		return m.CancelUnpaidOrdersFunc(placedBefore)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) RemindUnshippedOrders(paidBefore time.Time) ([]database.OrderSummary, error) {
+	if m.RemindUnshippedOrdersFunc != nil {
+		return m.RemindUnshippedOrdersFunc(paidBefore)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) CompleteShippedOrders(shippedBefore time.Time) ([]database.OrderSummary, error) {
+	if m.CompleteShippedOrdersFunc != nil {
+		return m.CompleteShippedOrdersFunc(shippedBefore)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) AdvisoryLock(key int64) *database.AdvisoryLock {
+	return nil
+}
+
+func (m *MockDBService) IsAdmin(userID int) (bool, error) {
+	if m.IsAdminFunc != nil {
+		return m.IsAdminFunc(userID)
+	}
+	return false, nil
+}
+
+func (m *MockDBService) ListUserDisputes(userID int) ([]database.Dispute, error) {
+	if m.ListUserDisputesFunc != nil {
+		return m.ListUserDisputesFunc(userID)
+	}
+	return []database.Dispute{}, nil
+}
+
+func (m *MockDBService) ListDisputesByStatus(status string) ([]database.Dispute, error) {
+	if m.ListDisputesByStatusFunc != nil {
+		return m.ListDisputesByStatusFunc(status)
+	}
+	return []database.Dispute{}, nil
+}
+
+func (m *MockDBService) GetDispute(disputeID int) (database.Dispute, error) {
+	if m.GetDisputeFunc != nil {
+		return m.GetDisputeFunc(disputeID)
+	}
+	return database.Dispute{}, nil
+}
+
+func (m *MockDBService) CreateDispute(orderID int, buyerID int, dispute database.DisputeRequest) (int, error) {
+	if m.CreateDisputeFunc != nil {
+		return m.CreateDisputeFunc(orderID, buyerID, dispute)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) CreateDisputeMessage(disputeID int, senderID int, body string) error {
+	if m.CreateDisputeMessageFunc != nil {
+		return m.CreateDisputeMessageFunc(disputeID, senderID, body)
+	}
+	return nil
+}
+
+func (m *MockDBService) AddDisputeEvidence(disputeID int, userID int, imageURL string) error {
+	if m.AddDisputeEvidenceFunc != nil {
+		return m.AddDisputeEvidenceFunc(disputeID, userID, imageURL)
+	}
+	return nil
+}
+
+func (m *MockDBService) EscalateDispute(disputeID int) error {
+	if m.EscalateDisputeFunc != nil {
+		return m.EscalateDisputeFunc(disputeID)
+	}
+	return nil
+}
+
+func (m *MockDBService) ResolveDispute(disputeID int, resolverID int, resolution database.DisputeResolutionRequest) error {
+	if m.ResolveDisputeFunc != nil {
+		return m.ResolveDisputeFunc(disputeID, resolverID, resolution)
+	}
+	return nil
+}
+
+func (m *MockDBService) ListPackingSlips(sellerID int, orderIDs []int) ([]database.PackingSlip, error) {
+	if m.ListPackingSlipsFunc != nil {
+		return m.ListPackingSlipsFunc(sellerID, orderIDs)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) ListAuctions() ([]database.Auction, error) {
+	if m.ListAuctionsFunc != nil {
+		return m.ListAuctionsFunc()
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) GetAuction(auctionID int) (database.Auction, error) {
+	if m.GetAuctionFunc != nil {
+		return m.GetAuctionFunc(auctionID)
+	}
+	return database.Auction{}, nil
+}
+
+func (m *MockDBService) CreateAuction(sellerID int, auction database.AuctionRequest) (int, error) {
+	if m.CreateAuctionFunc != nil {
+		return m.CreateAuctionFunc(sellerID, auction)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) CancelAuction(auctionID int, sellerID int) error {
+	if m.CancelAuctionFunc != nil {
+		return m.CancelAuctionFunc(auctionID, sellerID)
+	}
+	return nil
+}
+
+func (m *MockDBService) PlaceBid(auctionID int, bidderID int, bid database.BidRequest) (database.BidResult, error) {
+	if m.PlaceBidFunc != nil {
+		return m.PlaceBidFunc(auctionID, bidderID, bid)
+	}
+	return database.BidResult{}, nil
+}
+
+func (m *MockDBService) CloseEndedAuctions(now time.Time) ([]database.AuctionResult, error) {
+	if m.CloseEndedAuctionsFunc != nil {
+		return m.CloseEndedAuctionsFunc(now)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) ListUserOffers(userID int) ([]database.Offer, error) {
+	if m.ListUserOffersFunc != nil {
+		return m.ListUserOffersFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) CreateOffer(productID int, buyerID int, offer database.OfferRequest) (database.Offer, error) {
+	if m.CreateOfferFunc != nil {
+		return m.CreateOfferFunc(productID, buyerID, offer)
+	}
+	return database.Offer{}, nil
+}
+
+func (m *MockDBService) AcceptOffer(offerID int, userID int) (database.Offer, error) {
+	if m.AcceptOfferFunc != nil {
+		return m.AcceptOfferFunc(offerID, userID)
+	}
+	return database.Offer{}, nil
+}
+
+func (m *MockDBService) DeclineOffer(offerID int, userID int) (database.Offer, error) {
+	if m.DeclineOfferFunc != nil {
+		return m.DeclineOfferFunc(offerID, userID)
+	}
+	return database.Offer{}, nil
+}
+
+func (m *MockDBService) CounterOffer(offerID int, userID int, counter database.CounterOfferRequest) (database.Offer, error) {
+	if m.CounterOfferFunc != nil {
+		return m.CounterOfferFunc(offerID, userID, counter)
+	}
+	return database.Offer{}, nil
+}
+
+func (m *MockDBService) ExpireOffers(now time.Time) ([]database.Offer, error) {
+	if m.ExpireOffersFunc != nil {
+		return m.ExpireOffersFunc(now)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) ListUserTrades(userID int) ([]database.Trade, error) {
+	if m.ListUserTradesFunc != nil {
+		return m.ListUserTradesFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) GetTrade(tradeID int, userID int) (database.Trade, error) {
+	if m.GetTradeFunc != nil {
+		return m.GetTradeFunc(tradeID, userID)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) ProposeTrade(proposerID int, trade database.TradeRequest) (database.Trade, error) {
+	if m.ProposeTradeFunc != nil {
+		return m.ProposeTradeFunc(proposerID, trade)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) AcceptTrade(tradeID int, userID int, addressID int) (database.Trade, error) {
+	if m.AcceptTradeFunc != nil {
+		return m.AcceptTradeFunc(tradeID, userID, addressID)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) DeclineTrade(tradeID int, userID int) (database.Trade, error) {
+	if m.DeclineTradeFunc != nil {
+		return m.DeclineTradeFunc(tradeID, userID)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) CounterTrade(tradeID int, userID int, counter database.TradeRequest) (database.Trade, error) {
+	if m.CounterTradeFunc != nil {
+		return m.CounterTradeFunc(tradeID, userID, counter)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) PayTradeCash(tradeID int, userID int) (database.Trade, error) {
+	if m.PayTradeCashFunc != nil {
+		return m.PayTradeCashFunc(tradeID, userID)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) ShipTrade(tradeID int, userID int, carrier *string, trackingNumber *string) (database.Trade, error) {
+	if m.ShipTradeFunc != nil {
+		return m.ShipTradeFunc(tradeID, userID, carrier, trackingNumber)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) ReceiveTrade(tradeID int, userID int) (database.Trade, error) {
+	if m.ReceiveTradeFunc != nil {
+		return m.ReceiveTradeFunc(tradeID, userID)
+	}
+	return database.Trade{}, nil
+}
+
+func (m *MockDBService) CancelStalledTrades(acceptedBefore time.Time) ([]database.Trade, error) {
+	if m.CancelStalledTradesFunc != nil {
+		return m.CancelStalledTradesFunc(acceptedBefore)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) ListSealedProducts(filter database.SealedProductFilter) ([]database.SealedProduct, error) {
+	if m.ListSealedProductsFunc != nil {
+		return m.ListSealedProductsFunc(filter)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) GetSealedProduct(sealedProductID int) (database.SealedProduct, error) {
+	if m.GetSealedProductFunc != nil {
+		return m.GetSealedProductFunc(sealedProductID)
+	}
+	return database.SealedProduct{}, nil
+}
+
+func (m *MockDBService) CreateSealedProduct(product database.SealedProductRequest) (int, error) {
+	if m.CreateSealedProductFunc != nil {
+		return m.CreateSealedProductFunc(product)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) UpdateSealedProduct(sealedProductID int, product database.SealedProductRequest) error {
+	if m.UpdateSealedProductFunc != nil {
+		return m.UpdateSealedProductFunc(sealedProductID, product)
+	}
+	return nil
+}
+
+func (m *MockDBService) DeleteSealedProduct(sealedProductID int) error {
+	if m.DeleteSealedProductFunc != nil {
+		return m.DeleteSealedProductFunc(sealedProductID)
+	}
+	return nil
+}
+
+func (m *MockDBService) GetTCGGame(tcgGameID int) (database.TCGGame, error) {
+	if m.GetTCGGameFunc != nil {
+		return m.GetTCGGameFunc(tcgGameID)
+	}
+	return database.TCGGame{}, nil
+}
+
+func (m *MockDBService) ListCardsByGame(tcgGameID int) ([]database.Card, error) {
+	if m.ListCardsByGameFunc != nil {
+		return m.ListCardsByGameFunc(tcgGameID)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) ListWantlists(userID int) ([]database.Wantlist, error) {
+	if m.ListWantlistsFunc != nil {
+		return m.ListWantlistsFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) GetWantlist(wantlistID int, userID int) (database.Wantlist, error) {
+	if m.GetWantlistFunc != nil {
+		return m.GetWantlistFunc(wantlistID, userID)
+	}
+	return database.Wantlist{}, nil
+}
+
+func (m *MockDBService) CreateWantlist(userID int, wantlist database.WantlistRequest) (database.Wantlist, error) {
+	if m.CreateWantlistFunc != nil {
+		return m.CreateWantlistFunc(userID, wantlist)
+	}
+	return database.Wantlist{}, nil
+}
+
+func (m *MockDBService) DeleteWantlist(wantlistID int, userID int) error {
+	if m.DeleteWantlistFunc != nil {
+		return m.DeleteWantlistFunc(wantlistID, userID)
+	}
+	return nil
+}
+
+func (m *MockDBService) MatchWantlists(productID int) ([]database.WantlistMatch, error) {
+	if m.MatchWantlistsFunc != nil {
+		return m.MatchWantlistsFunc(productID)
+	}
+	return []database.WantlistMatch{}, nil
+}
+
+func (m *MockDBService) ListCart(userID int) ([]database.CartItem, error) {
+	if m.ListCartFunc != nil {
+		return m.ListCartFunc(userID)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) FillCart(userID int, wants []database.CartWant, excludeOwned bool) ([]database.CartFill, error) {
+	if m.FillCartFunc != nil {
+		return m.FillCartFunc(userID, wants, excludeOwned)
+	}
+	return nil, nil
+}
+
+func (m *MockDBService) RemoveCartItem(userID int, productID int) error {
+	if m.RemoveCartItemFunc != nil {
+		return m.RemoveCartItemFunc(userID, productID)
+	}
+	return nil
+}
+
+func (m *MockDBService) GetSellerProfile(username string) (database.SellerProfile, error) {
+	return m.GetSellerProfileFunc(username)
+}
+
+func (m *MockDBService) SetVacation(userID int, onVacation bool, until *time.Time) error {
+	return m.SetVacationFunc(userID, onVacation, until)
+}
+
+func (m *MockDBService) EndVacations(now time.Time) ([]int, error) {
+	return m.EndVacationsFunc(now)
+}
+
+func (m *MockDBService) ListProductImages(productID int) ([]database.ProductImage, error) {
+	if m.ListProductImagesFunc != nil {
+		return m.ListProductImagesFunc(productID)
+	}
+	return []database.ProductImage{}, nil
+}
+
+func (m *MockDBService) CheckProductImageUpload(productID int, sellerID int) error {
+	if m.CheckProductImageUploadFunc != nil {
+		return m.CheckProductImageUploadFunc(productID, sellerID)
+	}
+	return nil
+}
+
+func (m *MockDBService) AddProductImage(productID int, sellerID int, img database.ProductImageRequest) (database.ProductImage, error) {
+	return m.AddProductImageFunc(productID, sellerID, img)
+}
+
+func (m *MockDBService) DeleteProductImage(productID int, imageID int, sellerID int) (database.ProductImage, error) {
+	return m.DeleteProductImageFunc(productID, imageID, sellerID)
+}
+
+func (m *MockDBService) ReorderProductImages(productID int, sellerID int, imageIDs []int) ([]database.ProductImage, error) {
+	return m.ReorderProductImagesFunc(productID, sellerID, imageIDs)
+}
+
+func (m *MockDBService) ListWatchlist(userID int) ([]database.WatchlistEntry, error) {
+	return m.ListWatchlistFunc(userID)
+}
+
+func (m *MockDBService) AddWatchlistEntry(userID int, req database.WatchlistEntryRequest) (database.WatchlistEntry, error) {
+	return m.AddWatchlistEntryFunc(userID, req)
+}
+
+func (m *MockDBService) RemoveWatchlistEntry(userID int, entryID int) error {
+	return m.RemoveWatchlistEntryFunc(userID, entryID)
+}
+
+func (m *MockDBService) CheckWatchlist(productID int) ([]database.WatchlistAlert, error) {
+	if m.CheckWatchlistFunc != nil {
+		return m.CheckWatchlistFunc(productID)
+	}
+	return []database.WatchlistAlert{}, nil
+}
+
+func (m *MockDBService) GetCardOverview(cardID int) (database.CardOverview, error) {
+	return m.GetCardOverviewFunc(cardID)
+}
+
+func (m *MockDBService) GetInvoice(orderID int, userID int) (database.Invoice, error) {
+	if m.GetInvoiceFunc != nil {
+		return m.GetInvoiceFunc(orderID, userID)
+	}
+	return database.Invoice{}, nil
+}
+
+func (m *MockDBService) ListTaxRates() ([]database.TaxRate, error) {
+	if m.ListTaxRatesFunc != nil {
+		return m.ListTaxRatesFunc()
+	}
+	return []database.TaxRate{}, nil
+}
+
+func (m *MockDBService) CreateTaxRate(rate database.TaxRateRequest) (int, error) {
+	if m.CreateTaxRateFunc != nil {
+		return m.CreateTaxRateFunc(rate)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) ListExchangeRates() (currency.Rates, error) {
+	if m.ListExchangeRatesFunc != nil {
+		return m.ListExchangeRatesFunc()
+	}
+	return currency.Rates{currency.Base: 1}, nil
+}
+
+func (m *MockDBService) SaveExchangeRates(rates currency.Rates, source string) error {
+	if m.SaveExchangeRatesFunc != nil {
+		return m.SaveExchangeRatesFunc(rates, source)
+	}
+	return nil
+}
+
+func (m *MockDBService) ListAddresses(userID int) ([]database.Address, error) {
+	if m.ListAddressesFunc != nil {
+		return m.ListAddressesFunc(userID)
+	}
+	return []database.Address{}, nil
+}
+
+func (m *MockDBService) CreateAddress(userID int, address database.AddressRequest) (int, error) {
+	if m.CreateAddressFunc != nil {
+		return m.CreateAddressFunc(userID, address)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) UpdateAddress(addressID int, userID int, address database.AddressRequest) error {
+	if m.UpdateAddressFunc != nil {
+		return m.UpdateAddressFunc(addressID, userID, address)
+	}
+	return nil
+}
+
+func (m *MockDBService) DeleteAddress(addressID int, userID int) error {
+	if m.DeleteAddressFunc != nil {
+		return m.DeleteAddressFunc(addressID, userID)
+	}
+	return nil
+}
+
+func (m *MockDBService) ListShippingMethods() ([]database.ShippingMethod, error) {
+	if m.ListShippingMethodsFunc != nil {
+		return m.ListShippingMethodsFunc()
+	}
+	return []database.ShippingMethod{}, nil
+}
+
+func (m *MockDBService) ListSellerShippingMethods(sellerID int) ([]database.ShippingMethod, error) {
+	if m.ListSellerShippingMethodsFunc != nil {
+		return m.ListSellerShippingMethodsFunc(sellerID)
+	}
+	return []database.ShippingMethod{}, nil
+}
+
+func (m *MockDBService) SetSellerShippingMethods(sellerID int, methodIDs []int) error {
+	if m.SetSellerShippingMethodsFunc != nil {
+		return m.SetSellerShippingMethodsFunc(sellerID, methodIDs)
+	}
+	return nil
+}
+
+func (m *MockDBService) QuoteShipping(productID int, buyerID int, quantity int) ([]database.ShippingQuote, error) {
+	if m.QuoteShippingFunc != nil {
+		return m.QuoteShippingFunc(productID, buyerID, quantity)
+	}
+	return []database.ShippingQuote{}, nil
+}
+
+func (m *MockDBService) ListConversations(userID int) ([]database.Conversation, error) {
+	if m.ListConversationsFunc != nil {
+		return m.ListConversationsFunc(userID)
+	}
+	return []database.Conversation{}, nil
+}
+
+func (m *MockDBService) CreateConversation(senderID int, conversation database.ConversationRequest) (int, error) {
+	if m.CreateConversationFunc != nil {
+		return m.CreateConversationFunc(senderID, conversation)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) ListMessages(conversationID int, userID int) ([]database.Message, error) {
+	if m.ListMessagesFunc != nil {
+		return m.ListMessagesFunc(conversationID, userID)
+	}
+	return []database.Message{}, nil
+}
+
+func (m *MockDBService) CreateMessage(conversationID int, senderID int, message database.MessageRequest) (int, error) {
+	if m.CreateMessageFunc != nil {
+		return m.CreateMessageFunc(conversationID, senderID, message)
+	}
+	return 0, nil
+}
+
+func (m *MockDBService) CreateNotification(userID int, notificationType string, payload any) (database.Notification, error) {
+	if m.CreateNotificationFunc != nil {
+		return m.CreateNotificationFunc(userID, notificationType, payload)
+	}
+	return database.Notification{UserID: userID, Type: notificationType}, nil
+}
+
+func (m *MockDBService) ListenNotifications(ctx context.Context, deliver func(database.Notification)) error {
+	if m.ListenNotificationsFunc != nil {
+		return m.ListenNotificationsFunc(ctx, deliver)
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (m *MockDBService) ListNotifications(userID int, unreadOnly bool) ([]database.Notification, error) {
+	if m.ListNotificationsFunc != nil {
+		return m.ListNotificationsFunc(userID, unreadOnly)
+	}
+	return []database.Notification{}, nil
+}
+
+func (m *MockDBService) MarkNotificationRead(notificationID int, userID int) error {
+	if m.MarkNotificationReadFunc != nil {
+		return m.MarkNotificationReadFunc(notificationID, userID)
+	}
+	return nil
+}
+
+func (m *MockDBService) MarkAllNotificationsRead(userID int) error {
+	if m.MarkAllNotificationsReadFunc != nil {
+		return m.MarkAllNotificationsReadFunc(userID)
+	}
+	return nil
+}
+
 func TestHandler(t *testing.T) {
 	// Create a Fiber app for testing
 	app := fiber.New()
@@ -331,6 +1083,87 @@ func TestCardByIdHandler(t *testing.T) {
 	}
 }
 
+func TestCardOverviewHandler(t *testing.T) {
+	lowest, average := currency.New(1800, "EUR"), currency.New(2050, "EUR")
+	mockDB := MockDBService{
+		GetCardOverviewFunc: func(cardID int) (database.CardOverview, error) {
+			if cardID != 1 {
+				return database.CardOverview{}, sql.ErrNoRows
+			}
+			return database.CardOverview{
+				Card: database.Card{ID: 1, Name: "Charizard", SetName: "Base Set"},
+				Set:  database.CardSet{Name: "Base Set", TCGGame: "Pokemon", CardCount: 102},
+				Listings: []database.ListingGroup{{
+					Condition:    "near mint",
+					Language:     "English",
+					ListingCount: 2,
+					Quantity:     3,
+					LowestPrice:  currency.New(1800, "EUR"),
+					Listings: []database.CardListing{
+						{ProductID: 4, SellerID: 2, Seller: "cardshop", Price: currency.New(1800, "EUR"), Quantity: 1},
+						{ProductID: 5, SellerID: 3, Seller: "collector", Price: currency.New(2300, "EUR"), Quantity: 2},
+					},
+				}},
+				PriceGuide: database.PriceGuide{LowestPrice: &lowest, AverageListingPrice: &average, Days: 30},
+				Sellers:    []database.SellerRating{{UserID: 2, Username: "cardshop"}, {UserID: 3, Username: "collector"}},
+			}, nil
+		},
+		ListExchangeRatesFunc: func() (currency.Rates, error) {
+			return currency.Rates{"EUR": 1, "USD": 1.1}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/cards/:id/overview", s.getCardOverviewHandler)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/api/cards/1/overview?currency=USD", http.StatusOK},
+		{"/api/cards/2/overview", http.StatusNotFound},
+		{"/api/cards/abc/overview", http.StatusBadRequest},
+		{"/api/cards/1/overview?currency=XYZ", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", tt.path, nil)
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != tt.expectedStatus {
+			t.Fatalf("%s: expected status %v; got %v", tt.path, tt.expectedStatus, resp.Status)
+		}
+		if resp.StatusCode != http.StatusOK {
+			continue
+		}
+
+		var body struct {
+			Overview database.CardOverview `json:"overview"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("error decoding response. Err: %v", err)
+		}
+		group := body.Overview.Listings[0]
+		if group.ConvertedLowestPrice == nil || *group.ConvertedLowestPrice != currency.New(1980, "USD") {
+			t.Errorf("expected converted lowest price of 19.80 USD; got %v", group.ConvertedLowestPrice)
+		}
+		if group.Listings[1].ConvertedPrice == nil || *group.Listings[1].ConvertedPrice != currency.New(2530, "USD") {
+			t.Errorf("expected converted listing price of 25.30 USD; got %v", group.Listings[1].ConvertedPrice)
+		}
+		guide := body.Overview.PriceGuide
+		if guide.LowestPrice == nil || *guide.LowestPrice != currency.New(1980, "USD") || guide.AverageSellPrice != nil {
+			t.Errorf("unexpected price guide %+v", guide)
+		}
+		if len(body.Overview.Sellers) != 2 || body.Overview.Set.CardCount != 102 {
+			t.Errorf("unexpected overview %+v", body.Overview)
+		}
+	}
+}
+
 func TestCreateCardHandler(t *testing.T) {
 	mockDB := MockDBService{
 		CreateCardFunc: func(card database.CardRequest) error {
@@ -468,8 +1301,8 @@ func TestDeleteCardHandler(t *testing.T) {
 
 func TestListOrdersHandler(t *testing.T) {
 	orders := []database.Order{
-		{OrderID: 1, Buyer: "john_doe", OrderDate: time.Now(), Total: 99.99, Status: "Processing", CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		{OrderID: 2, Buyer: "jane_smith", OrderDate: time.Now(), Total: 49.49, Status: "Shipped", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{OrderID: 1, Buyer: "john_doe", OrderDate: time.Now(), Total: currency.New(9999, "EUR"), Status: "Processing", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{OrderID: 2, Buyer: "jane_smith", OrderDate: time.Now(), Total: currency.New(4949, "EUR"), Status: "Shipped", CreatedAt: time.Now(), UpdatedAt: time.Now()},
 	}
 	mockDB := MockDBService{
 		ListOrdersFunc: func() ([]database.Order, error) {
@@ -511,7 +1344,7 @@ func TestListOrdersHandler(t *testing.T) {
 }
 
 func TestGetOrderByIDHandler(t *testing.T) {
-	singleOrder := database.Order{OrderID: 1, Buyer: "john_doe", OrderDate: time.Now(), Total: 99.99, Status: "Processing", CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	singleOrder := database.Order{OrderID: 1, Buyer: "john_doe", OrderDate: time.Now(), Total: currency.New(9999, "EUR"), Status: "Processing", CreatedAt: time.Now(), UpdatedAt: time.Now()}
 	mockDB := MockDBService{
 		GetOrderByIDFunc: func(orderID int) (database.Order, error) {
 			return singleOrder, nil
@@ -552,19 +1385,25 @@ func TestGetOrderByIDHandler(t *testing.T) {
 
 func TestCreateOrderHandler(t *testing.T) {
 	mockDB := MockDBService{
-		CreateOrderFunc: func(order database.OrderRequest) error {
-			return nil
+		CreateOrderFunc: func(order database.OrderRequest) (int, error) {
+			if order.BuyerID != 3 {
+				t.Errorf("expected the order to be placed by the signed in user; got buyer %d", order.BuyerID)
+			}
+			return 1, nil
 		},
 	}
 	app := fiber.New()
 	s := &FiberServer{App: app, db: &mockDB}
-	app.Post("/api/orders", s.CreateOrderHandler)
+	app.Post("/api/orders", requireUser, s.CreateOrderHandler)
 
 	orderRequest := database.OrderRequest{
-		BuyerID:   1,
-		OrderDate: time.Now(),
-		Total:     99.99,
-		Status:    "Processing",
+		BuyerID:          1,
+		SellerID:         2,
+		ProductID:        1,
+		Quantity:         1,
+		ShippingMethodID: 1,
+		OrderDate:        time.Now(),
+		Status:           "Processing",
 	}
 	orderRequestBytes, err := json.Marshal(orderRequest)
 	if err != nil {
@@ -576,6 +1415,7 @@ func TestCreateOrderHandler(t *testing.T) {
 		t.Fatalf("error creating request. Err: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 3)
 
 	resp, err := app.Test(req)
 	if err != nil {
@@ -598,49 +1438,55 @@ func TestCreateOrderHandler(t *testing.T) {
 }
 
 func TestUpdateOrderHandler(t *testing.T) {
-	mockDB := MockDBService{
-		UpdateOrderFunc: func(orderID int, order database.OrderRequest) error {
-			return nil
-		},
-	}
-	app := fiber.New()
-	s := &FiberServer{App: app, db: &mockDB}
-	app.Put("/api/orders/:id", s.UpdateOrderHandler)
-
-	orderRequest := database.OrderRequest{
-		BuyerID:   1,
-		OrderDate: time.Now(),
-		Total:     89.99,
-		Status:    "Shipped",
-	}
-	orderRequestBytes, err := json.Marshal(orderRequest)
-	if err != nil {
-		t.Fatalf("error marshalling order request. Err: %v", err)
-	}
-
-	req, err := http.NewRequest("PUT", "/api/orders/1", strings.NewReader(string(orderRequestBytes)))
-	if err != nil {
-		t.Fatalf("error creating request. Err: %v", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := app.Test(req)
-	if err != nil {
-		t.Fatalf("error making request to server. Err: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		t.Errorf("expected status OK; got %v", resp.Status)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		t.Fatalf("error reading response body. Err: %v", err)
-	}
-
-	expected := "{\"message\":\"order updated\"}"
-	if expected != string(body) {
-		t.Errorf("expected response body to be %v; got %v", expected, string(body))
+	tests := []struct {
+		name           string
+		userID         int
+		body           string
+		dbErr          error
+		expectedStatus int
+	}{
+		{"buyer pays", 5, `{"status":"processing"}`, nil, http.StatusOK},
+		{"anonymous", 0, `{"status":"processing"}`, nil, http.StatusUnauthorized},
+		{"not a party", 7, `{"status":"processing"}`, database.ErrNotOrderParticipant, http.StatusForbidden},
+		{"buyer adds tracking", 5, `{"tracking_number":"1Z999AA10123456784"}`, database.ErrNotOrderSeller, http.StatusForbidden},
+		{"changed quantity", 5, `{"quantity":3}`, database.ErrOrderFixed, http.StatusBadRequest},
+		{"out of cancelled", 5, `{"status":"pending"}`, database.ErrOrderStatusChange, http.StatusConflict},
+		{"ships unpaid order", 1, `{"tracking_number":"1Z999AA10123456784"}`, database.ErrOrderNotShippable, http.StatusConflict},
+		{"unknown order", 5, `{"status":"processing"}`, sql.ErrNoRows, http.StatusNotFound},
+		{"invalid body", 5, `{"status":`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				UpdateOrderFunc: func(orderID int, userID int, order database.OrderRequest) (database.OrderChange, error) {
+					if userID != tt.userID {
+						t.Errorf("expected the order to be changed by user %d; got %d", tt.userID, userID)
+					}
+					return database.OrderChange{}, tt.dbErr
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Put("/api/orders/:id", requireUser, s.UpdateOrderHandler)
+
+			req, err := http.NewRequest("PUT", "/api/orders/1", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tt.userID != 0 {
+				setSession(t, req, tt.userID)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+		})
 	}
 }
 
@@ -764,8 +1610,8 @@ func TestGetUserByIDHandler(t *testing.T) {
 
 func TestCreateUserHandler(t *testing.T) {
 	mockDB := MockDBService{
-		CreateUserFunc: func(user database.UserRequest) error {
-			return nil
+		CreateUserFunc: func(user database.UserRequest) (int, error) {
+			return 1, nil
 		},
 	}
 	app := fiber.New()
@@ -826,7 +1672,7 @@ func TestUpdateUserHandler(t *testing.T) {
 	}
 	app := fiber.New()
 	s := &FiberServer{App: app, db: &mockDB}
-	app.Put("/api/users/:id", s.UpdateUserHandler)
+	app.Put("/api/users/:id", requireUser, s.UpdateUserHandler)
 
 	userRequest := database.UserRequest{
 		Username:     "john_doe_updated",
@@ -848,11 +1694,30 @@ func TestUpdateUserHandler(t *testing.T) {
 		t.Fatalf("error marshalling user request. Err: %v", err)
 	}
 
+	for userID, expectedStatus := range map[int]int{0: http.StatusUnauthorized, 2: http.StatusForbidden} {
+		req, err := http.NewRequest("PUT", "/api/users/1", strings.NewReader(string(userRequestBytes)))
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if userID != 0 {
+			setSession(t, req, userID)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("user %d: expected status %v; got %v", userID, expectedStatus, resp.Status)
+		}
+	}
+
 	req, err := http.NewRequest("PUT", "/api/users/1", strings.NewReader(string(userRequestBytes)))
 	if err != nil {
 		t.Fatalf("error creating request. Err: %v", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 1)
 
 	resp, err := app.Test(req)
 	if err != nil {
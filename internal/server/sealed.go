@@ -0,0 +1,116 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListSealedProductsHandler returns the sealed product catalog, optionally
+// narrowed to a game with ?tcg_game_id= and a set with ?set=.
+func (s *FiberServer) ListSealedProductsHandler(c *fiber.Ctx) error {
+	filter := database.SealedProductFilter{
+		TCGGameID: c.QueryInt("tcg_game_id"),
+		SetName:   c.Query("set"),
+	}
+	products, err := s.db.ListSealedProducts(filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sealed products",
+		})
+	}
+	return c.JSON(fiber.Map{"sealed_products": products})
+}
+
+func (s *FiberServer) GetSealedProductHandler(c *fiber.Ctx) error {
+	sealedProductID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sealed product ID",
+		})
+	}
+
+	product, err := s.db.GetSealedProduct(sealedProductID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Sealed product not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sealed product",
+		})
+	}
+	return c.JSON(fiber.Map{"sealed_product": product})
+}
+
+func (s *FiberServer) CreateSealedProductHandler(c *fiber.Ctx) error {
+	var req database.SealedProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	sealedProductID, err := s.db.CreateSealedProduct(req)
+	if err != nil {
+		return sealedProductError(c, err)
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"sealed_product_id": sealedProductID})
+}
+
+func (s *FiberServer) UpdateSealedProductHandler(c *fiber.Ctx) error {
+	sealedProductID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sealed product ID",
+		})
+	}
+
+	var req database.SealedProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if err := s.db.UpdateSealedProduct(sealedProductID, req); err != nil {
+		return sealedProductError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "sealed product updated"})
+}
+
+func (s *FiberServer) DeleteSealedProductHandler(c *fiber.Ctx) error {
+	sealedProductID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sealed product ID",
+		})
+	}
+
+	if err := s.db.DeleteSealedProduct(sealedProductID); err != nil {
+		return sealedProductError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "sealed product deleted"})
+}
+
+func sealedProductError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Sealed product not found",
+		})
+	case errors.Is(err, database.ErrInvalidSealedProduct):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Sealed products need a name, a game and a product type of " + strings.Join(database.SealedProductTypes, ", "),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save sealed product",
+		})
+	}
+}
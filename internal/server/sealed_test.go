@@ -0,0 +1,96 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"net/http"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestListSealedProductsHandler(t *testing.T) {
+	var received database.SealedProductFilter
+	mockDB := MockDBService{
+		ListSealedProductsFunc: func(filter database.SealedProductFilter) ([]database.SealedProduct, error) {
+			received = filter
+			return []database.SealedProduct{{SealedProductID: 1, Name: "Alpha Booster Box", ProductType: "booster_box", TCGGameID: 2}}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/sealed-products", s.ListSealedProductsHandler)
+
+	req, err := http.NewRequest("GET", "/api/sealed-products?tcg_game_id=2&set=Alpha", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", resp.Status)
+	}
+	if received.TCGGameID != 2 || received.SetName != "Alpha" {
+		t.Errorf("unexpected filter %+v", received)
+	}
+}
+
+func TestCreateSealedProductHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"created", nil, http.StatusCreated},
+		{"unknown product type", database.ErrInvalidSealedProduct, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				CreateSealedProductFunc: func(product database.SealedProductRequest) (int, error) {
+					return 1, tt.err
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/admin/sealed-products", s.CreateSealedProductHandler)
+
+			resp := postJSON(t, app, "/api/admin/sealed-products", database.SealedProductRequest{Name: "Alpha Booster Box", ProductType: "booster_box", TCGGameID: 2})
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+		})
+	}
+}
+
+func TestCreateProductHandlerRejectsInvalidItem(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"card and sealed product", database.ErrInvalidProduct},
+		{"card condition on sealed product", database.ErrInvalidCondition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				CreateProductFunc: func(product database.ProductRequest) (int, error) {
+					return 0, tt.err
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/products", requireUser, s.CreateProductHandler)
+
+			resp := postJSONAs(t, app, "/api/products", database.ProductRequest{SealedProductID: 1, Price: currency.New(12000, "EUR"), Condition: "mint", Quantity: 1, LanguageID: 1}, 1)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected status Bad Request; got %v", resp.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type vacationRequest struct {
+	OnVacation bool `json:"on_vacation"`
+	// ReturnDate is the day, as YYYY-MM-DD, vacation mode ends by itself.
+	ReturnDate string `json:"return_date,omitempty"`
+}
+
+// GetSellerHandler returns the public storefront profile of a seller, with a
+// notice while the seller is on vacation.
+func (s *FiberServer) GetSellerHandler(c *fiber.Ctx) error {
+	profile, err := s.db.GetSellerProfile(c.Params("username"))
+	if err != nil {
+		return sellerError(c, err)
+	}
+	response := fiber.Map{"seller": profile}
+	if profile.OnVacation {
+		response["notice"] = vacationNotice(profile)
+	}
+	return c.JSON(response)
+}
+
+// ListSellerProductsHandler lists the products of a seller's shop, taking
+// the same filters as ListProductsHandler. A seller on vacation has no
+// products listed.
+func (s *FiberServer) ListSellerProductsHandler(c *fiber.Ctx) error {
+	filter, err := productFilter(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product filter",
+		})
+	}
+
+	profile, err := s.db.GetSellerProfile(c.Params("username"))
+	if err != nil {
+		return sellerError(c, err)
+	}
+	if profile.OnVacation {
+		return c.JSON(fiber.Map{"products": []database.Product{}, "on_vacation": true, "notice": vacationNotice(profile)})
+	}
+
+	filter.SellerID = profile.UserID
+	return s.listProducts(c, filter)
+}
+
+// SetVacationHandler turns vacation mode of the current user on or off.
+func (s *FiberServer) SetVacationHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if userID != currentUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only change your own vacation mode",
+		})
+	}
+
+	var req vacationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var until *time.Time
+	if req.ReturnDate != "" {
+		date, err := time.Parse(time.DateOnly, req.ReturnDate)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Return date must be formatted as YYYY-MM-DD",
+			})
+		}
+		until = &date
+	}
+
+	if err := s.db.SetVacation(userID, req.OnVacation, until); err != nil {
+		if errors.Is(err, database.ErrInvalidVacation) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Return date must be in the future and requires vacation mode",
+			})
+		}
+		return sellerError(c, err)
+	}
+	return c.JSON(fiber.Map{"on_vacation": req.OnVacation, "vacation_until": until})
+}
+
+// vacationNotice tells storefront visitors that the seller is away.
+func vacationNotice(profile database.SellerProfile) string {
+	if profile.VacationUntil == nil {
+		return fmt.Sprintf("%s is on vacation. Their products are hidden until they return.", profile.Username)
+	}
+	return fmt.Sprintf("%s is on vacation until %s. Their products are hidden until then.", profile.Username, profile.VacationUntil.Format("2 January 2006"))
+}
+
+// endVacationsJob ends the vacations whose return date has passed, which
+// lists the sellers' products again, and tells the sellers.
+func (s *FiberServer) endVacationsJob() scheduler.Job {
+	return scheduler.Job{Name: "end_vacations", Run: func(ctx context.Context) error {
+		userIDs, err := s.db.EndVacations(time.Now())
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			s.notify(userID, database.NotificationVacationEnded, fiber.Map{"user_id": userID})
+		}
+		return nil
+	}}
+}
+
+func sellerError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Seller not found",
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to fetch seller",
+	})
+}
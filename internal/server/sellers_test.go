@@ -0,0 +1,268 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func sellerMockDB(onVacation bool, filters *[]database.ProductFilter) MockDBService {
+	return MockDBService{
+		GetSellerProfileFunc: func(username string) (database.SellerProfile, error) {
+			if username != "cardshop" {
+				return database.SellerProfile{}, sql.ErrNoRows
+			}
+			return database.SellerProfile{UserID: 7, Username: username, SellerType: "professional", OnVacation: onVacation}, nil
+		},
+		ListProductsFunc: func(filter database.ProductFilter) ([]database.Product, error) {
+			*filters = append(*filters, filter)
+			return []database.Product{{ProductID: 1, Seller: "cardshop"}}, nil
+		},
+	}
+}
+
+func TestGetSellerHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		username       string
+		expectedStatus int
+	}{
+		{"found", "cardshop", http.StatusOK},
+		{"unknown seller", "nobody", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters []database.ProductFilter
+			mockDB := sellerMockDB(false, &filters)
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Get("/api/sellers/:username", s.GetSellerHandler)
+
+			req, err := http.NewRequest("GET", "/api/sellers/"+tt.username, nil)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+		})
+	}
+}
+
+func TestListSellerProductsHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		onVacation     bool
+		expectedStatus int
+		expectedFilter *database.ProductFilter
+	}{
+		{"all products", "/api/sellers/cardshop/products", false, http.StatusOK, &database.ProductFilter{SellerID: 7}},
+		{"filtered", "/api/sellers/cardshop/products?type=single&tcg_game_id=2&condition=near+mint", false, http.StatusOK, &database.ProductFilter{SellerID: 7, TCGGameID: 2, Condition: "near mint", Kind: database.ProductKindSingle}},
+		{"on vacation", "/api/sellers/cardshop/products", true, http.StatusOK, nil},
+		{"invalid type", "/api/sellers/cardshop/products?type=graded", false, http.StatusBadRequest, nil},
+		{"invalid game", "/api/sellers/cardshop/products?tcg_game_id=abc", false, http.StatusBadRequest, nil},
+		{"unknown seller", "/api/sellers/nobody/products", false, http.StatusNotFound, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filters []database.ProductFilter
+			mockDB := sellerMockDB(tt.onVacation, &filters)
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Get("/api/sellers/:username/products", s.ListSellerProductsHandler)
+
+			req, err := http.NewRequest("GET", tt.path, nil)
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedFilter == nil {
+				if len(filters) != 0 {
+					t.Errorf("expected no product listing; got %+v", filters)
+				}
+				return
+			}
+			if len(filters) != 1 || filters[0] != *tt.expectedFilter {
+				t.Errorf("expected filter %+v; got %+v", *tt.expectedFilter, filters)
+			}
+		})
+	}
+
+	t.Run("vacation notice", func(t *testing.T) {
+		var filters []database.ProductFilter
+		mockDB := sellerMockDB(true, &filters)
+		app := fiber.New()
+		s := &FiberServer{App: app, db: &mockDB}
+		app.Get("/api/sellers/:username/products", s.ListSellerProductsHandler)
+
+		req, _ := http.NewRequest("GET", "/api/sellers/cardshop/products", nil)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		var body struct {
+			Products   []database.Product `json:"products"`
+			OnVacation bool               `json:"on_vacation"`
+			Notice     string             `json:"notice"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("error decoding response. Err: %v", err)
+		}
+		if !body.OnVacation || body.Notice == "" || body.Products == nil || len(body.Products) != 0 {
+			t.Errorf("unexpected response %+v", body)
+		}
+	})
+}
+
+func TestSetVacationHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedUntil  string
+	}{
+		{"own shop", "/api/users/2/vacation", `{"on_vacation":true}`, http.StatusOK, ""},
+		{"return date", "/api/users/2/vacation", `{"on_vacation":true,"return_date":"2099-08-01"}`, http.StatusOK, "2099-08-01"},
+		{"past return date", "/api/users/2/vacation", `{"on_vacation":true,"return_date":"2001-08-01"}`, http.StatusBadRequest, "2001-08-01"},
+		{"malformed return date", "/api/users/2/vacation", `{"on_vacation":true,"return_date":"1 August"}`, http.StatusBadRequest, ""},
+		{"other shop", "/api/users/3/vacation", `{"on_vacation":true}`, http.StatusForbidden, ""},
+		{"invalid id", "/api/users/abc/vacation", `{"on_vacation":true}`, http.StatusBadRequest, ""},
+		{"invalid body", "/api/users/2/vacation", `{"on_vacation":`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				called bool
+				until  *time.Time
+			)
+			mockDB := MockDBService{
+				SetVacationFunc: func(userID int, onVacation bool, returnDate *time.Time) error {
+					called, until = true, returnDate
+					if returnDate != nil && !returnDate.After(time.Now()) {
+						return database.ErrInvalidVacation
+					}
+					return nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Put("/api/users/:id/vacation", requireUser, s.SetVacationHandler)
+
+			req, err := http.NewRequest("PUT", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusOK && !called {
+				t.Errorf("expected vacation to be updated")
+			}
+			if tt.expectedUntil == "" {
+				if until != nil {
+					t.Errorf("expected no return date; got %v", until)
+				}
+			} else if until == nil || until.Format(time.DateOnly) != tt.expectedUntil {
+				t.Errorf("expected return date %v; got %v", tt.expectedUntil, until)
+			}
+		})
+	}
+}
+
+func TestVacationNotice(t *testing.T) {
+	until := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		profile database.SellerProfile
+		want    string
+	}{
+		{database.SellerProfile{Username: "cardshop", OnVacation: true}, "cardshop is on vacation. Their products are hidden until they return."},
+		{database.SellerProfile{Username: "cardshop", OnVacation: true, VacationUntil: &until}, "cardshop is on vacation until 1 August 2025. Their products are hidden until then."},
+	}
+	for _, tt := range tests {
+		if got := vacationNotice(tt.profile); got != tt.want {
+			t.Errorf("vacationNotice(%+v) = %q; want %q", tt.profile, got, tt.want)
+		}
+	}
+}
+
+func TestEndVacationsJob(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		EndVacationsFunc: func(now time.Time) ([]int, error) {
+			return []int{3, 5}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			if notificationType != database.NotificationVacationEnded {
+				t.Errorf("unexpected notification type %v", notificationType)
+			}
+			notified = append(notified, userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{db: &mockDB}
+
+	if err := s.endVacationsJob().Run(context.Background()); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !slices.Equal(notified, []int{3, 5}) {
+		t.Errorf("expected sellers 3 and 5 to be notified; got %v", notified)
+	}
+}
+
+func TestCreateOrderHandlerSellerOnVacation(t *testing.T) {
+	mockDB := MockDBService{
+		CreateOrderFunc: func(order database.OrderRequest) (int, error) {
+			return 0, database.ErrSellerOnVacation
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/orders", requireUser, s.CreateOrderHandler)
+
+	req, err := http.NewRequest("POST", "/api/orders", strings.NewReader(`{"buyer_id":1,"seller_id":2,"product_id":1,"quantity":1,"shipping_method_id":1}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 1)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("expected status Conflict; got %v", resp.Status)
+	}
+}
@@ -1,26 +1,149 @@
 package server
 
 import (
+	"cardmarket_backend/internal/currency"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 
 	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/images"
+	"cardmarket_backend/internal/mailer"
+	"cardmarket_backend/internal/notification"
+	"cardmarket_backend/internal/scheduler"
+	"cardmarket_backend/internal/storage"
+	"cardmarket_backend/internal/tracking"
+)
+
+// Mail dispatcher sizing: emails are queued in memory and sent by a small
+// pool of workers.
+const (
+	mailQueueSize = 256
+	mailWorkers   = 2
+)
+
+// Background job defaults, overridable through the environment variable named
+// in each comment.
+const (
+	// SCHEDULER_INTERVAL: how often the order jobs run and carriers are asked
+	// about shipments in transit.
+	defaultSchedulerInterval = 5 * time.Minute
+	// ORDER_PAYMENT_WINDOW: how long an order may stay unpaid.
+	defaultPaymentWindow = 72 * time.Hour
+	// ORDER_SHIPPING_REMINDER_AFTER: how long after payment sellers are
+	// reminded to ship.
+	defaultShippingReminderAfter = 48 * time.Hour
+	// ORDER_AUTO_COMPLETE_DAYS: how many days after shipping an order is
+	// completed when the buyer never confirms it.
+	defaultAutoCompleteDays = 14
+	// TRADE_SHIPPING_WINDOW: how long after acceptance a trade is cancelled
+	// when nothing was shipped.
+	defaultTradeShippingWindow = 7 * 24 * time.Hour
 )
 
+// schedulerLockKey identifies the advisory lock held by the replica running
+// the background jobs.
+const schedulerLockKey int64 = 7_310_101
+
 type FiberServer struct {
 	*fiber.App
 
-	db database.Service
+	db            database.Service
+	notifications *notification.Hub
+	mailer        *mailer.Dispatcher
+	scheduler     *scheduler.Scheduler
+	storage       storage.Storage
 }
 
 func New() *FiberServer {
+	if len(tokenSecret) == 0 {
+		log.Fatal("APP_SECRET must be set")
+	}
+
+	sender, err := mailer.NewSenderFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tracker, err := tracking.NewTrackerFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rateSource, err := currency.NewSourceFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store, err := storage.NewFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobs := orderJobsConfig{
+		paymentWindow:         durationFromEnv("ORDER_PAYMENT_WINDOW", defaultPaymentWindow),
+		shippingReminderAfter: durationFromEnv("ORDER_SHIPPING_REMINDER_AFTER", defaultShippingReminderAfter),
+		autoCompleteAfter:     time.Duration(intFromEnv("ORDER_AUTO_COMPLETE_DAYS", defaultAutoCompleteDays)) * 24 * time.Hour,
+	}
+
 	server := &FiberServer{
 		App: fiber.New(fiber.Config{
 			ServerHeader: "cardmarket_backend",
 			AppName:      "cardmarket_backend",
+			// Leave room for an image upload and its multipart framing.
+			BodyLimit: images.MaxSize + 1<<20,
 		}),
 
-		db: database.New(),
+		db:            database.New(),
+		notifications: notification.NewHub(),
+		mailer:        mailer.NewDispatcher(sender, mailQueueSize),
+		storage:       store,
+	}
+	server.mailer.Start(mailWorkers)
+	server.notifications.Start(server.db)
+
+	scheduled := append(server.orderJobs(jobs), server.exchangeRateJob(rateSource), server.closeAuctionsJob(), server.expireOffersJob(), server.endVacationsJob(), server.refreshReputationsJob(), server.cancelStalledTradesJob(durationFromEnv("TRADE_SHIPPING_WINDOW", defaultTradeShippingWindow)))
+	if tracker != nil {
+		scheduled = append(scheduled, server.trackShipmentsJob(tracker))
 	}
+	server.scheduler = scheduler.New(server.db.AdvisoryLock(schedulerLockKey), durationFromEnv("SCHEDULER_INTERVAL", defaultSchedulerInterval), scheduled...)
+	server.scheduler.Start()
 
 	return server
 }
+
+// Close stops the background workers once the HTTP server has shut down.
+func (s *FiberServer) Close() {
+	s.scheduler.Close()
+	s.notifications.Close()
+	s.mailer.Close()
+}
+
+// durationFromEnv reads a duration such as "90m" from the environment.
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Fatalf("invalid %s: %q", key, value)
+	}
+	return d
+}
+
+// intFromEnv reads a positive integer from the environment.
+func intFromEnv(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Fatalf("invalid %s: %q", key, value)
+	}
+	return n
+}
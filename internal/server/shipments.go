@@ -0,0 +1,197 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/pdf"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxBatchOrders bounds the number of orders printed in one request.
+const maxBatchOrders = 500
+
+type shipmentBatchRequest struct {
+	OrderIDs []int `json:"order_ids"`
+}
+
+// ShippingLabelsHandler returns address labels for a selection of the
+// seller's paid orders as a PDF.
+func (s *FiberServer) ShippingLabelsHandler(c *fiber.Ctx) error {
+	slips, err := s.packingSlips(c)
+	if err != nil || slips == nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="labels.pdf"`)
+	return c.Send(renderLabels(slips))
+}
+
+// PackingSlipsHandler returns a packing slip per order for a selection of
+// the seller's paid orders as a PDF.
+func (s *FiberServer) PackingSlipsHandler(c *fiber.Ctx) error {
+	slips, err := s.packingSlips(c)
+	if err != nil || slips == nil {
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `inline; filename="packing-slips.pdf"`)
+	return c.Send(renderPackingSlips(slips))
+}
+
+// packingSlips loads the orders selected in the request body. It writes the
+// error response itself and returns nil slips when the request fails.
+func (s *FiberServer) packingSlips(c *fiber.Ctx) ([]database.PackingSlip, error) {
+	var req shipmentBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	seen := make(map[int]bool, len(req.OrderIDs))
+	orderIDs := make([]int, 0, len(req.OrderIDs))
+	for _, id := range req.OrderIDs {
+		if !seen[id] {
+			seen[id] = true
+			orderIDs = append(orderIDs, id)
+		}
+	}
+	if len(orderIDs) == 0 || len(orderIDs) > maxBatchOrders {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("Select between 1 and %d orders", maxBatchOrders),
+		})
+	}
+
+	slips, err := s.db.ListPackingSlips(currentUserID(c), orderIDs)
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to load orders",
+		})
+	}
+
+	if len(slips) != len(orderIDs) {
+		found := make(map[int]bool, len(slips))
+		for _, slip := range slips {
+			found[slip.OrderID] = true
+		}
+		missing := []int{}
+		for _, id := range orderIDs {
+			if !found[id] {
+				missing = append(missing, id)
+			}
+		}
+		return nil, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":     "Only your own paid orders that have not shipped can be printed",
+			"order_ids": missing,
+		})
+	}
+	return slips, nil
+}
+
+// Labels are printed on A4 sheets of 2 by 7 labels.
+const (
+	labelColumns = 2
+	labelRows    = 7
+	labelPadding = 14.0
+)
+
+func renderLabels(slips []database.PackingSlip) []byte {
+	doc := pdf.New()
+	width := pdf.A4Width / labelColumns
+	height := pdf.A4Height / labelRows
+
+	var page *pdf.Page
+	for i, slip := range slips {
+		cell := i % (labelColumns * labelRows)
+		if cell == 0 {
+			page = doc.AddPage(pdf.A4Width, pdf.A4Height)
+		}
+		x := float64(cell%labelColumns) * width
+		top := pdf.A4Height - float64(cell/labelColumns)*height
+
+		y := top - labelPadding - 6
+		page.Text(x+labelPadding, y, pdf.Regular, 7, "From: "+strings.Join(addressLines(slip.Sender), ", "))
+
+		y -= 16
+		for j, line := range shipToLines(slip) {
+			font := pdf.Regular
+			if j == 0 {
+				font = pdf.Bold
+			}
+			page.Text(x+labelPadding, y, font, 10, line)
+			y -= 12
+		}
+
+		reference := fmt.Sprintf("Order #%d", slip.OrderID)
+		if slip.ShippingMethod != nil {
+			reference += " - " + *slip.ShippingMethod
+		}
+		page.Text(x+labelPadding, top-height+labelPadding, pdf.Regular, 7, reference)
+	}
+	return doc.Bytes()
+}
+
+func renderPackingSlips(slips []database.PackingSlip) []byte {
+	doc := pdf.New()
+	for _, slip := range slips {
+		page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+
+		y := pdf.A4Height - 60
+		page.Text(marginLeft, y, pdf.Bold, 18, fmt.Sprintf("Packing slip - Order #%d", slip.OrderID))
+		y -= 2 * lineHeight
+		page.Text(marginLeft, y, pdf.Regular, 10, "Order date: "+slip.OrderDate.Format("2006-01-02"))
+		y -= lineHeight
+		page.Text(marginLeft, y, pdf.Regular, 10, "Buyer: "+slip.Buyer)
+		if slip.ShippingMethod != nil {
+			y -= lineHeight
+			page.Text(marginLeft, y, pdf.Regular, 10, "Shipping: "+*slip.ShippingMethod)
+		}
+
+		y -= 2 * lineHeight
+		page.Text(marginLeft, y, pdf.Bold, 10, "Ship to")
+		for _, line := range shipToLines(slip) {
+			y -= lineHeight
+			page.Text(marginLeft, y, pdf.Regular, 10, line)
+		}
+
+		y -= 2 * lineHeight
+		page.Text(marginLeft, y, pdf.Bold, 10, "Qty")
+		page.Text(85, y, pdf.Bold, 10, "Card")
+		page.Text(250, y, pdf.Bold, 10, "Set")
+		page.Text(370, y, pdf.Bold, 10, "Number")
+		page.Text(425, y, pdf.Bold, 10, "Condition")
+		page.Text(490, y, pdf.Bold, 10, "Language")
+		y -= 6
+		page.Line(marginLeft, y, marginRight, y)
+		for _, item := range slip.Items {
+			y -= lineHeight
+			page.TextRight(70, y, 10, strconv.Itoa(item.Quantity))
+			page.Text(85, y, pdf.Regular, 10, item.Card)
+			page.Text(250, y, pdf.Regular, 10, item.SetName)
+			page.Text(370, y, pdf.Regular, 10, item.CardNumber)
+			page.Text(425, y, pdf.Regular, 10, item.Condition)
+			page.Text(490, y, pdf.Regular, 10, item.Language)
+		}
+	}
+	return doc.Bytes()
+}
+
+// shipToLines returns the recipient address of an order, splitting the
+// free-text address of older orders at commas and line breaks.
+func shipToLines(slip database.PackingSlip) []string {
+	if slip.ShipTo != nil {
+		return addressLines(*slip.ShipTo)
+	}
+	lines := []string{}
+	for _, line := range strings.FieldsFunc(slip.ShippingAddress, func(r rune) bool { return r == ',' || r == '\n' }) {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"bytes"
+	"cardmarket_backend/internal/database"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestShipmentDocumentHandlers(t *testing.T) {
+	method := "Tracked letter"
+	slips := map[int]database.PackingSlip{
+		4: {
+			OrderID:        4,
+			OrderDate:      time.Date(2025, time.November, 12, 10, 0, 0, 0, time.UTC),
+			Buyer:          "marie",
+			ShippingMethod: &method,
+			Sender:         database.OrderAddress{Name: "Hans Müller", StreetName: "Hauptstraße", StreetNumber: "1", City: "Berlin", ZipCode: "10115", Country: "Germany"},
+			ShipTo:         &database.OrderAddress{Name: "Marie Dubois", StreetName: "Rue de Rivoli", StreetNumber: "5", City: "Paris", ZipCode: "75001", Country: "France"},
+			Items:          []database.PackingSlipItem{{Quantity: 2, Card: "Black Lotus", SetName: "Alpha", CardNumber: "232", Condition: "near_mint", Language: "English"}},
+		},
+		5: {
+			OrderID:         5,
+			OrderDate:       time.Date(2025, time.November, 12, 11, 0, 0, 0, time.UTC),
+			Buyer:           "jan",
+			Sender:          database.OrderAddress{Name: "Hans Müller", StreetName: "Hauptstraße", StreetNumber: "1", City: "Berlin", ZipCode: "10115", Country: "Germany"},
+			ShippingAddress: "Jan de Vries, Damrak 1, 1012 LG Amsterdam",
+			Items:           []database.PackingSlipItem{{Quantity: 1, Card: "Mox Pearl", SetName: "Beta", CardNumber: "263", Condition: "excellent", Language: "German"}},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedIDs    []int
+		contains       []string
+	}{
+		{"labels", "/api/shipments/labels.pdf", `{"order_ids":[4,5,4]}`, http.StatusOK, []int{4, 5}, []string{"Marie Dubois", "Jan de Vries", "Damrak 1", "Order #4 - Tracked letter", "From: Hans M\\374ller, Hauptstra\\337e 1"}},
+		{"packing slips", "/api/shipments/packing-slips.pdf", `{"order_ids":[4,5]}`, http.StatusOK, []int{4, 5}, []string{"Packing slip - Order #4", "Black Lotus", "Alpha", "232", "near_mint", "English", "Mox Pearl", "/Count 2"}},
+		{"order not printable", "/api/shipments/labels.pdf", `{"order_ids":[4,9]}`, http.StatusConflict, []int{4, 9}, []string{`"order_ids":[9]`}},
+		{"no orders", "/api/shipments/labels.pdf", `{"order_ids":[]}`, http.StatusBadRequest, nil, nil},
+		{"invalid body", "/api/shipments/packing-slips.pdf", `{"order_ids":"4"}`, http.StatusBadRequest, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				requestedBy  int
+				requestedIDs []int
+			)
+			mockDB := MockDBService{
+				ListPackingSlipsFunc: func(sellerID int, orderIDs []int) ([]database.PackingSlip, error) {
+					requestedBy, requestedIDs = sellerID, orderIDs
+					found := []database.PackingSlip{}
+					for _, id := range orderIDs {
+						if slip, ok := slips[id]; ok {
+							found = append(found, slip)
+						}
+					}
+					return found, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			shipments := app.Group("/api/shipments", requireUser)
+			shipments.Post("/labels.pdf", s.ShippingLabelsHandler)
+			shipments.Post("/packing-slips.pdf", s.PackingSlipsHandler)
+
+			req, err := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 1)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedIDs != nil {
+				if requestedBy != 1 {
+					t.Errorf("expected orders of seller 1; got %v", requestedBy)
+				}
+				if !reflect.DeepEqual(requestedIDs, tt.expectedIDs) {
+					t.Errorf("expected orders %v; got %v", tt.expectedIDs, requestedIDs)
+				}
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("error reading response body. Err: %v", err)
+			}
+			if tt.expectedStatus == http.StatusOK && !bytes.HasPrefix(body, []byte("%PDF-")) {
+				t.Errorf("expected a PDF; got %q", body)
+			}
+			if tt.expectedStatus == http.StatusConflict && !json.Valid(body) {
+				t.Errorf("expected a JSON error; got %q", body)
+			}
+			for _, want := range tt.contains {
+				if !bytes.Contains(body, []byte(want)) {
+					t.Errorf("expected response to contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderLabelsPaginates(t *testing.T) {
+	slips := make([]database.PackingSlip, labelColumns*labelRows+1)
+	for i := range slips {
+		slips[i] = database.PackingSlip{OrderID: i + 1, ShippingAddress: "Somewhere"}
+	}
+
+	if got := renderLabels(slips); !bytes.Contains(got, []byte("/Count 2")) {
+		t.Errorf("expected labels to continue on a second page")
+	}
+}
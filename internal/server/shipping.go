@@ -0,0 +1,113 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListShippingMethodsHandler(c *fiber.Ctx) error {
+	methods, err := s.db.ListShippingMethods()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch shipping methods",
+		})
+	}
+	return c.JSON(fiber.Map{"shipping_methods": methods})
+}
+
+func (s *FiberServer) ListSellerShippingMethodsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	sellerID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	methods, err := s.db.ListSellerShippingMethods(sellerID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch shipping methods",
+		})
+	}
+	return c.JSON(fiber.Map{"shipping_methods": methods})
+}
+
+func (s *FiberServer) SetSellerShippingMethodsHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	sellerID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	if sellerID != currentUserID(c) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You can only configure your own shipping methods",
+		})
+	}
+
+	var req database.SellerShippingMethodsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if len(req.ShippingMethodIDs) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "At least one shipping method is required",
+		})
+	}
+
+	if err := s.db.SetSellerShippingMethods(sellerID, req.ShippingMethodIDs); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update shipping methods",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "shipping methods updated"})
+}
+
+// QuoteShippingHandler returns the shipping options for buying a product,
+// priced for the buyer's country and the requested quantity.
+func (s *FiberServer) QuoteShippingHandler(c *fiber.Ctx) error {
+	id := c.Params("id")
+	productID, err := strconv.Atoi(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	buyerID, err := strconv.Atoi(c.Query("buyer_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid buyer ID",
+		})
+	}
+
+	quantity := c.QueryInt("quantity", 1)
+	if quantity <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Quantity must be positive",
+		})
+	}
+
+	quotes, err := s.db.QuoteShipping(productID, buyerID, quantity)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product or buyer not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to quote shipping",
+		})
+	}
+	return c.JSON(fiber.Map{"shipping": quotes})
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestQuoteShippingHandler(t *testing.T) {
+	quotes := []database.ShippingQuote{
+		{ShippingMethod: database.ShippingMethod{ShippingMethodID: 1, Code: "letter", Name: "Standard letter"}, Price: currency.New(115, "EUR")},
+		{ShippingMethod: database.ShippingMethod{ShippingMethodID: 2, Code: "tracked_letter", Name: "Tracked letter", IsTracked: true}, Price: currency.New(495, "EUR")},
+	}
+	var requestedQuantity int
+	mockDB := MockDBService{
+		QuoteShippingFunc: func(productID int, buyerID int, quantity int) ([]database.ShippingQuote, error) {
+			requestedQuantity = quantity
+			return quotes, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/products/:id/shipping", s.QuoteShippingHandler)
+
+	req, err := http.NewRequest("GET", "/api/products/1/shipping?buyer_id=2&quantity=3", nil)
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected status OK; got %v", resp.Status)
+	}
+	if requestedQuantity != 3 {
+		t.Errorf("expected quote for 3 items; got %v", requestedQuantity)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("error reading response body. Err: %v", err)
+	}
+	bytes, err := json.Marshal(quotes)
+	if err != nil {
+		t.Fatalf("error marshalling expected quotes. Err: %v", err)
+	}
+
+	expected := "{\"shipping\":" + string(bytes) + "}"
+	if expected != string(body) {
+		t.Errorf("expected response body to be %v; got %v", expected, string(body))
+	}
+}
+
+func TestSetSellerShippingMethodsHandlerForbidden(t *testing.T) {
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &MockDBService{}}
+	app.Put("/api/users/:id/shipping-methods", requireUser, s.SetSellerShippingMethodsHandler)
+
+	req, err := http.NewRequest("PUT", "/api/users/1/shipping-methods", strings.NewReader(`{"shipping_method_ids":[1,2]}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected status Forbidden; got %v", resp.Status)
+	}
+}
+
+func TestCreateOrderHandlerShippingUnavailable(t *testing.T) {
+	mockDB := MockDBService{
+		CreateOrderFunc: func(order database.OrderRequest) (int, error) {
+			return 0, database.ErrShippingMethodUnavailable
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/orders", requireUser, s.CreateOrderHandler)
+
+	orderBytes, err := json.Marshal(database.OrderRequest{BuyerID: 2, SellerID: 1, ProductID: 1, Quantity: 1, ShippingMethodID: 4})
+	if err != nil {
+		t.Fatalf("error marshalling order request. Err: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", "/api/orders", strings.NewReader(string(orderBytes)))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status Bad Request; got %v", resp.Status)
+	}
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListTaxRatesHandler(c *fiber.Ctx) error {
+	rates, err := s.db.ListTaxRates()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch tax rates",
+		})
+	}
+	return c.JSON(fiber.Map{"tax_rates": rates})
+}
+
+// CreateTaxRateHandler schedules a new tax rate for a country. Orders placed
+// from its effective date on are taxed at the new rate.
+func (s *FiberServer) CreateTaxRateHandler(c *fiber.Ctx) error {
+	var req database.TaxRateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.CountryCode = strings.ToUpper(req.CountryCode)
+
+	if req.RateBasisPoints < 0 || req.RateBasisPoints > 10_000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Rate must be between 0 and 10000 basis points",
+		})
+	}
+	if req.EffectiveFrom.IsZero() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Effective from date is required",
+		})
+	}
+	if req.EffectiveTo != nil && !req.EffectiveTo.After(req.EffectiveFrom) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Effective to date must be after the effective from date",
+		})
+	}
+
+	taxRateID, err := s.db.CreateTaxRate(req)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrUnknownCountry):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Unknown country",
+			})
+		case errors.Is(err, database.ErrTaxRateOverlap):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Tax rate overlaps an existing rate",
+			})
+		case errors.Is(err, database.ErrTaxRateGap):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "No tax rate follows this rate's end date",
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create tax rate",
+			})
+		}
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"tax_rate_id": taxRateID})
+}
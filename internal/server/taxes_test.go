@@ -0,0 +1,96 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateTaxRateHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		dbErr          error
+		expectedStatus int
+	}{
+		{
+			name:           "rate change",
+			body:           `{"country_code":"de","rate_basis_points":1600,"effective_from":"2020-07-01T00:00:00Z","effective_to":"2021-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "rate above 100%",
+			body:           `{"country_code":"DE","rate_basis_points":12000,"effective_from":"2020-07-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing start",
+			body:           `{"country_code":"DE","rate_basis_points":1900}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "ends before it starts",
+			body:           `{"country_code":"DE","rate_basis_points":1900,"effective_from":"2021-01-01T00:00:00Z","effective_to":"2020-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown country",
+			body:           `{"country_code":"XX","rate_basis_points":1900,"effective_from":"2021-01-01T00:00:00Z"}`,
+			dbErr:          database.ErrUnknownCountry,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "overlapping rate",
+			body:           `{"country_code":"DE","rate_basis_points":1900,"effective_from":"2021-01-01T00:00:00Z"}`,
+			dbErr:          database.ErrTaxRateOverlap,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:           "rate leaving a gap",
+			body:           `{"country_code":"DE","rate_basis_points":1600,"effective_from":"2030-07-01T00:00:00Z","effective_to":"2031-01-01T00:00:00Z"}`,
+			dbErr:          database.ErrTaxRateGap,
+			expectedStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var created database.TaxRateRequest
+			mockDB := MockDBService{
+				IsAdminFunc: func(userID int) (bool, error) {
+					return true, nil
+				},
+				CreateTaxRateFunc: func(rate database.TaxRateRequest) (int, error) {
+					created = rate
+					return 1, tt.dbErr
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			admin := app.Group("/api/admin", requireUser, s.requireAdmin)
+			admin.Post("/tax-rates", s.CreateTaxRateHandler)
+
+			req, err := http.NewRequest("POST", "/api/admin/tax-rates", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 9)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusCreated && (created.CountryCode != "DE" || created.RateBasisPoints != 1600 || created.EffectiveTo == nil) {
+				t.Errorf("unexpected tax rate %+v", created)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"cardmarket_backend/internal/tracking"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// normalizeTracking validates the tracking number of an order update and
+// stores it in canonical form together with its carrier. A carrier given by
+// the client must match the number.
+func normalizeTracking(order *database.OrderRequest) error {
+	var carrier tracking.Carrier
+	if order.Carrier != nil {
+		carrier = tracking.Carrier(*order.Carrier)
+	}
+
+	detected, number, err := tracking.Validate(carrier, *order.TrackingNumber)
+	if err != nil {
+		return err
+	}
+
+	code := string(detected)
+	order.Carrier = &code
+	order.TrackingNumber = &number
+	return nil
+}
+
+// trackShipmentsJob asks the carrier about the orders in transit and marks
+// the delivered ones.
+func (s *FiberServer) trackShipmentsJob(tracker tracking.Tracker) scheduler.Job {
+	poller := tracking.NewPoller(s.db, tracker, s.orderDelivered)
+	return scheduler.Job{Name: "track_shipments", Run: poller.Poll}
+}
+
+// orderDelivered tells both parties that the carrier delivered an order.
+func (s *FiberServer) orderDelivered(shipment tracking.Shipment) {
+	payload := fiber.Map{
+		"order_id":        shipment.OrderID,
+		"carrier":         shipment.Carrier,
+		"tracking_number": shipment.TrackingNumber,
+	}
+	s.notify(shipment.BuyerID, database.NotificationOrderDelivered, payload)
+	s.notify(shipment.SellerID, database.NotificationOrderDelivered, payload)
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/tracking"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestUpdateOrderHandlerTrackingNumber(t *testing.T) {
+	tests := []struct {
+		name            string
+		body            string
+		expectedStatus  int
+		expectedCarrier string
+		expectedNumber  string
+	}{
+		{
+			name:            "detected carrier",
+			body:            `{"tracking_number":"1z 999 aa1 01 2345 6784"}`,
+			expectedStatus:  http.StatusOK,
+			expectedCarrier: "ups",
+			expectedNumber:  "1Z999AA10123456784",
+		},
+		{
+			name:           "carrier mismatch",
+			body:           `{"carrier":"dhl","tracking_number":"1Z999AA10123456784"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown format",
+			body:           `{"tracking_number":"not-a-number"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated database.OrderRequest
+			mockDB := MockDBService{
+				UpdateOrderFunc: func(orderID int, userID int, order database.OrderRequest) (database.OrderChange, error) {
+					updated = order
+					return database.OrderChange{PreviousStatus: "processing", Status: "processing"}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Put("/api/orders/:id", requireUser, s.UpdateOrderHandler)
+
+			req, err := http.NewRequest("PUT", "/api/orders/1", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 1)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			if updated.Carrier == nil || *updated.Carrier != tt.expectedCarrier {
+				t.Errorf("expected carrier %v; got %v", tt.expectedCarrier, updated.Carrier)
+			}
+			if updated.TrackingNumber == nil || *updated.TrackingNumber != tt.expectedNumber {
+				t.Errorf("expected tracking number %v; got %v", tt.expectedNumber, updated.TrackingNumber)
+			}
+		})
+	}
+}
+
+func TestOrderDeliveredNotifiesParties(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			if notificationType != database.NotificationOrderDelivered {
+				t.Errorf("expected %v notification; got %v", database.NotificationOrderDelivered, notificationType)
+			}
+			notified = append(notified, userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	s.orderDelivered(tracking.Shipment{OrderID: 1, BuyerID: 2, SellerID: 1, Carrier: tracking.CarrierUPS, TrackingNumber: "1Z999AA10123456784"})
+
+	if len(notified) != 2 || notified[0] != 2 || notified[1] != 1 {
+		t.Errorf("expected buyer and seller to be notified; got %v", notified)
+	}
+}
+
+func TestTrackShipmentsJob(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		ListTrackedShipmentsFunc: func() ([]tracking.Shipment, error) {
+			return []tracking.Shipment{{OrderID: 1, BuyerID: 2, SellerID: 1, Carrier: tracking.CarrierUPS, TrackingNumber: "1Z999AA10123456784", ShippedAt: time.Now()}}, nil
+		},
+		MarkOrderDeliveredFunc: func(orderID int, deliveredAt time.Time) (bool, error) {
+			return true, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			notified = append(notified, userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+	tracker := tracking.NewFakeTracker(48 * time.Hour)
+	tracker.SetStatus("1Z999AA10123456784", tracking.StatusDelivered)
+
+	if err := s.trackShipmentsJob(tracker).Run(context.Background()); err != nil {
+		t.Fatalf("job failed: %v", err)
+	}
+	if len(notified) != 2 || notified[0] != 2 || notified[1] != 1 {
+		t.Errorf("expected buyer and seller to be notified; got %v", notified)
+	}
+}
@@ -0,0 +1,302 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"cardmarket_backend/internal/scheduler"
+	"cardmarket_backend/internal/tracking"
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListTradesHandler returns the trade history of the current user.
+func (s *FiberServer) ListTradesHandler(c *fiber.Ctx) error {
+	trades, err := s.db.ListUserTrades(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch trades",
+		})
+	}
+	return c.JSON(fiber.Map{"trades": trades})
+}
+
+func (s *FiberServer) GetTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	trade, err := s.db.GetTrade(tradeID, currentUserID(c))
+	if err != nil {
+		return tradeError(c, err)
+	}
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+// ProposeTradeHandler proposes a trade of the current user to another user.
+func (s *FiberServer) ProposeTradeHandler(c *fiber.Ctx) error {
+	var req database.TradeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	trade, err := s.db.ProposeTrade(currentUserID(c), req)
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(trade.RecipientID, database.NotificationTradeReceived, trade)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"trade": trade})
+}
+
+type acceptTradeRequest struct {
+	AddressID int `json:"address_id"`
+}
+
+// AcceptTradeHandler accepts a trade proposed to the current user, whose
+// cards are sent to the given address or their default address.
+func (s *FiberServer) AcceptTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	var req acceptTradeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	trade, err := s.db.AcceptTrade(tradeID, currentUserID(c), req.AddressID)
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(trade.ProposerID, database.NotificationTradeUpdated, trade)
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+func (s *FiberServer) DeclineTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	trade, err := s.db.DeclineTrade(tradeID, currentUserID(c))
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(trade.ProposerID, database.NotificationTradeUpdated, trade)
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+// CounterTradeHandler answers a trade proposed to the current user with a
+// proposal of their own. Offered are the current user's cards.
+func (s *FiberServer) CounterTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	var req database.TradeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	counter, err := s.db.CounterTrade(tradeID, currentUserID(c), req)
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(counter.RecipientID, database.NotificationTradeReceived, counter)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"trade": counter})
+}
+
+// PayTradeCashHandler records that the current user paid the cash of an
+// accepted trade, so that the other party can ship.
+func (s *FiberServer) PayTradeCashHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	trade, err := s.db.PayTradeCash(tradeID, currentUserID(c))
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(otherTradeParty(trade, currentUserID(c)), database.NotificationTradeUpdated, trade)
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+type shipTradeRequest struct {
+	Carrier        *string `json:"carrier,omitempty"`
+	TrackingNumber *string `json:"tracking_number,omitempty"`
+}
+
+// ShipTradeHandler marks the current user's cards of a trade as sent.
+func (s *FiberServer) ShipTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	var req shipTradeRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+	if req.TrackingNumber != nil {
+		var carrier tracking.Carrier
+		if req.Carrier != nil {
+			carrier = tracking.Carrier(*req.Carrier)
+		}
+		detected, number, err := tracking.Validate(carrier, *req.TrackingNumber)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid tracking number for carrier",
+			})
+		}
+		code := string(detected)
+		req.Carrier, req.TrackingNumber = &code, &number
+	}
+
+	trade, err := s.db.ShipTrade(tradeID, currentUserID(c), req.Carrier, req.TrackingNumber)
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(otherTradeParty(trade, currentUserID(c)), database.NotificationTradeUpdated, trade)
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+// ReceiveTradeHandler confirms that the current user received the cards of a
+// trade.
+func (s *FiberServer) ReceiveTradeHandler(c *fiber.Ctx) error {
+	tradeID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid trade ID",
+		})
+	}
+
+	trade, err := s.db.ReceiveTrade(tradeID, currentUserID(c))
+	if err != nil {
+		return tradeError(c, err)
+	}
+
+	s.notifyTrade(otherTradeParty(trade, currentUserID(c)), database.NotificationTradeUpdated, trade)
+	return c.JSON(fiber.Map{"trade": trade})
+}
+
+// tradeError maps the errors of the trade endpoints to responses.
+func tradeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Trade, user or product not found",
+		})
+	case errors.Is(err, database.ErrNotTradeParticipant):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "You are not a party to this trade",
+		})
+	case errors.Is(err, database.ErrNotTradeRecipient):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Only the recipient can respond to a trade",
+		})
+	case errors.Is(err, database.ErrTradeClosed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Trade is no longer open",
+		})
+	case errors.Is(err, database.ErrInvalidShipmentState):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Shipment is already sent, not sent yet, or waiting for the cash to be paid",
+		})
+	case errors.Is(err, database.ErrNoCashDue):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "No cash is due from you on this trade",
+		})
+	case errors.Is(err, database.ErrInsufficientStock):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Not enough items in stock",
+		})
+	case errors.Is(err, database.ErrSellerOnVacation):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A party of the trade is on vacation",
+		})
+	case errors.Is(err, database.ErrInvalidTrade):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Trades need cards owned by each party, listed once, and a positive cash amount",
+		})
+	case errors.Is(err, currency.ErrCurrencyMismatch):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cash must be in the currency of the user receiving it",
+		})
+	case errors.Is(err, database.ErrAddressNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Shipping address not found",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process trade",
+		})
+	}
+}
+
+func otherTradeParty(trade database.Trade, userID int) int {
+	if trade.ProposerID == userID {
+		return trade.RecipientID
+	}
+	return trade.ProposerID
+}
+
+func (s *FiberServer) notifyTrade(userID int, notificationType string, trade database.Trade) {
+	s.notify(userID, notificationType, fiber.Map{
+		"trade_id": trade.TradeID,
+		"status":   trade.Status,
+	})
+}
+
+// cancelStalledTradesJob cancels the accepted trades on which nothing was
+// shipped within the window and tells both parties.
+func (s *FiberServer) cancelStalledTradesJob(window time.Duration) scheduler.Job {
+	return scheduler.Job{Name: "cancel_stalled_trades", Run: func(ctx context.Context) error {
+		trades, err := s.db.CancelStalledTrades(time.Now().Add(-window))
+		if err != nil {
+			return err
+		}
+		for _, trade := range trades {
+			s.notifyTrade(trade.ProposerID, database.NotificationTradeUpdated, trade)
+			s.notifyTrade(trade.RecipientID, database.NotificationTradeUpdated, trade)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProposeTradeHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"proposed", nil, http.StatusCreated},
+		{"card not owned", database.ErrInvalidTrade, http.StatusBadRequest},
+		{"cash in the wrong currency", currency.ErrCurrencyMismatch, http.StatusBadRequest},
+		{"not enough copies", database.ErrInsufficientStock, http.StatusConflict},
+		{"unknown recipient", sql.ErrNoRows, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				proposerID int
+				received   database.TradeRequest
+				notified   []int
+			)
+			mockDB := MockDBService{
+				ProposeTradeFunc: func(id int, req database.TradeRequest) (database.Trade, error) {
+					proposerID, received = id, req
+					return database.Trade{TradeID: 1, ProposerID: id, RecipientID: req.RecipientID, Status: database.TradeStatusPending}, tt.err
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					if notificationType == database.NotificationTradeReceived {
+						notified = append(notified, userID)
+					}
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/trades", requireUser, s.ProposeTradeHandler)
+
+			body := `{"recipient_id":3,"offered":[{"product_id":10,"quantity":1}],"requested":[{"product_id":20,"quantity":2}],"cash":{"amount":"5.00","currency":"EUR"},"cash_payer":"proposer"}`
+			req, err := http.NewRequest("POST", "/api/trades", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if proposerID != 2 || received.RecipientID != 3 || len(received.Offered) != 1 || len(received.Requested) != 1 || received.Requested[0].Quantity != 2 {
+				t.Errorf("unexpected proposal %+v by user %v", received, proposerID)
+			}
+			if received.Cash == nil || *received.Cash != currency.New(500, "EUR") || received.CashPayer != database.TradeCashFromProposer {
+				t.Errorf("unexpected cash %v paid by %q", received.Cash, received.CashPayer)
+			}
+			if wantNotified := tt.err == nil; wantNotified != (len(notified) == 1 && notified[0] == 3) {
+				t.Errorf("expected recipient notification %v; got %v", wantNotified, notified)
+			}
+		})
+	}
+}
+
+func TestRespondToTradeHandlers(t *testing.T) {
+	accepted := database.Trade{TradeID: 4, ProposerID: 2, RecipientID: 1, Status: database.TradeStatusAccepted, Shipments: []database.TradeShipment{
+		{TradeShipmentID: 1, SenderID: 2, ReceiverID: 1},
+		{TradeShipmentID: 2, SenderID: 1, ReceiverID: 2},
+	}}
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		err            error
+		expectedStatus int
+		notifiedUser   int
+	}{
+		{"accept", "/api/trades/4/accept", `{"address_id":7}`, nil, http.StatusOK, 2},
+		{"accept with default address", "/api/trades/4/accept", "", nil, http.StatusOK, 2},
+		{"decline", "/api/trades/4/decline", "", nil, http.StatusOK, 2},
+		{"counter", "/api/trades/4/counter", `{"offered":[{"product_id":21,"quantity":1}],"requested":[{"product_id":10,"quantity":1}]}`, nil, http.StatusCreated, 2},
+		{"pay cash", "/api/trades/4/pay", "", nil, http.StatusOK, 2},
+		{"no cash due", "/api/trades/4/pay", "", database.ErrNoCashDue, http.StatusConflict, 0},
+		{"ship with tracking", "/api/trades/4/ship", `{"tracking_number":"1z 999 aa1 01 2345 6784"}`, nil, http.StatusOK, 2},
+		{"receive", "/api/trades/4/receive", "", nil, http.StatusOK, 2},
+		{"invalid tracking number", "/api/trades/4/ship", `{"tracking_number":"not-a-number"}`, nil, http.StatusBadRequest, 0},
+		{"not the recipient", "/api/trades/4/accept", "", database.ErrNotTradeRecipient, http.StatusForbidden, 0},
+		{"already answered", "/api/trades/4/decline", "", database.ErrTradeClosed, http.StatusConflict, 0},
+		{"received before shipped", "/api/trades/4/receive", "", database.ErrInvalidShipmentState, http.StatusConflict, 0},
+		{"not a party", "/api/trades/4/ship", "", database.ErrNotTradeParticipant, http.StatusForbidden, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				respondent  int
+				addressID   = -1
+				shippedWith *string
+				notified    []int
+			)
+			mockDB := MockDBService{
+				AcceptTradeFunc: func(tradeID int, userID int, id int) (database.Trade, error) {
+					respondent, addressID = userID, id
+					return accepted, tt.err
+				},
+				DeclineTradeFunc: func(tradeID int, userID int) (database.Trade, error) {
+					respondent = userID
+					return database.Trade{TradeID: 4, ProposerID: 2, RecipientID: 1, Status: database.TradeStatusDeclined}, tt.err
+				},
+				CounterTradeFunc: func(tradeID int, userID int, req database.TradeRequest) (database.Trade, error) {
+					respondent = userID
+					return database.Trade{TradeID: 5, ProposerID: 1, RecipientID: 2, Status: database.TradeStatusPending}, tt.err
+				},
+				PayTradeCashFunc: func(tradeID int, userID int) (database.Trade, error) {
+					respondent = userID
+					return accepted, tt.err
+				},
+				ShipTradeFunc: func(tradeID int, userID int, carrier *string, trackingNumber *string) (database.Trade, error) {
+					respondent, shippedWith = userID, carrier
+					return accepted, tt.err
+				},
+				ReceiveTradeFunc: func(tradeID int, userID int) (database.Trade, error) {
+					respondent = userID
+					return accepted, tt.err
+				},
+				CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+					notified = append(notified, userID)
+					return database.Notification{UserID: userID, Type: notificationType}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			trades := app.Group("/api/trades", requireUser)
+			trades.Post("/:id/accept", s.AcceptTradeHandler)
+			trades.Post("/:id/decline", s.DeclineTradeHandler)
+			trades.Post("/:id/counter", s.CounterTradeHandler)
+			trades.Post("/:id/pay", s.PayTradeCashHandler)
+			trades.Post("/:id/ship", s.ShipTradeHandler)
+			trades.Post("/:id/receive", s.ReceiveTradeHandler)
+
+			req, err := http.NewRequest("POST", tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 1)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+			if tt.expectedStatus == http.StatusBadRequest {
+				return
+			}
+			if respondent != 1 {
+				t.Errorf("expected user 1 to respond; got %v", respondent)
+			}
+			if tt.notifiedUser == 0 {
+				if len(notified) != 0 {
+					t.Errorf("expected no notifications; got %v", notified)
+				}
+			} else if len(notified) != 1 || notified[0] != tt.notifiedUser {
+				t.Errorf("expected user %d to be notified; got %v", tt.notifiedUser, notified)
+			}
+
+			switch tt.name {
+			case "accept":
+				if addressID != 7 {
+					t.Errorf("expected address 7; got %v", addressID)
+				}
+			case "accept with default address":
+				if addressID != 0 {
+					t.Errorf("expected the default address; got %v", addressID)
+				}
+			case "ship with tracking":
+				if shippedWith == nil || *shippedWith != "ups" {
+					t.Errorf("expected the carrier to be detected; got %v", shippedWith)
+				}
+			}
+		})
+	}
+}
+
+func TestGetTradeHandler(t *testing.T) {
+	mockDB := MockDBService{
+		GetTradeFunc: func(tradeID int, userID int) (database.Trade, error) {
+			if userID != 2 {
+				return database.Trade{}, database.ErrNotTradeParticipant
+			}
+			return database.Trade{TradeID: tradeID}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Get("/api/trades/:id", requireUser, s.GetTradeHandler)
+
+	for userID, expectedStatus := range map[int]int{2: http.StatusOK, 3: http.StatusForbidden} {
+		req, err := http.NewRequest("GET", "/api/trades/4", nil)
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		setSession(t, req, userID)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != expectedStatus {
+			t.Errorf("user %d: expected status %v; got %v", userID, expectedStatus, resp.Status)
+		}
+	}
+}
+
+func TestCancelStalledTradesJob(t *testing.T) {
+	var notified []int
+	mockDB := MockDBService{
+		CancelStalledTradesFunc: func(acceptedBefore time.Time) ([]database.Trade, error) {
+			if time.Since(acceptedBefore) < 7*24*time.Hour {
+				t.Errorf("expected trades accepted a week ago; got %v", acceptedBefore)
+			}
+			return []database.Trade{{TradeID: 4, ProposerID: 2, RecipientID: 1, Status: database.TradeStatusCancelled}}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			if notificationType != database.NotificationTradeUpdated {
+				t.Errorf("expected %v notification; got %v", database.NotificationTradeUpdated, notificationType)
+			}
+			notified = append(notified, userID)
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	s := &FiberServer{App: fiber.New(), db: &mockDB}
+
+	if err := s.cancelStalledTradesJob(7 * 24 * time.Hour).Run(context.Background()); err != nil {
+		t.Fatalf("job failed: %v", err)
+	}
+	if len(notified) != 2 || notified[0] != 2 || notified[1] != 1 {
+		t.Errorf("expected both parties to be notified; got %v", notified)
+	}
+}
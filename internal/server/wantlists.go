@@ -0,0 +1,88 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (s *FiberServer) ListWantlistsHandler(c *fiber.Ctx) error {
+	wantlists, err := s.db.ListWantlists(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch wantlists",
+		})
+	}
+	return c.JSON(fiber.Map{"wantlists": wantlists})
+}
+
+func (s *FiberServer) GetWantlistHandler(c *fiber.Ctx) error {
+	wantlistID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wantlist ID",
+		})
+	}
+
+	wantlist, err := s.db.GetWantlist(wantlistID, currentUserID(c))
+	if err != nil {
+		return wantlistError(c, err)
+	}
+	return c.JSON(fiber.Map{"wantlist": wantlist})
+}
+
+func (s *FiberServer) DeleteWantlistHandler(c *fiber.Ctx) error {
+	wantlistID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid wantlist ID",
+		})
+	}
+
+	if err := s.db.DeleteWantlist(wantlistID, currentUserID(c)); err != nil {
+		return wantlistError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "wantlist deleted"})
+}
+
+func wantlistError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Wantlist not found",
+		})
+	case errors.Is(err, database.ErrInvalidWantlist):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Wantlists need a name and cards of their game",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to process wantlist",
+		})
+	}
+}
+
+// matchWantlists tells the users who want the card of a product that was
+// listed or changed. The product change has already been saved, so failures
+// are only logged.
+func (s *FiberServer) matchWantlists(productID int) {
+	matches, err := s.db.MatchWantlists(productID)
+	if err != nil {
+		log.Printf("failed to match wantlists for product %d: %v", productID, err)
+		return
+	}
+	for _, match := range matches {
+		s.notify(match.UserID, database.NotificationWantlistMatch, fiber.Map{
+			"wantlist_id": match.WantlistID,
+			"wantlist":    match.WantlistName,
+			"product_id":  match.ProductID,
+			"card_id":     match.CardID,
+			"card":        match.Card,
+			"price":       match.Price,
+		})
+	}
+}
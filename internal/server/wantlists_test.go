@@ -0,0 +1,63 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCreateProductHandlerSendsWantlistMatches(t *testing.T) {
+	var notified []database.Notification
+	mockDB := MockDBService{
+		CreateProductFunc: func(product database.ProductRequest) (int, error) {
+			if product.SellerID != 2 {
+				t.Errorf("expected the product to be listed by the signed in user; got seller %d", product.SellerID)
+			}
+			return 4, nil
+		},
+		MatchWantlistsFunc: func(productID int) ([]database.WantlistMatch, error) {
+			return []database.WantlistMatch{
+				{UserID: 3, WantlistID: 7, WantlistName: "Vintage", ProductID: productID, CardID: 9, Card: "Black Lotus", Price: currency.New(1999, "EUR")},
+			}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notified = append(notified, database.Notification{UserID: userID, Type: notificationType, Payload: data})
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Post("/api/products", requireUser, s.CreateProductHandler)
+
+	req, err := http.NewRequest("POST", "/api/products", strings.NewReader(`{"card_id":9,"price":{"amount":"19.99","currency":"EUR"},"condition":"mint","quantity":1,"is_available":true,"seller_id":1,"language_id":1}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected status Created; got %v", resp.Status)
+	}
+
+	if len(notified) != 1 || notified[0].UserID != 3 || notified[0].Type != database.NotificationWantlistMatch {
+		t.Fatalf("expected user 3 to be notified of a wantlist match; got %+v", notified)
+	}
+	want := `{"card":"Black Lotus","card_id":9,"price":{"amount":"19.99","currency":"EUR"},"product_id":4,"wantlist":"Vintage","wantlist_id":7}`
+	if string(notified[0].Payload) != want {
+		t.Errorf("unexpected payload\n got %s\nwant %s", notified[0].Payload, want)
+	}
+}
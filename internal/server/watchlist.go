@@ -0,0 +1,99 @@
+package server
+
+import (
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ListWatchlistHandler lists the products and cards the current user
+// watches.
+func (s *FiberServer) ListWatchlistHandler(c *fiber.Ctx) error {
+	entries, err := s.db.ListWatchlist(currentUserID(c))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch watchlist",
+		})
+	}
+	return c.JSON(fiber.Map{"watchlist": entries})
+}
+
+// AddWatchlistEntryHandler watches a product for price drops or a card for
+// new listings, optionally below a maximum price.
+func (s *FiberServer) AddWatchlistEntryHandler(c *fiber.Ctx) error {
+	var req database.WatchlistEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	entry, err := s.db.AddWatchlistEntry(currentUserID(c), req)
+	switch {
+	case errors.Is(err, database.ErrInvalidWatchlistEntry):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Watch either a product or a card, with a positive maximum price",
+		})
+	case errors.Is(err, sql.ErrNoRows):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product or card not found",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update watchlist",
+		})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"entry": entry})
+}
+
+func (s *FiberServer) RemoveWatchlistEntryHandler(c *fiber.Ctx) error {
+	entryID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid watchlist entry ID",
+		})
+	}
+
+	if err := s.db.RemoveWatchlistEntry(currentUserID(c), entryID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Watchlist entry not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update watchlist",
+		})
+	}
+	return c.JSON(fiber.Map{"message": "watchlist entry removed"})
+}
+
+// checkWatchlist notifies the watchers of a product that was listed or
+// changed. The product change has already been saved, so failures are only
+// logged.
+func (s *FiberServer) checkWatchlist(productID int) {
+	alerts, err := s.db.CheckWatchlist(productID)
+	if err != nil {
+		log.Printf("failed to check watchlists for product %d: %v", productID, err)
+		return
+	}
+	for _, alert := range alerts {
+		payload := fiber.Map{
+			"entry_id":   alert.EntryID,
+			"kind":       alert.Kind,
+			"product_id": alert.ProductID,
+			"name":       alert.Name,
+			"price":      alert.Price,
+		}
+		if alert.CardID != nil {
+			payload["card_id"] = *alert.CardID
+		}
+		if alert.PreviousPrice != nil {
+			payload["previous_price"] = *alert.PreviousPrice
+		}
+		s.notify(alert.UserID, database.NotificationWatchlistAlert, payload)
+	}
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/database"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestAddWatchlistEntryHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		dbErr          error
+		expectedStatus int
+	}{
+		{"product", `{"product_id":4}`, nil, http.StatusCreated},
+		{"card below a price", `{"card_id":9,"max_price":{"amount":"20.00","currency":"EUR"}}`, nil, http.StatusCreated},
+		{"product and card", `{"product_id":4,"card_id":9}`, database.ErrInvalidWatchlistEntry, http.StatusBadRequest},
+		{"unknown card", `{"card_id":999}`, sql.ErrNoRows, http.StatusNotFound},
+		{"invalid body", `{"card_id":`, nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := MockDBService{
+				AddWatchlistEntryFunc: func(userID int, req database.WatchlistEntryRequest) (database.WatchlistEntry, error) {
+					if userID != 2 {
+						t.Errorf("expected entry of user 2; got %v", userID)
+					}
+					if tt.dbErr != nil {
+						return database.WatchlistEntry{}, tt.dbErr
+					}
+					return database.WatchlistEntry{EntryID: 1, MaxPrice: req.MaxPrice}, nil
+				},
+			}
+			app := fiber.New()
+			s := &FiberServer{App: app, db: &mockDB}
+			app.Post("/api/watchlist", requireUser, s.AddWatchlistEntryHandler)
+
+			req, err := http.NewRequest("POST", "/api/watchlist", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("error creating request. Err: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			setSession(t, req, 2)
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("error making request to server. Err: %v", err)
+			}
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("expected status %v; got %v", tt.expectedStatus, resp.Status)
+			}
+		})
+	}
+}
+
+func TestRemoveWatchlistEntryHandler(t *testing.T) {
+	mockDB := MockDBService{
+		RemoveWatchlistEntryFunc: func(userID int, entryID int) error {
+			if userID != 2 || entryID != 1 {
+				return sql.ErrNoRows
+			}
+			return nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Delete("/api/watchlist/:id", requireUser, s.RemoveWatchlistEntryHandler)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{"/api/watchlist/1", http.StatusOK},
+		{"/api/watchlist/7", http.StatusNotFound},
+		{"/api/watchlist/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("DELETE", tt.path, nil)
+		if err != nil {
+			t.Fatalf("error creating request. Err: %v", err)
+		}
+		setSession(t, req, 2)
+
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("error making request to server. Err: %v", err)
+		}
+		if resp.StatusCode != tt.expectedStatus {
+			t.Errorf("%s: expected status %v; got %v", tt.path, tt.expectedStatus, resp.Status)
+		}
+	}
+}
+
+func TestUpdateProductHandlerSendsWatchlistAlerts(t *testing.T) {
+	cardID := 9
+	previous := currency.New(2500, "EUR")
+	var notified []database.Notification
+	mockDB := MockDBService{
+		UpdateProductFunc: func(productID int, product database.ProductRequest) error {
+			if product.SellerID != 2 {
+				t.Errorf("expected the product to be updated by the signed in user; got seller %d", product.SellerID)
+			}
+			return nil
+		},
+		CheckWatchlistFunc: func(productID int) ([]database.WatchlistAlert, error) {
+			return []database.WatchlistAlert{
+				{UserID: 3, EntryID: 1, Kind: database.WatchAlertPriceDrop, ProductID: productID, CardID: &cardID, Name: "Black Lotus", Price: currency.New(1999, "EUR"), PreviousPrice: &previous},
+				{UserID: 5, EntryID: 2, Kind: database.WatchAlertNewListing, ProductID: productID, CardID: &cardID, Name: "Black Lotus", Price: currency.New(1999, "EUR")},
+			}, nil
+		},
+		CreateNotificationFunc: func(userID int, notificationType string, payload any) (database.Notification, error) {
+			data, err := json.Marshal(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			notified = append(notified, database.Notification{UserID: userID, Type: notificationType, Payload: data})
+			return database.Notification{UserID: userID, Type: notificationType}, nil
+		},
+	}
+	app := fiber.New()
+	s := &FiberServer{App: app, db: &mockDB}
+	app.Put("/api/products/:id", requireUser, s.UpdateProductHandler)
+
+	req, err := http.NewRequest("PUT", "/api/products/4", strings.NewReader(`{"card_id":9,"price":{"amount":"19.99","currency":"EUR"},"condition":"mint","quantity":1,"is_available":true,"seller_id":1,"language_id":1}`))
+	if err != nil {
+		t.Fatalf("error creating request. Err: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setSession(t, req, 2)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("error making request to server. Err: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status OK; got %v", resp.Status)
+	}
+
+	if len(notified) != 2 || notified[0].UserID != 3 || notified[1].UserID != 5 {
+		t.Fatalf("expected users 3 and 5 to be notified; got %+v", notified)
+	}
+	for _, n := range notified {
+		if n.Type != database.NotificationWatchlistAlert {
+			t.Errorf("unexpected notification type %v", n.Type)
+		}
+	}
+	want := `{"card_id":9,"entry_id":1,"kind":"price_drop","name":"Black Lotus","previous_price":{"amount":"25.00","currency":"EUR"},"price":{"amount":"19.99","currency":"EUR"},"product_id":4}`
+	if string(notified[0].Payload) != want {
+		t.Errorf("unexpected payload\n got %s\nwant %s", notified[0].Payload, want)
+	}
+}
@@ -0,0 +1,78 @@
+// Package settlement computes what an order charges at checkout and what a
+// dispute refunds of it. All amounts of an order are in its currency.
+package settlement
+
+import (
+	"errors"
+
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/tax"
+)
+
+// Dispute resolutions.
+const (
+	FullRefund    = "full_refund"
+	PartialRefund = "partial_refund"
+	NoRefund      = "no_refund"
+)
+
+// ErrInvalidRefund is returned when a refund amount is not positive or
+// exceeds what is left to refund on the order.
+var ErrInvalidRefund = errors.New("invalid refund amount")
+
+// Totals are the amounts an order charges at checkout.
+type Totals struct {
+	Subtotal currency.Money
+	Shipping currency.Money
+	Taxes    tax.Breakdown
+	Total    currency.Money
+}
+
+// Checkout prices quantity items at unitPrice shipped for shipping, with the
+// tax on both under the sale's rates. The total is items, shipping and tax
+// together.
+func Checkout(unitPrice currency.Money, quantity int, shipping currency.Money, sale tax.Sale, rates []tax.Rate) (Totals, error) {
+	totals := Totals{Subtotal: unitPrice.Mul(quantity), Shipping: shipping}
+
+	taxes, err := tax.Calculate(sale, rates,
+		tax.Line{Kind: tax.KindItem, Amount: totals.Subtotal},
+		tax.Line{Kind: tax.KindShipping, Amount: shipping},
+	)
+	if err != nil {
+		return Totals{}, err
+	}
+	totals.Taxes = taxes
+
+	total, err := totals.Subtotal.Add(shipping)
+	if err != nil {
+		return Totals{}, err
+	}
+	if totals.Total, err = total.Add(taxes.Total); err != nil {
+		return Totals{}, err
+	}
+	return totals, nil
+}
+
+// Refund returns the amount a dispute resolution refunds of an order's total,
+// given what was refunded already. A full refund returns what is left, a
+// partial refund the requested amount, which must be less than what is left.
+func Refund(total currency.Money, refunded currency.Money, resolution string, requested currency.Money) (currency.Money, error) {
+	remaining, err := total.Sub(refunded)
+	if err != nil {
+		return currency.Money{}, err
+	}
+
+	switch resolution {
+	case FullRefund:
+		return remaining, nil
+	case PartialRefund:
+		if requested.Currency != total.Currency || !requested.IsPositive() || requested.Units >= remaining.Units {
+			return currency.Money{}, ErrInvalidRefund
+		}
+		return requested, nil
+	case NoRefund:
+		return currency.New(0, total.Currency), nil
+	default:
+		return currency.Money{}, ErrInvalidRefund
+	}
+}
@@ -0,0 +1,178 @@
+package settlement
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+	"cardmarket_backend/internal/tax"
+)
+
+var codes = []string{"EUR", "USD", "JPY"}
+
+// decimal returns the exact value of an amount, parsed back from its decimal
+// formatting, to check Money arithmetic against independent arithmetic.
+func decimal(t *testing.T, m currency.Money) *big.Rat {
+	t.Helper()
+	r, ok := new(big.Rat).SetString(m.Decimal())
+	if !ok {
+		t.Fatalf("cannot parse %q", m.Decimal())
+	}
+	return r
+}
+
+// taxedSale is a domestic sale by a professional seller, taxed at the rate
+// given in basis points.
+func taxedSale(basisPoints int64) (tax.Sale, []tax.Rate) {
+	de := tax.Party{Country: "DE", EU: true}
+	sale := tax.Sale{Seller: de, SellerType: "professional", Buyer: de, Date: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	rates := []tax.Rate{{Country: "DE", BasisPoints: basisPoints, EffectiveFrom: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	return sale, rates
+}
+
+func TestCheckout(t *testing.T) {
+	sale, rates := taxedSale(1900)
+	totals, err := Checkout(currency.New(1250, "EUR"), 3, currency.New(490, "EUR"), sale, rates)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	// 37.50 of items and 4.90 of shipping, taxed at 19%: 7.13 and 0.93.
+	if totals.Subtotal != currency.New(3750, "EUR") || totals.Taxes.Total != currency.New(806, "EUR") || totals.Total != currency.New(5046, "EUR") {
+		t.Errorf("unexpected totals %+v", totals)
+	}
+}
+
+func TestCheckoutRejectsMixedCurrencies(t *testing.T) {
+	sale, rates := taxedSale(1900)
+	if _, err := Checkout(currency.New(1250, "EUR"), 1, currency.New(490, "USD"), sale, rates); !errors.Is(err, currency.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch; got %v", err)
+	}
+}
+
+func TestPropertyCheckoutTotalsReconcile(t *testing.T) {
+	property := func(price uint32, quantity uint8, shipping uint16, rate uint16, pick uint8) bool {
+		code := codes[int(pick)%len(codes)]
+		unit := currency.New(int64(price), code)
+		cost := currency.New(int64(shipping), code)
+		items := int(quantity) + 1
+		sale, rates := taxedSale(int64(rate) % 10_001)
+
+		totals, err := Checkout(unit, items, cost, sale, rates)
+		if err != nil {
+			return false
+		}
+
+		// The subtotal is the unit price times the quantity, exactly.
+		subtotal := new(big.Rat).Mul(decimal(t, unit), big.NewRat(int64(items), 1))
+		if decimal(t, totals.Subtotal).Cmp(subtotal) != 0 {
+			return false
+		}
+
+		// Each tax line is within half a minor unit of its exact share, and
+		// the tax total is the sum of the rounded lines.
+		taxSum := currency.New(0, code)
+		for _, line := range totals.Taxes.Lines {
+			exact := new(big.Rat).SetFrac(big.NewInt(line.Taxable.Units*line.BasisPoints), big.NewInt(10_000))
+			diff := new(big.Rat).Sub(new(big.Rat).SetInt64(line.Tax.Units), exact)
+			if diff.Abs(diff).Cmp(big.NewRat(1, 2)) > 0 || line.Tax.Units < 0 {
+				return false
+			}
+			if taxSum, err = taxSum.Add(line.Tax); err != nil {
+				return false
+			}
+		}
+		if taxSum != totals.Taxes.Total {
+			return false
+		}
+
+		// The total is items, shipping and tax, to the minor unit.
+		want := new(big.Rat).Add(subtotal, decimal(t, cost))
+		want.Add(want, decimal(t, totals.Taxes.Total))
+		return decimal(t, totals.Total).Cmp(want) == 0 && totals.Total.Currency == code
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRefund(t *testing.T) {
+	total := currency.New(5000, "EUR")
+	refunded := currency.New(1000, "EUR")
+	tests := []struct {
+		name       string
+		resolution string
+		requested  currency.Money
+		want       currency.Money
+		err        error
+	}{
+		{"full refund of the rest", FullRefund, currency.Money{}, currency.New(4000, "EUR"), nil},
+		{"partial refund", PartialRefund, currency.New(1500, "EUR"), currency.New(1500, "EUR"), nil},
+		{"partial refund of the rest", PartialRefund, currency.New(4000, "EUR"), currency.Money{}, ErrInvalidRefund},
+		{"partial refund in another currency", PartialRefund, currency.New(1500, "USD"), currency.Money{}, ErrInvalidRefund},
+		{"zero partial refund", PartialRefund, currency.New(0, "EUR"), currency.Money{}, ErrInvalidRefund},
+		{"no refund", NoRefund, currency.Money{}, currency.New(0, "EUR"), nil},
+		{"unknown resolution", "store_credit", currency.Money{}, currency.Money{}, ErrInvalidRefund},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Refund(total, refunded, tt.resolution, tt.requested)
+			if !errors.Is(err, tt.err) || got != tt.want {
+				t.Errorf("expected %v, %v; got %v, %v", tt.want, tt.err, got, err)
+			}
+		})
+	}
+}
+
+func TestPropertyRefundsReconcile(t *testing.T) {
+	resolutions := []string{FullRefund, PartialRefund, NoRefund}
+	property := func(totalUnits uint32, requests []uint32, picks []uint8, pick uint8) bool {
+		total := currency.New(int64(totalUnits), codes[int(pick)%len(codes)])
+		refunded := currency.New(0, total.Currency)
+
+		for i, requested := range requests {
+			resolution := PartialRefund
+			if i < len(picks) {
+				resolution = resolutions[int(picks[i])%len(resolutions)]
+			}
+			before, err := total.Sub(refunded)
+			if err != nil {
+				return false
+			}
+
+			refund, err := Refund(total, refunded, resolution, currency.New(int64(requested), total.Currency))
+			if errors.Is(err, ErrInvalidRefund) {
+				// Rejected partial refunds are positive amounts of at least
+				// what is left, or nothing at all.
+				if resolution != PartialRefund || (requested > 0 && int64(requested) < before.Units) {
+					return false
+				}
+				continue
+			}
+			if err != nil || refund.Units < 0 || refund.Units > before.Units {
+				return false
+			}
+			if resolution == FullRefund && refund != before {
+				return false
+			}
+			if refunded, err = refunded.Add(refund); err != nil {
+				return false
+			}
+		}
+
+		// Refunds never exceed the total, and what was refunded and what is
+		// left add up to it.
+		remaining, err := total.Sub(refunded)
+		if err != nil {
+			return false
+		}
+		sum, err := refunded.Add(remaining)
+		return err == nil && sum == total && remaining.Units >= 0
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
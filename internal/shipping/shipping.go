@@ -0,0 +1,45 @@
+// Package shipping selects shipping rates from a seller's rate table.
+package shipping
+
+import (
+	"errors"
+
+	"cardmarket_backend/internal/currency"
+)
+
+// ErrNoRate is returned when no rate band covers the order.
+var ErrNoRate = errors.New("no shipping rate covers this order")
+
+// Rate is one band of a rate table: it applies to orders with at most MaxItems
+// cards and an order value of at most MaxValue. A seller's rates are in the
+// seller's currency, like the orders they apply to.
+type Rate struct {
+	MaxItems int
+	MaxValue currency.Money
+	Price    currency.Money
+}
+
+// Covers reports whether the band applies to an order.
+func (r Rate) Covers(items int, value currency.Money) bool {
+	return items <= r.MaxItems && value.Units <= r.MaxValue.Units
+}
+
+// SelectRate returns the cheapest band that covers the order.
+func SelectRate(rates []Rate, items int, value currency.Money) (Rate, error) {
+	var (
+		best  Rate
+		found bool
+	)
+	for _, rate := range rates {
+		if !rate.Covers(items, value) {
+			continue
+		}
+		if !found || rate.Price.Units < best.Price.Units {
+			best, found = rate, true
+		}
+	}
+	if !found {
+		return Rate{}, ErrNoRate
+	}
+	return best, nil
+}
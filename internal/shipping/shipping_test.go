@@ -0,0 +1,63 @@
+package shipping
+
+import (
+	"errors"
+	"testing"
+
+	"cardmarket_backend/internal/currency"
+)
+
+func eur(units int64) currency.Money {
+	return currency.New(units, "EUR")
+}
+
+func TestSelectRate(t *testing.T) {
+	rates := []Rate{
+		{MaxItems: 4, MaxValue: eur(2500), Price: eur(110)},
+		{MaxItems: 20, MaxValue: eur(2500), Price: eur(190)},
+		{MaxItems: 20, MaxValue: eur(10000), Price: eur(450)},
+		{MaxItems: 200, MaxValue: eur(10000), Price: eur(600)},
+	}
+
+	tests := []struct {
+		name  string
+		items int
+		value currency.Money
+		want  currency.Money
+		err   error
+	}{
+		{"small letter", 1, eur(1000), eur(110), nil},
+		{"upper bound is inclusive", 4, eur(2500), eur(110), nil},
+		{"more cards", 5, eur(1000), eur(190), nil},
+		{"higher value", 3, eur(6000), eur(450), nil},
+		{"bulk order", 150, eur(9000), eur(600), nil},
+		{"too valuable", 1, eur(50000), currency.Money{}, ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, err := SelectRate(rates, tt.items, tt.value)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if rate.Price != tt.want {
+				t.Errorf("expected price %v; got %v", tt.want, rate.Price)
+			}
+		})
+	}
+}
+
+func TestSelectRatePrefersCheapestBand(t *testing.T) {
+	rates := []Rate{
+		{MaxItems: 100, MaxValue: eur(100000), Price: eur(900)},
+		{MaxItems: 10, MaxValue: eur(5000), Price: eur(300)},
+	}
+
+	rate, err := SelectRate(rates, 2, eur(2000))
+	if err != nil {
+		t.Fatalf("select: %v", err)
+	}
+	if rate.Price != eur(300) {
+		t.Errorf("expected cheapest covering band; got %v", rate.Price)
+	}
+}
@@ -0,0 +1,54 @@
+// Package storage keeps uploaded files, such as product images, in a pluggable
+// object store.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Storage stores objects under slash separated keys and tells where they can
+// be downloaded from.
+type Storage interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public address of an object.
+	URL(key string) string
+}
+
+// NewFromEnv builds the storage selected by STORAGE_DRIVER. "s3" stores
+// objects in the S3 compatible bucket configured by the S3_* settings,
+// "local" (the default) writes them below STORAGE_DIR, served under
+// STORAGE_URL_PREFIX.
+func NewFromEnv() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "s3":
+		s := &S3Storage{
+			Endpoint:        strings.TrimSuffix(os.Getenv("S3_ENDPOINT"), "/"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       strings.TrimSuffix(os.Getenv("S3_PUBLIC_URL"), "/"),
+		}
+		if s.Endpoint == "" || s.Region == "" || s.Bucket == "" {
+			return nil, fmt.Errorf("S3_ENDPOINT, S3_REGION and S3_BUCKET must be set")
+		}
+		return s, nil
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "tmp/uploads"
+		}
+		prefix := os.Getenv("STORAGE_URL_PREFIX")
+		if prefix == "" {
+			prefix = "/uploads"
+		}
+		return &LocalStorage{Dir: dir, URLPrefix: strings.TrimSuffix(prefix, "/")}, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+	}
+}
@@ -0,0 +1,139 @@
+// Package tax calculates the VAT and sales tax charged on orders.
+//
+// Only professional sellers charge tax. Sales between two EU countries are
+// taxed at the rate of the buyer's country; other sales are taxed only when
+// buyer and seller are in the same country, and exports are zero-rated.
+// Countries without a rate table do not levy tax.
+package tax
+
+import (
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+// Kinds of taxed order lines.
+const (
+	KindItem     = "item"
+	KindShipping = "shipping"
+)
+
+// Seller types that charge tax. Private sellers are not registered for VAT.
+var taxableSellers = map[string]bool{
+	"professional": true,
+	"powerseller":  true,
+}
+
+// Rate is a tax rate of a country, in basis points (1900 is 19%), applying
+// from EffectiveFrom until, but not including, EffectiveTo.
+type Rate struct {
+	Country       string
+	BasisPoints   int64
+	EffectiveFrom time.Time
+	EffectiveTo   *time.Time
+}
+
+// EffectiveAt reports whether the rate applies at t.
+func (r Rate) EffectiveAt(t time.Time) bool {
+	return !t.Before(r.EffectiveFrom) && (r.EffectiveTo == nil || t.Before(*r.EffectiveTo))
+}
+
+// Party is the buyer or seller of a sale.
+type Party struct {
+	Country string
+	EU      bool
+}
+
+// Sale describes who sells to whom, and when.
+type Sale struct {
+	Seller     Party
+	SellerType string
+	Buyer      Party
+	Date       time.Time
+}
+
+// Jurisdiction returns the country whose tax applies to the sale, or "" when
+// the sale is not taxed.
+func Jurisdiction(sale Sale) string {
+	if !taxableSellers[sale.SellerType] {
+		return ""
+	}
+	if sale.Seller.EU && sale.Buyer.EU {
+		return sale.Buyer.Country
+	}
+	if sale.Seller.Country == sale.Buyer.Country {
+		return sale.Seller.Country
+	}
+	return ""
+}
+
+// Line is an amount to be taxed.
+type Line struct {
+	Kind   string
+	Amount currency.Money
+}
+
+// TaxLine is the tax charged on one line of an order.
+type TaxLine struct {
+	Kind        string         `json:"kind"`
+	Country     string         `json:"country"`
+	BasisPoints int64          `json:"rate_basis_points"`
+	Taxable     currency.Money `json:"taxable_amount"`
+	Tax         currency.Money `json:"tax_amount"`
+}
+
+// Breakdown is the tax charged on a sale.
+type Breakdown struct {
+	Lines []TaxLine
+	Total currency.Money
+}
+
+// Calculate returns the tax on each line of a sale, using the rate of the
+// jurisdiction in effect on the sale date. Each line is rounded to the minor
+// unit on its own; Total is the sum of the rounded lines. All lines must be in
+// the same currency.
+func Calculate(sale Sale, rates []Rate, lines ...Line) (Breakdown, error) {
+	var code string
+	if len(lines) > 0 {
+		code = lines[0].Amount.Currency
+	}
+	breakdown := Breakdown{Lines: []TaxLine{}, Total: currency.New(0, code)}
+
+	country := Jurisdiction(sale)
+	if country == "" {
+		return breakdown, nil
+	}
+	rate, ok := effectiveRate(rates, country, sale.Date)
+	if !ok {
+		return breakdown, nil
+	}
+
+	for _, line := range lines {
+		if line.Amount.IsZero() {
+			continue
+		}
+		taxLine := TaxLine{
+			Kind:        line.Kind,
+			Country:     country,
+			BasisPoints: rate.BasisPoints,
+			Taxable:     line.Amount,
+			Tax:         line.Amount.Percent(rate.BasisPoints),
+		}
+		total, err := breakdown.Total.Add(taxLine.Tax)
+		if err != nil {
+			return Breakdown{}, err
+		}
+		breakdown.Total = total
+		breakdown.Lines = append(breakdown.Lines, taxLine)
+	}
+	return breakdown, nil
+}
+
+func effectiveRate(rates []Rate, country string, at time.Time) (Rate, bool) {
+	for _, rate := range rates {
+		if rate.Country == country && rate.EffectiveAt(at) {
+			return rate, true
+		}
+	}
+	return Rate{}, false
+}
@@ -0,0 +1,118 @@
+package tax
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"cardmarket_backend/internal/currency"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestJurisdiction(t *testing.T) {
+	de := Party{Country: "DE", EU: true}
+	fr := Party{Country: "FR", EU: true}
+	gb := Party{Country: "GB"}
+	us := Party{Country: "US"}
+
+	tests := []struct {
+		name       string
+		seller     Party
+		sellerType string
+		buyer      Party
+		want       string
+	}{
+		{"private seller", de, "private", de, ""},
+		{"domestic EU sale", de, "professional", de, "DE"},
+		{"cross-border EU sale", de, "powerseller", fr, "FR"},
+		{"export from the EU", de, "professional", us, ""},
+		{"import into the EU", gb, "professional", de, ""},
+		{"domestic non-EU sale", gb, "professional", gb, "GB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Jurisdiction(Sale{Seller: tt.seller, SellerType: tt.sellerType, Buyer: tt.buyer})
+			if got != tt.want {
+				t.Errorf("expected %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCalculateUsesRateInEffect(t *testing.T) {
+	cut, restored := date(2020, time.July, 1), date(2021, time.January, 1)
+	rates := []Rate{
+		{Country: "DE", BasisPoints: 1900, EffectiveFrom: date(2007, time.January, 1), EffectiveTo: &cut},
+		{Country: "DE", BasisPoints: 1600, EffectiveFrom: cut, EffectiveTo: &restored},
+		{Country: "DE", BasisPoints: 1900, EffectiveFrom: restored},
+	}
+	sale := Sale{Seller: Party{"DE", true}, SellerType: "professional", Buyer: Party{"DE", true}}
+
+	for _, tt := range []struct {
+		date time.Time
+		want int64
+	}{
+		{date(2020, time.June, 30), 1900},
+		{cut, 1600},
+		{date(2020, time.December, 31), 1600},
+		{restored, 1900},
+	} {
+		sale.Date = tt.date
+		breakdown, err := Calculate(sale, rates, Line{KindItem, currency.New(1000, "EUR")})
+		if err != nil {
+			t.Fatalf("calculate: %v", err)
+		}
+		if len(breakdown.Lines) != 1 || breakdown.Lines[0].BasisPoints != tt.want {
+			t.Errorf("%s: expected rate %d; got %+v", tt.date.Format(time.DateOnly), tt.want, breakdown.Lines)
+		}
+	}
+}
+
+func TestCalculateTaxesItemsAndShipping(t *testing.T) {
+	rates := []Rate{{Country: "FR", BasisPoints: 2000, EffectiveFrom: date(2014, time.January, 1)}}
+	sale := Sale{Seller: Party{"DE", true}, SellerType: "professional", Buyer: Party{"FR", true}, Date: date(2025, time.November, 11)}
+
+	breakdown, err := Calculate(sale, rates,
+		Line{KindItem, currency.New(1999, "EUR")},
+		Line{KindShipping, currency.New(115, "EUR")},
+	)
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+
+	if len(breakdown.Lines) != 2 {
+		t.Fatalf("expected item and shipping lines; got %+v", breakdown.Lines)
+	}
+	if breakdown.Lines[0].Tax != currency.New(400, "EUR") || breakdown.Lines[1].Tax != currency.New(23, "EUR") {
+		t.Errorf("unexpected line taxes %+v", breakdown.Lines)
+	}
+	if breakdown.Total != currency.New(423, "EUR") {
+		t.Errorf("expected total tax 4.23 EUR; got %v", breakdown.Total)
+	}
+}
+
+func TestCalculateWithoutTax(t *testing.T) {
+	sale := Sale{Seller: Party{"US", false}, SellerType: "professional", Buyer: Party{"US", false}, Date: date(2025, time.November, 11)}
+
+	breakdown, err := Calculate(sale, nil, Line{KindItem, currency.New(500, "USD")})
+	if err != nil {
+		t.Fatalf("calculate: %v", err)
+	}
+	if len(breakdown.Lines) != 0 || breakdown.Total != currency.New(0, "USD") {
+		t.Errorf("expected no tax; got %+v", breakdown)
+	}
+}
+
+func TestCalculateRejectsMixedCurrencies(t *testing.T) {
+	rates := []Rate{{Country: "DE", BasisPoints: 1900, EffectiveFrom: date(2007, time.January, 1)}}
+	sale := Sale{Seller: Party{"DE", true}, SellerType: "professional", Buyer: Party{"DE", true}, Date: date(2025, time.November, 11)}
+
+	_, err := Calculate(sale, rates, Line{KindItem, currency.New(500, "EUR")}, Line{KindShipping, currency.New(100, "USD")})
+	if !errors.Is(err, currency.ErrCurrencyMismatch) {
+		t.Errorf("expected currency mismatch; got %v", err)
+	}
+}
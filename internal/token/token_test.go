@@ -0,0 +1,72 @@
+package token
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var secret = []byte("test-secret")
+
+func TestSignAndVerify(t *testing.T) {
+	claims, err := New(7, PurposePasswordReset, time.Hour)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	signed, err := Sign(secret, claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	got, err := Verify(secret, signed, PurposePasswordReset)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if got != claims {
+		t.Errorf("expected claims %+v; got %+v", claims, got)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	claims, err := New(7, PurposeEmailVerification, time.Hour)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	signed, err := Sign(secret, claims)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	expired, err := New(7, PurposeEmailVerification, -time.Minute)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	signedExpired, err := Sign(secret, expired)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	payload, sig, _ := strings.Cut(signed, ".")
+	tests := []struct {
+		name    string
+		token   string
+		secret  []byte
+		purpose string
+		want    error
+	}{
+		{"wrong secret", signed, []byte("other"), PurposeEmailVerification, ErrInvalid},
+		{"wrong purpose", signed, secret, PurposePasswordReset, ErrInvalid},
+		{"tampered payload", payload + "x." + sig, secret, PurposeEmailVerification, ErrInvalid},
+		{"missing signature", payload, secret, PurposeEmailVerification, ErrInvalid},
+		{"expired", signedExpired, secret, PurposeEmailVerification, ErrExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(tt.secret, tt.token, tt.purpose); !errors.Is(err, tt.want) {
+				t.Errorf("expected %v; got %v", tt.want, err)
+			}
+		})
+	}
+}
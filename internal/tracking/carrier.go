@@ -0,0 +1,118 @@
+// Package tracking detects parcel carriers from tracking numbers and polls
+// carriers for delivery of shipped orders.
+package tracking
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidNumber is returned when a tracking number matches no known
+// carrier format, or not the format of the carrier given.
+var ErrInvalidNumber = errors.New("invalid tracking number")
+
+// Carrier identifies a parcel carrier.
+type Carrier string
+
+const (
+	CarrierDHL          Carrier = "dhl"
+	CarrierDeutschePost Carrier = "deutsche_post"
+	CarrierDPD          Carrier = "dpd"
+	CarrierGLS          Carrier = "gls"
+	CarrierLaPoste      Carrier = "la_poste"
+	CarrierPostNL       Carrier = "postnl"
+	CarrierRoyalMail    Carrier = "royal_mail"
+	CarrierUPS          Carrier = "ups"
+	CarrierUSPS         Carrier = "usps"
+	// CarrierPost is a national postal operator without specific support,
+	// recognised from an international (UPU S10) tracking number.
+	CarrierPost Carrier = "post"
+)
+
+// s10Format is the UPU S10 format used by postal operators for international
+// items: two service letters, eight digits, a check digit and the ISO country
+// code of the issuing operator.
+var s10Format = regexp.MustCompile(`^[A-Z]{2}(\d{8})(\d)([A-Z]{2})$`)
+
+// s10Operators maps the country code of an S10 number to its postal operator.
+var s10Operators = map[string]Carrier{
+	"DE": CarrierDeutschePost,
+	"FR": CarrierLaPoste,
+	"GB": CarrierRoyalMail,
+	"NL": CarrierPostNL,
+	"US": CarrierUSPS,
+}
+
+// carrierFormats holds the domestic tracking number formats of each carrier.
+// The formats do not overlap so at most one carrier matches a number.
+var carrierFormats = []struct {
+	carrier Carrier
+	format  *regexp.Regexp
+}{
+	{CarrierUPS, regexp.MustCompile(`^1Z[0-9A-Z]{16}$`)},
+	{CarrierDHL, regexp.MustCompile(`^(\d{12}|00340\d{15})$`)},
+	{CarrierDPD, regexp.MustCompile(`^\d{14}$`)},
+	{CarrierGLS, regexp.MustCompile(`^\d{11}$`)},
+	{CarrierPostNL, regexp.MustCompile(`^3S[0-9A-Z]{11,13}$`)},
+	{CarrierUSPS, regexp.MustCompile(`^9[2-5]\d{20}$`)},
+	{CarrierLaPoste, regexp.MustCompile(`^\d[A-Z]\d{11}$`)},
+}
+
+// Normalize strips spaces and dashes and upper-cases a tracking number.
+func Normalize(number string) string {
+	number = strings.ToUpper(number)
+	return strings.NewReplacer(" ", "", "-", "", "\t", "").Replace(number)
+}
+
+// Detect returns the carrier issuing a normalized tracking number.
+func Detect(number string) (Carrier, error) {
+	if m := s10Format.FindStringSubmatch(number); m != nil {
+		if s10CheckDigit(m[1]) != m[2][0]-'0' {
+			return "", ErrInvalidNumber
+		}
+		if carrier, ok := s10Operators[m[3]]; ok {
+			return carrier, nil
+		}
+		return CarrierPost, nil
+	}
+
+	for _, f := range carrierFormats {
+		if f.format.MatchString(number) {
+			return f.carrier, nil
+		}
+	}
+	return "", ErrInvalidNumber
+}
+
+// Validate normalizes a tracking number and returns it with its carrier.
+// When carrier is not empty, the number must belong to that carrier.
+func Validate(carrier Carrier, number string) (Carrier, string, error) {
+	number = Normalize(number)
+	detected, err := Detect(number)
+	if err != nil {
+		return "", "", err
+	}
+	if carrier != "" && carrier != detected {
+		return "", "", ErrInvalidNumber
+	}
+	return detected, number, nil
+}
+
+// s10CheckDigit computes the check digit of the eight serial digits of an S10
+// number.
+func s10CheckDigit(serial string) byte {
+	weights := [8]int{8, 6, 4, 2, 3, 5, 9, 7}
+	sum := 0
+	for i, weight := range weights {
+		sum += int(serial[i]-'0') * weight
+	}
+	switch check := 11 - sum%11; check {
+	case 10:
+		return 0
+	case 11:
+		return 5
+	default:
+		return byte(check)
+	}
+}
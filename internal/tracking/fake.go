@@ -0,0 +1,57 @@
+package tracking
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FakeTracker simulates a carrier for local development and tests. Shipments
+// are delivered a fixed time after they were shipped unless a status was set
+// explicitly for their tracking number.
+type FakeTracker struct {
+	deliverAfter time.Duration
+	now          func() time.Time
+
+	mu       sync.Mutex
+	statuses map[string]Status
+}
+
+func NewFakeTracker(deliverAfter time.Duration) *FakeTracker {
+	return &FakeTracker{
+		deliverAfter: deliverAfter,
+		now:          time.Now,
+		statuses:     make(map[string]Status),
+	}
+}
+
+// SetStatus forces the status reported for a tracking number.
+func (t *FakeTracker) SetStatus(number string, status Status) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.statuses[Normalize(number)] = status
+}
+
+func (t *FakeTracker) Track(ctx context.Context, shipment Shipment) (Update, error) {
+	if err := ctx.Err(); err != nil {
+		return Update{}, err
+	}
+
+	t.mu.Lock()
+	status, ok := t.statuses[shipment.TrackingNumber]
+	t.mu.Unlock()
+
+	now := t.now()
+	if !ok {
+		status = StatusInTransit
+		if now.Sub(shipment.ShippedAt) >= t.deliverAfter {
+			status = StatusDelivered
+		}
+	}
+
+	update := Update{Status: status}
+	if status == StatusDelivered {
+		update.DeliveredAt = now
+	}
+	return update, nil
+}
@@ -0,0 +1,67 @@
+package tracking
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Store loads shipments awaiting delivery and records their delivery.
+type Store interface {
+	ListTrackedShipments() ([]Shipment, error)
+	// MarkOrderDelivered reports whether the order was updated, so a delivery
+	// is only reported once.
+	MarkOrderDelivered(orderID int, deliveredAt time.Time) (bool, error)
+}
+
+// Poller asks the carrier about every shipment in transit and marks
+// delivered orders. It is run periodically by the scheduler.
+type Poller struct {
+	store       Store
+	tracker     Tracker
+	onDelivered func(Shipment)
+}
+
+// NewPoller creates a poller. onDelivered is called for every order marked
+// delivered and may be nil.
+func NewPoller(store Store, tracker Tracker, onDelivered func(Shipment)) *Poller {
+	return &Poller{
+		store:       store,
+		tracker:     tracker,
+		onDelivered: onDelivered,
+	}
+}
+
+// Poll checks every shipment in transit once. Failures of single shipments
+// are logged and the shipment is retried on the next poll.
+func (p *Poller) Poll(ctx context.Context) error {
+	shipments, err := p.store.ListTrackedShipments()
+	if err != nil {
+		return err
+	}
+
+	for _, shipment := range shipments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		update, err := p.tracker.Track(ctx, shipment)
+		if err != nil {
+			log.Printf("failed to track order %d (%s %s): %v", shipment.OrderID, shipment.Carrier, shipment.TrackingNumber, err)
+			continue
+		}
+		if update.Status != StatusDelivered {
+			continue
+		}
+
+		updated, err := p.store.MarkOrderDelivered(shipment.OrderID, update.DeliveredAt)
+		if err != nil {
+			log.Printf("failed to mark order %d delivered: %v", shipment.OrderID, err)
+			continue
+		}
+		if updated && p.onDelivered != nil {
+			p.onDelivered(shipment)
+		}
+	}
+	return nil
+}
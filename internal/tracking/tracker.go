@@ -0,0 +1,62 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Status is the delivery state reported by a carrier.
+type Status string
+
+const (
+	StatusInTransit Status = "in_transit"
+	StatusDelivered Status = "delivered"
+	StatusException Status = "exception"
+)
+
+// Shipment is a shipped order awaiting delivery.
+type Shipment struct {
+	OrderID        int
+	BuyerID        int
+	SellerID       int
+	Carrier        Carrier
+	TrackingNumber string
+	ShippedAt      time.Time
+}
+
+// Update is the latest state of a shipment at the carrier.
+type Update struct {
+	Status      Status
+	DeliveredAt time.Time
+}
+
+// Tracker queries a carrier for the state of a shipment.
+type Tracker interface {
+	Track(ctx context.Context, shipment Shipment) (Update, error)
+}
+
+// NewTrackerFromEnv builds the tracker selected by TRACKING_DRIVER. Tracking
+// is disabled unless a driver is set, and the tracker is nil then. Only the
+// "fake" driver exists until carrier APIs are integrated; it reports
+// shipments delivered TRACKING_FAKE_DELIVERY_AFTER (default 48h) after they
+// were shipped, so it is meant for development only.
+func NewTrackerFromEnv() (Tracker, error) {
+	switch driver := os.Getenv("TRACKING_DRIVER"); driver {
+	case "", "none":
+		return nil, nil
+	case "fake":
+		deliverAfter := 48 * time.Hour
+		if value := os.Getenv("TRACKING_FAKE_DELIVERY_AFTER"); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid TRACKING_FAKE_DELIVERY_AFTER: %w", err)
+			}
+			deliverAfter = d
+		}
+		return NewFakeTracker(deliverAfter), nil
+	default:
+		return nil, fmt.Errorf("unknown TRACKING_DRIVER %q", driver)
+	}
+}
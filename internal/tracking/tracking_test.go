@@ -0,0 +1,121 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		carrier    Carrier
+		number     string
+		want       Carrier
+		wantNumber string
+		err        error
+	}{
+		{"ups", "", "1Z 999 AA1 01 2345 6784", CarrierUPS, "1Z999AA10123456784", nil},
+		{"dhl paket", "", "003404341234567890 12", CarrierDHL, "00340434123456789012", nil},
+		{"dpd", "", "01234567890123", CarrierDPD, "01234567890123", nil},
+		{"postnl", "", "3sabcd1234567", CarrierPostNL, "3SABCD1234567", nil},
+		{"usps", "", "9400 1000 0000 0000 0000 00", CarrierUSPS, "9400100000000000000000", nil},
+		{"s10 germany", "", "RR 123 456 785 DE", CarrierDeutschePost, "RR123456785DE", nil},
+		{"s10 other country", "", "RR123456785JP", CarrierPost, "RR123456785JP", nil},
+		{"s10 wrong check digit", "", "RR123456784DE", "", "", ErrInvalidNumber},
+		{"matching carrier", CarrierUPS, "1Z999AA10123456784", CarrierUPS, "1Z999AA10123456784", nil},
+		{"other carrier", CarrierDPD, "1Z999AA10123456784", "", "", ErrInvalidNumber},
+		{"unknown format", "", "ABC", "", "", ErrInvalidNumber},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carrier, number, err := Validate(tt.carrier, tt.number)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v; got %v", tt.err, err)
+			}
+			if carrier != tt.want || number != tt.wantNumber {
+				t.Errorf("expected %v %v; got %v %v", tt.want, tt.wantNumber, carrier, number)
+			}
+		})
+	}
+}
+
+type memoryStore struct {
+	shipments []Shipment
+	delivered map[int]time.Time
+}
+
+func (s *memoryStore) ListTrackedShipments() ([]Shipment, error) {
+	var pending []Shipment
+	for _, shipment := range s.shipments {
+		if _, ok := s.delivered[shipment.OrderID]; !ok {
+			pending = append(pending, shipment)
+		}
+	}
+	return pending, nil
+}
+
+func (s *memoryStore) MarkOrderDelivered(orderID int, deliveredAt time.Time) (bool, error) {
+	if _, ok := s.delivered[orderID]; ok {
+		return false, nil
+	}
+	s.delivered[orderID] = deliveredAt
+	return true, nil
+}
+
+func TestPollerMarksDeliveredShipments(t *testing.T) {
+	now := time.Now()
+	store := &memoryStore{
+		shipments: []Shipment{
+			{OrderID: 1, Carrier: CarrierDHL, TrackingNumber: "003404341234567890", ShippedAt: now.Add(-72 * time.Hour)},
+			{OrderID: 2, Carrier: CarrierDPD, TrackingNumber: "01234567890123", ShippedAt: now.Add(-time.Hour)},
+			{OrderID: 3, Carrier: CarrierUPS, TrackingNumber: "1Z999AA10123456784", ShippedAt: now.Add(-time.Hour)},
+		},
+		delivered: make(map[int]time.Time),
+	}
+	tracker := NewFakeTracker(48 * time.Hour)
+	tracker.SetStatus("1Z999AA10123456784", StatusDelivered)
+
+	var notified []int
+	poller := NewPoller(store, tracker, func(shipment Shipment) {
+		notified = append(notified, shipment.OrderID)
+	})
+
+	for range 2 {
+		if err := poller.Poll(context.Background()); err != nil {
+			t.Fatalf("Poll() error = %v", err)
+		}
+	}
+
+	if len(notified) != 2 || notified[0] != 1 || notified[1] != 3 {
+		t.Errorf("expected orders 1 and 3 to be delivered once; got %v", notified)
+	}
+	if _, ok := store.delivered[2]; ok {
+		t.Errorf("expected order 2 to still be in transit")
+	}
+}
+
+func TestNewTrackerFromEnv(t *testing.T) {
+	tests := []struct {
+		driver  string
+		want    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"none", false, false},
+		{"fake", true, false},
+		{"ups", false, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("TRACKING_DRIVER", tt.driver)
+		tracker, err := NewTrackerFromEnv()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("driver %q: unexpected error %v", tt.driver, err)
+		}
+		if (tracker != nil) != tt.want {
+			t.Errorf("driver %q: expected a tracker %v; got %v", tt.driver, tt.want, tracker)
+		}
+	}
+}
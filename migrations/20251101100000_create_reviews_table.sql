@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE "reviews"(
+    "review_id" SERIAL PRIMARY KEY,
+    "order_id" INTEGER NOT NULL REFERENCES "orders"("order_id") ON DELETE CASCADE,
+    "reviewer_id" INTEGER NOT NULL REFERENCES "users"("user_id"),
+    "reviewee_id" INTEGER NOT NULL REFERENCES "users"("user_id"),
+    "overall" SMALLINT NOT NULL CHECK ("overall" BETWEEN 1 AND 5),
+    "item_description" SMALLINT NOT NULL CHECK ("item_description" BETWEEN 1 AND 5),
+    "packaging" SMALLINT NOT NULL CHECK ("packaging" BETWEEN 1 AND 5),
+    "shipping_speed" SMALLINT NOT NULL CHECK ("shipping_speed" BETWEEN 1 AND 5),
+    "comment" TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("order_id", "reviewer_id")
+);
+
+CREATE INDEX "idx_reviews_reviewee" ON "reviews"("reviewee_id", "created_at");
+
+CREATE TABLE "user_reputations"(
+    "user_id" INTEGER PRIMARY KEY REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "review_count" INTEGER NOT NULL DEFAULT 0,
+    "average_rating" DECIMAL(3, 2) NOT NULL DEFAULT 0,
+    "positive_percentage" DECIMAL(5, 2) NOT NULL DEFAULT 0,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE "user_reputations";
+DROP INDEX "idx_reviews_reviewee";
+DROP TABLE "reviews";
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE "conversations"(
+    "conversation_id" SERIAL PRIMARY KEY,
+    "user_one_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "user_two_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "order_id" INTEGER REFERENCES "orders"("order_id") ON DELETE SET NULL,
+    "product_id" INTEGER REFERENCES "products"("product_id") ON DELETE SET NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ("user_one_id" < "user_two_id")
+);
+
+CREATE UNIQUE INDEX "idx_conversations_unique" ON "conversations"("user_one_id", "user_two_id", COALESCE("order_id", 0), COALESCE("product_id", 0));
+CREATE INDEX "idx_conversations_user_two" ON "conversations"("user_two_id");
+
+CREATE TABLE "messages"(
+    "message_id" SERIAL PRIMARY KEY,
+    "conversation_id" INTEGER NOT NULL REFERENCES "conversations"("conversation_id") ON DELETE CASCADE,
+    "sender_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "body" TEXT NOT NULL CHECK (LENGTH("body") > 0),
+    "read_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_messages_conversation" ON "messages"("conversation_id", "created_at");
+CREATE INDEX "idx_messages_unread" ON "messages"("conversation_id") WHERE "read_at" IS NULL;
+
+-- +goose Down
+DROP INDEX "idx_messages_unread";
+DROP INDEX "idx_messages_conversation";
+DROP TABLE "messages";
+DROP INDEX "idx_conversations_user_two";
+DROP INDEX "idx_conversations_unique";
+DROP TABLE "conversations";
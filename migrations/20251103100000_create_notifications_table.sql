@@ -0,0 +1,33 @@
+-- +goose Up
+CREATE TABLE "notifications"(
+    "notification_id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "type" VARCHAR(30) CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received')) NOT NULL,
+    "payload" JSONB NOT NULL DEFAULT '{}',
+    "read_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_notifications_user" ON "notifications"("user_id", "created_at");
+CREATE INDEX "idx_notifications_unread" ON "notifications"("user_id") WHERE "read_at" IS NULL;
+
+-- Every server process listens on the "notifications" channel to push new
+-- notifications to the clients connected to it, whichever process stored them.
+-- +goose StatementBegin
+CREATE FUNCTION "announce_notification"() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('notifications', NEW."notification_id"::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER "notifications_announce" AFTER INSERT ON "notifications"
+    FOR EACH ROW EXECUTE FUNCTION "announce_notification"();
+
+-- +goose Down
+DROP TRIGGER "notifications_announce" ON "notifications";
+DROP FUNCTION "announce_notification";
+DROP INDEX "idx_notifications_unread";
+DROP INDEX "idx_notifications_user";
+DROP TABLE "notifications";
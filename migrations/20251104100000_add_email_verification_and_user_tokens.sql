@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE "users" ADD COLUMN "email_verified_at" TIMESTAMP WITH TIME ZONE;
+
+-- Accounts that existed before verification was introduced are trusted.
+UPDATE "users" SET "email_verified_at" = "created_at";
+
+CREATE TABLE "user_tokens"(
+    "token_id" VARCHAR(64) PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "purpose" VARCHAR(30) CHECK ("purpose" IN ('email_verification', 'password_reset')) NOT NULL,
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "used_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_user_tokens_user" ON "user_tokens"("user_id");
+
+-- +goose Down
+DROP INDEX "idx_user_tokens_user";
+DROP TABLE "user_tokens";
+ALTER TABLE "users" DROP COLUMN "email_verified_at";
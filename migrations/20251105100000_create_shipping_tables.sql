@@ -0,0 +1,84 @@
+-- +goose Up
+CREATE TABLE "shipping_methods"(
+    "shipping_method_id" SERIAL PRIMARY KEY,
+    "code" VARCHAR(30) UNIQUE NOT NULL,
+    "name" VARCHAR(100) NOT NULL,
+    "is_tracked" BOOLEAN NOT NULL DEFAULT FALSE,
+    "is_insured" BOOLEAN NOT NULL DEFAULT FALSE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A rate applies to orders with at most "max_items" cards and a value of at most
+-- "max_order_value". A NULL destination is the fallback for every destination
+-- without a specific rate.
+CREATE TABLE "shipping_rates"(
+    "shipping_rate_id" SERIAL PRIMARY KEY,
+    "shipping_method_id" INTEGER NOT NULL REFERENCES "shipping_methods"("shipping_method_id") ON DELETE CASCADE,
+    "origin_country_id" INTEGER NOT NULL REFERENCES "countries"("country_id"),
+    "destination_country_id" INTEGER REFERENCES "countries"("country_id"),
+    "max_items" INTEGER NOT NULL CHECK ("max_items" > 0),
+    "max_order_value" DECIMAL(10, 2) NOT NULL CHECK ("max_order_value" > 0),
+    "price" DECIMAL(10, 2) NOT NULL CHECK ("price" >= 0),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_shipping_rates_route" ON "shipping_rates"("shipping_method_id", "origin_country_id", "destination_country_id");
+
+-- Sellers without any row here offer every shipping method.
+CREATE TABLE "seller_shipping_methods"(
+    "seller_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "shipping_method_id" INTEGER NOT NULL REFERENCES "shipping_methods"("shipping_method_id") ON DELETE CASCADE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("seller_id", "shipping_method_id")
+);
+
+ALTER TABLE "orders" ADD COLUMN "shipping_method_id" INTEGER REFERENCES "shipping_methods"("shipping_method_id");
+
+INSERT INTO shipping_methods (code, name, is_tracked, is_insured) VALUES
+('letter', 'Letter', false, false),
+('tracked_letter', 'Tracked letter', true, false),
+('parcel', 'Parcel', true, false),
+('insured', 'Insured parcel', true, true);
+
+-- Domestic rates for every country
+INSERT INTO shipping_rates (shipping_method_id, origin_country_id, destination_country_id, max_items, max_order_value, price)
+SELECT m.shipping_method_id, c.country_id, c.country_id, b.max_items, b.max_order_value, b.price
+FROM shipping_methods m
+CROSS JOIN countries c
+JOIN (VALUES
+    ('letter', 4, 25.00, 1.10),
+    ('letter', 20, 25.00, 1.90),
+    ('tracked_letter', 4, 100.00, 3.50),
+    ('tracked_letter', 20, 100.00, 4.90),
+    ('parcel', 50, 500.00, 6.50),
+    ('parcel', 200, 500.00, 9.90),
+    ('insured', 50, 5000.00, 12.00),
+    ('insured', 200, 5000.00, 18.00),
+    ('insured', 50, 50000.00, 45.00)
+) AS b(code, max_items, max_order_value, price) ON b.code = m.code;
+
+-- International fallback rates for every origin country
+INSERT INTO shipping_rates (shipping_method_id, origin_country_id, destination_country_id, max_items, max_order_value, price)
+SELECT m.shipping_method_id, c.country_id, NULL, b.max_items, b.max_order_value, b.price
+FROM shipping_methods m
+CROSS JOIN countries c
+JOIN (VALUES
+    ('letter', 4, 25.00, 2.50),
+    ('letter', 20, 25.00, 4.00),
+    ('tracked_letter', 4, 100.00, 8.50),
+    ('tracked_letter', 20, 100.00, 11.00),
+    ('parcel', 50, 500.00, 18.00),
+    ('parcel', 200, 500.00, 25.00),
+    ('insured', 50, 5000.00, 30.00),
+    ('insured', 200, 5000.00, 40.00),
+    ('insured', 50, 50000.00, 85.00)
+) AS b(code, max_items, max_order_value, price) ON b.code = m.code;
+
+-- +goose Down
+ALTER TABLE "orders" DROP COLUMN "shipping_method_id";
+DROP TABLE "seller_shipping_methods";
+DROP INDEX "idx_shipping_rates_route";
+DROP TABLE "shipping_rates";
+DROP TABLE "shipping_methods";
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE "addresses"(
+    "address_id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "recipient_name" VARCHAR(200) NOT NULL,
+    "street_name" VARCHAR(255) NOT NULL,
+    "street_number" VARCHAR(20) NOT NULL,
+    "city" VARCHAR(100) NOT NULL,
+    "state" VARCHAR(100) NOT NULL,
+    "zip_code" VARCHAR(20) NOT NULL,
+    "country_id" INTEGER NOT NULL REFERENCES "countries"("country_id"),
+    "is_default" BOOLEAN NOT NULL DEFAULT FALSE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_addresses_user" ON "addresses"("user_id");
+CREATE UNIQUE INDEX "idx_addresses_user_default" ON "addresses"("user_id") WHERE "is_default";
+
+-- Every existing user starts with the address from their profile.
+INSERT INTO addresses (user_id, recipient_name, street_name, street_number, city, state, zip_code, country_id, is_default)
+SELECT user_id, first_name || ' ' || last_name, street_name, street_number, city, state, zip_code, country_id, TRUE FROM users;
+
+-- Orders keep a copy of the address they ship to so later changes to the
+-- address book do not alter placed orders. Orders placed before this
+-- migration only have the free-text "shipping_address".
+ALTER TABLE "orders"
+    ADD COLUMN "shipping_name" VARCHAR(200),
+    ADD COLUMN "shipping_street_name" VARCHAR(255),
+    ADD COLUMN "shipping_street_number" VARCHAR(20),
+    ADD COLUMN "shipping_city" VARCHAR(100),
+    ADD COLUMN "shipping_state" VARCHAR(100),
+    ADD COLUMN "shipping_zip_code" VARCHAR(20),
+    ADD COLUMN "shipping_country_id" INTEGER REFERENCES "countries"("country_id");
+
+-- +goose Down
+ALTER TABLE "orders"
+    DROP COLUMN "shipping_country_id",
+    DROP COLUMN "shipping_zip_code",
+    DROP COLUMN "shipping_state",
+    DROP COLUMN "shipping_city",
+    DROP COLUMN "shipping_street_number",
+    DROP COLUMN "shipping_street_name",
+    DROP COLUMN "shipping_name";
+DROP INDEX "idx_addresses_user_default";
+DROP INDEX "idx_addresses_user";
+DROP TABLE "addresses";
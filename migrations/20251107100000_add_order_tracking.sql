@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE "orders" ADD COLUMN "carrier" VARCHAR(30);
+
+ALTER TABLE "orders" DROP CONSTRAINT "orders_status_check";
+ALTER TABLE "orders" ADD CONSTRAINT "orders_status_check" CHECK ("status" IN('pending', 'processing', 'delivered', 'completed', 'cancelled'));
+
+-- The tracking poller looks up shipped orders that are not delivered yet.
+CREATE INDEX "idx_orders_in_transit" ON "orders"("shipped_at") WHERE "tracking_number" IS NOT NULL AND "delivered_at" IS NULL;
+
+-- Buyers are notified when the carrier reports a delivery.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('order_delivered');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received'));
+
+DROP INDEX "idx_orders_in_transit";
+
+UPDATE orders SET status = 'completed' WHERE status = 'delivered';
+ALTER TABLE "orders" DROP CONSTRAINT "orders_status_check";
+ALTER TABLE "orders" ADD CONSTRAINT "orders_status_check" CHECK ("status" IN('pending', 'processing', 'completed', 'cancelled'));
+
+ALTER TABLE "orders" DROP COLUMN "carrier";
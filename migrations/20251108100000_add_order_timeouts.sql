@@ -0,0 +1,24 @@
+-- +goose Up
+-- "paid_at" is set when an order moves to processing. The scheduler uses it to
+-- remind sellers of paid orders they have not shipped, once per order.
+ALTER TABLE "orders"
+    ADD COLUMN "paid_at" TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN "seller_reminded_at" TIMESTAMP WITH TIME ZONE;
+
+UPDATE orders SET paid_at = updated_at WHERE status <> 'pending' AND status <> 'cancelled';
+
+CREATE INDEX "idx_orders_pending_created" ON "orders"("created_at") WHERE "status" = 'pending';
+
+-- Sellers are reminded of paid orders they have not shipped yet.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('shipping_reminder');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered'));
+
+DROP INDEX "idx_orders_pending_created";
+ALTER TABLE "orders"
+    DROP COLUMN "seller_reminded_at",
+    DROP COLUMN "paid_at";
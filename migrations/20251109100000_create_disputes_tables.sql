@@ -0,0 +1,70 @@
+-- +goose Up
+ALTER TABLE "users" ADD COLUMN "is_admin" BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE "orders" ADD COLUMN "refunded_amount" DECIMAL(10, 2) NOT NULL DEFAULT 0 CHECK ("refunded_amount" >= 0);
+
+ALTER TABLE "orders" DROP CONSTRAINT "orders_status_check";
+ALTER TABLE "orders" ADD CONSTRAINT "orders_status_check" CHECK ("status" IN('pending', 'processing', 'delivered', 'completed', 'cancelled', 'refunded'));
+
+-- An order has at most one dispute. "refund_amount" and "restocked" record the
+-- outcome once the dispute is resolved.
+CREATE TABLE "disputes"(
+    "dispute_id" SERIAL PRIMARY KEY,
+    "order_id" INTEGER UNIQUE NOT NULL REFERENCES "orders"("order_id") ON DELETE CASCADE,
+    "reason" VARCHAR(30) CHECK ("reason" IN('not_received', 'not_as_described', 'damaged', 'wrong_item', 'other')) NOT NULL,
+    "description" TEXT NOT NULL,
+    "status" VARCHAR(20) CHECK ("status" IN('open', 'seller_responded', 'escalated', 'resolved')) NOT NULL DEFAULT 'open',
+    "resolution" VARCHAR(20) CHECK ("resolution" IN('full_refund', 'partial_refund', 'no_refund')),
+    "refund_amount" DECIMAL(10, 2) CHECK ("refund_amount" >= 0),
+    "restocked" BOOLEAN NOT NULL DEFAULT FALSE,
+    "resolved_by" INTEGER REFERENCES "users"("user_id") ON DELETE SET NULL,
+    "resolved_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (("status" = 'resolved') = ("resolution" IS NOT NULL))
+);
+
+CREATE INDEX "idx_disputes_status" ON "disputes"("status");
+
+CREATE TABLE "dispute_messages"(
+    "dispute_message_id" SERIAL PRIMARY KEY,
+    "dispute_id" INTEGER NOT NULL REFERENCES "disputes"("dispute_id") ON DELETE CASCADE,
+    "sender_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "body" TEXT NOT NULL CHECK (LENGTH("body") > 0),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_dispute_messages_dispute" ON "dispute_messages"("dispute_id", "created_at");
+
+CREATE TABLE "dispute_evidence"(
+    "dispute_evidence_id" SERIAL PRIMARY KEY,
+    "dispute_id" INTEGER NOT NULL REFERENCES "disputes"("dispute_id") ON DELETE CASCADE,
+    "uploaded_by" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "image_url" VARCHAR(500) NOT NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_dispute_evidence_dispute" ON "dispute_evidence"("dispute_id");
+
+-- Both parties of a dispute are notified as it progresses.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('dispute_opened', 'dispute_updated', 'dispute_resolved');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder'));
+
+DROP INDEX "idx_dispute_evidence_dispute";
+DROP TABLE "dispute_evidence";
+DROP INDEX "idx_dispute_messages_dispute";
+DROP TABLE "dispute_messages";
+DROP INDEX "idx_disputes_status";
+DROP TABLE "disputes";
+
+UPDATE orders SET status = 'cancelled' WHERE status = 'refunded';
+ALTER TABLE "orders" DROP CONSTRAINT "orders_status_check";
+ALTER TABLE "orders" ADD CONSTRAINT "orders_status_check" CHECK ("status" IN('pending', 'processing', 'delivered', 'completed', 'cancelled'));
+
+ALTER TABLE "orders" DROP COLUMN "refunded_amount";
+ALTER TABLE "users" DROP COLUMN "is_admin";
@@ -0,0 +1,95 @@
+-- +goose Up
+-- Sellers settle in the currency of their country.
+ALTER TABLE "countries" ADD COLUMN "currency_code" CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE countries SET currency_code = CASE country_code
+    WHEN 'US' THEN 'USD'
+    WHEN 'GB' THEN 'GBP'
+    WHEN 'JP' THEN 'JPY'
+    WHEN 'CA' THEN 'CAD'
+    ELSE 'EUR'
+END;
+
+-- Units of each currency worth one euro.
+CREATE TABLE "exchange_rates"(
+    "currency_code" CHAR(3) PRIMARY KEY,
+    "rate" DECIMAL(18, 8) NOT NULL CHECK ("rate" > 0),
+    "source" VARCHAR(50) NOT NULL,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO exchange_rates (currency_code, rate, source) VALUES
+('EUR', 1, 'seed'),
+('USD', 1.08, 'seed'),
+('GBP', 0.85, 'seed'),
+('JPY', 162.5, 'seed'),
+('CAD', 1.47, 'seed');
+
+-- Prices and order amounts are in the seller's currency. Existing amounts
+-- were in euros and are converted at the seeded rates, rounded to the minor
+-- units of the currency.
+ALTER TABLE "products" ADD COLUMN "currency" CHAR(3) NOT NULL DEFAULT 'EUR';
+ALTER TABLE "orders" ADD COLUMN "currency" CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE products p SET
+    currency = c.currency_code,
+    price = ROUND(p.price * er.rate, CASE WHEN c.currency_code IN ('JPY', 'KRW') THEN 0 ELSE 2 END)
+FROM users u
+JOIN countries c ON u.country_id = c.country_id
+JOIN exchange_rates er ON er.currency_code = c.currency_code
+WHERE p.seller_id = u.user_id AND c.currency_code <> 'EUR';
+
+UPDATE orders o SET
+    currency = p.currency,
+    shipping_cost = ROUND(o.shipping_cost * er.rate, CASE WHEN p.currency IN ('JPY', 'KRW') THEN 0 ELSE 2 END),
+    total_amount = ROUND(o.total_amount * er.rate, CASE WHEN p.currency IN ('JPY', 'KRW') THEN 0 ELSE 2 END),
+    refunded_amount = ROUND(o.refunded_amount * er.rate, CASE WHEN p.currency IN ('JPY', 'KRW') THEN 0 ELSE 2 END)
+FROM products p
+JOIN exchange_rates er ON er.currency_code = p.currency
+WHERE o.product_id = p.product_id AND p.currency <> 'EUR';
+
+UPDATE disputes d SET refund_amount = ROUND(d.refund_amount * er.rate, CASE WHEN o.currency IN ('JPY', 'KRW') THEN 0 ELSE 2 END)
+FROM orders o
+JOIN exchange_rates er ON er.currency_code = o.currency
+WHERE d.order_id = o.order_id AND d.refund_amount IS NOT NULL AND o.currency <> 'EUR';
+
+-- Shipping rates are in the currency of the origin country, so that their
+-- bands compare against order values of the sellers shipping from there.
+ALTER TABLE "shipping_rates" ADD COLUMN "currency" CHAR(3) NOT NULL DEFAULT 'EUR';
+
+UPDATE shipping_rates sr SET
+    currency = c.currency_code,
+    max_order_value = ROUND(sr.max_order_value * er.rate, CASE WHEN c.currency_code IN ('JPY', 'KRW') THEN 0 ELSE 2 END),
+    price = ROUND(sr.price * er.rate, CASE WHEN c.currency_code IN ('JPY', 'KRW') THEN 0 ELSE 2 END)
+FROM countries c
+JOIN exchange_rates er ON er.currency_code = c.currency_code
+WHERE sr.origin_country_id = c.country_id AND c.currency_code <> 'EUR';
+
+-- +goose Down
+UPDATE shipping_rates sr SET
+    max_order_value = ROUND(sr.max_order_value / er.rate, 2),
+    price = ROUND(sr.price / er.rate, 2)
+FROM exchange_rates er
+WHERE er.currency_code = sr.currency AND sr.currency <> 'EUR';
+ALTER TABLE "shipping_rates" DROP COLUMN "currency";
+
+UPDATE disputes d SET refund_amount = ROUND(d.refund_amount / er.rate, 2)
+FROM orders o
+JOIN exchange_rates er ON er.currency_code = o.currency
+WHERE d.order_id = o.order_id AND d.refund_amount IS NOT NULL AND o.currency <> 'EUR';
+
+UPDATE orders o SET
+    shipping_cost = ROUND(o.shipping_cost / er.rate, 2),
+    total_amount = ROUND(o.total_amount / er.rate, 2),
+    refunded_amount = ROUND(o.refunded_amount / er.rate, 2)
+FROM exchange_rates er
+WHERE er.currency_code = o.currency AND o.currency <> 'EUR';
+
+UPDATE products p SET price = ROUND(p.price / er.rate, 2)
+FROM exchange_rates er
+WHERE er.currency_code = p.currency AND p.currency <> 'EUR';
+
+DROP TABLE "exchange_rates";
+ALTER TABLE "orders" DROP COLUMN "currency";
+ALTER TABLE "products" DROP COLUMN "currency";
+ALTER TABLE "countries" DROP COLUMN "currency_code";
@@ -0,0 +1,53 @@
+-- +goose Up
+-- Sales between EU member states are taxed at the rate of the buyer's country.
+ALTER TABLE "countries" ADD COLUMN "is_eu" BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE countries SET is_eu = TRUE WHERE country_code IN ('DE', 'FR', 'IT', 'ES', 'NL');
+
+-- Rates in basis points (1900 is 19%), valid from effective_from until, but
+-- not including, effective_to.
+CREATE TABLE "tax_rates"(
+    "tax_rate_id" SERIAL PRIMARY KEY,
+    "country_id" INTEGER NOT NULL REFERENCES "countries"("country_id") ON DELETE CASCADE,
+    "rate_basis_points" INTEGER NOT NULL CHECK ("rate_basis_points" BETWEEN 0 AND 10000),
+    "effective_from" DATE NOT NULL,
+    "effective_to" DATE CHECK ("effective_to" > "effective_from"),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("country_id", "effective_from")
+);
+
+INSERT INTO tax_rates (country_id, rate_basis_points, effective_from, effective_to)
+SELECT c.country_id, r.rate_basis_points, r.effective_from::DATE, r.effective_to::DATE
+FROM countries c
+JOIN (VALUES
+    ('DE', 1900, '2007-01-01', '2020-07-01'),
+    ('DE', 1600, '2020-07-01', '2021-01-01'),
+    ('DE', 1900, '2021-01-01', NULL),
+    ('FR', 2000, '2014-01-01', NULL),
+    ('IT', 2200, '2013-10-01', NULL),
+    ('ES', 2100, '2012-09-01', NULL),
+    ('NL', 2100, '2012-10-01', NULL),
+    ('GB', 2000, '2011-01-04', NULL),
+    ('JP', 1000, '2019-10-01', NULL),
+    ('CA', 500, '2008-01-01', NULL)
+) AS r(country_code, rate_basis_points, effective_from, effective_to) ON c.country_code = r.country_code;
+
+ALTER TABLE "orders" ADD COLUMN "tax_amount" DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE "order_tax_lines"(
+    "order_tax_line_id" SERIAL PRIMARY KEY,
+    "order_id" INTEGER NOT NULL REFERENCES "orders"("order_id") ON DELETE CASCADE,
+    "kind" VARCHAR(20) NOT NULL CHECK ("kind" IN ('item', 'shipping')),
+    "country_id" INTEGER NOT NULL REFERENCES "countries"("country_id"),
+    "rate_basis_points" INTEGER NOT NULL,
+    "taxable_amount" DECIMAL(10, 2) NOT NULL,
+    "tax_amount" DECIMAL(10, 2) NOT NULL
+);
+
+CREATE INDEX "idx_order_tax_lines_order" ON "order_tax_lines"("order_id");
+
+-- +goose Down
+DROP TABLE "order_tax_lines";
+ALTER TABLE "orders" DROP COLUMN "tax_amount";
+DROP TABLE "tax_rates";
+ALTER TABLE "countries" DROP COLUMN "is_eu";
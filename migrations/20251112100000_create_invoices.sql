@@ -0,0 +1,64 @@
+-- +goose Up
+-- The unit price paid per item, printed on the invoice. Existing orders take
+-- it from what is left of the total after shipping and tax.
+ALTER TABLE "orders" ADD COLUMN "unit_price" DECIMAL(10, 2);
+UPDATE "orders" SET "unit_price" = ROUND(("total_amount" - "shipping_cost" - "tax_amount") / "quantity", 2);
+ALTER TABLE "orders" ALTER COLUMN "unit_price" SET NOT NULL;
+
+-- The last invoice number handed out per seller. Numbers are taken in the
+-- same transaction that stores the invoice, so they have no gaps and are
+-- never reused.
+CREATE TABLE "invoice_counters"(
+    "seller_id" INTEGER PRIMARY KEY REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "last_number" INTEGER NOT NULL CHECK ("last_number" > 0)
+);
+
+-- Invoices outlive deleted orders so their numbers stay accounted for. The
+-- seller's and buyer's names and addresses are copied when the invoice is
+-- issued, so later profile changes do not alter issued invoices.
+CREATE TABLE "invoices"(
+    "invoice_id" SERIAL PRIMARY KEY,
+    "order_id" INTEGER UNIQUE REFERENCES "orders"("order_id") ON DELETE SET NULL,
+    "seller_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "invoice_number" INTEGER NOT NULL,
+    "issued_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "seller_type" VARCHAR(20) NOT NULL,
+    "seller_name" VARCHAR(200) NOT NULL,
+    "seller_street_name" VARCHAR(255) NOT NULL,
+    "seller_street_number" VARCHAR(20) NOT NULL,
+    "seller_city" VARCHAR(100) NOT NULL,
+    "seller_state" VARCHAR(100) NOT NULL,
+    "seller_zip_code" VARCHAR(20) NOT NULL,
+    "seller_country" VARCHAR(100) NOT NULL,
+    "buyer_name" VARCHAR(200) NOT NULL,
+    "buyer_street_name" VARCHAR(255) NOT NULL,
+    "buyer_street_number" VARCHAR(20) NOT NULL,
+    "buyer_city" VARCHAR(100) NOT NULL,
+    "buyer_state" VARCHAR(100) NOT NULL,
+    "buyer_zip_code" VARCHAR(20) NOT NULL,
+    "buyer_country" VARCHAR(100) NOT NULL,
+    UNIQUE ("seller_id", "invoice_number")
+);
+
+-- Orders completed before invoicing existed are invoiced in order of their
+-- IDs.
+INSERT INTO "invoices" (order_id, seller_id, invoice_number, seller_type,
+    seller_name, seller_street_name, seller_street_number, seller_city, seller_state, seller_zip_code, seller_country,
+    buyer_name, buyer_street_name, buyer_street_number, buyer_city, buyer_state, buyer_zip_code, buyer_country)
+SELECT o.order_id, o.seller_id, ROW_NUMBER() OVER (PARTITION BY o.seller_id ORDER BY o.order_id), sellers.seller_type,
+    sellers.first_name || ' ' || sellers.last_name, sellers.street_name, sellers.street_number, sellers.city, sellers.state, sellers.zip_code, seller_countries.country_name,
+    buyers.first_name || ' ' || buyers.last_name, buyers.street_name, buyers.street_number, buyers.city, buyers.state, buyers.zip_code, buyer_countries.country_name
+FROM orders o
+JOIN users sellers ON o.seller_id = sellers.user_id
+JOIN countries seller_countries ON sellers.country_id = seller_countries.country_id
+JOIN users buyers ON o.buyer_id = buyers.user_id
+JOIN countries buyer_countries ON buyers.country_id = buyer_countries.country_id
+WHERE o.status = 'completed';
+
+INSERT INTO "invoice_counters" (seller_id, last_number)
+SELECT seller_id, MAX(invoice_number) FROM invoices GROUP BY seller_id;
+
+-- +goose Down
+DROP TABLE "invoices";
+DROP TABLE "invoice_counters";
+ALTER TABLE "orders" DROP COLUMN "unit_price";
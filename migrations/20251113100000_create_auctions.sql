@@ -0,0 +1,51 @@
+-- +goose Up
+-- An auction sells one copy of a product. The copy is taken out of the
+-- product's stock while the auction runs and put back if it ends unsold.
+CREATE TABLE "auctions"(
+    "auction_id" SERIAL PRIMARY KEY,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "seller_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "currency" CHAR(3) NOT NULL,
+    "start_price" DECIMAL(10, 2) NOT NULL CHECK ("start_price" > 0),
+    "reserve_price" DECIMAL(10, 2) CHECK ("reserve_price" >= "start_price"),
+    "bid_increment" DECIMAL(10, 2) NOT NULL CHECK ("bid_increment" > 0),
+    -- The leader's maximum is hidden from other bidders; the current price
+    -- is what they see.
+    "current_price" DECIMAL(10, 2) NOT NULL,
+    "leader_id" INTEGER REFERENCES "users"("user_id") ON DELETE SET NULL,
+    "max_bid" DECIMAL(10, 2),
+    "bid_count" INTEGER NOT NULL DEFAULT 0,
+    "ends_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'active' CHECK ("status" IN ('active', 'sold', 'unsold', 'cancelled')),
+    "order_id" INTEGER REFERENCES "orders"("order_id") ON DELETE SET NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_auctions_active_ends_at" ON "auctions"("ends_at") WHERE "status" = 'active';
+CREATE INDEX "idx_auctions_seller" ON "auctions"("seller_id");
+
+-- Bids record each bidder's maximum and where the card goes if they win.
+CREATE TABLE "bids"(
+    "bid_id" SERIAL PRIMARY KEY,
+    "auction_id" INTEGER NOT NULL REFERENCES "auctions"("auction_id") ON DELETE CASCADE,
+    "bidder_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "max_amount" DECIMAL(10, 2) NOT NULL CHECK ("max_amount" > 0),
+    "address_id" INTEGER REFERENCES "addresses"("address_id") ON DELETE SET NULL,
+    "shipping_method_id" INTEGER NOT NULL REFERENCES "shipping_methods"("shipping_method_id"),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_bids_auction" ON "bids"("auction_id", "bidder_id");
+
+-- Bidders and sellers are notified of bids and closed auctions.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('outbid', 'auction_won', 'auction_ended');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved'));
+
+DROP TABLE "bids";
+DROP TABLE "auctions";
@@ -0,0 +1,38 @@
+-- +goose Up
+-- Offers to buy a product below its asking price. A counter-offer closes the
+-- offer it answers and refers back to it, so a negotiation is a chain of
+-- offers of which at most one is pending.
+CREATE TABLE "offers"(
+    "offer_id" SERIAL PRIMARY KEY,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "buyer_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "seller_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "offered_by" VARCHAR(10) NOT NULL CHECK ("offered_by" IN ('buyer', 'seller')),
+    "parent_offer_id" INTEGER REFERENCES "offers"("offer_id") ON DELETE SET NULL,
+    -- The price per card, in the currency of the product.
+    "amount" DECIMAL(10, 2) NOT NULL CHECK ("amount" > 0),
+    "currency" CHAR(3) NOT NULL,
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    "address_id" INTEGER REFERENCES "addresses"("address_id") ON DELETE SET NULL,
+    "shipping_method_id" INTEGER NOT NULL REFERENCES "shipping_methods"("shipping_method_id"),
+    "status" VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'accepted', 'declined', 'countered', 'expired')),
+    "expires_at" TIMESTAMP WITH TIME ZONE NOT NULL,
+    "order_id" INTEGER REFERENCES "orders"("order_id") ON DELETE SET NULL,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_offers_buyer" ON "offers"("buyer_id");
+CREATE INDEX "idx_offers_seller" ON "offers"("seller_id");
+CREATE INDEX "idx_offers_pending_expires_at" ON "offers"("expires_at") WHERE "status" = 'pending';
+
+-- Sellers and buyers are notified of offers and counter-offers.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('offer_received', 'offer_updated');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended'));
+
+DROP TABLE "offers";
@@ -0,0 +1,70 @@
+-- +goose Up
+-- A trade proposes to swap cards from the products of two users, optionally
+-- with cash from one of them. Products that are not for sale count as the
+-- owner's collection and can be traded too. A counter-proposal closes the
+-- proposal it answers and refers back to it. An accepted trade on which
+-- nothing was shipped in time is cancelled and its cards are put back.
+CREATE TABLE "trades"(
+    "trade_id" SERIAL PRIMARY KEY,
+    "proposer_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "recipient_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "parent_trade_id" INTEGER REFERENCES "trades"("trade_id") ON DELETE SET NULL,
+    "proposer_address_id" INTEGER REFERENCES "addresses"("address_id") ON DELETE SET NULL,
+    -- Cash is in the currency of the user receiving it.
+    "cash_amount" DECIMAL(10, 2) CHECK ("cash_amount" > 0),
+    "cash_currency" CHAR(3),
+    "cash_payer_id" INTEGER REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "status" VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK ("status" IN ('pending', 'accepted', 'declined', 'countered', 'completed', 'cancelled')),
+    "accepted_at" TIMESTAMP WITH TIME ZONE,
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ("proposer_id" <> "recipient_id"),
+    CHECK (("cash_amount" IS NULL) = ("cash_payer_id" IS NULL))
+);
+
+CREATE INDEX "idx_trades_proposer" ON "trades"("proposer_id");
+CREATE INDEX "idx_trades_recipient" ON "trades"("recipient_id");
+
+CREATE TABLE "trade_items"(
+    "trade_item_id" SERIAL PRIMARY KEY,
+    "trade_id" INTEGER NOT NULL REFERENCES "trades"("trade_id") ON DELETE CASCADE,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "owner_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0)
+);
+
+CREATE INDEX "idx_trade_items_trade" ON "trade_items"("trade_id");
+
+-- An accepted trade ships in both directions. Each shipment keeps a copy of
+-- the address it goes to, like orders do.
+CREATE TABLE "trade_shipments"(
+    "trade_shipment_id" SERIAL PRIMARY KEY,
+    "trade_id" INTEGER NOT NULL REFERENCES "trades"("trade_id") ON DELETE CASCADE,
+    "sender_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "receiver_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "shipping_name" VARCHAR(200) NOT NULL,
+    "shipping_street_name" VARCHAR(255) NOT NULL,
+    "shipping_street_number" VARCHAR(20) NOT NULL,
+    "shipping_city" VARCHAR(100) NOT NULL,
+    "shipping_state" VARCHAR(100) NOT NULL,
+    "shipping_zip_code" VARCHAR(20) NOT NULL,
+    "shipping_country_id" INTEGER NOT NULL REFERENCES "countries"("country_id"),
+    "carrier" VARCHAR(30),
+    "tracking_number" VARCHAR(50),
+    "shipped_at" TIMESTAMP WITH TIME ZONE,
+    "received_at" TIMESTAMP WITH TIME ZONE,
+    UNIQUE ("trade_id", "sender_id")
+);
+
+-- Both parties of a trade are notified of proposals and answers.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated', 'trade_received', 'trade_updated'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('trade_received', 'trade_updated');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated'));
+
+DROP TABLE "trade_shipments";
+DROP TABLE "trade_items";
+DROP TABLE "trades";
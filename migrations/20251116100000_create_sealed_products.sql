@@ -0,0 +1,42 @@
+-- +goose Up
+-- Sealed products are the catalog entries for booster packs, boxes, elite
+-- trainer boxes and preconstructed decks of a game and set, next to the
+-- singles in cards.
+CREATE TABLE "sealed_products"(
+    "sealed_product_id" SERIAL PRIMARY KEY,
+    "name" VARCHAR(255) NOT NULL,
+    "product_type" VARCHAR(30) NOT NULL CHECK ("product_type" IN ('booster', 'booster_box', 'elite_trainer_box', 'precon_deck', 'bundle', 'other')),
+    "set_name" VARCHAR(100),
+    "image_url" VARCHAR(500),
+    "description" TEXT,
+    "tcg_game_id" INTEGER NOT NULL REFERENCES "tcg_games"("tcg_game_id"),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_sealed_products_tcg_game" ON "sealed_products"("tcg_game_id", "set_name");
+
+-- A product lists either a single card or a sealed product. Sealed products
+-- have their own conditions.
+ALTER TABLE "products"
+    ALTER COLUMN "card_id" DROP NOT NULL,
+    ADD COLUMN "sealed_product_id" INTEGER REFERENCES "sealed_products"("sealed_product_id"),
+    DROP CONSTRAINT "products_condition_check",
+    ADD CONSTRAINT "products_item_check" CHECK (num_nonnulls("card_id", "sealed_product_id") = 1),
+    ADD CONSTRAINT "products_condition_check" CHECK (
+        ("card_id" IS NOT NULL AND "condition" IN ('mint', 'near mint', 'excellent', 'good', 'light_played', 'played', 'poor'))
+        OR ("sealed_product_id" IS NOT NULL AND "condition" IN ('sealed', 'opened', 'damaged box'))
+    );
+
+CREATE INDEX "idx_products_sealed_product" ON "products"("sealed_product_id");
+
+-- +goose Down
+DROP INDEX "idx_products_sealed_product";
+DELETE FROM "products" WHERE "sealed_product_id" IS NOT NULL;
+ALTER TABLE "products"
+    DROP CONSTRAINT "products_condition_check",
+    DROP CONSTRAINT "products_item_check",
+    DROP COLUMN "sealed_product_id",
+    ALTER COLUMN "card_id" SET NOT NULL,
+    ADD CONSTRAINT "products_condition_check" CHECK ("condition" IN ('mint', 'near mint', 'excellent', 'good', 'light_played', 'played', 'poor'));
+DROP TABLE "sealed_products";
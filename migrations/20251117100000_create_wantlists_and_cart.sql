@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE "wantlists"(
+    "wantlist_id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "name" VARCHAR(100) NOT NULL,
+    "tcg_game_id" INTEGER NOT NULL REFERENCES "tcg_games"("tcg_game_id"),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    "updated_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX "idx_wantlists_user" ON "wantlists"("user_id");
+
+CREATE TABLE "wantlist_items"(
+    "wantlist_item_id" SERIAL PRIMARY KEY,
+    "wantlist_id" INTEGER NOT NULL REFERENCES "wantlists"("wantlist_id") ON DELETE CASCADE,
+    "card_id" INTEGER NOT NULL REFERENCES "cards"("card_id") ON DELETE CASCADE,
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    UNIQUE ("wantlist_id", "card_id")
+);
+
+-- The cart holds the listings a buyer means to order. Stock is only
+-- reserved when the order is placed.
+CREATE TABLE "cart_items"(
+    "cart_item_id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "quantity" INTEGER NOT NULL CHECK ("quantity" > 0),
+    "added_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE ("user_id", "product_id")
+);
+
+-- +goose Down
+DROP TABLE "cart_items";
+DROP TABLE "wantlist_items";
+DROP TABLE "wantlists";
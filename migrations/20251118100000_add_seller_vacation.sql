@@ -0,0 +1,6 @@
+-- +goose Up
+-- Sellers on vacation keep their products, but they are not listed.
+ALTER TABLE "users" ADD COLUMN "on_vacation" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE "users" DROP COLUMN "on_vacation";
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Vacations with a return date end automatically once it has passed.
+ALTER TABLE "users" ADD COLUMN "vacation_until" TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX "idx_users_vacation_until" ON "users"("vacation_until") WHERE "on_vacation";
+
+-- Sellers are notified when their vacation ends on schedule.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated', 'trade_received', 'trade_updated', 'vacation_ended'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('vacation_ended');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated', 'trade_received', 'trade_updated'));
+
+DROP INDEX "idx_users_vacation_until";
+
+ALTER TABLE "users" DROP COLUMN "vacation_until";
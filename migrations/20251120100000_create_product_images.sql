@@ -0,0 +1,20 @@
+-- +goose Up
+-- Images uploaded by sellers, such as scans of the actual card. The files
+-- live in the object store under the stored keys.
+CREATE TABLE "product_images"(
+    "product_image_id" SERIAL PRIMARY KEY,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "storage_key" VARCHAR(255) NOT NULL,
+    "thumbnail_key" VARCHAR(255) NOT NULL,
+    "content_type" VARCHAR(50) NOT NULL,
+    "width" INTEGER NOT NULL CHECK ("width" > 0),
+    "height" INTEGER NOT NULL CHECK ("height" > 0),
+    "size_bytes" INTEGER NOT NULL CHECK ("size_bytes" > 0),
+    "position" INTEGER NOT NULL CHECK ("position" > 0),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- Deferred so that reordering can swap positions within a transaction.
+    UNIQUE ("product_id", "position") DEFERRABLE INITIALLY DEFERRED
+);
+
+-- +goose Down
+DROP TABLE "product_images";
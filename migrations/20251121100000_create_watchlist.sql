@@ -0,0 +1,42 @@
+-- +goose Up
+-- Buyers watch a listing for price drops or a card for new listings,
+-- optionally only at or below a price.
+CREATE TABLE "watchlist_entries"(
+    "watchlist_entry_id" SERIAL PRIMARY KEY,
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "product_id" INTEGER REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "card_id" INTEGER REFERENCES "cards"("card_id") ON DELETE CASCADE,
+    "max_price" DECIMAL(10, 2) CHECK ("max_price" > 0),
+    "max_price_currency" CHAR(3),
+    -- The price of the watched product when it was last checked.
+    "last_price" DECIMAL(10, 2),
+    "created_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT "watchlist_entries_item_check" CHECK (num_nonnulls("product_id", "card_id") = 1),
+    CONSTRAINT "watchlist_entries_max_price_check" CHECK (("max_price" IS NULL) = ("max_price_currency" IS NULL)),
+    UNIQUE ("user_id", "product_id"),
+    UNIQUE ("user_id", "card_id")
+);
+
+CREATE INDEX "idx_watchlist_entries_product" ON "watchlist_entries"("product_id") WHERE "product_id" IS NOT NULL;
+CREATE INDEX "idx_watchlist_entries_card" ON "watchlist_entries"("card_id") WHERE "card_id" IS NOT NULL;
+
+-- Listings of a watched card the watcher already knows about: announced by
+-- an alert, or matching when the card was watched.
+CREATE TABLE "watchlist_seen_products"(
+    "watchlist_entry_id" INTEGER NOT NULL REFERENCES "watchlist_entries"("watchlist_entry_id") ON DELETE CASCADE,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "seen_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("watchlist_entry_id", "product_id")
+);
+
+-- Users are notified of price drops and new listings on their watchlist.
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated', 'trade_received', 'trade_updated', 'vacation_ended', 'watchlist_alert'));
+
+-- +goose Down
+DELETE FROM notifications WHERE type IN ('watchlist_alert');
+ALTER TABLE "notifications" DROP CONSTRAINT "notifications_type_check";
+ALTER TABLE "notifications" ADD CONSTRAINT "notifications_type_check" CHECK ("type" IN ('order_created', 'order_status_changed', 'new_message', 'wantlist_match', 'review_received', 'order_delivered', 'shipping_reminder', 'dispute_opened', 'dispute_updated', 'dispute_resolved', 'outbid', 'auction_won', 'auction_ended', 'offer_received', 'offer_updated', 'trade_received', 'trade_updated', 'vacation_ended'));
+
+DROP TABLE "watchlist_seen_products";
+DROP TABLE "watchlist_entries";
@@ -0,0 +1,9 @@
+-- +goose Up
+-- The card overview counts the cards of a set and averages the sales of a
+-- card's products.
+CREATE INDEX "idx_cards_set" ON "cards"("tcg_game_id", "set_name");
+CREATE INDEX "idx_orders_product" ON "orders"("product_id");
+
+-- +goose Down
+DROP INDEX "idx_orders_product";
+DROP INDEX "idx_cards_set";
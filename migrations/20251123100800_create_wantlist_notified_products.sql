@@ -0,0 +1,12 @@
+-- +goose Up
+-- Listings a user was told match one of their wantlists, so a listing is
+-- only announced once however often it is changed.
+CREATE TABLE "wantlist_notified_products"(
+    "user_id" INTEGER NOT NULL REFERENCES "users"("user_id") ON DELETE CASCADE,
+    "product_id" INTEGER NOT NULL REFERENCES "products"("product_id") ON DELETE CASCADE,
+    "notified_at" TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("user_id", "product_id")
+);
+
+-- +goose Down
+DROP TABLE "wantlist_notified_products";
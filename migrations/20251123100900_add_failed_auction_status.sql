@@ -0,0 +1,10 @@
+-- +goose Up
+-- An auction fails when its winner's order cannot be created. The copy goes
+-- back in stock as for unsold auctions.
+ALTER TABLE "auctions" DROP CONSTRAINT "auctions_status_check";
+ALTER TABLE "auctions" ADD CONSTRAINT "auctions_status_check" CHECK ("status" IN ('active', 'sold', 'unsold', 'cancelled', 'failed'));
+
+-- +goose Down
+UPDATE "auctions" SET "status" = 'unsold' WHERE "status" = 'failed';
+ALTER TABLE "auctions" DROP CONSTRAINT "auctions_status_check";
+ALTER TABLE "auctions" ADD CONSTRAINT "auctions_status_check" CHECK ("status" IN ('active', 'sold', 'unsold', 'cancelled'));
@@ -0,0 +1,10 @@
+-- +goose Up
+-- The cash part of an accepted trade is paid by its payer like an order is
+-- paid by its buyer, and "cash_paid_at" records when. The party receiving
+-- the cash only ships once it is paid. Trades are not orders otherwise: they
+-- are not taxed or invoiced and cannot be disputed or refunded. Unpaid cash
+-- times out with the trade when nothing is shipped.
+ALTER TABLE "trades" ADD COLUMN "cash_paid_at" TIMESTAMP WITH TIME ZONE;
+
+-- +goose Down
+ALTER TABLE "trades" DROP COLUMN "cash_paid_at";